@@ -14,7 +14,12 @@ import (
 var Cmd cli.Command
 
 func findPublishedTheme(c *cli.Context) (int64, error) {
-	themes, err := listThemes(cmd.NewGraphQLClient(c))
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return 0, err
+	}
+
+	themes, err := listThemes(client)
 	if err != nil {
 		return 0, err
 	}
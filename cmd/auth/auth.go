@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/pkg/browser"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+var Cmd cli.Command
+
+type loginResult struct {
+	token *gql.OAuthToken
+	err   error
+}
+
+// loginFlow obtains an access token using OAuth's authorization code grant.
+// open and exchange are replaced in tests with stand-ins for the browser and
+// Shopify.
+type loginFlow struct {
+	shop         string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	port         int
+	timeout      time.Duration
+	open         func(url string) error
+	exchange     func(shop, clientID, clientSecret, code string) (*gql.OAuthToken, error)
+}
+
+func (f *loginFlow) run() (*gql.OAuthToken, error) {
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", f.port))
+	if err != nil {
+		return nil, fmt.Errorf("Cannot start OAuth callback server: %s", err)
+	}
+
+	result := make(chan loginResult, 1)
+	server := &http.Server{Handler: f.callbackHandler(state, result)}
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	redirectURI := fmt.Sprintf("http://localhost:%d/callback", listener.Addr().(*net.TCPAddr).Port)
+	authorizeURL := gql.AuthorizeURL(f.shop, f.clientID, f.scopes, redirectURI, state)
+
+	fmt.Fprintf(os.Stderr, "Opening %s\nIf your browser does not open visit the above URL to continue\n", authorizeURL)
+	if err := f.open(authorizeURL); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open browser: %s\n", err)
+	}
+
+	select {
+	case r := <-result:
+		return r.token, r.err
+	case <-time.After(f.timeout):
+		return nil, fmt.Errorf("Timed out after %s waiting for authorization", f.timeout)
+	}
+}
+
+func (f *loginFlow) callbackHandler(state string, result chan<- loginResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		token, err := f.verifyCallback(r, state)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Authorization failed: %s", err), http.StatusBadRequest)
+		} else {
+			fmt.Fprintf(w, "Logged in to %s, you can close this window\n", f.shop)
+		}
+
+		// Only the first callback counts
+		select {
+		case result <- loginResult{token, err}:
+		default:
+		}
+	})
+
+	return mux
+}
+
+func (f *loginFlow) verifyCallback(r *http.Request, state string) (*gql.OAuthToken, error) {
+	query := r.URL.Query()
+
+	if !gql.VerifyHMAC(query, f.clientSecret) {
+		return nil, fmt.Errorf("HMAC verification failed")
+	}
+
+	if query.Get("state") != state {
+		return nil, fmt.Errorf("state does not match")
+	}
+
+	if query.Get("shop") != shopDomain(f.shop) {
+		return nil, fmt.Errorf("callback is for shop %s, expected %s", query.Get("shop"), shopDomain(f.shop))
+	}
+
+	if query.Get("code") == "" {
+		return nil, fmt.Errorf("callback has no authorization code")
+	}
+
+	return f.exchange(f.shop, f.clientID, f.clientSecret, query.Get("code"))
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("Cannot generate OAuth state: %s", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func shopDomain(shop string) string {
+	shop = strings.ToLower(shop)
+	if strings.HasSuffix(shop, ".myshopify.com") {
+		return shop
+	}
+
+	return shop + ".myshopify.com"
+}
+
+func loginAction(c *cli.Context) error {
+	flow := &loginFlow{
+		shop:         c.String("shop"),
+		clientID:     c.String("client-id"),
+		clientSecret: c.String("client-secret"),
+		scopes:       c.StringSlice("scopes"),
+		port:         c.Int("port"),
+		timeout:      c.Duration("timeout"),
+		open:         browser.OpenURL,
+		exchange:     gql.ExchangeAuthorizationCode,
+	}
+
+	token, err := flow.run()
+	if err != nil {
+		return err
+	}
+
+	credentials, err := cmd.LoadCredentials()
+	if err != nil {
+		return err
+	}
+
+	credentials.Set(flow.shop, &cmd.Credential{
+		AccessToken: token.AccessToken,
+		Scope:       token.Scope,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	})
+
+	if err := credentials.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Access token for %s saved with scopes %s\n", flow.shop, token.Scope)
+
+	return nil
+}
+
+func logoutAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("You must supply a shop")
+	}
+
+	credentials, err := cmd.LoadCredentials()
+	if err != nil {
+		return err
+	}
+
+	shop := c.Args().Get(0)
+	if !credentials.Delete(shop) {
+		return fmt.Errorf("No access token saved for %s", shop)
+	}
+
+	return credentials.Save()
+}
+
+func cacheListAction(c *cli.Context) error {
+	cache, err := cmd.LoadTokenCache()
+	if err != nil {
+		return err
+	}
+
+	t := tabby.New()
+	t.AddHeader("Shop", "Expires")
+
+	for _, token := range cache.Tokens() {
+		t.AddLine(token.Shop, token.ExpiresAt.Local().Format(time.RFC3339))
+	}
+
+	t.Print()
+
+	return nil
+}
+
+func cacheClearAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return cmd.ClearTokenCache()
+	}
+
+	cache, err := cmd.LoadTokenCache()
+	if err != nil {
+		return err
+	}
+
+	for _, shop := range c.Args().Slice() {
+		if !cache.Delete(shop) {
+			return fmt.Errorf("No access token cached for %s", shop)
+		}
+	}
+
+	return cache.Save()
+}
+
+func init() {
+	loginFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "shop",
+			Usage:    "Shopify domain or shop name to login to",
+			Required: true,
+			EnvVars:  []string{"SHOPIFY_SHOP"},
+		},
+		&cli.StringFlag{
+			Name:     "client-id",
+			Usage:    "The app's client ID (API key)",
+			Required: true,
+			EnvVars:  []string{"SHOPIFY_API_KEY"},
+		},
+		&cli.StringFlag{
+			Name:     "client-secret",
+			Usage:    "The app's client secret",
+			Required: true,
+			EnvVars:  []string{"SHOPIFY_API_SECRET"},
+		},
+		&cli.StringSliceFlag{
+			Name:     "scopes",
+			Usage:    "Comma separated access scopes to request, e.g., read_products,write_products",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "port",
+			Usage: "Port for the OAuth callback server; the app must allow http://localhost:PORT/callback as a redirect URL",
+			Value: 3456,
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "How long to wait for authorization",
+			Value: 5 * time.Minute,
+		},
+	}
+
+	Cmd = cli.Command{
+		Name:  "auth",
+		Usage: "Obtain and manage access tokens",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "login",
+				Usage:  "Obtain an access token by installing the app via OAuth and save it for use by other commands",
+				Flags:  loginFlags,
+				Action: loginAction,
+			},
+			{
+				Name:      "logout",
+				Usage:     "Remove the saved access token for the given shop",
+				ArgsUsage: "SHOP",
+				Action:    logoutAction,
+			},
+			{
+				Name:  "cache",
+				Usage: "Manage the access token command's cache",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "ls",
+						Usage:  "List the shops with cached access tokens",
+						Action: cacheListAction,
+					},
+					{
+						Name:      "clear",
+						Usage:     "Remove the cached access tokens for the given shops or, if none are given, all shops",
+						ArgsUsage: "[SHOP ...]",
+						Action:    cacheClearAction,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// shopify returns a stand-in for Shopify's authorize page: it "approves" the
+// request by redirecting to its redirect_uri with the given callback params,
+// signed using secret.
+func shopify(t *testing.T, secret string, params func(authorize url.Values) url.Values) func(string) error {
+	return func(authorizeURL string) error {
+		u, err := url.Parse(authorizeURL)
+		if err != nil {
+			t.Fatalf("cannot parse authorize URL: %s", err)
+		}
+
+		query := params(u.Query())
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(query.Encode()))
+		query.Set("hmac", hex.EncodeToString(mac.Sum(nil)))
+
+		resp, err := http.Get(u.Query().Get("redirect_uri") + "?" + query.Encode())
+		if err != nil {
+			t.Fatalf("callback request failed: %s", err)
+		}
+
+		resp.Body.Close()
+
+		return nil
+	}
+}
+
+func testFlow(open func(string) error) *loginFlow {
+	return &loginFlow{
+		shop:         "acme",
+		clientID:     "key",
+		clientSecret: "secret",
+		scopes:       []string{"read_products"},
+		timeout:      5 * time.Second,
+		open:         open,
+		exchange: func(shop, clientID, clientSecret, code string) (*gql.OAuthToken, error) {
+			return &gql.OAuthToken{AccessToken: "shpat_" + code, Scope: "read_products"}, nil
+		},
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	flow := testFlow(shopify(t, "secret", func(authorize url.Values) url.Values {
+		if got := authorize.Get("scope"); got != "read_products" {
+			t.Errorf("scope = %q, want %q", got, "read_products")
+		}
+
+		return url.Values{"code": {"abc"}, "shop": {"acme.myshopify.com"}, "state": {authorize.Get("state")}, "timestamp": {"1700000000"}}
+	}))
+
+	token, err := flow.run()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if token.AccessToken != "shpat_abc" {
+		t.Errorf("access token = %q, want %q", token.AccessToken, "shpat_abc")
+	}
+}
+
+func TestLoginFlowRejectsInvalidCallbacks(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		params  func(authorize url.Values) url.Values
+		wantErr string
+	}{
+		{
+			name:   "bad hmac",
+			secret: "wrong",
+			params: func(authorize url.Values) url.Values {
+				return url.Values{"code": {"abc"}, "shop": {"acme.myshopify.com"}, "state": {authorize.Get("state")}}
+			},
+			wantErr: "HMAC verification failed",
+		},
+		{
+			name:   "bad state",
+			secret: "secret",
+			params: func(authorize url.Values) url.Values {
+				return url.Values{"code": {"abc"}, "shop": {"acme.myshopify.com"}, "state": {"forged"}}
+			},
+			wantErr: "state does not match",
+		},
+		{
+			name:   "other shop",
+			secret: "secret",
+			params: func(authorize url.Values) url.Values {
+				return url.Values{"code": {"abc"}, "shop": {"evil.myshopify.com"}, "state": {authorize.Get("state")}}
+			},
+			wantErr: "callback is for shop evil.myshopify.com, expected acme.myshopify.com",
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := testFlow(shopify(t, tt.secret, tt.params)).run()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
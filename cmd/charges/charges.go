@@ -106,7 +106,10 @@ func createCharge(c *cli.Context) error {
 
 	returnURL := c.Args().Get(2)
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	if c.IsSet("interval") {
 		charge, err := createRecurringCharge(client, c.Args().Get(0), price.String(), c.Bool("test"), returnURL, c.String("interval"))
@@ -168,7 +171,10 @@ func cancelCharge(c *cli.Context) error {
 		return fmt.Errorf("You must supply at least one charge id")
 	}
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 	prorate := c.Bool("prorate")
 
 	for _, id := range ids {
@@ -187,7 +193,10 @@ func cancelCharge(c *cli.Context) error {
 func listOneTimeCharges(c *cli.Context, gids []string) error {
 	var charges []OneTimeCharge
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	if len(gids) > 0 {
 		byID, err := getOneTimeChargesByID(client, gids)
@@ -218,7 +227,10 @@ func listOneTimeCharges(c *cli.Context, gids []string) error {
 func listRecurringCharges(c *cli.Context, gids []string) error {
 	var charges []RecurringCharge
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	if len(gids) > 0 {
 		byID, err := getRecurringChargesByID(client, gids)
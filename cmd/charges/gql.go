@@ -1,7 +1,6 @@
 package charges
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -231,60 +230,26 @@ type oneTimeChargeNodeJSON struct {
 }
 
 type oneTimeChargeCreateResponse struct {
-	Data struct {
-		AppPurchaseOneTimeCreate struct {
-			AppPurchaseOneTime *oneTimeChargeNodeJSON `json:"appPurchaseOneTime"`
-			ConfirmationURL    string                 `json:"confirmationUrl"`
-			UserErrors         []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"appPurchaseOneTimeCreate"`
-	} `json:"data"`
+	AppPurchaseOneTimeCreate struct {
+		AppPurchaseOneTime *oneTimeChargeNodeJSON `json:"appPurchaseOneTime"`
+		ConfirmationURL    string                 `json:"confirmationUrl"`
+	} `json:"appPurchaseOneTimeCreate"`
 }
 
 type recurringChargeCreateResponse struct {
-	Data struct {
-		AppSubscriptionCreate struct {
-			AppSubscription *recurringChargeNodeJSON `json:"appSubscription"`
-			ConfirmationURL string                   `json:"confirmationUrl"`
-			UserErrors      []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"appSubscriptionCreate"`
-	} `json:"data"`
+	AppSubscriptionCreate struct {
+		AppSubscription *recurringChargeNodeJSON `json:"appSubscription"`
+		ConfirmationURL string                   `json:"confirmationUrl"`
+	} `json:"appSubscriptionCreate"`
 }
 
 type appSubscriptionCancelResponse struct {
-	Data struct {
-		AppSubscriptionCancel struct {
-			AppSubscription *struct {
-				ID     string `json:"id"`
-				Status string `json:"status"`
-			} `json:"appSubscription"`
-			UserErrors []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"appSubscriptionCancel"`
-	} `json:"data"`
-}
-
-type oneTimeChargesResponse struct {
-	Data struct {
-		CurrentAppInstallation struct {
-			OneTimePurchases struct {
-				Edges []struct {
-					Node oneTimeChargeNodeJSON `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"oneTimePurchases"`
-		} `json:"currentAppInstallation"`
-	} `json:"data"`
+	AppSubscriptionCancel struct {
+		AppSubscription *struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"appSubscription"`
+	} `json:"appSubscriptionCancel"`
 }
 
 type recurringChargeNodeJSON struct {
@@ -303,22 +268,6 @@ type recurringChargeNodeJSON struct {
 	} `json:"lineItems"`
 }
 
-type recurringChargesResponse struct {
-	Data struct {
-		CurrentAppInstallation struct {
-			AllSubscriptions struct {
-				Edges []struct {
-					Node recurringChargeNodeJSON `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"allSubscriptions"`
-		} `json:"currentAppInstallation"`
-	} `json:"data"`
-}
-
 type chargeNodeJSON struct {
 	Typename           string     `json:"__typename"`
 	ID                 string     `json:"id"`
@@ -339,17 +288,21 @@ type chargeNodeJSON struct {
 }
 
 func shopCurrencyCode(client *gql.Client) (string, error) {
-	data, err := client.Execute(shopCurrencyQuery)
-	if err != nil {
+	var response struct {
+		Shop struct {
+			CurrencyCode string `json:"currencyCode"`
+		} `json:"shop"`
+	}
+
+	if err := client.ExecuteInto(shopCurrencyQuery, nil, &response); err != nil {
 		return "", fmt.Errorf("Cannot get shop currency: %s", err)
 	}
 
-	values, _ := data.ValuesForPath("data.shop.currencyCode")
-	if len(values) == 0 {
+	if response.Shop.CurrencyCode == "" {
 		return "", fmt.Errorf("Cannot get shop currency: no currencyCode in response")
 	}
 
-	return fmt.Sprint(values[0]), nil
+	return response.Shop.CurrencyCode, nil
 }
 
 func createOneTimeCharge(client *gql.Client, name, price string, test bool, returnURL string) (*OneTimeCharge, error) {
@@ -358,40 +311,22 @@ func createOneTimeCharge(client *gql.Client, name, price string, test bool, retu
 		return nil, err
 	}
 
-	data, err := client.Execute(oneTimeChargeCreateMutation, map[string]interface{}{
+	var response oneTimeChargeCreateResponse
+	if err := client.ExecuteInto(oneTimeChargeCreateMutation, map[string]interface{}{
 		"name":      name,
 		"price":     map[string]interface{}{"amount": price, "currencyCode": currencyCode},
 		"returnUrl": returnURL,
 		"test":      test,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return nil, fmt.Errorf("Cannot create one-time charge: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode one-time charge response: %s", err)
-	}
-
-	var response oneTimeChargeCreateResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse one-time charge response: %s", err)
-	}
-
-	if errs := response.Data.AppPurchaseOneTimeCreate.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return nil, fmt.Errorf("Cannot create one-time charge: %s", strings.Join(messages, ", "))
-	}
-
-	n := response.Data.AppPurchaseOneTimeCreate.AppPurchaseOneTime
+	n := response.AppPurchaseOneTimeCreate.AppPurchaseOneTime
 	if n == nil {
 		return nil, fmt.Errorf("Cannot create one-time charge: no charge in response")
 	}
 
-	return oneTimeChargeFromNode(n, response.Data.AppPurchaseOneTimeCreate.ConfirmationURL, returnURL), nil
+	return oneTimeChargeFromNode(n, response.AppPurchaseOneTimeCreate.ConfirmationURL, returnURL), nil
 }
 
 // recurringIntervalFor returns the GraphQL AppPricingInterval value for
@@ -419,7 +354,8 @@ func createRecurringCharge(client *gql.Client, name, price string, test bool, re
 		return nil, err
 	}
 
-	data, err := client.Execute(recurringChargeCreateMutation, map[string]interface{}{
+	var response recurringChargeCreateResponse
+	if err := client.ExecuteInto(recurringChargeCreateMutation, map[string]interface{}{
 		"name": name,
 		"lineItems": []interface{}{
 			map[string]interface{}{
@@ -433,36 +369,17 @@ func createRecurringCharge(client *gql.Client, name, price string, test bool, re
 		},
 		"returnUrl": returnURL,
 		"test":      test,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return nil, fmt.Errorf("Cannot create recurring charge: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode recurring charge response: %s", err)
-	}
-
-	var response recurringChargeCreateResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse recurring charge response: %s", err)
-	}
-
-	if errs := response.Data.AppSubscriptionCreate.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return nil, fmt.Errorf("Cannot create recurring charge: %s", strings.Join(messages, ", "))
-	}
-
-	n := response.Data.AppSubscriptionCreate.AppSubscription
+	n := response.AppSubscriptionCreate.AppSubscription
 	if n == nil {
 		return nil, fmt.Errorf("Cannot create recurring charge: no charge in response")
 	}
 
 	charge := recurringChargeFromNode(*n)
-	charge.ConfirmationURL = response.Data.AppSubscriptionCreate.ConfirmationURL
+	charge.ConfirmationURL = response.AppSubscriptionCreate.ConfirmationURL
 	return &charge, nil
 }
 
@@ -471,33 +388,15 @@ func createRecurringCharge(client *gql.Client, name, price string, test bool, re
 // issued for the unused portion of the subscription. Returns the
 // cancelled charge's id and status.
 func CancelRecurringCharge(client *gql.Client, gid string, prorate bool) (int64, string, error) {
-	data, err := client.Execute(appSubscriptionCancelMutation, map[string]interface{}{
+	var response appSubscriptionCancelResponse
+	if err := client.ExecuteInto(appSubscriptionCancelMutation, map[string]interface{}{
 		"id":      gid,
 		"prorate": prorate,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return 0, "", fmt.Errorf("Cannot cancel recurring charge: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return 0, "", fmt.Errorf("Cannot re-encode cancel charge response: %s", err)
-	}
-
-	var response appSubscriptionCancelResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return 0, "", fmt.Errorf("Cannot parse cancel charge response: %s", err)
-	}
-
-	if errs := response.Data.AppSubscriptionCancel.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return 0, "", fmt.Errorf("Cannot cancel recurring charge: %s", strings.Join(messages, ", "))
-	}
-
-	n := response.Data.AppSubscriptionCancel.AppSubscription
+	n := response.AppSubscriptionCancel.AppSubscription
 	if n == nil {
 		return 0, "", fmt.Errorf("Cannot cancel recurring charge: no charge in response")
 	}
@@ -546,74 +445,30 @@ func recurringChargeFromNode(n recurringChargeNodeJSON) RecurringCharge {
 }
 
 func listOneTimeChargesGQL(client *gql.Client) ([]OneTimeCharge, error) {
-	vars := map[string]interface{}{
-		"first": 250,
-	}
-
 	var charges []OneTimeCharge
 
-	for {
-		data, err := client.Execute(oneTimeChargesQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list one-time charges: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list one-time charges: %s", err)
-		}
-
-		var response oneTimeChargesResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list one-time charges: %s", err)
-		}
-
-		for _, edge := range response.Data.CurrentAppInstallation.OneTimePurchases.Edges {
-			charges = append(charges, *oneTimeChargeFromNode(&edge.Node, "", ""))
-		}
-
-		if !response.Data.CurrentAppInstallation.OneTimePurchases.PageInfo.HasNextPage {
-			break
-		}
+	err := gql.Paginate(client, oneTimeChargesQuery, nil, "currentAppInstallation.oneTimePurchases", func(n oneTimeChargeNodeJSON) error {
+		charges = append(charges, *oneTimeChargeFromNode(&n, "", ""))
+		return nil
+	})
 
-		vars["after"] = response.Data.CurrentAppInstallation.OneTimePurchases.PageInfo.EndCursor
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list one-time charges: %s", err)
 	}
 
 	return charges, nil
 }
 
 func listRecurringChargesGQL(client *gql.Client) ([]RecurringCharge, error) {
-	vars := map[string]interface{}{
-		"first": 250,
-	}
-
 	var charges []RecurringCharge
 
-	for {
-		data, err := client.Execute(recurringChargesQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list recurring charges: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list recurring charges: %s", err)
-		}
-
-		var response recurringChargesResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list recurring charges: %s", err)
-		}
-
-		for _, edge := range response.Data.CurrentAppInstallation.AllSubscriptions.Edges {
-			charges = append(charges, recurringChargeFromNode(edge.Node))
-		}
-
-		if !response.Data.CurrentAppInstallation.AllSubscriptions.PageInfo.HasNextPage {
-			break
-		}
+	err := gql.Paginate(client, recurringChargesQuery, nil, "currentAppInstallation.allSubscriptions", func(n recurringChargeNodeJSON) error {
+		charges = append(charges, recurringChargeFromNode(n))
+		return nil
+	})
 
-		vars["after"] = response.Data.CurrentAppInstallation.AllSubscriptions.PageInfo.EndCursor
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list recurring charges: %s", err)
 	}
 
 	return charges, nil
@@ -623,28 +478,16 @@ func listRecurringChargesGQL(client *gql.Client) ([]RecurringCharge, error) {
 // all be of the given GraphQL type (AppPurchaseOneTime or
 // AppSubscription).
 func fetchChargesByID(client *gql.Client, gids []string) ([]chargeNodeJSON, error) {
-	data, err := client.Execute(chargesQuery, map[string]interface{}{"ids": gids})
-	if err != nil {
-		return nil, err
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, err
-	}
-
 	var response struct {
-		Data struct {
-			Nodes []*chargeNodeJSON `json:"nodes"`
-		} `json:"data"`
+		Nodes []*chargeNodeJSON `json:"nodes"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
+	if err := client.ExecuteInto(chargesQuery, map[string]interface{}{"ids": gids}, &response); err != nil {
 		return nil, err
 	}
 
 	var nodes []chargeNodeJSON
-	for i, node := range response.Data.Nodes {
+	for i, node := range response.Nodes {
 		if node == nil {
 			return nil, fmt.Errorf("no charge found with id %s", gids[i])
 		}
@@ -1,8 +1,9 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
 	"regexp"
 	"strconv"
@@ -16,6 +17,7 @@ import (
 )
 
 var Flags []cli.Flag
+var shopFlag, accessTokenFlag *cli.StringFlag
 var accessTokenCommand = regexp.MustCompile(`\A\s*<\s*(.+)\z`)
 
 // APIVersionFlag is the shared --api-version flag. Its Destination writes the
@@ -27,10 +29,104 @@ var APIVersionFlag = &cli.StringFlag{
 	Destination: &gql.DefaultAPIVersion,
 }
 
-func NewGraphQLClient(c *cli.Context) *gql.Client {
+// NewGraphQLClient returns a client for the shop authenticated using the
+// credentials given on the command-line. See AccessToken.
+func NewGraphQLClient(c *cli.Context) (*gql.Client, error) {
+	token, err := AccessToken(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return gql.NewClient(c.String("shop"), token), nil
+}
+
+// AccessToken returns the access token for the shop given on the command-line.
+// It's either --access-token (or the output of its command), a private app's
+// --api-password, or a token obtained by exchanging --api-key and --api-secret
+// using the client credentials grant. When no credentials are given the token
+// saved by "auth login" is used. When replaying no token is needed.
+func AccessToken(c *cli.Context) (string, error) {
+	if replaying {
+		return "", nil
+	}
+
 	shop := c.String("shop")
-	token := LookupAccessToken(shop, c.String("access-token"))
-	return gql.NewClient(shop, token)
+
+	if !hasCredentials(c) {
+		token, err := storedAccessToken(shop)
+		if err != nil {
+			return "", err
+		}
+
+		if token != "" {
+			return token, nil
+		}
+	}
+
+	if err := validateCredentials(c); err != nil {
+		return "", err
+	}
+
+	if c.String("api-password") != "" {
+		// The Admin API accepts a private app's password as its access token
+		return c.String("api-password"), nil
+	}
+
+	if c.String("api-secret") != "" {
+		token, err := gql.ExchangeClientCredentials(shop, c.String("api-key"), c.String("api-secret"))
+		if err != nil {
+			return "", err
+		}
+
+		return token.AccessToken, nil
+	}
+
+	return LookupAccessToken(shop, accessTokenArg(c))
+}
+
+func hasCredentials(c *cli.Context) bool {
+	return accessTokenArg(c) != "" || c.String("api-key") != "" || c.String("api-password") != "" || c.String("api-secret") != ""
+}
+
+// accessTokenArg returns --access-token's value. A default from a profile is
+// ignored when an API key is given.
+func accessTokenArg(c *cli.Context) string {
+	if !c.IsSet("access-token") && c.String("api-key") != "" {
+		return ""
+	}
+
+	return c.String("access-token")
+}
+
+// validateCredentials checks that one, and only one, way to authenticate was
+// given on the command-line.
+func validateCredentials(c *cli.Context) error {
+	token := accessTokenArg(c)
+	key := c.String("api-key")
+	password := c.String("api-password")
+	secret := c.String("api-secret")
+
+	if token != "" && (key != "" || password != "" || secret != "") {
+		return fmt.Errorf("--access-token cannot be used with --api-key, --api-password, or --api-secret")
+	}
+
+	if password != "" && secret != "" {
+		return fmt.Errorf("--api-password and --api-secret cannot be used together: use --api-password for a private app or --api-secret for a custom app")
+	}
+
+	if key == "" && (password != "" || secret != "") {
+		return fmt.Errorf("--api-key is required when using --api-password or --api-secret")
+	}
+
+	if key != "" && password == "" && secret == "" {
+		return fmt.Errorf("--api-key requires --api-password (private app) or --api-secret (custom app)")
+	}
+
+	if token == "" && key == "" {
+		return fmt.Errorf("You must supply credentials: --access-token, --api-key with --api-password or --api-secret, or login with 'sdt auth login'")
+	}
+
+	return nil
 }
 
 func ParseIntAt(c *cli.Context, pos int) (int64, error) {
@@ -63,6 +159,20 @@ func ParseIDArgs(args []string, idDesc string) ([]int64, []string, error) {
 	return ids, skus, nil
 }
 
+// Confirm writes the question to out and reports whether the answer read from in is yes
+func Confirm(in io.Reader, out io.Writer, question string) (bool, error) {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("Cannot read answer: %s", err)
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
+
 func PrintSeparator() {
 	fmt.Printf("%s\n", strings.Repeat("-", 20))
 }
@@ -103,50 +213,67 @@ func PrintMetafields(items []MetafieldPrintable) {
 	}
 }
 
-func LookupAccessToken(shop, token string) string {
+// LookupAccessToken returns the access token for shop given the value of
+// --access-token. If it's an access token command (begins with "<") the
+// command is run and its output returned, or, when the token cache is enabled,
+// the output of a previous run.
+func LookupAccessToken(shop, token string) (string, error) {
 	match := accessTokenCommand.FindStringSubmatch(token)
 	if len(match) == 0 {
-		return token
+		return token, nil
+	}
+
+	if tokenCacheTTL > 0 {
+		return cachedAccessToken(match[1], shop)
 	}
 
-	out, err := exec.Command(match[1], shop).Output()
-	// FIXME: return an error. Exit should be done in caller
+	return runAccessTokenCommand(match[1], shop)
+}
+
+func runAccessTokenCommand(command, shop string) (string, error) {
+	out, err := exec.Command(command, shop).Output()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "access token command failed: %s\n", err)
-		os.Exit(2)
+		return "", fmt.Errorf("access token command failed: %s", err)
 	}
 
-	return strings.TrimSuffix(string(out), "\n")
+	return strings.TrimSuffix(string(out), "\n"), nil
 }
 
 func init() {
+	shopFlag = &cli.StringFlag{
+		Name:     "shop",
+		Usage:    "Shopify domain or shop name to perform command against",
+		Required: true,
+		EnvVars:  []string{"SHOPIFY_SHOP"},
+	}
+
+	accessTokenFlag = &cli.StringFlag{
+		Name:    "access-token",
+		Usage:   "Shopify access token for shop",
+		EnvVars: []string{"SHOPIFY_ACCESS_TOKEN", "SHOPIFY_API_TOKEN"},
+	}
+
 	Flags = []cli.Flag{
 		&cli.BoolFlag{
 			Name:  "verbose",
 			Usage: "Output Shopify API request/response",
 		},
-		altsrc.NewStringFlag(
-			&cli.StringFlag{
-				Name:     "shop",
-				Usage:    "Shopify domain or shop name to perform command against",
-				Required: true,
-				EnvVars:  []string{"SHOPIFY_SHOP"},
-			},
-		),
+		altsrc.NewStringFlag(shopFlag),
 		&cli.StringFlag{
 			Name:    "api-password",
 			Usage:   "Shopify API password",
 			EnvVars: []string{"SHOPIFY_API_PASSWORD"},
 		},
-		&cli.StringFlag{
-			Name:    "access-token",
-			Usage:   "Shopify access token for shop",
-			EnvVars: []string{"SHOPIFY_ACCESS_TOKEN", "SHOPIFY_API_TOKEN"},
-		},
+		accessTokenFlag,
 		&cli.StringFlag{
 			Name:    "api-key",
 			Usage:   "Shopify API key to for shop",
 			EnvVars: []string{"SHOPIFY_API_KEY"},
 		},
+		&cli.StringFlag{
+			Name:    "api-secret",
+			Usage:   "Shopify API secret key for the custom app, exchanged for an access token with --api-key",
+			EnvVars: []string{"SHOPIFY_API_SECRET"},
+		},
 	}
 }
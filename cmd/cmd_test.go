@@ -1,8 +1,17 @@
 package cmd
 
 import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
 )
 
 func TestParseIDArgs(t *testing.T) {
@@ -93,3 +102,109 @@ func TestParseIDArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateCredentials(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "access token", args: []string{"--access-token", "shpat"}},
+		{name: "private app", args: []string{"--api-key", "key", "--api-password", "pass"}},
+		{name: "custom app", args: []string{"--api-key", "key", "--api-secret", "secret"}},
+		{name: "nothing", wantErr: "You must supply credentials: --access-token, --api-key with --api-password or --api-secret, or login with 'sdt auth login'"},
+		{name: "token and key", args: []string{"--access-token", "shpat", "--api-key", "key", "--api-password", "pass"}, wantErr: "--access-token cannot be used with --api-key, --api-password, or --api-secret"},
+		{name: "password and secret", args: []string{"--api-key", "key", "--api-password", "pass", "--api-secret", "secret"}, wantErr: "--api-password and --api-secret cannot be used together: use --api-password for a private app or --api-secret for a custom app"},
+		{name: "password without key", args: []string{"--api-password", "pass"}, wantErr: "--api-key is required when using --api-password or --api-secret"},
+		{name: "key alone", args: []string{"--api-key", "key"}, wantErr: "--api-key requires --api-password (private app) or --api-secret (custom app)"},
+	}
+
+	for _, tt := range tests {
+		set := flag.NewFlagSet(tt.name, flag.ContinueOnError)
+		for _, name := range []string{"access-token", "api-key", "api-password", "api-secret"} {
+			set.String(name, "", "")
+		}
+
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("%s: cannot parse args: %s", tt.name, err)
+		}
+
+		err := validateCredentials(cli.NewContext(nil, set, nil))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", tt.name, err)
+			}
+		} else if err == nil || err.Error() != tt.wantErr {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewGraphQLClientSendsPasswordAsAccessToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, ok := r.BasicAuth(); ok {
+			t.Error("request used basic auth")
+		}
+
+		if token := r.Header.Get("X-Shopify-Access-Token"); token != "pass" {
+			t.Errorf("X-Shopify-Access-Token = %q, want \"pass\"", token)
+		}
+
+		w.Write([]byte(`{"data":{"shop":{"name":"Acme"}}}`))
+	}))
+	defer server.Close()
+
+	old := gql.AdminURL
+	gql.AdminURL = server.URL
+	defer func() { gql.AdminURL = old }()
+
+	set := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	for _, name := range []string{"shop", "access-token", "api-key", "api-password", "api-secret"} {
+		set.String(name, "", "")
+	}
+
+	if err := set.Parse([]string{"--shop", "acme", "--api-key", "key", "--api-password", "pass"}); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewGraphQLClient(cli.NewContext(nil, set, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Execute("{ shop { name } }"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{"Yes\n", true},
+		{" y ", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+		{"yep\n", false},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+
+		got, err := Confirm(strings.NewReader(tt.answer), &out, "Delete 2 products?")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != tt.want {
+			t.Errorf("Confirm(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+
+		if out.String() != "Delete 2 products? [y/N] " {
+			t.Errorf("Confirm output = %q", out.String())
+		}
+	}
+}
@@ -14,7 +14,10 @@ var Cmd cli.Command
 
 func listAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.Args().Len() > 0 {
 		collection, err := gql.GetCollection(shop, token, c.Args().Get(0))
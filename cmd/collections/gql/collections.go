@@ -1,7 +1,6 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -98,31 +97,19 @@ func jsonToCollection(n collectionJSON) Collection {
 func GetCollection(shop, token, id string) (*Collection, error) {
 	client := gqlclient.NewClient(shop, token)
 
-	data, err := client.Execute(collectionQuery, map[string]interface{}{"id": ToGID(id)})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get collection: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode collection response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			Collection *collectionJSON `json:"collection"`
-		} `json:"data"`
+		Collection *collectionJSON `json:"collection"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse collection response: %s", err)
+	if err := client.ExecuteInto(collectionQuery, map[string]interface{}{"id": ToGID(id)}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get collection: %s", err)
 	}
 
-	if response.Data.Collection == nil {
+	if response.Collection == nil {
 		return nil, fmt.Errorf("Collection not found")
 	}
 
-	c := jsonToCollection(*response.Data.Collection)
+	c := jsonToCollection(*response.Collection)
 	return &c, nil
 }
 
@@ -146,15 +133,13 @@ func ListCollections(shop, token string, limit, page int, title string, custom,
 	}
 
 	var response struct {
-		Data struct {
-			Collections struct {
-				Nodes    []collectionJSON `json:"nodes"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"collections"`
-		} `json:"data"`
+		Collections struct {
+			Nodes    []collectionJSON `json:"nodes"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"collections"`
 	}
 
 	var after string
@@ -164,32 +149,120 @@ func ListCollections(shop, token string, limit, page int, title string, custom,
 			vars["after"] = after
 		}
 
-		data, err := client.Execute(collectionsQuery, vars)
-		if err != nil {
+		response.Collections.Nodes = nil
+		if err := client.ExecuteInto(collectionsQuery, vars, &response); err != nil {
 			return nil, fmt.Errorf("Cannot list collections: %s", err)
 		}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot re-encode collections response: %s", err)
-		}
-
-		response.Data.Collections.Nodes = nil
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot parse collections response: %s", err)
-		}
-
-		if !response.Data.Collections.PageInfo.HasNextPage && i < page-1 {
+		if !response.Collections.PageInfo.HasNextPage && i < page-1 {
 			break
 		}
 
-		after = response.Data.Collections.PageInfo.EndCursor
+		after = response.Collections.PageInfo.EndCursor
 	}
 
 	var result []Collection
-	for _, n := range response.Data.Collections.Nodes {
+	for _, n := range response.Collections.Nodes {
 		result = append(result, jsonToCollection(n))
 	}
 
 	return result, nil
 }
+
+const collectionByHandleQuery = `
+query($handle: String!) {
+  collectionByIdentifier(identifier: { handle: $handle }) {
+    id
+    title
+    handle
+    productsCount {
+      count
+    }
+    updatedAt
+    ruleSet {
+      appliedDisjunctively
+    }
+  }
+}
+`
+
+const collectionCreateMutation = `
+mutation($input: CollectionInput!) {
+  collectionCreate(input: $input) {
+    collection {
+      id
+      title
+      handle
+      productsCount {
+        count
+      }
+      updatedAt
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+// FindCollection returns the collection with the handle or, if there isn't
+// one, the title, or nil if neither exist. Titles are not case-sensitive.
+func FindCollection(shop, token, handleOrTitle string, options ...map[string]interface{}) (*Collection, error) {
+	client := gqlclient.NewClient(shop, token, options...)
+
+	var response struct {
+		Collection *collectionJSON `json:"collectionByIdentifier"`
+	}
+
+	if err := client.ExecuteInto(collectionByHandleQuery, map[string]interface{}{"handle": handleOrTitle}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot find collection %q: %s", handleOrTitle, err)
+	}
+
+	if response.Collection != nil {
+		c := jsonToCollection(*response.Collection)
+		return &c, nil
+	}
+
+	var found *Collection
+
+	// Title search is by word so the matches must be checked
+	query := fmt.Sprintf("title:%q", handleOrTitle)
+	err := gqlclient.Paginate(client, collectionsQuery, map[string]interface{}{"query": query}, "collections", func(n collectionJSON) error {
+		if found == nil && strings.EqualFold(n.Title, handleOrTitle) {
+			c := jsonToCollection(n)
+			found = &c
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot find collection %q: %s", handleOrTitle, err)
+	}
+
+	return found, nil
+}
+
+// CreateCollection creates a custom collection with the title
+func CreateCollection(shop, token, title string, options ...map[string]interface{}) (*Collection, error) {
+	client := gqlclient.NewClient(shop, token, options...)
+
+	var response struct {
+		CollectionCreate struct {
+			Collection *collectionJSON `json:"collection"`
+		} `json:"collectionCreate"`
+	}
+
+	input := map[string]interface{}{"title": title}
+	if err := client.ExecuteInto(collectionCreateMutation, map[string]interface{}{"input": input}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot create collection %q: %s", title, err)
+	}
+
+	if response.CollectionCreate.Collection == nil {
+		return nil, fmt.Errorf("Cannot create collection %q", title)
+	}
+
+	c := jsonToCollection(*response.CollectionCreate.Collection)
+	return &c, nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// Profile is a named set of credentials and settings for a shop.
+type Profile struct {
+	Shop               string `toml:"shop"`
+	AccessToken        string `toml:"access-token,omitempty"`
+	AccessTokenCommand string `toml:"access-token-command,omitempty"`
+	APIVersion         string `toml:"api-version,omitempty"`
+	ReadOnly           bool   `toml:"read-only,omitempty"`
+}
+
+// Config is the contents of the config file. Default names the profile used
+// when --profile is not given.
+type Config struct {
+	Default  string              `toml:"default,omitempty"`
+	Profiles map[string]*Profile `toml:"profiles"`
+}
+
+// ProfileFlag selects a profile from the config file. It's a global option,
+// i.e., it must be given before the command.
+var ProfileFlag = &cli.StringFlag{
+	Name:    "profile",
+	Usage:   "Use the shop and credentials from the named profile in the config file",
+	EnvVars: []string{"SDT_PROFILE"},
+}
+
+// ConfigPath returns the location of the config file: $SDT_CONFIG if set,
+// otherwise sdt/config.toml in $XDG_CONFIG_HOME or ~/.config.
+func ConfigPath() (string, error) {
+	if path := os.Getenv("SDT_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Cannot determine config file location: %s", err)
+		}
+
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, "sdt", "config.toml"), nil
+}
+
+// LoadConfig reads the config file. A missing file results in an empty config.
+func LoadConfig() (*Config, error) {
+	config := &Config{Profiles: map[string]*Profile{}}
+
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = toml.DecodeFile(path, config)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Cannot read config file %s: %s", path, err)
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = map[string]*Profile{}
+	}
+
+	return config, nil
+}
+
+// Save writes the config file. It's only readable by the user as it may
+// contain access tokens.
+func (c *Config) Save() error {
+	path, err := ConfigPath()
+	if err != nil {
+		return err
+	}
+
+	return writeTOML(path, c)
+}
+
+// writeTOML writes v to path, creating its directory if necessary. The file is
+// only readable by the user.
+func writeTOML(path string, v interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("Cannot create directory for %s: %s", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("Cannot write %s: %s", path, err)
+	}
+
+	defer file.Close()
+
+	if err := toml.NewEncoder(file).Encode(v); err != nil {
+		return fmt.Errorf("Cannot write %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// ProfileNames returns the names of the configured profiles in sorted order.
+func (c *Config) ProfileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Profile returns the profile named name, or the default profile when name
+// is empty. A nil profile is returned if name is empty and there's no default.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Default
+		if name == "" {
+			return nil, nil
+		}
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("Profile '%s' not found", name)
+	}
+
+	return profile, nil
+}
+
+// ApplyProfile makes the selected profile's settings the defaults for the
+// shared flags. Flags and environment variables given on the command line
+// take precedence. Meant to be used as the app's Before function.
+func ApplyProfile(c *cli.Context) error {
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	profile, err := config.Profile(c.String(ProfileFlag.Name))
+	if err != nil || profile == nil {
+		return err
+	}
+
+	applyProfile(profile)
+
+	return nil
+}
+
+func applyProfile(profile *Profile) {
+	if profile.Shop != "" {
+		shopFlag.Value = profile.Shop
+		shopFlag.Required = false
+	}
+
+	if profile.AccessTokenCommand != "" {
+		accessTokenFlag.Value = "<" + profile.AccessTokenCommand
+	} else if profile.AccessToken != "" {
+		accessTokenFlag.Value = profile.AccessToken
+	}
+
+	// Don't show the token in the help output
+	if accessTokenFlag.Value != "" {
+		accessTokenFlag.DefaultText = "from profile"
+	}
+
+	// Not all commands have --api-version so set the client default too
+	if profile.APIVersion != "" {
+		APIVersionFlag.Value = profile.APIVersion
+		gql.DefaultAPIVersion = profile.APIVersion
+	}
+
+	gql.ReadOnly = profile.ReadOnly
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+func TestConfigSaveAndLoad(t *testing.T) {
+	t.Setenv("SDT_CONFIG", filepath.Join(t.TempDir(), "sdt", "config.toml"))
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error loading missing config: %s", err)
+	}
+
+	if len(config.Profiles) != 0 {
+		t.Errorf("profiles = %v, want none", config.Profiles)
+	}
+
+	config.Default = "acme"
+	config.Profiles["acme"] = &Profile{Shop: "acme", AccessTokenCommand: "token-for", APIVersion: "2026-07"}
+	config.Profiles["beta"] = &Profile{Shop: "beta", AccessToken: "shpat_123", ReadOnly: true}
+
+	if err := config.Save(); err != nil {
+		t.Fatalf("unexpected error saving config: %s", err)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("unexpected error loading config: %s", err)
+	}
+
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("loaded config = %+v, want %+v", loaded, config)
+	}
+
+	if want := []string{"acme", "beta"}; !reflect.DeepEqual(loaded.ProfileNames(), want) {
+		t.Errorf("ProfileNames() = %v, want %v", loaded.ProfileNames(), want)
+	}
+}
+
+func TestConfigProfile(t *testing.T) {
+	config := &Config{
+		Default: "acme",
+		Profiles: map[string]*Profile{
+			"acme": {Shop: "acme"},
+			"beta": {Shop: "beta"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		defaultName string
+		wantShop    string
+		wantErr     bool
+	}{
+		{name: "beta", defaultName: "acme", wantShop: "beta"},
+		{name: "", defaultName: "acme", wantShop: "acme"},
+		{name: "", defaultName: ""},
+		{name: "nope", defaultName: "acme", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		config.Default = tt.defaultName
+
+		profile, err := config.Profile(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Profile(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		shop := ""
+		if profile != nil {
+			shop = profile.Shop
+		}
+
+		if shop != tt.wantShop {
+			t.Errorf("Profile(%q) shop = %q, want %q", tt.name, shop, tt.wantShop)
+		}
+	}
+}
+
+func TestApplyProfileSetsFlagDefaults(t *testing.T) {
+	shop, token, version := *shopFlag, *accessTokenFlag, *APIVersionFlag
+	defaultVersion, readOnly := gql.DefaultAPIVersion, gql.ReadOnly
+	defer func() {
+		*shopFlag, *accessTokenFlag, *APIVersionFlag = shop, token, version
+		gql.DefaultAPIVersion, gql.ReadOnly = defaultVersion, readOnly
+	}()
+
+	applyProfile(&Profile{Shop: "acme", AccessTokenCommand: "token-for", APIVersion: "2026-07", ReadOnly: true})
+
+	if shopFlag.Value != "acme" || shopFlag.Required {
+		t.Errorf("shop flag value = %q, required = %v; want \"acme\", false", shopFlag.Value, shopFlag.Required)
+	}
+
+	if accessTokenFlag.Value != "<token-for" {
+		t.Errorf("access-token flag value = %q, want %q", accessTokenFlag.Value, "<token-for")
+	}
+
+	if APIVersionFlag.Value != "2026-07" || gql.DefaultAPIVersion != "2026-07" {
+		t.Errorf("API version = %q, default = %q; want \"2026-07\"", APIVersionFlag.Value, gql.DefaultAPIVersion)
+	}
+
+	if !gql.ReadOnly {
+		t.Error("gql.ReadOnly = false, want true")
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Credential is an access token obtained by "auth login".
+type Credential struct {
+	AccessToken string    `toml:"access-token"`
+	Scope       string    `toml:"scope,omitempty"`
+	CreatedAt   time.Time `toml:"created-at"`
+}
+
+// Credentials is the credential store, keyed by shop name.
+type Credentials struct {
+	Shops map[string]*Credential `toml:"shops"`
+}
+
+// CredentialsPath returns the location of the credential store, credentials.toml
+// in the same directory as the config file.
+func CredentialsPath() (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "credentials.toml"), nil
+}
+
+// LoadCredentials reads the credential store. A missing file results in an
+// empty store.
+func LoadCredentials() (*Credentials, error) {
+	credentials := &Credentials{Shops: map[string]*Credential{}}
+
+	path, err := CredentialsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = toml.DecodeFile(path, credentials)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Cannot read credentials file %s: %s", path, err)
+	}
+
+	if credentials.Shops == nil {
+		credentials.Shops = map[string]*Credential{}
+	}
+
+	return credentials, nil
+}
+
+// Save writes the credential store. It's only readable by the user.
+func (c *Credentials) Save() error {
+	path, err := CredentialsPath()
+	if err != nil {
+		return err
+	}
+
+	return writeTOML(path, c)
+}
+
+// Get returns the shop's credential or nil if there isn't one.
+func (c *Credentials) Get(shop string) *Credential {
+	return c.Shops[credentialKey(shop)]
+}
+
+// Set stores the shop's credential, replacing any existing one.
+func (c *Credentials) Set(shop string, credential *Credential) {
+	c.Shops[credentialKey(shop)] = credential
+}
+
+// Delete removes the shop's credential. It returns false if there wasn't one.
+func (c *Credentials) Delete(shop string) bool {
+	key := credentialKey(shop)
+	if _, ok := c.Shops[key]; !ok {
+		return false
+	}
+
+	delete(c.Shops, key)
+
+	return true
+}
+
+// storedAccessToken returns the shop's access token from the credential store
+// or an empty string if there isn't one.
+func storedAccessToken(shop string) (string, error) {
+	credentials, err := LoadCredentials()
+	if err != nil {
+		return "", err
+	}
+
+	credential := credentials.Get(shop)
+	if credential == nil {
+		return "", nil
+	}
+
+	return credential.AccessToken, nil
+}
+
+// credentialKey allows the shop to be given as NAME or NAME.myshopify.com.
+func credentialKey(shop string) string {
+	return strings.SplitN(strings.ToLower(shop), ".", 2)[0]
+}
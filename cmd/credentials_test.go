@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCredentialsSaveAndLoad(t *testing.T) {
+	t.Setenv("SDT_CONFIG", filepath.Join(t.TempDir(), "sdt", "config.toml"))
+
+	credentials, err := LoadCredentials()
+	if err != nil {
+		t.Fatalf("unexpected error loading missing credentials: %s", err)
+	}
+
+	credentials.Set("Acme.myshopify.com", &Credential{AccessToken: "shpat_123", Scope: "read_products", CreatedAt: time.Now().UTC().Truncate(time.Second)})
+	if err := credentials.Save(); err != nil {
+		t.Fatalf("unexpected error saving credentials: %s", err)
+	}
+
+	token, err := storedAccessToken("acme")
+	if err != nil {
+		t.Fatalf("unexpected error reading stored token: %s", err)
+	}
+
+	if token != "shpat_123" {
+		t.Errorf("storedAccessToken() = %q, want %q", token, "shpat_123")
+	}
+
+	loaded, _ := LoadCredentials()
+	if !loaded.Delete("acme.myshopify.com") {
+		t.Error("Delete() = false, want true")
+	}
+
+	if loaded.Get("acme") != nil {
+		t.Errorf("Get() after Delete() = %+v, want nil", loaded.Get("acme"))
+	}
+}
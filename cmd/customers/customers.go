@@ -14,7 +14,10 @@ var Cmd cli.Command
 
 func listAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.Args().Len() > 0 {
 		customer, err := gql.GetCustomer(shop, token, c.Args().Get(0))
@@ -57,7 +60,10 @@ func printCustomers(customers []gql.Customer) {
 
 func segmentsListAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.Args().Len() > 0 {
 		id := c.Args().Get(0)
@@ -92,7 +98,12 @@ func segmentsDeleteAction(c *cli.Context) error {
 
 	id := c.Args().Get(0)
 	shop := c.String("shop")
-	deletedID, err := gql.DeleteSegment(shop, cmd.LookupAccessToken(shop, c.String("access-token")), id)
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	deletedID, err := gql.DeleteSegment(shop, token, id)
 	if err != nil {
 		return err
 	}
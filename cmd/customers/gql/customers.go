@@ -1,7 +1,6 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -101,61 +100,37 @@ func jsonToCustomer(n customerJSON) Customer {
 func GetCustomer(shop, token, id string) (*Customer, error) {
 	client := gqlclient.NewClient(shop, token)
 
-	data, err := client.Execute(customerQuery, map[string]interface{}{"id": CustomerToGID(id)})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get customer: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode customer response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			Customer *customerJSON `json:"customer"`
-		} `json:"data"`
+		Customer *customerJSON `json:"customer"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse customer response: %s", err)
+	if err := client.ExecuteInto(customerQuery, map[string]interface{}{"id": CustomerToGID(id)}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get customer: %s", err)
 	}
 
-	if response.Data.Customer == nil {
+	if response.Customer == nil {
 		return nil, fmt.Errorf("Customer not found")
 	}
 
-	c := jsonToCustomer(*response.Data.Customer)
+	c := jsonToCustomer(*response.Customer)
 	return &c, nil
 }
 
 func ListCustomers(shop, token string, limit int) ([]Customer, error) {
 	client := gqlclient.NewClient(shop, token)
 
-	data, err := client.Execute(customersQuery, map[string]interface{}{"first": limit})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list customers: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode customers response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			Customers struct {
-				Nodes []customerJSON `json:"nodes"`
-			} `json:"customers"`
-		} `json:"data"`
+		Customers struct {
+			Nodes []customerJSON `json:"nodes"`
+		} `json:"customers"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse customers response: %s", err)
+	if err := client.ExecuteInto(customersQuery, map[string]interface{}{"first": limit}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list customers: %s", err)
 	}
 
 	var result []Customer
-	for _, n := range response.Data.Customers.Nodes {
+	for _, n := range response.Customers.Nodes {
 		result = append(result, jsonToCustomer(n))
 	}
 
@@ -1,7 +1,6 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -65,13 +64,11 @@ type segmentJSON struct {
 }
 
 type segmentsResponse struct {
-	Data struct {
-		Segments struct {
-			Edges []struct {
-				Node segmentJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"segments"`
-	} `json:"data"`
+	Segments struct {
+		Edges []struct {
+			Node segmentJSON `json:"node"`
+		} `json:"edges"`
+	} `json:"segments"`
 }
 
 func ToGID(id string) string {
@@ -84,31 +81,19 @@ func ToGID(id string) string {
 func GetSegment(shop, token, id string) (*Segment, error) {
 	client := gqlclient.NewClient(shop, token)
 
-	data, err := client.Execute(segmentQuery, map[string]interface{}{"id": ToGID(id)})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get segment: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode segment response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			Segment *segmentJSON `json:"segment"`
-		} `json:"data"`
+		Segment *segmentJSON `json:"segment"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse segment response: %s", err)
+	if err := client.ExecuteInto(segmentQuery, map[string]interface{}{"id": ToGID(id)}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get segment: %s", err)
 	}
 
-	if response.Data.Segment == nil {
+	if response.Segment == nil {
 		return nil, fmt.Errorf("Segment not found")
 	}
 
-	n := response.Data.Segment
+	n := response.Segment
 	return &Segment{
 		ID:           n.ID,
 		Name:         n.Name,
@@ -121,23 +106,13 @@ func GetSegment(shop, token, id string) (*Segment, error) {
 func ListSegments(shop, token string, limit int) ([]Segment, error) {
 	client := gqlclient.NewClient(shop, token)
 
-	data, err := client.Execute(segmentsQuery, map[string]interface{}{"first": limit})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list segments: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode segments response: %s", err)
-	}
-
 	var response segmentsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse segments response: %s", err)
+	if err := client.ExecuteInto(segmentsQuery, map[string]interface{}{"first": limit}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list segments: %s", err)
 	}
 
 	var result []Segment
-	for _, edge := range response.Data.Segments.Edges {
+	for _, edge := range response.Segments.Edges {
 		n := edge.Node
 		result = append(result, Segment{
 			ID:           n.ID,
@@ -156,43 +131,19 @@ func DeleteSegment(shop, token, id string) (string, error) {
 
 	gid := ToGID(id)
 
-	data, err := client.Execute(segmentDeleteMutation, map[string]interface{}{"id": gid})
-	if err != nil {
-		return "", fmt.Errorf("Cannot delete segment: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("Cannot re-encode segment delete response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			SegmentDelete struct {
-				DeletedSegmentId *string `json:"deletedSegmentId"`
-				UserErrors       []struct {
-					Field   []string `json:"field"`
-					Message string   `json:"message"`
-				} `json:"userErrors"`
-			} `json:"segmentDelete"`
-		} `json:"data"`
+		SegmentDelete struct {
+			DeletedSegmentId *string `json:"deletedSegmentId"`
+		} `json:"segmentDelete"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return "", fmt.Errorf("Cannot parse segment delete response: %s", err)
-	}
-
-	if errs := response.Data.SegmentDelete.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return "", fmt.Errorf("Cannot delete segment: %s", strings.Join(messages, ", "))
+	if err := client.ExecuteInto(segmentDeleteMutation, map[string]interface{}{"id": gid}, &response); err != nil {
+		return "", fmt.Errorf("Cannot delete segment: %s", err)
 	}
 
-	if response.Data.SegmentDelete.DeletedSegmentId == nil {
+	if response.SegmentDelete.DeletedSegmentId == nil {
 		return "", fmt.Errorf("Segment not found")
 	}
 
-	return *response.Data.SegmentDelete.DeletedSegmentId, nil
+	return *response.SegmentDelete.DeletedSegmentId, nil
 }
@@ -28,7 +28,12 @@ func listAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	orders, err := listDraftOrders(shop, cmd.LookupAccessToken(shop, c.String("access-token")), ids, skus, status, c.Int("limit"), sortKey)
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := listDraftOrders(shop, token, ids, skus, status, c.Int("limit"), sortKey)
 	if err != nil {
 		return err
 	}
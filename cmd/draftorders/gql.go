@@ -1,7 +1,6 @@
 package draftorders
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -96,13 +95,11 @@ type draftOrderJSON struct {
 }
 
 type draftOrdersResponse struct {
-	Data struct {
-		DraftOrders struct {
-			Edges []struct {
-				Node draftOrderJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"draftOrders"`
-	} `json:"data"`
+	DraftOrders struct {
+		Edges []struct {
+			Node draftOrderJSON `json:"node"`
+		} `json:"edges"`
+	} `json:"draftOrders"`
 }
 
 var draftOrderSortKeys = map[string]string{
@@ -155,23 +152,13 @@ func listDraftOrders(shop, token string, ids []int64, skus []string, status stri
 
 	query := buildQuery(ids, skus, status)
 
-	data, err := client.Execute(draftOrdersQuery, map[string]interface{}{"query": query, "first": limit, "sortKey": sortKey})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list draft orders: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode draft orders response: %s", err)
-	}
-
 	var response draftOrdersResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse draft orders response: %s", err)
+	if err := client.ExecuteInto(draftOrdersQuery, map[string]interface{}{"query": query, "first": limit, "sortKey": sortKey}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list draft orders: %s", err)
 	}
 
 	var result []DraftOrder
-	for _, edge := range response.Data.DraftOrders.Edges {
+	for _, edge := range response.DraftOrders.Edges {
 		n := edge.Node
 		order := DraftOrder{
 			ID:                    n.LegacyResourceId,
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+)
+
+const defaultConcurrency = 5
+
+// FanOutFlags are the global options for running a command against many shops.
+var FanOutFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "shops",
+		Usage: "Run the command against each shop in `FILE`, one per line; use - to read from stdin",
+	},
+	&cli.BoolFlag{
+		Name:  "all-profiles",
+		Usage: "Run the command against the shop of each profile in the config file",
+	},
+	&cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "Number of shops to run the command against at once when using --shops or --all-profiles",
+		Value: defaultConcurrency,
+	},
+}
+
+type fanOutTarget struct {
+	shop    string
+	token   string
+	profile string
+}
+
+// FanOut runs the command given on the command-line once per shop when
+// --shops or --all-profiles is given. Each run is a separate sdt process with
+// the shop and its access token in the environment. Output lines are prefixed
+// with the shop; JSON objects get a "shop" property instead.
+// Meant to be used in the app's Before function: when it runs the command it
+// returns a cli.ExitCoder so the app exits without running it again.
+func FanOut(c *cli.Context) error {
+	if !c.IsSet("shops") && !c.Bool("all-profiles") {
+		return nil
+	}
+
+	if c.NArg() == 0 {
+		return fmt.Errorf("You must supply a command to run against the shops")
+	}
+
+	targets, err := fanOutTargets(c)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		return fmt.Errorf("No shops to run the command against")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Cannot determine path to sdt: %s", err)
+	}
+
+	concurrency := c.Int("concurrency")
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	var failed int
+	var wg sync.WaitGroup
+
+	queue := make(chan fanOutTarget)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for target := range queue {
+				if err := runForShop(executable, target, c.Args().Slice(), &mu); err != nil {
+					mu.Lock()
+					failed++
+					fmt.Fprintln(os.Stderr, prefixLine(target.shop, err.Error()))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		queue <- target
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if failed > 0 {
+		return cli.Exit(fmt.Sprintf("Command failed for %d of %d shops", failed, len(targets)), 1)
+	}
+
+	return cli.Exit("", 0)
+}
+
+func fanOutTargets(c *cli.Context) ([]fanOutTarget, error) {
+	var targets []fanOutTarget
+
+	if c.Bool("all-profiles") {
+		config, err := LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range config.ProfileNames() {
+			profile := config.Profiles[name]
+
+			token := profile.AccessToken
+			if profile.AccessTokenCommand != "" {
+				token = "<" + profile.AccessTokenCommand
+			}
+
+			targets = append(targets, fanOutTarget{shop: profile.Shop, token: token, profile: name})
+		}
+	}
+
+	if c.IsSet("shops") {
+		shops, err := readShopsFile(c.String("shops"))
+		if err != nil {
+			return nil, err
+		}
+
+		// The token from the environment or, if not set, the selected profile
+		token := accessTokenFlag.Value
+		for _, name := range accessTokenFlag.EnvVars {
+			if value := os.Getenv(name); value != "" {
+				token = value
+				break
+			}
+		}
+
+		if err := checkSharedToken(token, shops); err != nil {
+			return nil, err
+		}
+
+		// Each shop's token is looked up when the command is run for it, see runForShop
+		for _, shop := range shops {
+			targets = append(targets, fanOutTarget{shop: shop, token: token})
+		}
+	}
+
+	return targets, nil
+}
+
+// checkSharedToken returns an error if token, used for all the shops, is an access token and
+// there's more than one shop. An access token is only valid for the shop it was created for,
+// only an access token command can give one for each shop.
+func checkSharedToken(token string, shops []string) error {
+	if token == "" || len(shops) < 2 || accessTokenCommand.MatchString(token) {
+		return nil
+	}
+
+	return fmt.Errorf("An access token can only be used for one shop: use an access token command to look up each shop's token, or --all-profiles")
+}
+
+func readShopsFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Cannot open shops file: %s", err)
+		}
+
+		defer file.Close()
+		r = file
+	}
+
+	shops, err := readShops(r)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read shops file: %s", err)
+	}
+
+	return shops, nil
+}
+
+// readShops returns the shop on each line of r, skipping blank lines and
+// lines starting with #.
+func readShops(r io.Reader) ([]string, error) {
+	var shops []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		shops = append(shops, line)
+	}
+
+	return shops, scanner.Err()
+}
+
+func runForShop(executable string, target fanOutTarget, args []string, mu *sync.Mutex) error {
+	if target.profile != "" {
+		args = append([]string{"--profile", target.profile}, args...)
+	}
+
+	child := exec.Command(executable, args...)
+	child.Env = append(os.Environ(), "SHOPIFY_SHOP="+target.shop)
+
+	if target.token != "" {
+		token, err := LookupAccessToken(target.shop, target.token)
+		if err != nil {
+			return err
+		}
+
+		// The token replaces any API credentials in the environment
+		child.Env = append(child.Env, "SHOPIFY_ACCESS_TOKEN="+token, "SHOPIFY_API_KEY=", "SHOPIFY_API_PASSWORD=", "SHOPIFY_API_SECRET=")
+	}
+
+	stdout, err := child.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := child.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("Cannot run command: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go copyPrefixed(os.Stdout, stdout, target.shop, mu, &wg)
+	go copyPrefixed(os.Stderr, stderr, target.shop, mu, &wg)
+	wg.Wait()
+
+	if err := child.Wait(); err != nil {
+		return fmt.Errorf("Command failed: %s", err)
+	}
+
+	return nil
+}
+
+func copyPrefixed(w io.Writer, r io.Reader, shop string, mu *sync.Mutex, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			mu.Lock()
+			fmt.Fprintln(w, prefixLine(shop, strings.TrimSuffix(line, "\n")))
+			mu.Unlock()
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// prefixLine prefixes line with shop. If line is a JSON object a "shop"
+// property is added to it instead.
+func prefixLine(shop, line string) string {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return shop + ": " + line
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(trimmed), &object); err != nil {
+		return shop + ": " + line
+	}
+
+	if _, ok := object["shop"]; ok {
+		return line
+	}
+
+	name, _ := json.Marshal(shop)
+
+	rest := strings.TrimSpace(trimmed[1:])
+	if rest == "}" {
+		return `{"shop":` + string(name) + "}"
+	}
+
+	return `{"shop":` + string(name) + "," + rest
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrefixLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "text", line: "ID  Topic", want: "acme: ID  Topic"},
+		{name: "json object", line: `{"id":1,"topic":"orders/create"}`, want: `{"shop":"acme","id":1,"topic":"orders/create"}`},
+		{name: "empty json object", line: `{}`, want: `{"shop":"acme"}`},
+		{name: "json with shop", line: `{"shop":"other","id":1}`, want: `{"shop":"other","id":1}`},
+		{name: "invalid json", line: `{ not json`, want: "acme: { not json"},
+	}
+
+	for _, tt := range tests {
+		if got := prefixLine("acme", tt.line); got != tt.want {
+			t.Errorf("%s: prefixLine() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadShops(t *testing.T) {
+	shops, err := readShops(strings.NewReader("acme\n\n# clients\n  beta.myshopify.com  \n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := []string{"acme", "beta.myshopify.com"}; !reflect.DeepEqual(shops, want) {
+		t.Errorf("shops = %v, want %v", shops, want)
+	}
+}
+
+func TestCheckSharedToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		shops   []string
+		wantErr bool
+	}{
+		{name: "token for one shop", token: "shpat", shops: []string{"acme"}},
+		{name: "token for many shops", token: "shpat", shops: []string{"acme", "beta"}, wantErr: true},
+		{name: "command for many shops", token: "< token-for", shops: []string{"acme", "beta"}},
+		{name: "no token", shops: []string{"acme", "beta"}},
+	}
+
+	for _, tt := range tests {
+		err := checkSharedToken(tt.token, tt.shops)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkSharedToken() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
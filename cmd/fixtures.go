@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// replaying is true when responses come from --replay's fixtures, in which
+// case no credentials are needed.
+var replaying bool
+
+// FixtureFlags are the global options for recording and replaying HTTP
+// requests.
+var FixtureFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    "record",
+		Usage:   "Write each HTTP request and response to a file in `DIR`, with credentials removed",
+		EnvVars: []string{"SDT_RECORD"},
+	},
+	&cli.StringFlag{
+		Name:    "replay",
+		Usage:   "Respond to HTTP requests with the responses recorded in `DIR` instead of contacting Shopify",
+		EnvVars: []string{"SDT_REPLAY"},
+	},
+}
+
+// AdminURLFlag is the global option for sending Admin API requests somewhere
+// other than Shopify, e.g., to "sdt mock-server".
+var AdminURLFlag = &cli.StringFlag{
+	Name:        "admin-url",
+	Usage:       "Send Admin API requests to `URL` instead of Shopify; %s is replaced with the shop's name",
+	EnvVars:     []string{"SDT_ADMIN_URL"},
+	Value:       gql.AdminURL,
+	Destination: &gql.AdminURL,
+}
+
+// UseFixtures sets up recording or replaying of HTTP requests when --record or
+// --replay is given. Meant to be used in the app's Before function.
+func UseFixtures(c *cli.Context) error {
+	record, replay := c.String("record"), c.String("replay")
+
+	// Commands run by --shops and --all-profiles are separate processes
+	if c.IsSet("admin-url") {
+		os.Setenv("SDT_ADMIN_URL", gql.AdminURL)
+	}
+
+	if record != "" && replay != "" {
+		return fmt.Errorf("--record and --replay cannot be used together")
+	}
+
+	if record != "" {
+		gql.HTTPClient.Transport = gql.NewRecorder(record, nil)
+		os.Setenv("SDT_RECORD", record)
+	}
+
+	if replay != "" {
+		if _, err := os.Stat(replay); err != nil {
+			return fmt.Errorf("Cannot replay from %s: %s", replay, err)
+		}
+
+		gql.HTTPClient.Transport = gql.NewReplayer(replay)
+		replaying = true
+		os.Setenv("SDT_REPLAY", replay)
+	}
+
+	return nil
+}
@@ -61,7 +61,13 @@ func queryAction(c *cli.Context) error {
 		"extras":  c.Bool("extras"),
 		"verbose": c.Bool("verbose"),
 	}
-	client := gql.NewClient(shop, cmd.LookupAccessToken(shop, c.String("access-token")), options)
+
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	client := gql.NewClient(shop, token, options)
 
 	query, err := findQuery(c)
 	if err != nil {
@@ -1,7 +1,6 @@
 package inventory
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -43,9 +42,7 @@ type inventoryItemJSON struct {
 }
 
 type inventoryItemsResponse struct {
-	Data struct {
-		Nodes []*inventoryItemJSON `json:"nodes"`
-	} `json:"data"`
+	Nodes []*inventoryItemJSON `json:"nodes"`
 }
 
 // FetchProductsByInventoryItemIDs returns the products (with their
@@ -53,22 +50,12 @@ type inventoryItemsResponse struct {
 // share a product are merged into a single product. The second return
 // value holds the ids for which no inventory item was found.
 func FetchProductsByInventoryItemIDs(client *gqlclient.Client, ids []string) ([]productsgql.Product, []string, error) {
-	data, err := client.Execute(inventoryItemsQuery, map[string]interface{}{"ids": ids})
-	if err != nil {
-		return nil, nil, fmt.Errorf("Cannot fetch inventory items: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, nil, fmt.Errorf("Cannot re-encode inventory items response: %s", err)
-	}
-
 	var response inventoryItemsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, nil, fmt.Errorf("Cannot parse inventory items response: %s", err)
+	if err := client.ExecuteInto(inventoryItemsQuery, map[string]interface{}{"ids": ids}, &response); err != nil {
+		return nil, nil, fmt.Errorf("Cannot fetch inventory items: %s", err)
 	}
 
-	return productsFromInventoryItems(response.Data.Nodes, ids)
+	return productsFromInventoryItems(response.Nodes, ids)
 }
 
 // productsFromInventoryItems converts the inventory item nodes into the
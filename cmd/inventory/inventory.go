@@ -56,7 +56,12 @@ func items(c *cli.Context) error {
 		argForGID[gid] = arg
 	}
 
-	productList, missing, err := FetchProductsByInventoryItemIDs(cmd.NewGraphQLClient(c), ids)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	productList, missing, err := FetchProductsByInventoryItemIDs(client, ids)
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,6 @@
 package locations
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -73,78 +72,33 @@ type locationNodeJSON struct {
 	} `json:"address"`
 }
 
-type locationsResponse struct {
-	Data struct {
-		Locations struct {
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-			Edges []struct {
-				Node locationNodeJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"locations"`
-	} `json:"data"`
-}
-
 type locationsByIDResponse struct {
-	Data struct {
-		Nodes []*locationNodeJSON `json:"nodes"`
-	} `json:"data"`
+	Nodes []*locationNodeJSON `json:"nodes"`
 }
 
 func ListLocations(client *gql.Client) ([]Location, error) {
 	var locations []Location
-	vars := map[string]interface{}{"first": 250}
-
-	for {
-		data, err := client.Execute(locationsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list locations: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot re-encode locations response: %s", err)
-		}
-
-		var response locationsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot parse locations response: %s", err)
-		}
-
-		for _, edge := range response.Data.Locations.Edges {
-			locations = append(locations, locationFromNode(edge.Node))
-		}
 
-		if !response.Data.Locations.PageInfo.HasNextPage {
-			break
-		}
+	err := gql.Paginate(client, locationsQuery, nil, "locations", func(n locationNodeJSON) error {
+		locations = append(locations, locationFromNode(n))
+		return nil
+	})
 
-		vars["after"] = response.Data.Locations.PageInfo.EndCursor
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list locations: %s", err)
 	}
 
 	return locations, nil
 }
 
 func LocationsByID(client *gql.Client, ids []string) ([]Location, error) {
-	data, err := client.Execute(locationsByIDQuery, map[string]interface{}{"ids": ids})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get locations: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode locations response: %s", err)
-	}
-
 	var response locationsByIDResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse locations response: %s", err)
+	if err := client.ExecuteInto(locationsByIDQuery, map[string]interface{}{"ids": ids}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get locations: %s", err)
 	}
 
 	var locations []Location
-	for i, node := range response.Data.Nodes {
+	for i, node := range response.Nodes {
 		if node == nil {
 			return nil, fmt.Errorf("Cannot get location %s: no location found with that id", ids[i])
 		}
@@ -24,10 +24,12 @@ func toLocationGID(id string) string {
 }
 
 func listLocations(c *cli.Context) error {
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	var locations []Location
-	var err error
 
 	if c.NArg() > 0 {
 		ids := make([]string, 0, c.NArg())
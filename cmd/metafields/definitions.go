@@ -0,0 +1,682 @@
+package metafields
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const (
+	definitionsFormatJSON = "json"
+	definitionsFormatYAML = "yaml"
+)
+
+// DefinitionDocument is a metafield definition in a definitions file
+type DefinitionDocument struct {
+	OwnerType   string                `json:"ownerType" yaml:"ownerType"`
+	Namespace   string                `json:"namespace" yaml:"namespace"`
+	Key         string                `json:"key" yaml:"key"`
+	Name        string                `json:"name" yaml:"name"`
+	Description string                `json:"description,omitempty" yaml:"description,omitempty"`
+	Type        string                `json:"type" yaml:"type"`
+	Validations []MetafieldValidation `json:"validations,omitempty" yaml:"validations,omitempty"`
+	// Access not given is not changed
+	Access *DefinitionAccess `json:"access,omitempty" yaml:"access,omitempty"`
+	Pinned bool              `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+}
+
+type DefinitionAccess struct {
+	Admin      string `json:"admin,omitempty" yaml:"admin,omitempty"`
+	Storefront string `json:"storefront,omitempty" yaml:"storefront,omitempty"`
+}
+
+func (d DefinitionDocument) String() string {
+	return d.OwnerType + " " + d.Namespace + "." + d.Key
+}
+
+// definitionChange is what applying a DefinitionDocument does
+type definitionChange struct {
+	Document DefinitionDocument
+	// create, update, or unchanged
+	Action string
+	// Properties that are changed by an update
+	Changes []string
+	Error   string
+}
+
+// isAppNamespace returns true if namespace is reserved for an app. Only these definitions can have their
+// admin access set.
+func isAppNamespace(namespace string) bool {
+	return strings.HasPrefix(namespace, "$app") || strings.HasPrefix(namespace, "app--")
+}
+
+// DefinitionToDocument converts the definition for a definitions file. Access that is the default
+// is omitted so the file can be applied to other shops.
+func DefinitionToDocument(d MetafieldDefinition) DefinitionDocument {
+	doc := DefinitionDocument{
+		OwnerType:   d.OwnerType,
+		Namespace:   d.Namespace,
+		Key:         d.Key,
+		Name:        d.Name,
+		Description: d.Description,
+		Type:        d.Type,
+		Validations: d.Validations,
+		Pinned:      d.PinnedPosition > 0,
+	}
+
+	access := DefinitionAccess{}
+	if isAppNamespace(d.Namespace) {
+		access.Admin = d.AdminAccess
+	}
+
+	if d.StorefrontAccess != "" && d.StorefrontAccess != "NONE" {
+		access.Storefront = d.StorefrontAccess
+	}
+
+	if access != (DefinitionAccess{}) {
+		doc.Access = &access
+	}
+
+	return doc
+}
+
+// definitionsFileFormat returns format if given, otherwise the format of filename based on its extension
+func definitionsFileFormat(filename, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return definitionsFormatJSON, nil
+	case "yaml", "yml":
+		return definitionsFormatYAML, nil
+	}
+
+	return "", fmt.Errorf("Unknown format for %s: must be json or yaml, use --format", filename)
+}
+
+// readDefinitions reads a list of definitions in format. Unknown properties are an error.
+func readDefinitions(r io.Reader, format string) ([]DefinitionDocument, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read metafield definitions: %s", err)
+	}
+
+	var docs []DefinitionDocument
+
+	if format == definitionsFormatYAML {
+		err = yaml.UnmarshalStrict(data, &docs)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&docs)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot parse metafield definitions: %s", err)
+	}
+
+	if err := ValidateDefinitions(docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// ValidateDefinitions checks that the definitions have the required properties and are only given once.
+// Owner types and access are uppercased and names default to the key.
+func ValidateDefinitions(docs []DefinitionDocument) error {
+	seen := map[string]bool{}
+
+	for i := range docs {
+		doc := &docs[i]
+		doc.OwnerType = strings.ToUpper(doc.OwnerType)
+
+		if doc.OwnerType == "" || doc.Namespace == "" || doc.Key == "" || doc.Type == "" {
+			return fmt.Errorf("Metafield definition %d invalid: ownerType, namespace, key, and type are required", i+1)
+		}
+
+		if seen[doc.String()] {
+			return fmt.Errorf("Metafield definition %d invalid: %s is given more than once", i+1, doc)
+		}
+
+		seen[doc.String()] = true
+
+		if doc.Name == "" {
+			doc.Name = doc.Key
+		}
+
+		if doc.Access != nil {
+			doc.Access.Admin = strings.ToUpper(doc.Access.Admin)
+			doc.Access.Storefront = strings.ToUpper(doc.Access.Storefront)
+		}
+	}
+
+	return nil
+}
+
+func writeDefinitions(w io.Writer, docs []DefinitionDocument, format string) error {
+	var data []byte
+	var err error
+
+	if format == definitionsFormatYAML {
+		data, err = yaml.Marshal(docs)
+	} else {
+		data, err = json.MarshalIndent(docs, "", "  ")
+		data = append(data, '\n')
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cannot encode metafield definitions: %s", err)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+func accessInput(access *DefinitionAccess) map[string]interface{} {
+	input := map[string]interface{}{}
+	if access.Admin != "" {
+		input["admin"] = access.Admin
+	}
+
+	if access.Storefront != "" {
+		input["storefront"] = access.Storefront
+	}
+
+	return input
+}
+
+// DefinitionInput returns the MetafieldDefinitionInput, or with update the MetafieldDefinitionUpdateInput, for doc
+func DefinitionInput(doc DefinitionDocument, update bool) map[string]interface{} {
+	validations := doc.Validations
+	if validations == nil {
+		validations = []MetafieldValidation{}
+	}
+
+	input := map[string]interface{}{
+		"ownerType":   doc.OwnerType,
+		"namespace":   doc.Namespace,
+		"key":         doc.Key,
+		"name":        doc.Name,
+		"description": doc.Description,
+		"validations": validations,
+		"pin":         doc.Pinned,
+	}
+
+	if !update {
+		input["type"] = doc.Type
+	}
+
+	if doc.Access != nil {
+		input["access"] = accessInput(doc.Access)
+	}
+
+	return input
+}
+
+func sortedValidations(validations []MetafieldValidation) []MetafieldValidation {
+	sorted := append([]MetafieldValidation{}, validations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	return sorted
+}
+
+// definitionChanges returns the properties of current that differ from doc
+func definitionChanges(current MetafieldDefinition, doc DefinitionDocument) ([]string, error) {
+	if current.Type != doc.Type {
+		return nil, fmt.Errorf("Type cannot be changed from %s to %s, delete the definition first", current.Type, doc.Type)
+	}
+
+	var changes []string
+
+	if current.Name != doc.Name {
+		changes = append(changes, "name")
+	}
+
+	if current.Description != doc.Description {
+		changes = append(changes, "description")
+	}
+
+	if len(current.Validations) != len(doc.Validations) ||
+		(len(doc.Validations) > 0 && !equalValidations(sortedValidations(current.Validations), sortedValidations(doc.Validations))) {
+		changes = append(changes, "validations")
+	}
+
+	if doc.Access != nil {
+		if doc.Access.Admin != "" && doc.Access.Admin != current.AdminAccess {
+			changes = append(changes, "admin access")
+		}
+
+		if doc.Access.Storefront != "" && doc.Access.Storefront != current.StorefrontAccess {
+			changes = append(changes, "storefront access")
+		}
+	}
+
+	if (current.PinnedPosition > 0) != doc.Pinned {
+		changes = append(changes, "pinned")
+	}
+
+	return changes, nil
+}
+
+func equalValidations(a, b []MetafieldValidation) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// planDefinitions returns the changes needed for the shop's definitions to match docs. Definitions
+// not in docs are left as is.
+func planDefinitions(client *gql.Client, docs []DefinitionDocument) ([]definitionChange, error) {
+	// Current definitions by owner type then namespace.key
+	current := map[string]map[string]MetafieldDefinition{}
+	changes := make([]definitionChange, len(docs))
+
+	for i, doc := range docs {
+		if _, ok := current[doc.OwnerType]; !ok {
+			definitions, err := ListMetafieldDefinitions(client, doc.OwnerType, "")
+			if err != nil {
+				return nil, err
+			}
+
+			current[doc.OwnerType] = map[string]MetafieldDefinition{}
+			for _, d := range definitions {
+				current[doc.OwnerType][d.Namespace+"."+d.Key] = d
+			}
+		}
+
+		changes[i] = definitionChange{Document: doc, Action: "create"}
+
+		definition, ok := current[doc.OwnerType][doc.Namespace+"."+doc.Key]
+		if !ok {
+			continue
+		}
+
+		properties, err := definitionChanges(definition, doc)
+		if err != nil {
+			changes[i].Error = err.Error()
+		}
+
+		changes[i].Changes = properties
+		changes[i].Action = "update"
+		if err == nil && len(properties) == 0 {
+			changes[i].Action = "unchanged"
+		}
+	}
+
+	return changes, nil
+}
+
+// applyDefinitionChanges makes the changes, setting the Error of those that fail
+func applyDefinitionChanges(client *gql.Client, changes []definitionChange) {
+	for i := range changes {
+		change := &changes[i]
+		if change.Error != "" {
+			continue
+		}
+
+		var err error
+
+		switch change.Action {
+		case "create":
+			_, err = CreateMetafieldDefinition(client, DefinitionInput(change.Document, false))
+		case "update":
+			_, err = UpdateMetafieldDefinition(client, DefinitionInput(change.Document, true))
+		}
+
+		if err != nil {
+			change.Error = err.Error()
+		}
+	}
+}
+
+func printDefinitionChanges(changes []definitionChange) {
+	t := tabby.New()
+	t.AddHeader("Owner Type", "Definition", "Action", "Status")
+
+	for _, change := range changes {
+		status := "OK"
+		if change.Error != "" {
+			status = "Error: " + change.Error
+		} else if len(change.Changes) > 0 {
+			status = "Changes: " + strings.Join(change.Changes, ", ")
+		}
+
+		t.AddLine(change.Document.OwnerType, change.Document.Namespace+"."+change.Document.Key, change.Action, status)
+	}
+
+	t.Print()
+}
+
+func printDefinition(def MetafieldDefinition) {
+	t := tabby.New()
+	t.AddLine("Gid", def.ID)
+	t.AddLine("Name", def.Name)
+	t.AddLine("Namespace", def.Namespace)
+	t.AddLine("Key", def.Key)
+	t.AddLine("Description", def.Description)
+	t.AddLine("Type", def.Type)
+	t.AddLine("Owner Type", def.OwnerType)
+
+	for _, v := range def.Validations {
+		t.AddLine("Validation", v.Name+": "+v.Value)
+	}
+
+	t.AddLine("Admin Access", def.AdminAccess)
+	t.AddLine("Storefront Access", def.StorefrontAccess)
+
+	if def.PinnedPosition > 0 {
+		t.AddLine("Pinned", def.PinnedPosition)
+	} else {
+		t.AddLine("Pinned", "No")
+	}
+
+	t.Print()
+	fmt.Printf("%s\n", strings.Repeat("-", 20))
+}
+
+// definitionArgs returns the owner type and the namespace and key of the definition given by the arguments
+func definitionArgs(c *cli.Context) (string, string, string, error) {
+	if c.NArg() != 2 {
+		return "", "", "", errors.New("Resource name and namespace.key required")
+	}
+
+	namespace, key, err := splitNamespaceKey(c.Args().Get(1))
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return strings.ToUpper(c.Args().Get(0)), namespace, key, nil
+}
+
+// findDefinitionArgs returns the definition given by the arguments
+func findDefinitionArgs(c *cli.Context) (*gql.Client, MetafieldDefinition, error) {
+	ownerType, namespace, key, err := definitionArgs(c)
+	if err != nil {
+		return nil, MetafieldDefinition{}, err
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return nil, MetafieldDefinition{}, err
+	}
+
+	definition, err := findMetafieldDefinition(client, ownerType, namespace, key)
+	if err != nil {
+		return nil, MetafieldDefinition{}, err
+	}
+
+	if definition == nil {
+		return nil, MetafieldDefinition{}, fmt.Errorf("Metafield definition %s.%s not found for %s", namespace, key, ownerType)
+	}
+
+	return client, *definition, nil
+}
+
+// definitionFlagsInput adds the definition properties given by the flags to input
+func definitionFlagsInput(c *cli.Context, input map[string]interface{}) error {
+	for _, name := range []string{"name", "description"} {
+		if c.IsSet(name) {
+			input[name] = c.String(name)
+		}
+	}
+
+	if c.IsSet("validation") {
+		validations := []MetafieldValidation{}
+		for _, arg := range c.StringSlice("validation") {
+			name, value, ok := strings.Cut(arg, "=")
+			if !ok || name == "" {
+				return fmt.Errorf("Validation %q invalid: must be in name=value format", arg)
+			}
+
+			validations = append(validations, MetafieldValidation{Name: name, Value: value})
+		}
+
+		input["validations"] = validations
+	}
+
+	access := accessInput(&DefinitionAccess{
+		Admin:      strings.ToUpper(c.String("admin-access")),
+		Storefront: strings.ToUpper(c.String("storefront-access")),
+	})
+
+	if len(access) > 0 {
+		input["access"] = access
+	}
+
+	return nil
+}
+
+func definitionCreateAction(c *cli.Context) error {
+	ownerType, namespace, key, err := definitionArgs(c)
+	if err != nil {
+		return err
+	}
+
+	if c.String("type") == "" {
+		return errors.New("Type required, use --type")
+	}
+
+	input := map[string]interface{}{
+		"ownerType": ownerType,
+		"namespace": namespace,
+		"key":       key,
+		"name":      key,
+		"type":      c.String("type"),
+		"pin":       c.Bool("pin"),
+	}
+
+	if err := definitionFlagsInput(c, input); err != nil {
+		return err
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	definition, err := CreateMetafieldDefinition(client, input)
+	if err != nil {
+		return err
+	}
+
+	printDefinition(definition)
+	return nil
+}
+
+func definitionUpdateAction(c *cli.Context) error {
+	ownerType, namespace, key, err := definitionArgs(c)
+	if err != nil {
+		return err
+	}
+
+	input := map[string]interface{}{
+		"ownerType": ownerType,
+		"namespace": namespace,
+		"key":       key,
+	}
+
+	if err := definitionFlagsInput(c, input); err != nil {
+		return err
+	}
+
+	if len(input) == 3 {
+		return errors.New("Nothing to update, give one or more of --name, --description, --validation, --admin-access, or --storefront-access")
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	definition, err := UpdateMetafieldDefinition(client, input)
+	if err != nil {
+		return err
+	}
+
+	printDefinition(definition)
+	return nil
+}
+
+func definitionDeleteAction(c *cli.Context) error {
+	client, definition, err := findDefinitionArgs(c)
+	if err != nil {
+		return err
+	}
+
+	withValues := c.Bool("delete-with-values")
+
+	if withValues && !c.Bool("yes") {
+		question := fmt.Sprintf("Delete the metafield definition %s.%s and all %s metafields with its namespace and key?", definition.Namespace, definition.Key, definition.OwnerType)
+
+		ok, err := cmd.Confirm(os.Stdin, os.Stdout, question)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("Nothing was changed")
+		}
+	}
+
+	if err := deleteMetafieldDefinition(client, definition.ID, withValues); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted %s\n", definition.ID)
+	return nil
+}
+
+func definitionPinAction(pin bool) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		client, definition, err := findDefinitionArgs(c)
+		if err != nil {
+			return err
+		}
+
+		if err := pinMetafieldDefinition(client, definition.ID, pin); err != nil {
+			return err
+		}
+
+		if pin {
+			fmt.Printf("Pinned %s\n", definition.ID)
+		} else {
+			fmt.Printf("Unpinned %s\n", definition.ID)
+		}
+
+		return nil
+	}
+}
+
+func definitionExportAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("Resource name required")
+	}
+
+	output := c.String("output")
+	format := c.String("format")
+
+	if output != "" || format != "" {
+		var err error
+		if format, err = definitionsFileFormat(output, format); err != nil {
+			return err
+		}
+	} else {
+		format = definitionsFormatJSON
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	docs := []DefinitionDocument{}
+	for _, arg := range c.Args().Slice() {
+		definitions, err := ListMetafieldDefinitions(client, strings.ToUpper(arg), c.String("namespace"))
+		if err != nil {
+			return err
+		}
+
+		for _, d := range definitions {
+			docs = append(docs, DefinitionToDocument(d))
+		}
+	}
+
+	if output == "" {
+		return writeDefinitions(os.Stdout, docs, format)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("Cannot create definitions file: %s", err)
+	}
+	defer f.Close()
+
+	return writeDefinitions(f, docs, format)
+}
+
+func definitionApplyAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("File path required")
+	}
+
+	filename := c.Args().First()
+	format, err := definitionsFileFormat(filename, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("Cannot open definitions file: %s", err)
+	}
+	defer f.Close()
+
+	docs, err := readDefinitions(f, format)
+	if err != nil {
+		return err
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	changes, err := planDefinitions(client, docs)
+	if err != nil {
+		return err
+	}
+
+	if !c.Bool("dry-run") {
+		applyDefinitionChanges(client, changes)
+	}
+
+	printDefinitionChanges(changes)
+
+	for _, change := range changes {
+		if change.Error != "" {
+			return cli.Exit("", 1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package metafields
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+var definitionsSeed = mock.Seed{
+	Products: setSeed.Products,
+	MetafieldDefinitions: []map[string]interface{}{
+		{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit", "name": "Fit", "type": "single_line_text_field"},
+		{
+			"ownerType":   "PRODUCT",
+			"namespace":   "custom",
+			"key":         "weight",
+			"name":        "Weight",
+			"type":        "number_integer",
+			"validations": []interface{}{map[string]interface{}{"name": "max", "value": "100"}},
+		},
+	},
+}
+
+func TestReadDefinitions(t *testing.T) {
+	yamlFile := `
+- ownerType: product
+  namespace: custom
+  key: fit
+  type: single_line_text_field
+  pinned: true
+  access:
+    storefront: public_read
+- ownerType: PRODUCTVARIANT
+  namespace: custom
+  key: weight
+  name: Weight
+  type: number_integer
+  validations:
+    - name: max
+      value: "100"
+`
+
+	docs, err := readDefinitions(strings.NewReader(yamlFile), definitionsFormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DefinitionDocument{
+		{OwnerType: "PRODUCT", Namespace: "custom", Key: "fit", Name: "fit", Type: "single_line_text_field", Pinned: true, Access: &DefinitionAccess{Storefront: "PUBLIC_READ"}},
+		{OwnerType: "PRODUCTVARIANT", Namespace: "custom", Key: "weight", Name: "Weight", Type: "number_integer", Validations: []MetafieldValidation{{Name: "max", Value: "100"}}},
+	}
+
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("docs = %+v, want %+v", docs, want)
+	}
+
+	var out bytes.Buffer
+	if err := writeDefinitions(&out, docs, definitionsFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	roundTrip, err := readDefinitions(&out, definitionsFormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(roundTrip, want) {
+		t.Errorf("JSON docs = %+v, want %+v", roundTrip, want)
+	}
+
+	invalid := []string{
+		`[{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit"}]`,
+		`[{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit", "type": "url", "pin": true}]`,
+		`[{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit", "type": "url"}, {"ownerType": "product", "namespace": "custom", "key": "fit", "type": "url"}]`,
+	}
+
+	for _, file := range invalid {
+		if _, err := readDefinitions(strings.NewReader(file), definitionsFormatJSON); err == nil {
+			t.Errorf("readDefinitions(%s) did not fail", file)
+		}
+	}
+}
+
+func TestDefinitionToDocument(t *testing.T) {
+	tests := []struct {
+		definition MetafieldDefinition
+		want       *DefinitionAccess
+	}{
+		{MetafieldDefinition{Namespace: "custom", AdminAccess: "PUBLIC_READ_WRITE", StorefrontAccess: "NONE"}, nil},
+		{MetafieldDefinition{Namespace: "custom", AdminAccess: "PUBLIC_READ_WRITE", StorefrontAccess: "PUBLIC_READ"}, &DefinitionAccess{Storefront: "PUBLIC_READ"}},
+		{MetafieldDefinition{Namespace: "$app:reviews", AdminAccess: "MERCHANT_READ", StorefrontAccess: "NONE"}, &DefinitionAccess{Admin: "MERCHANT_READ"}},
+	}
+
+	for _, tt := range tests {
+		got := DefinitionToDocument(tt.definition).Access
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DefinitionToDocument(%+v).Access = %+v, want %+v", tt.definition, got, tt.want)
+		}
+	}
+}
+
+func TestApplyDefinitions(t *testing.T) {
+	client := mockClient(t, definitionsSeed)
+
+	docs := []DefinitionDocument{
+		{OwnerType: "PRODUCT", Namespace: "custom", Key: "fit", Name: "Fit", Type: "single_line_text_field"},
+		{OwnerType: "PRODUCT", Namespace: "custom", Key: "weight", Name: "Weight (g)", Type: "number_integer", Validations: []MetafieldValidation{{Name: "min", Value: "1"}, {Name: "max", Value: "500"}}, Pinned: true},
+		{OwnerType: "PRODUCT", Namespace: "custom", Key: "care", Name: "Care", Type: "multi_line_text_field", Access: &DefinitionAccess{Storefront: "PUBLIC_READ"}},
+		{OwnerType: "COLLECTION", Namespace: "custom", Key: "fit", Name: "Fit", Type: "single_line_text_field"},
+	}
+
+	changes, err := planDefinitions(client, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		action  string
+		changes []string
+	}{
+		{"unchanged", nil},
+		{"update", []string{"name", "validations", "pinned"}},
+		{"create", nil},
+		{"create", nil},
+	}
+
+	for i, w := range want {
+		if changes[i].Action != w.action || !reflect.DeepEqual(changes[i].Changes, w.changes) || changes[i].Error != "" {
+			t.Errorf("change %d = %+v, want %s %v", i, changes[i], w.action, w.changes)
+		}
+	}
+
+	applyDefinitionChanges(client, changes)
+
+	for _, change := range changes {
+		if change.Error != "" {
+			t.Errorf("%s failed: %s", change.Document, change.Error)
+		}
+	}
+
+	changes, err = planDefinitions(client, docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, change := range changes {
+		if change.Action != "unchanged" {
+			t.Errorf("%s action = %s %v after applying, want unchanged", change.Document, change.Action, change.Changes)
+		}
+	}
+
+	care, err := findMetafieldDefinition(client, "PRODUCT", "custom", "care")
+	if err != nil || care == nil {
+		t.Fatalf("findMetafieldDefinition(care) = %v, %v", care, err)
+	}
+
+	if care.StorefrontAccess != "PUBLIC_READ" {
+		t.Errorf("care storefront access = %s, want PUBLIC_READ", care.StorefrontAccess)
+	}
+
+	docs[0].Type = "url"
+
+	changes, err = planDefinitions(client, docs[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes[0].Error == "" {
+		t.Errorf("changing the type = %+v, want an error", changes[0])
+	}
+}
+
+func TestPinAndDeleteDefinition(t *testing.T) {
+	client := mockClient(t, definitionsSeed)
+
+	fit, err := findMetafieldDefinition(client, "PRODUCT", "custom", "fit")
+	if err != nil || fit == nil {
+		t.Fatalf("findMetafieldDefinition(fit) = %v, %v", fit, err)
+	}
+
+	if err := pinMetafieldDefinition(client, fit.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pinMetafieldDefinition(client, fit.ID, true); err == nil {
+		t.Error("pinning a pinned definition did not fail")
+	}
+
+	if fit, _ = findMetafieldDefinition(client, "PRODUCT", "custom", "fit"); fit.PinnedPosition != 1 {
+		t.Errorf("pinned position = %d, want 1", fit.PinnedPosition)
+	}
+
+	if err := pinMetafieldDefinition(client, fit.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := resolveOwners(client, []string{"handle:hat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := metafieldSetInput{OwnerID: ids["handle:hat"], Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}
+	if _, err := setMetafields(client, []metafieldSetInput{input}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deleteMetafieldDefinition(client, fit.ID, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if fit, err = findMetafieldDefinition(client, "PRODUCT", "custom", "fit"); fit != nil || err != nil {
+		t.Errorf("findMetafieldDefinition(fit) after deleting = %v, %v", fit, err)
+	}
+
+	metafields, err := listOwnerMetafields(client, ids["handle:hat"], "custom", "fit", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 0 {
+		t.Errorf("metafields after deleting with values = %+v, want none", metafields)
+	}
+}
@@ -1,7 +1,6 @@
 package metafields
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
@@ -11,20 +10,33 @@ import (
 	"github.com/ScreenStaring/shopify-dev-tools/gql"
 )
 
+const metafieldDefinitionFields = `
+id
+name
+namespace
+key
+description
+type {
+  name
+}
+ownerType
+validations {
+  name
+  value
+}
+access {
+  admin
+  storefront
+}
+pinnedPosition
+`
+
 const metafieldDefinitionsQuery = `
-query($ownerType: MetafieldOwnerType!, $first: Int!, $after: String, $namespace: String) {
-  metafieldDefinitions(ownerType: $ownerType, first: $first, after: $after, namespace: $namespace) {
+query($ownerType: MetafieldOwnerType!, $first: Int!, $after: String, $namespace: String, $key: String) {
+  metafieldDefinitions(ownerType: $ownerType, first: $first, after: $after, namespace: $namespace, key: $key) {
     edges {
       node {
-        id
-        name
-        namespace
-        key
-        description
-        type {
-          name
-        }
-        ownerType
+        ` + metafieldDefinitionFields + `
       }
     }
     pageInfo {
@@ -43,6 +55,17 @@ type MetafieldDefinition struct {
 	Description string
 	Type        string
 	OwnerType   string
+	Validations []MetafieldValidation
+	// MetafieldAdminAccess and MetafieldStorefrontAccess, e.g., MERCHANT_READ_WRITE and PUBLIC_READ
+	AdminAccess      string
+	StorefrontAccess string
+	// 0 when not pinned
+	PinnedPosition int
+}
+
+type MetafieldValidation struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
 }
 
 // Metafield is the package's native metafield shape as returned by the
@@ -56,33 +79,45 @@ type Metafield struct {
 	Type        string `json:"type"`
 	CreatedAt   string `json:"createdAt"`
 	UpdatedAt   string `json:"updatedAt"`
+	// Resource the metafield belongs to as metafield import accepts, see portableOwner
+	Owner string `json:"owner"`
 }
 
-type metafieldDefinitionsResponse struct {
-	Data struct {
-		MetafieldDefinitions struct {
-			Edges []struct {
-				Node struct {
-					ID          string `json:"id"`
-					Name        string `json:"name"`
-					Namespace   string `json:"namespace"`
-					Key         string `json:"key"`
-					Description string `json:"description"`
-					Type        struct {
-						Name string `json:"name"`
-					} `json:"type"`
-					OwnerType string `json:"ownerType"`
-				} `json:"node"`
-			} `json:"edges"`
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-		} `json:"metafieldDefinitions"`
-	} `json:"data"`
-}
-
-func listMetafieldDefinitions(client *gql.Client, ownerType, namespace string) ([]MetafieldDefinition, error) {
+type metafieldDefinitionJSON struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Type        struct {
+		Name string `json:"name"`
+	} `json:"type"`
+	OwnerType   string                `json:"ownerType"`
+	Validations []MetafieldValidation `json:"validations"`
+	Access      struct {
+		Admin      string `json:"admin"`
+		Storefront string `json:"storefront"`
+	} `json:"access"`
+	PinnedPosition int `json:"pinnedPosition"`
+}
+
+func (n metafieldDefinitionJSON) definition() MetafieldDefinition {
+	return MetafieldDefinition{
+		ID:               n.ID,
+		Name:             n.Name,
+		Namespace:        n.Namespace,
+		Key:              n.Key,
+		Description:      n.Description,
+		Type:             n.Type.Name,
+		OwnerType:        n.OwnerType,
+		Validations:      n.Validations,
+		AdminAccess:      n.Access.Admin,
+		StorefrontAccess: n.Access.Storefront,
+		PinnedPosition:   n.PinnedPosition,
+	}
+}
+
+func ListMetafieldDefinitions(client *gql.Client, ownerType, namespace string) ([]MetafieldDefinition, error) {
 	vars := map[string]interface{}{
 		"ownerType": ownerType,
 		"first":     250,
@@ -94,206 +129,184 @@ func listMetafieldDefinitions(client *gql.Client, ownerType, namespace string) (
 
 	var definitions []MetafieldDefinition
 
-	for {
-		data, err := client.Execute(metafieldDefinitionsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafield definitions: %s", err)
-		}
+	err := gql.Paginate(client, metafieldDefinitionsQuery, vars, "metafieldDefinitions", func(n metafieldDefinitionJSON) error {
+		definitions = append(definitions, n.definition())
+		return nil
+	})
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafield definitions: %s", err)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metafield definitions: %s", err)
+	}
 
-		var response metafieldDefinitionsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafield definitions: %s", err)
-		}
+	return definitions, nil
+}
 
-		for _, edge := range response.Data.MetafieldDefinitions.Edges {
-			n := edge.Node
-			definitions = append(definitions, MetafieldDefinition{
-				ID:          n.ID,
-				Name:        n.Name,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Type:        n.Type.Name,
-				OwnerType:   n.OwnerType,
-			})
-		}
+// findMetafieldDefinition returns the ownerType's definition with the namespace and key, or nil if there isn't one
+func findMetafieldDefinition(client *gql.Client, ownerType, namespace, key string) (*MetafieldDefinition, error) {
+	vars := map[string]interface{}{
+		"ownerType": ownerType,
+		"namespace": namespace,
+		"key":       key,
+		"first":     1,
+	}
 
-		if !response.Data.MetafieldDefinitions.PageInfo.HasNextPage {
-			break
-		}
+	var response struct {
+		MetafieldDefinitions struct {
+			Edges []struct {
+				Node metafieldDefinitionJSON `json:"node"`
+			} `json:"edges"`
+		} `json:"metafieldDefinitions"`
+	}
 
-		vars["after"] = response.Data.MetafieldDefinitions.PageInfo.EndCursor
+	if err := client.ExecuteInto(metafieldDefinitionsQuery, vars, &response); err != nil {
+		return nil, fmt.Errorf("Cannot find metafield definition %s.%s: %s", namespace, key, err)
 	}
 
-	return definitions, nil
+	for _, edge := range response.MetafieldDefinitions.Edges {
+		// The key argument may be ignored by older API versions
+		if edge.Node.Namespace == namespace && edge.Node.Key == key {
+			definition := edge.Node.definition()
+			return &definition, nil
+		}
+	}
+
+	return nil, nil
 }
 
-const appInstallationMetafieldsQuery = `
-query($first: Int!, $after: String, $namespace: String) {
-  currentAppInstallation {
-    id
-    metafields(first: $first, after: $after, namespace: $namespace) {
-      edges {
-        node {
-          id
-          namespace
-          key
-          description
-          value
-          type
-          createdAt
-          updatedAt
-        }
-      }
-      pageInfo {
-        hasNextPage
-        endCursor
-      }
+const metafieldDefinitionCreateMutation = `
+mutation($definition: MetafieldDefinitionInput!) {
+  metafieldDefinitionCreate(definition: $definition) {
+    createdDefinition {
+      ` + metafieldDefinitionFields + `
+    }
+    userErrors {
+      field
+      message
+      code
     }
   }
 }
 `
 
-type appInstallationMetafieldsResponse struct {
-	Data struct {
-		CurrentAppInstallation struct {
-			ID         string `json:"id"`
-			Metafields struct {
-				Edges []struct {
-					Node struct {
-						ID          string `json:"id"`
-						Namespace   string `json:"namespace"`
-						Key         string `json:"key"`
-						Description string `json:"description"`
-						Value       string `json:"value"`
-						Type        string `json:"type"`
-						CreatedAt   string `json:"createdAt"`
-						UpdatedAt   string `json:"updatedAt"`
-					} `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metafields"`
-		} `json:"currentAppInstallation"`
-	} `json:"data"`
+const metafieldDefinitionUpdateMutation = `
+mutation($definition: MetafieldDefinitionUpdateInput!) {
+  metafieldDefinitionUpdate(definition: $definition) {
+    updatedDefinition {
+      ` + metafieldDefinitionFields + `
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
 }
+`
 
-func listAppInstallationMetafields(client *gql.Client, namespace string) ([]Metafield, error) {
-	vars := map[string]interface{}{
-		"first": 250,
-	}
-
-	if namespace != "" {
-		vars["namespace"] = namespace
-	}
+const metafieldDefinitionDeleteMutation = `
+mutation($id: ID!, $deleteAllAssociatedMetafields: Boolean) {
+  metafieldDefinitionDelete(id: $id, deleteAllAssociatedMetafields: $deleteAllAssociatedMetafields) {
+    deletedDefinitionId
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}
+`
 
-	var metafields []Metafield
+const metafieldDefinitionPinMutation = `
+mutation($definitionId: ID!) {
+  metafieldDefinitionPin(definitionId: $definitionId) {
+    pinnedDefinition {
+      id
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}
+`
 
-	for {
-		data, err := client.Execute(appInstallationMetafieldsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for app installation: %s", err)
-		}
+const metafieldDefinitionUnpinMutation = `
+mutation($definitionId: ID!) {
+  metafieldDefinitionUnpin(definitionId: $definitionId) {
+    unpinnedDefinition {
+      id
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}
+`
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for app installation: %s", err)
-		}
+// CreateMetafieldDefinition creates the definition in input, a MetafieldDefinitionInput
+func CreateMetafieldDefinition(client *gql.Client, input map[string]interface{}) (MetafieldDefinition, error) {
+	var response struct {
+		MetafieldDefinitionCreate struct {
+			CreatedDefinition metafieldDefinitionJSON `json:"createdDefinition"`
+		} `json:"metafieldDefinitionCreate"`
+	}
 
-		var response appInstallationMetafieldsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for app installation: %s", err)
-		}
+	if err := client.ExecuteInto(metafieldDefinitionCreateMutation, map[string]interface{}{"definition": input}, &response); err != nil {
+		return MetafieldDefinition{}, fmt.Errorf("Cannot create metafield definition %s.%s: %s", input["namespace"], input["key"], err)
+	}
 
-		for _, edge := range response.Data.CurrentAppInstallation.Metafields.Edges {
-			n := edge.Node
-			metafields = append(metafields, Metafield{
-				ID:          n.ID,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Value:       n.Value,
-				Type:        n.Type,
-				CreatedAt:   n.CreatedAt,
-				UpdatedAt:   n.UpdatedAt,
-			})
-		}
+	return response.MetafieldDefinitionCreate.CreatedDefinition.definition(), nil
+}
 
-		if !response.Data.CurrentAppInstallation.Metafields.PageInfo.HasNextPage {
-			break
-		}
+// UpdateMetafieldDefinition updates the definition identified by input's ownerType, namespace, and key.
+// input is a MetafieldDefinitionUpdateInput, properties not given are not changed.
+func UpdateMetafieldDefinition(client *gql.Client, input map[string]interface{}) (MetafieldDefinition, error) {
+	var response struct {
+		MetafieldDefinitionUpdate struct {
+			UpdatedDefinition metafieldDefinitionJSON `json:"updatedDefinition"`
+		} `json:"metafieldDefinitionUpdate"`
+	}
 
-		vars["after"] = response.Data.CurrentAppInstallation.Metafields.PageInfo.EndCursor
+	if err := client.ExecuteInto(metafieldDefinitionUpdateMutation, map[string]interface{}{"definition": input}, &response); err != nil {
+		return MetafieldDefinition{}, fmt.Errorf("Cannot update metafield definition %s.%s: %s", input["namespace"], input["key"], err)
 	}
 
-	return metafields, nil
+	return response.MetafieldDefinitionUpdate.UpdatedDefinition.definition(), nil
 }
 
-const customerMetafieldsQuery = `
-query($ownerId: ID!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
-  customer(id: $ownerId) {
-    id
-    metafields(first: $first, after: $after, namespace: $namespace, keys: $keys, reverse: $reverse) {
-      edges {
-        node {
-          id
-          namespace
-          key
-          description
-          value
-          type
-          createdAt
-          updatedAt
-        }
-      }
-      pageInfo {
-        hasNextPage
-        endCursor
-      }
-    }
-  }
-}
-`
+// deleteMetafieldDefinition deletes the definition with the GID and, if withValues, its metafields
+func deleteMetafieldDefinition(client *gql.Client, id string, withValues bool) error {
+	vars := map[string]interface{}{"id": id, "deleteAllAssociatedMetafields": withValues}
+
+	if err := client.ExecuteInto(metafieldDefinitionDeleteMutation, vars, nil); err != nil {
+		return fmt.Errorf("Cannot delete metafield definition %s: %s", id, err)
+	}
 
-type customerMetafieldsResponse struct {
-	Data struct {
-		Customer struct {
-			ID         string `json:"id"`
-			Metafields struct {
-				Edges []struct {
-					Node struct {
-						ID          string `json:"id"`
-						Namespace   string `json:"namespace"`
-						Key         string `json:"key"`
-						Description string `json:"description"`
-						Value       string `json:"value"`
-						Type        string `json:"type"`
-						CreatedAt   string `json:"createdAt"`
-						UpdatedAt   string `json:"updatedAt"`
-					} `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metafields"`
-		} `json:"customer"`
-	} `json:"data"`
+	return nil
 }
 
-func listCustomerMetafields(client *gql.Client, customerID int64, namespace, key string, reverse bool) ([]Metafield, error) {
-	vars := map[string]interface{}{
-		"ownerId": fmt.Sprintf("gid://shopify/Customer/%d", customerID),
-		"first":   250,
+// pinMetafieldDefinition pins, or unpins, the definition with the GID
+func pinMetafieldDefinition(client *gql.Client, id string, pin bool) error {
+	mutation, action := metafieldDefinitionPinMutation, "pin"
+	if !pin {
+		mutation, action = metafieldDefinitionUnpinMutation, "unpin"
+	}
+
+	if err := client.ExecuteInto(mutation, map[string]interface{}{"definitionId": id}, nil); err != nil {
+		return fmt.Errorf("Cannot %s metafield definition %s: %s", action, id, err)
 	}
 
+	return nil
+}
+
+// metafieldFilterVars adds the namespace, key, and reverse filters to vars.
+// The GraphQL keys argument requires the namespace.key format, so a bare key
+// filter (no namespace) can't be sent; true is returned when the caller must
+// apply it client-side.
+func metafieldFilterVars(vars map[string]interface{}, namespace, key string, reverse bool) bool {
 	if namespace != "" {
 		vars["namespace"] = namespace
 	}
@@ -302,68 +315,38 @@ func listCustomerMetafields(client *gql.Client, customerID int64, namespace, key
 		vars["reverse"] = true
 	}
 
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
 	if key != "" {
 		if namespace != "" {
 			vars["keys"] = []string{namespace + "." + key}
 		} else {
-			filterByKey = true
+			return true
 		}
 	}
 
-	var metafields []Metafield
-
-	for {
-		data, err := client.Execute(customerMetafieldsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for customer: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for customer: %s", err)
-		}
-
-		var response customerMetafieldsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for customer: %s", err)
-		}
-
-		for _, edge := range response.Data.Customer.Metafields.Edges {
-			n := edge.Node
-			if filterByKey && n.Key != key {
-				continue
-			}
+	return false
+}
 
-			metafields = append(metafields, Metafield{
-				ID:          n.ID,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Value:       n.Value,
-				Type:        n.Type,
-				CreatedAt:   n.CreatedAt,
-				UpdatedAt:   n.UpdatedAt,
-			})
-		}
+// paginateMetafields reads all metafields of owner from the connection at path. When
+// filterByKey is true only those with the given key are returned.
+func paginateMetafields(client *gql.Client, query string, vars map[string]interface{}, path, owner, key string, filterByKey bool) ([]Metafield, error) {
+	var metafields []Metafield
 
-		if !response.Data.Customer.Metafields.PageInfo.HasNextPage {
-			break
+	err := gql.Paginate(client, query, vars, path, func(mf Metafield) error {
+		if !filterByKey || mf.Key == key {
+			mf.Owner = owner
+			metafields = append(metafields, mf)
 		}
+		return nil
+	})
 
-		vars["after"] = response.Data.Customer.Metafields.PageInfo.EndCursor
-	}
-
-	return metafields, nil
+	return metafields, err
 }
 
-const productMetafieldsQuery = `
-query($ownerId: ID!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
-  product(id: $ownerId) {
+const appInstallationMetafieldsQuery = `
+query($first: Int!, $after: String, $namespace: String) {
+  currentAppInstallation {
     id
-    metafields(first: $first, after: $after, namespace: $namespace, keys: $keys, reverse: $reverse) {
+    metafields(first: $first, after: $after, namespace: $namespace) {
       edges {
         node {
           id
@@ -385,445 +368,155 @@ query($ownerId: ID!, $first: Int!, $after: String, $namespace: String, $keys: [S
 }
 `
 
-type productMetafieldsResponse struct {
-	Data struct {
-		Product struct {
-			ID         string `json:"id"`
-			Metafields struct {
-				Edges []struct {
-					Node struct {
-						ID          string `json:"id"`
-						Namespace   string `json:"namespace"`
-						Key         string `json:"key"`
-						Description string `json:"description"`
-						Value       string `json:"value"`
-						Type        string `json:"type"`
-						CreatedAt   string `json:"createdAt"`
-						UpdatedAt   string `json:"updatedAt"`
-					} `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metafields"`
-		} `json:"product"`
-	} `json:"data"`
-}
-
-// listProductMetafields lists metafields for the given product. When the
-// product doesn't exist (e.g. it was deleted or access is denied) the query
-// returns null and the error is non-nil.
-func listProductMetafields(client *gql.Client, productID int64, namespace, key string, reverse bool) ([]Metafield, error) {
+func listAppInstallationMetafields(client *gql.Client, namespace string) ([]Metafield, error) {
 	vars := map[string]interface{}{
-		"ownerId": fmt.Sprintf("gid://shopify/Product/%d", productID),
-		"first":   250,
+		"first": 250,
 	}
 
 	if namespace != "" {
 		vars["namespace"] = namespace
 	}
 
-	if reverse {
-		vars["reverse"] = true
-	}
-
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
-	if key != "" {
-		if namespace != "" {
-			vars["keys"] = []string{namespace + "." + key}
-		} else {
-			filterByKey = true
-		}
+	metafields, err := paginateMetafields(client, appInstallationMetafieldsQuery, vars, "currentAppInstallation.metafields", "app", "", false)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metafields for app installation: %s", err)
 	}
 
-	var metafields []Metafield
-	found := false
-
-	for {
-		data, err := client.Execute(productMetafieldsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for product: %s", err)
-		}
+	return metafields, nil
+}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for product: %s", err)
-		}
+const ownerMetafieldsQuery = `
+query($ownerId: ID!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
+  node(id: $ownerId) {
+    id
+    ... on HasMetafields {
+      metafields(first: $first, after: $after, namespace: $namespace, keys: $keys, reverse: $reverse) {
+        edges {
+          node {
+            id
+            namespace
+            key
+            description
+            value
+            type
+            createdAt
+            updatedAt
+          }
+        }
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+      }
+    }
+  }
+}
+`
 
-		var response productMetafieldsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for product: %s", err)
-		}
+// listOwnerMetafields lists metafields for the owner with the given GID, which can be any
+// resource with metafields. When the owner doesn't exist (e.g. it was deleted or access is
+// denied) the query returns null and the error is gql.ErrNotFound.
+func listOwnerMetafields(client *gql.Client, ownerID, namespace, key string, reverse bool) ([]Metafield, error) {
+	vars := map[string]interface{}{
+		"ownerId": ownerID,
+		"first":   250,
+	}
 
-		if response.Data.Product.ID != "" {
-			found = true
-		}
+	filterByKey := metafieldFilterVars(vars, namespace, key, reverse)
 
-		for _, edge := range response.Data.Product.Metafields.Edges {
-			n := edge.Node
-			if filterByKey && n.Key != key {
-				continue
-			}
+	metafields, err := paginateMetafields(client, ownerMetafieldsQuery, vars, "node.metafields", ownerID, key, filterByKey)
+	if errors.Is(err, gql.ErrNotFound) {
+		return nil, err
+	}
 
-			metafields = append(metafields, Metafield{
-				ID:          n.ID,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Value:       n.Value,
-				Type:        n.Type,
-				CreatedAt:   n.CreatedAt,
-				UpdatedAt:   n.UpdatedAt,
-			})
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metafields for %s: %s", ownerID, err)
+	}
 
-		if !response.Data.Product.Metafields.PageInfo.HasNextPage {
-			break
-		}
+	if len(metafields) == 0 {
+		return metafields, nil
+	}
 
-		vars["after"] = response.Data.Product.Metafields.PageInfo.EndCursor
+	owner, err := portableOwner(client, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metafields for %s: %s", ownerID, err)
 	}
 
-	if !found {
-		return nil, errors.New("not found")
+	for i := range metafields {
+		metafields[i].Owner = owner
 	}
 
 	return metafields, nil
 }
 
-const productMetafieldsBySkuQuery = `
-query($query: String!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
-  products(first: $first, after: $after, query: $query) {
-    edges {
-      node {
-        id
-        variants(first: 250) {
-          nodes {
-            sku
-          }
-        }
-        metafields(first: 250, namespace: $namespace, keys: $keys, reverse: $reverse) {
-          edges {
-            node {
-              id
-              namespace
-              key
-              description
-              value
-              type
-              createdAt
-              updatedAt
-            }
-          }
-          pageInfo {
-            hasNextPage
-            endCursor
-          }
-        }
-      }
+const portableOwnerQuery = `
+query($id: ID!) {
+  node(id: $id) {
+    ... on Product {
+      handle
     }
-    pageInfo {
-      hasNextPage
-      endCursor
+    ... on ProductVariant {
+      sku
     }
   }
 }
 `
 
-type productMetafieldsBySkuResponse struct {
-	Data struct {
-		Products struct {
-			Edges []struct {
-				Node struct {
-					ID       string `json:"id"`
-					Variants struct {
-						Nodes []struct {
-							SKU string `json:"sku"`
-						} `json:"nodes"`
-					} `json:"variants"`
-					Metafields struct {
-						Edges []struct {
-							Node struct {
-								ID          string `json:"id"`
-								Namespace   string `json:"namespace"`
-								Key         string `json:"key"`
-								Description string `json:"description"`
-								Value       string `json:"value"`
-								Type        string `json:"type"`
-								CreatedAt   string `json:"createdAt"`
-								UpdatedAt   string `json:"updatedAt"`
-							} `json:"node"`
-						} `json:"edges"`
-						PageInfo struct {
-							HasNextPage bool   `json:"hasNextPage"`
-							EndCursor   string `json:"endCursor"`
-						} `json:"pageInfo"`
-					} `json:"metafields"`
-				} `json:"node"`
-			} `json:"edges"`
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-		} `json:"products"`
-	} `json:"data"`
-}
+// portableOwner returns the owner of metafields as it's given to metafield import in another shop:
+// handle:VALUE for products, sku:VALUE for variants with a SKU, and the GID of others, which
+// only exists in this shop.
+func portableOwner(client *gql.Client, ownerID string) (string, error) {
+	if !strings.HasPrefix(ownerID, "gid://shopify/Product/") && !strings.HasPrefix(ownerID, "gid://shopify/ProductVariant/") {
+		return ownerID, nil
+	}
 
-// listProductMetafieldsBySku lists metafields for the products whose variants
-// have any of the given SKUs, filtering the products connection directly by
-// sku instead of resolving SKUs to product IDs first. Metafields are limited
-// to the first 250 per product. The returned slice reports which of the
-// requested SKUs matched a variant; products with more than 250 variants may
-// report a false miss.
-func listProductMetafieldsBySku(client *gql.Client, skus []string, namespace, key string, reverse bool) ([]Metafield, []string, error) {
-	vars := map[string]interface{}{
-		"query": skuQuery(skus),
-		"first": 250,
+	var response struct {
+		Node *struct {
+			Handle string `json:"handle"`
+			SKU    string `json:"sku"`
+		} `json:"node"`
 	}
 
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
-	if key != "" {
-		if namespace != "" {
-			vars["keys"] = []string{namespace + "." + key}
-		} else {
-			filterByKey = true
-		}
+	if err := client.ExecuteInto(portableOwnerQuery, map[string]interface{}{"id": ownerID}, &response); err != nil {
+		return "", err
 	}
 
-	if namespace != "" {
-		vars["namespace"] = namespace
+	if response.Node == nil {
+		return ownerID, nil
 	}
 
-	if reverse {
-		vars["reverse"] = true
+	if response.Node.Handle != "" {
+		return "handle:" + response.Node.Handle, nil
 	}
 
-	var metafields []Metafield
-	foundSkus := make(map[string]bool)
+	if response.Node.SKU != "" {
+		return "sku:" + response.Node.SKU, nil
+	}
 
-	for {
-		data, err := client.Execute(productMetafieldsBySkuQuery, vars)
-		if err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for product: %s", err)
-		}
+	return ownerID, nil
+}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for product: %s", err)
-		}
+type metafieldConnectionJSON struct {
+	Edges []struct {
+		Node Metafield `json:"node"`
+	} `json:"edges"`
+}
 
-		var response productMetafieldsBySkuResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for product: %s", err)
+// appendMetafields appends the connection's metafields, which belong to owner, to metafields.
+// When filterByKey is true only those with the given key are appended.
+func appendMetafields(metafields []Metafield, conn metafieldConnectionJSON, owner, key string, filterByKey bool) []Metafield {
+	for _, edge := range conn.Edges {
+		if filterByKey && edge.Node.Key != key {
+			continue
 		}
 
-		for _, edge := range response.Data.Products.Edges {
-			for _, v := range edge.Node.Variants.Nodes {
-				if v.SKU != "" {
-					foundSkus[v.SKU] = true
-				}
-			}
+		mf := edge.Node
+		mf.Owner = owner
+		metafields = append(metafields, mf)
+	}
 
-			for _, mfEdge := range edge.Node.Metafields.Edges {
-				n := mfEdge.Node
-				if filterByKey && n.Key != key {
-					continue
-				}
-
-				metafields = append(metafields, Metafield{
-					ID:          n.ID,
-					Namespace:   n.Namespace,
-					Key:         n.Key,
-					Description: n.Description,
-					Value:       n.Value,
-					Type:        n.Type,
-					CreatedAt:   n.CreatedAt,
-					UpdatedAt:   n.UpdatedAt,
-				})
-			}
-		}
-
-		if !response.Data.Products.PageInfo.HasNextPage {
-			break
-		}
-
-		vars["after"] = response.Data.Products.PageInfo.EndCursor
-	}
-
-	var found []string
-	for _, sku := range skus {
-		if foundSkus[sku] {
-			found = append(found, sku)
-		}
-	}
-
-	return metafields, found, nil
-}
-
-const variantMetafieldsBySkuQuery = `
-query($query: String!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
-  productVariants(first: $first, after: $after, query: $query) {
-    edges {
-      node {
-        id
-        sku
-        metafields(first: 250, namespace: $namespace, keys: $keys, reverse: $reverse) {
-          edges {
-            node {
-              id
-              namespace
-              key
-              description
-              value
-              type
-              createdAt
-              updatedAt
-            }
-          }
-          pageInfo {
-            hasNextPage
-            endCursor
-          }
-        }
-      }
-    }
-    pageInfo {
-      hasNextPage
-      endCursor
-    }
-  }
-}
-`
-
-type variantMetafieldsBySkuResponse struct {
-	Data struct {
-		ProductVariants struct {
-			Edges []struct {
-				Node struct {
-					ID         string `json:"id"`
-					SKU        string `json:"sku"`
-					Metafields struct {
-						Edges []struct {
-							Node struct {
-								ID          string `json:"id"`
-								Namespace   string `json:"namespace"`
-								Key         string `json:"key"`
-								Description string `json:"description"`
-								Value       string `json:"value"`
-								Type        string `json:"type"`
-								CreatedAt   string `json:"createdAt"`
-								UpdatedAt   string `json:"updatedAt"`
-							} `json:"node"`
-						} `json:"edges"`
-						PageInfo struct {
-							HasNextPage bool   `json:"hasNextPage"`
-							EndCursor   string `json:"endCursor"`
-						} `json:"pageInfo"`
-					} `json:"metafields"`
-				} `json:"node"`
-			} `json:"edges"`
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-		} `json:"productVariants"`
-	} `json:"data"`
-}
-
-// listVariantMetafieldsBySku lists metafields for the variants with any of the
-// given SKUs, filtering the productVariants connection directly by sku instead
-// of resolving SKUs to variant IDs first. Metafields are limited to the first
-// 250 per variant. The returned slice reports which of the requested SKUs
-// matched a variant.
-func listVariantMetafieldsBySku(client *gql.Client, skus []string, namespace, key string, reverse bool) ([]Metafield, []string, error) {
-	vars := map[string]interface{}{
-		"query": skuQuery(skus),
-		"first": 250,
-	}
-
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
-	if key != "" {
-		if namespace != "" {
-			vars["keys"] = []string{namespace + "." + key}
-		} else {
-			filterByKey = true
-		}
-	}
-
-	if namespace != "" {
-		vars["namespace"] = namespace
-	}
-
-	if reverse {
-		vars["reverse"] = true
-	}
-
-	var metafields []Metafield
-	foundSkus := make(map[string]bool)
-
-	for {
-		data, err := client.Execute(variantMetafieldsBySkuQuery, vars)
-		if err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		var response variantMetafieldsBySkuResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		for _, edge := range response.Data.ProductVariants.Edges {
-			if edge.Node.SKU != "" {
-				foundSkus[edge.Node.SKU] = true
-			}
-
-			for _, mfEdge := range edge.Node.Metafields.Edges {
-				n := mfEdge.Node
-				if filterByKey && n.Key != key {
-					continue
-				}
-
-				metafields = append(metafields, Metafield{
-					ID:          n.ID,
-					Namespace:   n.Namespace,
-					Key:         n.Key,
-					Description: n.Description,
-					Value:       n.Value,
-					Type:        n.Type,
-					CreatedAt:   n.CreatedAt,
-					UpdatedAt:   n.UpdatedAt,
-				})
-			}
-		}
-
-		if !response.Data.ProductVariants.PageInfo.HasNextPage {
-			break
-		}
-
-		vars["after"] = response.Data.ProductVariants.PageInfo.EndCursor
-	}
-
-	var found []string
-	for _, sku := range skus {
-		if foundSkus[sku] {
-			found = append(found, sku)
-		}
-	}
-
-	return metafields, found, nil
-}
+	return metafields
+}
 
 // skuQuery builds a GraphQL search query matching any of the given SKUs,
 // e.g. "sku:FOO OR sku:BAR".
@@ -835,141 +528,6 @@ func skuQuery(skus []string) string {
 	return strings.Join(parts, " OR ")
 }
 
-const variantMetafieldsQuery = `
-query($ownerId: ID!, $first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
-  productVariant(id: $ownerId) {
-    id
-    metafields(first: $first, after: $after, namespace: $namespace, keys: $keys, reverse: $reverse) {
-      edges {
-        node {
-          id
-          namespace
-          key
-          description
-          value
-          type
-          createdAt
-          updatedAt
-        }
-      }
-      pageInfo {
-        hasNextPage
-        endCursor
-      }
-    }
-  }
-}
-`
-
-type variantMetafieldsResponse struct {
-	Data struct {
-		ProductVariant struct {
-			ID         string `json:"id"`
-			Metafields struct {
-				Edges []struct {
-					Node struct {
-						ID          string `json:"id"`
-						Namespace   string `json:"namespace"`
-						Key         string `json:"key"`
-						Description string `json:"description"`
-						Value       string `json:"value"`
-						Type        string `json:"type"`
-						CreatedAt   string `json:"createdAt"`
-						UpdatedAt   string `json:"updatedAt"`
-					} `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metafields"`
-		} `json:"productVariant"`
-	} `json:"data"`
-}
-
-// listVariantMetafields lists metafields for the given variant. When the
-// variant doesn't exist (e.g. it was deleted or access is denied) the query
-// returns null and the error is non-nil.
-func listVariantMetafields(client *gql.Client, variantID int64, namespace, key string, reverse bool) ([]Metafield, error) {
-	vars := map[string]interface{}{
-		"ownerId": fmt.Sprintf("gid://shopify/ProductVariant/%d", variantID),
-		"first":   250,
-	}
-
-	if namespace != "" {
-		vars["namespace"] = namespace
-	}
-
-	if reverse {
-		vars["reverse"] = true
-	}
-
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
-	if key != "" {
-		if namespace != "" {
-			vars["keys"] = []string{namespace + "." + key}
-		} else {
-			filterByKey = true
-		}
-	}
-
-	var metafields []Metafield
-	found := false
-
-	for {
-		data, err := client.Execute(variantMetafieldsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		var response variantMetafieldsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for variant: %s", err)
-		}
-
-		if response.Data.ProductVariant.ID != "" {
-			found = true
-		}
-
-		for _, edge := range response.Data.ProductVariant.Metafields.Edges {
-			n := edge.Node
-			if filterByKey && n.Key != key {
-				continue
-			}
-
-			metafields = append(metafields, Metafield{
-				ID:          n.ID,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Value:       n.Value,
-				Type:        n.Type,
-				CreatedAt:   n.CreatedAt,
-				UpdatedAt:   n.UpdatedAt,
-			})
-		}
-
-		if !response.Data.ProductVariant.Metafields.PageInfo.HasNextPage {
-			break
-		}
-
-		vars["after"] = response.Data.ProductVariant.Metafields.PageInfo.EndCursor
-	}
-
-	if !found {
-		return nil, errors.New("not found")
-	}
-
-	return metafields, nil
-}
-
 const shopMetafieldsQuery = `
 query($first: Int!, $after: String, $namespace: String, $keys: [String!], $reverse: Boolean) {
   shop {
@@ -996,97 +554,16 @@ query($first: Int!, $after: String, $namespace: String, $keys: [String!], $rever
 }
 `
 
-type shopMetafieldsResponse struct {
-	Data struct {
-		Shop struct {
-			ID         string `json:"id"`
-			Metafields struct {
-				Edges []struct {
-					Node struct {
-						ID          string `json:"id"`
-						Namespace   string `json:"namespace"`
-						Key         string `json:"key"`
-						Description string `json:"description"`
-						Value       string `json:"value"`
-						Type        string `json:"type"`
-						CreatedAt   string `json:"createdAt"`
-						UpdatedAt   string `json:"updatedAt"`
-					} `json:"node"`
-				} `json:"edges"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metafields"`
-		} `json:"shop"`
-	} `json:"data"`
-}
-
 func listShopMetafields(client *gql.Client, namespace, key string, reverse bool) ([]Metafield, error) {
 	vars := map[string]interface{}{
 		"first": 250,
 	}
 
-	if namespace != "" {
-		vars["namespace"] = namespace
-	}
+	filterByKey := metafieldFilterVars(vars, namespace, key, reverse)
 
-	if reverse {
-		vars["reverse"] = true
-	}
-
-	// The GraphQL keys argument requires the namespace.key format, so a bare
-	// key filter (no namespace) is applied client-side below.
-	filterByKey := false
-	if key != "" {
-		if namespace != "" {
-			vars["keys"] = []string{namespace + "." + key}
-		} else {
-			filterByKey = true
-		}
-	}
-
-	var metafields []Metafield
-
-	for {
-		data, err := client.Execute(shopMetafieldsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for shop: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for shop: %s", err)
-		}
-
-		var response shopMetafieldsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list metafields for shop: %s", err)
-		}
-
-		for _, edge := range response.Data.Shop.Metafields.Edges {
-			n := edge.Node
-			if filterByKey && n.Key != key {
-				continue
-			}
-
-			metafields = append(metafields, Metafield{
-				ID:          n.ID,
-				Namespace:   n.Namespace,
-				Key:         n.Key,
-				Description: n.Description,
-				Value:       n.Value,
-				Type:        n.Type,
-				CreatedAt:   n.CreatedAt,
-				UpdatedAt:   n.UpdatedAt,
-			})
-		}
-
-		if !response.Data.Shop.Metafields.PageInfo.HasNextPage {
-			break
-		}
-
-		vars["after"] = response.Data.Shop.Metafields.PageInfo.EndCursor
+	metafields, err := paginateMetafields(client, shopMetafieldsQuery, vars, "shop.metafields", "shop", key, filterByKey)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metafields for shop: %s", err)
 	}
 
 	return metafields, nil
@@ -1124,20 +601,10 @@ type DeletedMetafield struct {
 }
 
 // indexFromField extracts the numeric input index from a userError field path.
-// The field value is a []interface{} of path segments (e.g. ["metafields", "0", "key"]);
-// joined with "." that becomes "metafields.0.key" and we match the first ".N" segment.
-func indexFromField(field interface{}) (int, bool) {
-	items, ok := field.([]interface{})
-	if !ok {
-		return 0, false
-	}
-
-	parts := make([]string, len(items))
-	for i, p := range items {
-		parts[i] = fmt.Sprint(p)
-	}
-
-	match := fieldIndexRe.FindStringSubmatch(strings.Join(parts, "."))
+// The field path (e.g. ["metafields", "0", "key"]) joined with "." becomes
+// "metafields.0.key" and we match the first ".N" segment.
+func indexFromField(field []string) (int, bool) {
+	match := fieldIndexRe.FindStringSubmatch(strings.Join(field, "."))
 	if match == nil {
 		return 0, false
 	}
@@ -1150,6 +617,16 @@ func indexFromField(field interface{}) (int, bool) {
 	return idx, true
 }
 
+type metafieldsDeleteResponse struct {
+	MetafieldsDelete struct {
+		DeletedMetafields []*struct {
+			Key       string `json:"key"`
+			Namespace string `json:"namespace"`
+			OwnerID   string `json:"ownerId"`
+		} `json:"deletedMetafields"`
+	} `json:"metafieldsDelete"`
+}
+
 func deleteMetafields(shop, token string, metafields []metafieldInput) ([]DeletedMetafield, error) {
 	inputs := make([]map[string]interface{}, len(metafields))
 	for i, mf := range metafields {
@@ -1162,10 +639,13 @@ func deleteMetafields(shop, token string, metafields []metafieldInput) ([]Delete
 
 	client := gql.NewClient(shop, token)
 
-	data, err := client.Execute(metafieldsDeleteMutation, map[string]interface{}{
+	var response metafieldsDeleteResponse
+	err := client.ExecuteInto(metafieldsDeleteMutation, map[string]interface{}{
 		"metafields": inputs,
-	})
-	if err != nil {
+	}, &response)
+
+	var userErrors gql.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
 		return nil, fmt.Errorf("Cannot delete metafields: %s", err)
 	}
 
@@ -1173,20 +653,17 @@ func deleteMetafields(shop, token string, metafields []metafieldInput) ([]Delete
 
 	// Map user errors back to their input positions via the index embedded in the field path.
 	erroredIndices := make(map[int]bool)
-	userErrors, _ := data.ValuesForPath("data.metafieldsDelete.userErrors")
 	for _, ue := range userErrors {
-		ueMap := ue.(map[string]interface{})
-		message := fmt.Sprint(ueMap["message"])
-		idx, ok := indexFromField(ueMap["field"])
+		idx, ok := indexFromField(ue.Field)
 		if ok && idx < len(result) {
 			erroredIndices[idx] = true
-			result[idx] = DeletedMetafield{Error: message, OwnerID: metafields[idx].OwnerID, Namespace: metafields[idx].Namespace, Key: metafields[idx].Key}
+			result[idx] = DeletedMetafield{Error: ue.Message, OwnerID: metafields[idx].OwnerID, Namespace: metafields[idx].Namespace, Key: metafields[idx].Key}
 		} else {
 			// No index in field path: general error, apply to all non-errored slots.
 			for i := range result {
 				if !erroredIndices[i] {
 					erroredIndices[i] = true
-					result[i] = DeletedMetafield{Error: message, OwnerID: metafields[i].OwnerID, Namespace: metafields[i].Namespace, Key: metafields[i].Key}
+					result[i] = DeletedMetafield{Error: ue.Message, OwnerID: metafields[i].OwnerID, Namespace: metafields[i].Namespace, Key: metafields[i].Key}
 				}
 			}
 		}
@@ -1200,22 +677,339 @@ func deleteMetafields(shop, token string, metafields []metafieldInput) ([]Delete
 		}
 	}
 
-	nodes, _ := data.ValuesForPath("data.metafieldsDelete.deletedMetafields")
-	for i, node := range nodes {
+	for i, n := range response.MetafieldsDelete.DeletedMetafields {
 		if i >= len(successIndices) {
 			break
 		}
-		n, ok := node.(map[string]interface{})
-		if !ok {
+		if n == nil {
 			result[successIndices[i]] = DeletedMetafield{Error: "Not found or access denied", OwnerID: metafields[successIndices[i]].OwnerID, Namespace: metafields[successIndices[i]].Namespace, Key: metafields[successIndices[i]].Key}
 			continue
 		}
 		result[successIndices[i]] = DeletedMetafield{
-			Key:       fmt.Sprint(n["key"]),
-			Namespace: fmt.Sprint(n["namespace"]),
-			OwnerID:   fmt.Sprint(n["ownerId"]),
+			Key:       n.Key,
+			Namespace: n.Namespace,
+			OwnerID:   n.OwnerID,
 		}
 	}
 
 	return result, nil
 }
+
+const metafieldsSetMutation = `
+mutation metafieldsSet($metafields: [MetafieldsSetInput!]!) {
+  metafieldsSet(metafields: $metafields) {
+    metafields {
+      id
+      namespace
+      key
+      description
+      value
+      type
+      createdAt
+      updatedAt
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+// The most metafields metafieldsSet accepts
+const metafieldsSetBatchSize = 25
+
+type metafieldSetInput struct {
+	OwnerID   string `json:"ownerId"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Type      string `json:"type,omitempty"`
+	Value     string `json:"value"`
+}
+
+type SetMetafield struct {
+	Metafield
+	OwnerID string
+	Error   string
+}
+
+type metafieldsSetResponse struct {
+	MetafieldsSet struct {
+		Metafields []Metafield `json:"metafields"`
+	} `json:"metafieldsSet"`
+}
+
+// setMetafields sets the metafields metafieldsSetBatchSize at a time
+func setMetafields(client *gql.Client, metafields []metafieldSetInput) ([]SetMetafield, error) {
+	result := make([]SetMetafield, len(metafields))
+
+	for start := 0; start < len(metafields); start += metafieldsSetBatchSize {
+		end := min(start+metafieldsSetBatchSize, len(metafields))
+		if err := setMetafieldsBatch(client, metafields[start:end], result[start:end], true); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// setMetafieldsBatch sets the batch's metafields and their results. metafieldsSet is atomic so,
+// if retry, those without errors are set again when others in the batch have errors.
+func setMetafieldsBatch(client *gql.Client, batch []metafieldSetInput, result []SetMetafield, retry bool) error {
+	var response metafieldsSetResponse
+	err := client.ExecuteInto(metafieldsSetMutation, map[string]interface{}{"metafields": batch}, &response)
+
+	var userErrors gql.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
+		return fmt.Errorf("Cannot set metafields: %s", err)
+	}
+
+	for i, mf := range batch {
+		result[i] = SetMetafield{OwnerID: mf.OwnerID, Metafield: Metafield{Namespace: mf.Namespace, Key: mf.Key, Type: mf.Type, Value: mf.Value}}
+	}
+
+	if len(userErrors) == 0 {
+		// metafields is ordered to match the inputs
+		for i, mf := range response.MetafieldsSet.Metafields {
+			if i < len(batch) {
+				result[i].Metafield = mf
+			}
+		}
+
+		return nil
+	}
+
+	general := false
+	for _, ue := range userErrors {
+		idx, ok := indexFromField(ue.Field)
+		if ok && idx < len(batch) {
+			result[idx].Error = ue.Message
+			continue
+		}
+
+		// No index in field path: general error, apply to all
+		general = true
+		for i := range result {
+			if result[i].Error == "" {
+				result[i].Error = ue.Message
+			}
+		}
+	}
+
+	var rest []int
+	for i := range result {
+		if result[i].Error == "" {
+			rest = append(rest, i)
+		}
+	}
+
+	if len(rest) == 0 {
+		return nil
+	}
+
+	if !retry || general {
+		for _, i := range rest {
+			result[i].Error = "Not set, another metafield in the batch has errors"
+		}
+
+		return nil
+	}
+
+	inputs := make([]metafieldSetInput, len(rest))
+	for i, idx := range rest {
+		inputs[i] = batch[idx]
+	}
+
+	retried := make([]SetMetafield, len(rest))
+	if err := setMetafieldsBatch(client, inputs, retried, false); err != nil {
+		return err
+	}
+
+	for i, idx := range rest {
+		result[idx] = retried[i]
+	}
+
+	return nil
+}
+
+const ownerIDQuery = `
+query {
+  %s {
+    id
+  }
+}
+`
+
+// lookupOwnerID returns the GID of the shop or the app installation associated with the credentials
+func lookupOwnerID(client *gql.Client, owner string) (string, error) {
+	root := map[string]string{"shop": "shop", "app": "currentAppInstallation"}[owner]
+	if root == "" {
+		return "", fmt.Errorf("Unknown owner %q", owner)
+	}
+
+	var response map[string]struct {
+		ID string `json:"id"`
+	}
+
+	if err := client.ExecuteInto(fmt.Sprintf(ownerIDQuery, root), nil, &response); err != nil {
+		return "", fmt.Errorf("Cannot find the %s's ID: %s", owner, err)
+	}
+
+	return response[root].ID, nil
+}
+
+const variantIDsBySkuQuery = `
+query($query: String!, $first: Int!, $after: String) {
+  productVariants(first: $first, after: $after, query: $query) {
+    edges {
+      node {
+        id
+        sku
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`
+
+// lookupVariantIDsBySku returns the GIDs of the variants with the given SKUs by SKU
+func lookupVariantIDsBySku(client *gql.Client, skus []string) (map[string]string, error) {
+	vars := map[string]interface{}{
+		"query": skuQuery(skus),
+		"first": 250,
+	}
+
+	ids := make(map[string]string)
+
+	err := gql.Paginate(client, variantIDsBySkuQuery, vars, "productVariants", func(n struct {
+		ID  string `json:"id"`
+		SKU string `json:"sku"`
+	}) error {
+		if n.SKU != "" {
+			ids[n.SKU] = n.ID
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot find variants: %s", err)
+	}
+
+	return ids, nil
+}
+
+const idsByFieldQuery = `
+query($query: String!, $first: Int!, $after: String) {
+  %s(first: $first, after: $after, query: $query) {
+    edges {
+      node {
+        id
+        %s
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`
+
+// Fields lookupIDs compares case-insensitively, as Shopify does
+var caseInsensitiveFields = map[string]bool{"email": true, "handle": true, "name": true}
+
+// lookupIDs returns the GIDs of the nodes of the connection, e.g., orders, whose field, e.g., name,
+// has one of the values by value. Searches aren't exact so the field is compared to the values.
+func lookupIDs(client *gql.Client, connection, field string, values []string) (map[string]string, error) {
+	fold := func(value string) string {
+		if caseInsensitiveFields[field] {
+			return strings.ToLower(value)
+		}
+
+		return value
+	}
+
+	parts := make([]string, len(values))
+	// Values by how they're compared
+	wanted := make(map[string][]string, len(values))
+
+	for i, value := range values {
+		parts[i] = fmt.Sprintf("%s:%q", field, value)
+		wanted[fold(value)] = append(wanted[fold(value)], value)
+	}
+
+	vars := map[string]interface{}{
+		"query": strings.Join(parts, " OR "),
+		"first": 250,
+	}
+
+	ids := make(map[string]string)
+
+	err := gql.Paginate(client, fmt.Sprintf(idsByFieldQuery, connection, field), vars, connection, func(n map[string]string) error {
+		for _, value := range wanted[fold(n[field])] {
+			ids[value] = n["id"]
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot find %s: %s", connection, err)
+	}
+
+	return ids, nil
+}
+
+const productIDsBySkuQuery = `
+query($query: String!, $first: Int!, $after: String) {
+  productVariants(first: $first, after: $after, query: $query) {
+    edges {
+      node {
+        sku
+        product {
+          id
+        }
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`
+
+// lookupProductIDsBySku returns the GIDs of the products with variants with the given SKUs by SKU
+func lookupProductIDsBySku(client *gql.Client, skus []string) (map[string]string, error) {
+	vars := map[string]interface{}{
+		"query": skuQuery(skus),
+		"first": 250,
+	}
+
+	ids := make(map[string]string)
+
+	err := gql.Paginate(client, productIDsBySkuQuery, vars, "productVariants", func(n struct {
+		SKU     string `json:"sku"`
+		Product struct {
+			ID string `json:"id"`
+		} `json:"product"`
+	}) error {
+		if n.SKU != "" {
+			ids[n.SKU] = n.Product.ID
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot find products: %s", err)
+	}
+
+	return ids, nil
+}
+
+// lookupProductIDsByHandle returns the GIDs of the products with the given handles by handle
+func lookupProductIDsByHandle(client *gql.Client, handles []string) (map[string]string, error) {
+	return lookupIDs(client, "products", "handle", handles)
+}
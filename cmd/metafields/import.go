@@ -0,0 +1,339 @@
+package metafields
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/metafield"
+)
+
+// importRow is a metafield to import and its row in the import file
+type importRow struct {
+	Row   int
+	Owner string
+	metafieldSetInput
+	// Why the metafield can't be imported, if it can't
+	Error string
+}
+
+// importDocument is a metafield in a JSONL file. Lines output by printJSONL are accepted, their
+// other properties are ignored.
+type importDocument struct {
+	Owner     string      `json:"owner"`
+	Namespace string      `json:"namespace"`
+	Key       string      `json:"key"`
+	Type      string      `json:"type"`
+	Value     interface{} `json:"value"`
+}
+
+// importFileFormat returns format if given, otherwise the format of filename based on its extension
+func importFileFormat(filename, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+		if format == "ndjson" {
+			format = "jsonl"
+		}
+	}
+
+	format = strings.ToLower(format)
+	if format != "csv" && format != "jsonl" {
+		return "", fmt.Errorf("Cannot import %s: format must be csv or jsonl, use --format", filename)
+	}
+
+	return format, nil
+}
+
+// readImportCSV reads the Owner, Namespace, Key, Type, and Value columns of the CSV file
+func readImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read CSV header: %s", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	for _, name := range []string{"namespace", "key", "value"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("CSV file must have a %s column", name)
+		}
+	}
+
+	get := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+
+		return ""
+	}
+
+	var rows []importRow
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CSV row: %s", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		rows = append(rows, importRow{
+			Row:   line,
+			Owner: strings.TrimSpace(get(record, "owner")),
+			metafieldSetInput: metafieldSetInput{
+				Namespace: strings.TrimSpace(get(record, "namespace")),
+				Key:       strings.TrimSpace(get(record, "key")),
+				Type:      strings.TrimSpace(get(record, "type")),
+				Value:     get(record, "value"),
+			},
+		})
+	}
+
+	return rows, nil
+}
+
+// readImportJSONL reads one metafield per line. Values that aren't strings are converted to JSON.
+func readImportJSONL(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	var rows []importRow
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var doc importDocument
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("Cannot parse metafield on line %d: %s", line, err)
+		}
+
+		row := importRow{
+			Row:               line,
+			Owner:             doc.Owner,
+			metafieldSetInput: metafieldSetInput{Namespace: doc.Namespace, Key: doc.Key, Type: doc.Type},
+		}
+
+		switch value := doc.Value.(type) {
+		case nil:
+		case string:
+			row.Value = value
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for metafield on line %d: %s", line, err)
+			}
+
+			row.Value = string(b)
+		}
+
+		rows = append(rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read metafield file: %s", err)
+	}
+
+	return rows, nil
+}
+
+// validateImportRows sets the Error of rows that can't be imported. Rows without an owner are
+// given owner. Rows without a type are checked against their definition by importRows, once
+// their owners are found.
+func validateImportRows(rows []importRow, owner string) {
+	for i := range rows {
+		row := &rows[i]
+
+		if row.Owner == "" {
+			row.Owner = owner
+		}
+
+		switch {
+		case row.Owner == "":
+			row.Error = "Owner is required, use --owner to give one for all rows"
+		case row.Key == "":
+			row.Error = "Key is required"
+		}
+
+		if row.Error != "" {
+			continue
+		}
+
+		if _, _, err := parseOwner(row.Owner); err != nil {
+			row.Error = err.Error()
+			continue
+		}
+
+		if row.Type != "" {
+			if err := metafield.ValidateValue(row.Type, row.Value); err != nil {
+				row.Error = fmt.Sprintf("Invalid value for %s.%s: %s", row.Namespace, row.Key, err)
+			}
+		}
+	}
+}
+
+// importRows sets the metafields of the rows without errors, setting the Error of those that fail
+func importRows(client *gql.Client, rows []importRow) error {
+	var owners []string
+	for _, row := range rows {
+		if row.Error == "" {
+			owners = append(owners, row.Owner)
+		}
+	}
+
+	ids, err := resolveOwners(client, owners)
+	if err != nil {
+		return err
+	}
+
+	var inputs []metafieldSetInput
+	var indexes []int
+
+	for i := range rows {
+		if rows[i].Error != "" {
+			continue
+		}
+
+		id, ok := ids[rows[i].Owner]
+		if !ok {
+			rows[i].Error = "Owner not found"
+			continue
+		}
+
+		rows[i].OwnerID = id
+		inputs = append(inputs, rows[i].metafieldSetInput)
+		indexes = append(indexes, i)
+	}
+
+	// Rows without a type are checked against their metafield's definition
+	errs, err := typeFromDefinitions(client, inputs)
+	if err != nil {
+		return err
+	}
+
+	var valid []metafieldSetInput
+	var validIndexes []int
+
+	for i, input := range inputs {
+		if errs[i] != nil {
+			rows[indexes[i]].Error = errs[i].Error()
+			continue
+		}
+
+		valid = append(valid, input)
+		validIndexes = append(validIndexes, indexes[i])
+	}
+
+	if len(valid) == 0 {
+		return nil
+	}
+
+	results, err := setMetafields(client, valid)
+	if err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		rows[validIndexes[i]].Error = result.Error
+	}
+
+	return nil
+}
+
+func printImportResults(rows []importRow) {
+	t := tabby.New()
+	t.AddHeader("Row", "Owner", "Metafield", "Status")
+
+	for _, row := range rows {
+		status := "OK"
+		if row.Error != "" {
+			status = "Error: " + row.Error
+		}
+
+		t.AddLine(row.Row, row.Owner, row.Namespace+"."+row.Key, status)
+	}
+
+	t.Print()
+}
+
+func importAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return errors.New("File path required")
+	}
+
+	filename := c.Args().First()
+	format, err := importFileFormat(filename, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("Cannot open metafield file: %s", err)
+	}
+	defer f.Close()
+
+	var rows []importRow
+	if format == "csv" {
+		rows, err = readImportCSV(f)
+	} else {
+		rows, err = readImportJSONL(f)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return fmt.Errorf("No metafields found in %s", filename)
+	}
+
+	validateImportRows(rows, c.String("owner"))
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	if err := importRows(client, rows); err != nil {
+		return err
+	}
+
+	printImportResults(rows)
+
+	for _, row := range rows {
+		if row.Error != "" {
+			return cli.Exit("", 1)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,279 @@
+package metafields
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+func TestReadImportCSV(t *testing.T) {
+	csv := "Owner,Namespace,Key,Type,Value\n" +
+		"sku:HAT, custom ,fit,single_line_text_field,Snug\n" +
+		",custom,notes,multi_line_text_field,\"Hand wash\nDry flat\"\n"
+
+	rows, err := readImportCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []importRow{
+		{Row: 2, Owner: "sku:HAT", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}},
+		{Row: 3, metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "notes", Type: "multi_line_text_field", Value: "Hand wash\nDry flat"}},
+	}
+
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+
+	if _, err := readImportCSV(strings.NewReader("owner,namespace,key\n")); err == nil {
+		t.Error("readImportCSV without a value column did not fail")
+	}
+}
+
+func TestReadImportJSONL(t *testing.T) {
+	// As output by printJSONL
+	exported, err := json.Marshal(Metafield{ID: "gid://shopify/Metafield/1", Namespace: "custom", Key: "fit", Value: "Snug", Type: "single_line_text_field", CreatedAt: "2026-10-18T00:00:00Z", Owner: "gid://shopify/Product/1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonl := string(exported) + "\n\n" +
+		`{"owner": "handle:hat", "namespace": "custom", "key": "size", "type": "dimension", "value": {"value": 2.50, "unit": "cm"}}` + "\n"
+
+	rows, err := readImportJSONL(strings.NewReader(jsonl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []importRow{
+		{Row: 1, Owner: "gid://shopify/Product/1", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}},
+		{Row: 3, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "size", Type: "dimension", Value: `{"unit":"cm","value":2.50}`}},
+	}
+
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+
+	if _, err := readImportJSONL(strings.NewReader("{\n")); err == nil {
+		t.Error("readImportJSONL with invalid JSON did not fail")
+	}
+}
+
+func TestImportRows(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	rows := []importRow{
+		{Row: 2, Owner: "sku:HAT", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}},
+		{Row: 3, metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "3"}},
+		{Row: 4, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "three"}},
+		{Row: 5, Owner: "handle:nope", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "3"}},
+		{Row: 6, Owner: "hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "3"}},
+		{Row: 7, Owner: "shop", metafieldSetInput: metafieldSetInput{Namespace: "custom"}},
+	}
+
+	validateImportRows(rows, "handle:hat")
+
+	if err := importRows(client, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	wantErrors := []string{
+		"",
+		"",
+		"Invalid value for custom.count: must be an integer",
+		"Owner not found",
+		"Owner 'hat' invalid: must be a GID, 'sku:VALUE', 'handle:VALUE', 'shop', or 'app'",
+		"Key is required",
+	}
+
+	for i, want := range wantErrors {
+		if rows[i].Error != want {
+			t.Errorf("row %d error = %q, want %q", rows[i].Row, rows[i].Error, want)
+		}
+	}
+
+	if !strings.HasPrefix(rows[0].OwnerID, "gid://shopify/ProductVariant/") || !strings.HasPrefix(rows[1].OwnerID, "gid://shopify/Product/") {
+		t.Errorf("owners = %s, %s", rows[0].OwnerID, rows[1].OwnerID)
+	}
+
+	metafields, err := listOwnerMetafields(client, rows[0].OwnerID, "custom", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 1 || metafields[0].Value != "Snug" {
+		t.Errorf("variant metafields = %+v, want custom.fit", metafields)
+	}
+}
+
+func TestImportRowsWithoutType(t *testing.T) {
+	client := mockClient(t, definitionsSeed)
+
+	rows := []importRow{
+		{Row: 2, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "weight", Value: "heavy"}},
+		{Row: 3, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Value: "Snug"}},
+	}
+
+	validateImportRows(rows, "")
+
+	if err := importRows(client, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"Invalid value for custom.weight: must be an integer", ""} {
+		if rows[i].Error != want {
+			t.Errorf("row %d error = %q, want %q", rows[i].Row, rows[i].Error, want)
+		}
+	}
+
+	metafields, err := listOwnerMetafields(client, rows[1].OwnerID, "custom", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 1 || metafields[0].Key != "fit" || metafields[0].Type != "single_line_text_field" {
+		t.Errorf("product metafields = %+v, want custom.fit", metafields)
+	}
+}
+
+func TestImportExportedJSONL(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	rows := []importRow{
+		{Row: 1, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}},
+		{Row: 2, Owner: "sku:HAT", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "3"}},
+		{Row: 3, Owner: "shop", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "banner", Type: "single_line_text_field", Value: "Sale"}},
+	}
+
+	if err := importRows(client, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var metafields []Metafield
+	for _, list := range []func() ([]Metafield, error){
+		func() ([]Metafield, error) { return listOwnerMetafields(client, rows[0].OwnerID, "custom", "", false) },
+		func() ([]Metafield, error) { return listOwnerMetafields(client, rows[1].OwnerID, "custom", "", false) },
+		func() ([]Metafield, error) { return listShopMetafields(client, "custom", "", false) },
+	} {
+		mfs, err := list()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metafields = append(metafields, mfs...)
+	}
+
+	var exported bytes.Buffer
+	printJSONL(&exported, metafields)
+
+	imported, err := readImportJSONL(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validateImportRows(imported, "")
+
+	if err := importRows(client, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	wantOwners := []string{rows[0].OwnerID, rows[1].OwnerID, rows[2].OwnerID}
+	if len(imported) != len(wantOwners) {
+		t.Fatalf("imported %d metafields, want %d", len(imported), len(wantOwners))
+	}
+
+	for i, row := range imported {
+		if row.Error != "" || row.OwnerID != wantOwners[i] || row.Value != metafields[i].Value {
+			t.Errorf("row %d = %+v, want owner %s and value %q", row.Row, row, wantOwners[i], metafields[i].Value)
+		}
+	}
+}
+
+func TestImportExportedJSONLIntoAnotherShop(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	rows := []importRow{
+		{Row: 1, Owner: "handle:hat", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "fit", Type: "single_line_text_field", Value: "Snug"}},
+		{Row: 2, Owner: "sku:HAT", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "count", Type: "number_integer", Value: "3"}},
+		{Row: 3, Owner: "shop", metafieldSetInput: metafieldSetInput{Namespace: "custom", Key: "banner", Type: "single_line_text_field", Value: "Sale"}},
+	}
+
+	if err := importRows(client, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var metafields []Metafield
+	for _, list := range []func() ([]Metafield, error){
+		func() ([]Metafield, error) { return listOwnerMetafields(client, rows[0].OwnerID, "custom", "", false) },
+		func() ([]Metafield, error) { return listOwnerMetafields(client, rows[1].OwnerID, "custom", "", false) },
+		func() ([]Metafield, error) { return listShopMetafields(client, "custom", "", false) },
+	} {
+		mfs, err := list()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		metafields = append(metafields, mfs...)
+	}
+
+	wantOwners := []string{"handle:hat", "sku:HAT", "shop"}
+	if len(metafields) != len(wantOwners) {
+		t.Fatalf("exported %d metafields, want %d", len(metafields), len(wantOwners))
+	}
+
+	for i, mf := range metafields {
+		if mf.Owner != wantOwners[i] {
+			t.Errorf("metafield %d owner = %q, want %q", i, mf.Owner, wantOwners[i])
+		}
+	}
+
+	var exported bytes.Buffer
+	printJSONL(&exported, metafields)
+
+	// A shop with another product first so its GIDs differ from the exporting shop's
+	other := mock.Seed{
+		Products: []map[string]interface{}{
+			{"title": "Scarf", "handle": "scarf", "variants": []interface{}{map[string]interface{}{"sku": "SCARF"}}},
+			setSeed.Products[0],
+		},
+	}
+
+	client = mockClient(t, other)
+
+	imported, err := readImportJSONL(&exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validateImportRows(imported, "")
+
+	if err := importRows(client, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, err := resolveOwners(client, wantOwners)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, row := range imported {
+		if row.Error != "" || row.OwnerID != ids[wantOwners[i]] {
+			t.Errorf("row %d = %+v, want owner %s", row.Row, row, ids[wantOwners[i]])
+		}
+	}
+
+	for i, owner := range wantOwners[:2] {
+		got, err := listOwnerMetafields(client, ids[owner], "custom", "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != 1 || got[0].Value != metafields[i].Value {
+			t.Errorf("%s metafields = %+v, want %s.%s = %q", owner, got, metafields[i].Namespace, metafields[i].Key, metafields[i].Value)
+		}
+	}
+}
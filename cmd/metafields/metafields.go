@@ -5,8 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/cheynewallace/tabby"
@@ -34,20 +34,20 @@ func contextToOptions(c *cli.Context) metafieldOptions {
 
 func printMetafields(metafields []Metafield, options metafieldOptions) {
 	if options.JSONL {
-		printJSONL(metafields)
+		printJSONL(os.Stdout, metafields)
 	} else {
 		printFormatted(metafields)
 	}
 }
 
-func printJSONL(metafields []Metafield) {
+func printJSONL(w io.Writer, metafields []Metafield) {
 	for _, metafield := range metafields {
 		line, err := json.Marshal(metafield)
 		if err != nil {
 			panic(err)
 		}
 
-		fmt.Println(string(line))
+		fmt.Fprintln(w, string(line))
 	}
 }
 
@@ -69,75 +69,12 @@ func printFormatted(metafields []Metafield) {
 	cmd.PrintMetafields(items)
 }
 
-func customerAction(c *cli.Context) error {
-	if c.NArg() == 0 {
-		return errors.New("Customer id required")
-	}
-
-	id, err := strconv.ParseInt(c.Args().Get(0), 10, 64)
-	if err != nil {
-		return fmt.Errorf("Customer id '%s' invalid: must be an int", c.Args().Get(0))
-	}
-
+func shopAction(c *cli.Context) error {
 	options := contextToOptions(c)
-	client := cmd.NewGraphQLClient(c)
-	metafields, err := listCustomerMetafields(client, id, options.Namespace, options.Key, c.Bool("reverse"))
-	if err != nil {
-		return fmt.Errorf("Cannot list metafields for customer: %s", err)
-	}
-
-	printMetafields(metafields, options)
-	return nil
-}
-
-func productAction(c *cli.Context) error {
-	if c.NArg() == 0 {
-		return errors.New("Product id required")
-	}
-
-	ids, skus, err := cmd.ParseIDArgs(c.Args().Slice(), "an ID")
+	client, err := cmd.NewGraphQLClient(c)
 	if err != nil {
 		return err
 	}
-
-	options := contextToOptions(c)
-	client := cmd.NewGraphQLClient(c)
-
-	var metafields []Metafield
-	var failures []string
-	for _, id := range ids {
-		mfs, err := listProductMetafields(client, id, options.Namespace, options.Key, c.Bool("reverse"))
-		if err != nil {
-			failures = append(failures, fmt.Sprintf("%d: %s", id, err))
-			continue
-		}
-		metafields = append(metafields, mfs...)
-	}
-
-	if len(skus) > 0 {
-		mfs, foundSkus, err := listProductMetafieldsBySku(client, skus, options.Namespace, options.Key, c.Bool("reverse"))
-		if err != nil {
-			failures = append(failures, err.Error())
-		} else {
-			metafields = append(metafields, mfs...)
-			for _, sku := range missingSkus(skus, foundSkus) {
-				failures = append(failures, fmt.Sprintf("sku:%s: not found", sku))
-			}
-		}
-	}
-
-	printMetafields(metafields, options)
-
-	if len(failures) > 0 {
-		return fmt.Errorf("Cannot retrieve metafield(s): %s", strings.Join(failures, ", "))
-	}
-
-	return nil
-}
-
-func shopAction(c *cli.Context) error {
-	options := contextToOptions(c)
-	client := cmd.NewGraphQLClient(c)
 	metafields, err := listShopMetafields(client, options.Namespace, options.Key, c.Bool("reverse"))
 	if err != nil {
 		return fmt.Errorf("Cannot list metafields for shop: %s", err)
@@ -149,7 +86,10 @@ func shopAction(c *cli.Context) error {
 }
 
 func appAction(c *cli.Context) error {
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	metafields, err := listAppInstallationMetafields(client, c.String("namespace"))
 	if err != nil {
@@ -175,72 +115,13 @@ func appAction(c *cli.Context) error {
 	return nil
 }
 
-func variantAction(c *cli.Context) error {
-	if c.NArg() == 0 {
-		return errors.New("Variant id required")
-	}
-
-	ids, skus, err := cmd.ParseIDArgs(c.Args().Slice(), "an ID")
+func storefrontListAction(c *cli.Context) error {
+	shop := c.String("shop")
+	token, err := cmd.AccessToken(c)
 	if err != nil {
 		return err
 	}
 
-	options := contextToOptions(c)
-	client := cmd.NewGraphQLClient(c)
-
-	var metafields []Metafield
-	var failures []string
-	for _, id := range ids {
-		mfs, err := listVariantMetafields(client, id, options.Namespace, options.Key, c.Bool("reverse"))
-		if err != nil {
-			failures = append(failures, fmt.Sprintf("%d: %s", id, err))
-			continue
-		}
-		metafields = append(metafields, mfs...)
-	}
-
-	if len(skus) > 0 {
-		mfs, foundSkus, err := listVariantMetafieldsBySku(client, skus, options.Namespace, options.Key, c.Bool("reverse"))
-		if err != nil {
-			failures = append(failures, err.Error())
-		} else {
-			metafields = append(metafields, mfs...)
-			for _, sku := range missingSkus(skus, foundSkus) {
-				failures = append(failures, fmt.Sprintf("sku:%s: not found", sku))
-			}
-		}
-	}
-
-	printMetafields(metafields, options)
-
-	if len(failures) > 0 {
-		return fmt.Errorf("Cannot retrieve metafield(s): %s", strings.Join(failures, ", "))
-	}
-
-	return nil
-}
-
-// missingSkus returns the requested SKUs that were not found.
-func missingSkus(requested, found []string) []string {
-	foundSet := make(map[string]bool, len(found))
-	for _, s := range found {
-		foundSet[s] = true
-	}
-
-	var missing []string
-	for _, s := range requested {
-		if !foundSet[s] {
-			missing = append(missing, s)
-		}
-	}
-
-	return missing
-}
-
-func storefrontListAction(c *cli.Context) error {
-	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
-
 	metafields, err := storefront.New(shop, token).List()
 	if err != nil {
 		return err
@@ -266,7 +147,10 @@ func storefrontListAction(c *cli.Context) error {
 
 func storefrontEnableAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.Args().Len() < 2 {
 		return fmt.Errorf("You must supply a key and owner")
@@ -302,24 +186,18 @@ func definitionsAction(c *cli.Context) error {
 	}
 
 	ownerType := strings.ToUpper(c.Args().Get(0))
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
-	definitions, err := listMetafieldDefinitions(client, ownerType, c.String("namespace"))
+	definitions, err := ListMetafieldDefinitions(client, ownerType, c.String("namespace"))
 	if err != nil {
 		return err
 	}
 
-	t := tabby.New()
 	for _, def := range definitions {
-		t.AddLine("Gid", def.ID)
-		t.AddLine("Name", def.Name)
-		t.AddLine("Namespace", def.Namespace)
-		t.AddLine("Key", def.Key)
-		t.AddLine("Description", def.Description)
-		t.AddLine("Type", def.Type)
-		t.AddLine("Owner Type", def.OwnerType)
-		t.Print()
-		fmt.Printf("%s\n", strings.Repeat("-", 20))
+		printDefinition(def)
 	}
 
 	return nil
@@ -343,7 +221,10 @@ func printDeleteResults(deleted []DeletedMetafield) {
 
 func deleteAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.NArg() > 0 {
 		var inputs []metafieldInput
@@ -436,6 +317,29 @@ func init() {
 		},
 	}
 
+	definitionFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "name",
+			Usage: "Name of the definition, defaults to its key",
+		},
+		&cli.StringFlag{
+			Name:  "description",
+			Usage: "Description of the definition",
+		},
+		&cli.StringSliceFlag{
+			Name:  "validation",
+			Usage: "Validation in name=value format, e.g., max=100 or choices='[\"S\",\"M\"]', can be given multiple times",
+		},
+		&cli.StringFlag{
+			Name:  "admin-access",
+			Usage: "Admin access, e.g., MERCHANT_READ or MERCHANT_READ_WRITE; only for app namespaces",
+		},
+		&cli.StringFlag{
+			Name:  "storefront-access",
+			Usage: "Storefront access: PUBLIC_READ or NONE",
+		},
+	}
+
 	Cmd = cli.Command{
 		Name:    "metafield",
 		Aliases: []string{"m", "meta"},
@@ -457,6 +361,101 @@ func init() {
 						Action: definitionsAction,
 						Usage:  "List metafield definitions for the given resource",
 					},
+					{
+						Name:      "create",
+						ArgsUsage: "resource namespace.key",
+						Flags: append(append(cmd.Flags, apiVersionFlag,
+							&cli.StringFlag{
+								Name:    "type",
+								Aliases: []string{"t"},
+								Usage:   "Metafield type, e.g., single_line_text_field or list.product_reference",
+							},
+							&cli.BoolFlag{
+								Name:  "pin",
+								Usage: "Pin the definition",
+							},
+						), definitionFlags...),
+						Action: definitionCreateAction,
+						Usage:  "Create a metafield definition for the given resource",
+					},
+					{
+						Name:        "update",
+						ArgsUsage:   "resource namespace.key",
+						Description: "Only the properties given are changed. --validation replaces all of the definition's validations",
+						Flags:       append(append(cmd.Flags, apiVersionFlag), definitionFlags...),
+						Action:      definitionUpdateAction,
+						Usage:       "Update a metafield definition for the given resource",
+					},
+					{
+						Name:      "delete",
+						ArgsUsage: "resource namespace.key",
+						Flags: append(cmd.Flags, apiVersionFlag,
+							&cli.BoolFlag{
+								Name:  "delete-with-values",
+								Usage: "Also delete the metafields with the definition's namespace and key, asks for confirmation",
+							},
+							&cli.BoolFlag{
+								Name:    "yes",
+								Aliases: []string{"y"},
+								Usage:   "Do not ask for confirmation",
+							},
+						),
+						Action: definitionDeleteAction,
+						Usage:  "Delete a metafield definition for the given resource",
+					},
+					{
+						Name:      "pin",
+						ArgsUsage: "resource namespace.key",
+						Flags:     append(cmd.Flags, apiVersionFlag),
+						Action:    definitionPinAction(true),
+						Usage:     "Pin a metafield definition for the given resource",
+					},
+					{
+						Name:      "unpin",
+						ArgsUsage: "resource namespace.key",
+						Flags:     append(cmd.Flags, apiVersionFlag),
+						Action:    definitionPinAction(false),
+						Usage:     "Unpin a metafield definition for the given resource",
+					},
+					{
+						Name:      "export",
+						ArgsUsage: "resource [resource ...]",
+						Flags: append(cmd.Flags, apiVersionFlag,
+							&cli.StringFlag{
+								Name:    "namespace",
+								Aliases: []string{"n"},
+								Usage:   "Filter by namespace",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Format of the file: json or yaml, defaults to the output file's extension or json",
+							},
+							&cli.StringFlag{
+								Name:    "output",
+								Aliases: []string{"o"},
+								Usage:   "Write the definitions to this file instead of stdout",
+							},
+						),
+						Action: definitionExportAction,
+						Usage:  "Export metafield definitions for the given resources to JSON or YAML",
+					},
+					{
+						Name:        "apply",
+						ArgsUsage:   "definitions.json|definitions.yaml",
+						Description: "Definitions in the file that don't exist are created and those that differ are updated. Other definitions are not changed",
+						Flags: append(cmd.Flags, apiVersionFlag,
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "Format of the file: json or yaml, defaults to the file's extension",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Output the changes without making them",
+							},
+						),
+						Action: definitionApplyAction,
+						Usage:  "Create or update metafield definitions from a JSON or YAML file",
+					},
 				},
 			},
 			{
@@ -468,6 +467,43 @@ func init() {
 				Action:      deleteAction,
 				Usage:       "Delete one or more metafields",
 			},
+			{
+				Name:        "set",
+				ArgsUsage:   "OWNER namespace.key VALUE [OWNER namespace.key VALUE ...]",
+				Description: "OWNER is a GID, 'sku:VALUE' for a variant, 'handle:VALUE' for a product, 'shop', or 'app' for the app installation associated with the credentials",
+				Flags: append(cmd.Flags, apiVersionFlag,
+					&cli.StringFlag{
+						Name:    "type",
+						Aliases: []string{"t"},
+						Usage:   "Metafield type, e.g., single_line_text_field or list.product_reference; values are checked against it. Optional when the metafields exist",
+					},
+					&cli.BoolFlag{
+						Name:    "jsonl",
+						Aliases: []string{"j"},
+						Usage:   "Output the metafields in JSONL format",
+					},
+				),
+				Action: setAction,
+				Usage:  "Create or update one or more metafields",
+			},
+			{
+				Name:        "import",
+				ArgsUsage:   "metafields.csv|metafields.jsonl",
+				Description: "Rows have an owner, namespace, key, type, and value. The owner is a GID, 'sku:VALUE' for a variant, 'handle:VALUE' for a product, 'shop', or 'app'",
+				Flags: append(cmd.Flags, apiVersionFlag,
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Format of the file: csv or jsonl, defaults to the file's extension",
+					},
+					&cli.StringFlag{
+						Name:    "owner",
+						Aliases: []string{"o"},
+						Usage:   "Owner of the metafields in rows without one, e.g., to import metafields exported with --jsonl",
+					},
+				),
+				Action: importAction,
+				Usage:  "Create or update metafields from a CSV or JSONL file",
+			},
 			{
 				Name: "app",
 				Flags: append(cmd.Flags, apiVersionFlag, &cli.StringFlag{
@@ -478,21 +514,6 @@ func init() {
 				Action: appAction,
 				Usage:  "List metafields for the app installation associated with the credentials",
 			},
-			{
-				Name:    "customer",
-				Flags:   append(append(cmd.Flags, metafieldFlags...), apiVersionFlag),
-				Aliases: []string{"c"},
-				Action:  customerAction,
-				Usage:   "List metafields for the given customer",
-			},
-			{
-				Name:      "product",
-				Flags:     append(append(cmd.Flags, metafieldFlags...), apiVersionFlag),
-				Aliases:   []string{"products", "prod", "p"},
-				Action:    productAction,
-				Usage:     "List metafields for the given product(s)",
-				ArgsUsage: "[ID|sku:VALUE [ID|sku:VALUE ...]]",
-			},
 			{
 				Name:    "shop",
 				Flags:   append(append(cmd.Flags, metafieldFlags...), apiVersionFlag),
@@ -521,15 +542,9 @@ func init() {
 					},
 				},
 			},
-			{
-				Name:      "variant",
-				Aliases:   []string{"var", "v"},
-				Flags:     append(append(cmd.Flags, metafieldFlags...), apiVersionFlag),
-				Action:    variantAction,
-				Usage:     "List metafields for the given variant(s)",
-				ArgsUsage: "[ID|sku:VALUE [ID|sku:VALUE ...]]",
-			},
 		},
 	}
 
+	Cmd.Subcommands = append(Cmd.Subcommands, ownerCommands(append(append(cmd.Flags, metafieldFlags...), apiVersionFlag))...)
+
 }
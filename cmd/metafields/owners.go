@@ -0,0 +1,194 @@
+package metafields
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// metafieldOwner is a resource whose metafields are listed by ID, GID, or lookup key
+type metafieldOwner struct {
+	Name    string
+	Aliases []string
+	// Used in the subcommand's usage, e.g., order(s)
+	Plural string
+	// Object type of the resource's GIDs, e.g., DraftOrder
+	Type string
+	// Lookup keys are given as Field:VALUE and found using the connection, e.g., name:#1001 using orders.
+	// Resources without a Field can only be given by ID or GID.
+	Field      string
+	Connection string
+	// Finds the GIDs by value when the connection can't, e.g., products by their variants' SKUs
+	Lookup func(client *gql.Client, values []string) (map[string]string, error)
+}
+
+var metafieldOwners = []metafieldOwner{
+	{Name: "article", Plural: "article(s)", Type: "Article", Field: "handle", Connection: "articles"},
+	{Name: "blog", Plural: "blog(s)", Type: "Blog", Field: "handle", Connection: "blogs"},
+	{Name: "collection", Aliases: []string{"coll"}, Plural: "collection(s)", Type: "Collection", Field: "handle", Connection: "collections"},
+	{Name: "company", Plural: "companies", Type: "Company", Field: "name", Connection: "companies"},
+	{Name: "customer", Aliases: []string{"c"}, Plural: "customer(s)", Type: "Customer", Field: "email", Connection: "customers"},
+	{Name: "draft-order", Aliases: []string{"draftorder", "do"}, Plural: "draft order(s)", Type: "DraftOrder", Field: "name", Connection: "draftOrders"},
+	{Name: "location", Aliases: []string{"loc"}, Plural: "location(s)", Type: "Location", Field: "name", Connection: "locations"},
+	{Name: "market", Plural: "market(s)", Type: "Market"},
+	{Name: "order", Aliases: []string{"o"}, Plural: "order(s)", Type: "Order", Field: "name", Connection: "orders"},
+	{Name: "page", Plural: "page(s)", Type: "Page", Field: "handle", Connection: "pages"},
+	{Name: "product", Aliases: []string{"products", "prod", "p"}, Plural: "product(s)", Type: "Product", Field: "sku", Lookup: lookupProductIDsBySku},
+	{Name: "product-image", Aliases: []string{"productimage", "image"}, Plural: "product image(s)", Type: "ProductImage"},
+	{Name: "variant", Aliases: []string{"var", "v"}, Plural: "variant(s)", Type: "ProductVariant", Field: "sku", Connection: "productVariants"},
+}
+
+// label returns the resource's name for messages, e.g., draft order
+func (o metafieldOwner) label() string {
+	return strings.ReplaceAll(o.Name, "-", " ")
+}
+
+func (o metafieldOwner) argsUsage() string {
+	if o.Field == "" {
+		return "ID|GID [ID|GID ...]"
+	}
+
+	return fmt.Sprintf("ID|GID|%s:VALUE [ID|GID|%[1]s:VALUE ...]", o.Field)
+}
+
+// parseOwnerArg returns the GID for an ID or GID argument, or the value of a lookup key argument
+func (o metafieldOwner) parseOwnerArg(arg string) (string, string, error) {
+	if strings.HasPrefix(arg, "gid://") {
+		return arg, "", nil
+	}
+
+	if id, err := strconv.ParseInt(arg, 10, 64); err == nil && id > 0 {
+		return fmt.Sprintf("gid://shopify/%s/%d", o.Type, id), "", nil
+	}
+
+	prefix := o.Field + ":"
+	if o.Field != "" && strings.HasPrefix(strings.ToLower(arg), prefix) {
+		if value := arg[len(prefix):]; value != "" {
+			return "", value, nil
+		}
+
+		return "", "", fmt.Errorf("%s '%s' invalid: value missing after '%s'", o.label(), arg, prefix)
+	}
+
+	if o.Field == "" {
+		return "", "", fmt.Errorf("%s '%s' invalid: must be an ID or GID", o.label(), arg)
+	}
+
+	return "", "", fmt.Errorf("%s '%s' invalid: must be an ID, GID, or '%s:VALUE'", o.label(), arg, o.Field)
+}
+
+// resolveOwnerArgs returns the GIDs of the args in order and the args that weren't found
+func (o metafieldOwner) resolveOwnerArgs(client *gql.Client, args []string) ([]string, []string, error) {
+	ids := make([]string, len(args))
+	// Lookup key values by arg index
+	values := make(map[int]string)
+	var lookup []string
+
+	for i, arg := range args {
+		id, value, err := o.parseOwnerArg(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ids[i] = id
+		if value != "" {
+			values[i] = value
+			lookup = append(lookup, value)
+		}
+	}
+
+	if len(values) == 0 {
+		return ids, nil, nil
+	}
+
+	var found map[string]string
+	var err error
+
+	if o.Lookup != nil {
+		found, err = o.Lookup(client, lookup)
+	} else {
+		found, err = lookupIDs(client, o.Connection, o.Field, lookup)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resolved, missing []string
+	for i, arg := range args {
+		value, ok := values[i]
+		if !ok {
+			resolved = append(resolved, ids[i])
+		} else if id, ok := found[value]; ok {
+			resolved = append(resolved, id)
+		} else {
+			missing = append(missing, arg)
+		}
+	}
+
+	return resolved, missing, nil
+}
+
+func (o metafieldOwner) action(c *cli.Context) error {
+	if c.NArg() == 0 {
+		label := o.label()
+		return fmt.Errorf("%s%s ID required", strings.ToUpper(label[:1]), label[1:])
+	}
+
+	options := contextToOptions(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	ids, missing, err := o.resolveOwnerArgs(client, c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, arg := range missing {
+		failures = append(failures, fmt.Sprintf("%s: not found", arg))
+	}
+
+	var metafields []Metafield
+	for _, id := range ids {
+		mfs, err := listOwnerMetafields(client, id, options.Namespace, options.Key, c.Bool("reverse"))
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", id, err))
+			continue
+		}
+		metafields = append(metafields, mfs...)
+	}
+
+	printMetafields(metafields, options)
+
+	if len(failures) > 0 {
+		return fmt.Errorf("Cannot retrieve metafield(s): %s", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+// ownerCommands returns the listing subcommands of the metafieldOwners
+func ownerCommands(flags []cli.Flag) []*cli.Command {
+	commands := make([]*cli.Command, len(metafieldOwners))
+
+	for i, owner := range metafieldOwners {
+		commands[i] = &cli.Command{
+			Name:      owner.Name,
+			Aliases:   owner.Aliases,
+			Flags:     flags,
+			Action:    owner.action,
+			Usage:     "List metafields for the given " + owner.Plural,
+			ArgsUsage: owner.argsUsage(),
+		}
+	}
+
+	return commands
+}
@@ -0,0 +1,241 @@
+package metafields
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+func TestParseOwnerArg(t *testing.T) {
+	order := metafieldOwner{Name: "draft-order", Type: "DraftOrder", Field: "name", Connection: "draftOrders"}
+	market := metafieldOwner{Name: "market", Type: "Market"}
+
+	tests := []struct {
+		owner metafieldOwner
+		arg   string
+		id    string
+		value string
+		err   string
+	}{
+		{owner: order, arg: "123", id: "gid://shopify/DraftOrder/123"},
+		{owner: order, arg: "gid://shopify/DraftOrder/9", id: "gid://shopify/DraftOrder/9"},
+		{owner: order, arg: "name:#D1", value: "#D1"},
+		{owner: order, arg: "NAME:#D1", value: "#D1"},
+		{owner: order, arg: "name:", err: "draft order 'name:' invalid: value missing after 'name:'"},
+		{owner: order, arg: "#D1", err: "draft order '#D1' invalid: must be an ID, GID, or 'name:VALUE'"},
+		{owner: market, arg: "5", id: "gid://shopify/Market/5"},
+		{owner: market, arg: "handle:us", err: "market 'handle:us' invalid: must be an ID or GID"},
+	}
+
+	for _, tt := range tests {
+		id, value, err := tt.owner.parseOwnerArg(tt.arg)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("parseOwnerArg(%q) error = %v, want %q", tt.arg, err, tt.err)
+			}
+			continue
+		}
+
+		if err != nil || id != tt.id || value != tt.value {
+			t.Errorf("parseOwnerArg(%q) = %q, %q, %v, want %q, %q", tt.arg, id, value, err, tt.id, tt.value)
+		}
+	}
+}
+
+func TestListOwnerMetafields(t *testing.T) {
+	seed := setSeed
+	seed.Collections = []mock.SeedCollection{{Title: "Hats", Handle: "hats"}, {Title: "Hat Stands", Handle: "hats-stands"}}
+	seed.Orders = []mock.SeedOrder{{Name: "#1001"}, {Name: "#10011"}}
+	seed.Customers = []mock.SeedCustomer{{FirstName: "Ada", Email: "ada@example.com"}, {FirstName: "Ann", Email: "ann@example.com"}}
+
+	client := mockClient(t, seed)
+
+	collection := metafieldOwner{Name: "collection", Type: "Collection", Field: "handle", Connection: "collections"}
+	order := metafieldOwner{Name: "order", Type: "Order", Field: "name", Connection: "orders"}
+
+	collections, missing, err := collection.resolveOwnerArgs(client, []string{"handle:hats", "handle:nope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(collections) != 1 || !strings.HasPrefix(collections[0], "gid://shopify/Collection/") || !reflect.DeepEqual(missing, []string{"handle:nope"}) {
+		t.Fatalf("collections = %v, missing = %v", collections, missing)
+	}
+
+	orders, missing, err := order.resolveOwnerArgs(client, []string{"name:#1001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(orders) != 1 || !strings.HasPrefix(orders[0], "gid://shopify/Order/") || len(missing) != 0 {
+		t.Fatalf("orders = %v, missing = %v", orders, missing)
+	}
+
+	_, err = setMetafields(client, []metafieldSetInput{
+		{OwnerID: collections[0], Namespace: "custom", Key: "season", Type: "single_line_text_field", Value: "Winter"},
+		{OwnerID: collections[0], Namespace: "custom", Key: "color", Type: "single_line_text_field", Value: "Red"},
+		{OwnerID: orders[0], Namespace: "custom", Key: "gift", Type: "boolean", Value: "true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metafields, err := listOwnerMetafields(client, collections[0], "", "season", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 1 || metafields[0].Value != "Winter" {
+		t.Errorf("collection metafields = %+v, want custom.season", metafields)
+	}
+
+	metafields, err = listOwnerMetafields(client, orders[0], "custom", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 1 || metafields[0].Key != "gift" {
+		t.Errorf("order metafields = %+v, want custom.gift", metafields)
+	}
+
+	if _, err := listOwnerMetafields(client, "gid://shopify/Order/999999", "", "", false); !errors.Is(err, gql.ErrNotFound) {
+		t.Errorf("listOwnerMetafields for a missing order = %v, want gql.ErrNotFound", err)
+	}
+
+	customer := ownerNamed(t, "customer")
+	customers, missing, err := customer.resolveOwnerArgs(client, []string{"email:ada@example.com", "email:bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(customers) != 1 || !strings.HasPrefix(customers[0], "gid://shopify/Customer/") || !reflect.DeepEqual(missing, []string{"email:bob@example.com"}) {
+		t.Fatalf("customers = %v, missing = %v", customers, missing)
+	}
+
+	_, err = setMetafields(client, []metafieldSetInput{
+		{OwnerID: customers[0], Namespace: "custom", Key: "tier", Type: "single_line_text_field", Value: "Gold"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metafields, err = listOwnerMetafields(client, customers[0], "custom", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != 1 || metafields[0].Value != "Gold" || metafields[0].Owner != customers[0] {
+		t.Errorf("customer metafields = %+v, want custom.tier", metafields)
+	}
+}
+
+func TestResolveSkuOwnerArgs(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	ids, err := resolveOwners(client, []string{"handle:hat", "sku:HAT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		owner   string
+		args    []string
+		ids     []string
+		missing []string
+	}{
+		{owner: "product", args: []string{"sku:HAT", "sku:NOPE"}, ids: []string{ids["handle:hat"]}, missing: []string{"sku:NOPE"}},
+		{owner: "variant", args: []string{"5", "sku:HAT"}, ids: []string{"gid://shopify/ProductVariant/5", ids["sku:HAT"]}},
+		{owner: "variant", args: []string{"sku:NOPE", "sku:HAT", "sku:NADA"}, ids: []string{ids["sku:HAT"]}, missing: []string{"sku:NOPE", "sku:NADA"}},
+	}
+
+	for _, tt := range tests {
+		owner := ownerNamed(t, tt.owner)
+		got, missing, err := owner.resolveOwnerArgs(client, tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, tt.ids) || !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("%s resolveOwnerArgs(%v) = %v, %v, want %v, %v", tt.owner, tt.args, got, missing, tt.ids, tt.missing)
+		}
+	}
+}
+
+func TestResolveOwnerArgsIgnoresCase(t *testing.T) {
+	seed := setSeed
+	seed.Collections = []mock.SeedCollection{{Title: "Hats", Handle: "hats"}}
+	seed.Orders = []mock.SeedOrder{{Name: "#1001-A"}}
+	seed.Customers = []mock.SeedCustomer{{FirstName: "Ada", Email: "Ada@Example.com"}}
+
+	client := mockClient(t, seed)
+
+	tests := []struct {
+		owner string
+		arg   string
+		gid   string
+	}{
+		{"customer", "email:ada@example.COM", "gid://shopify/Customer/"},
+		{"collection", "handle:HATS", "gid://shopify/Collection/"},
+		{"order", "name:#1001-a", "gid://shopify/Order/"},
+	}
+
+	for _, tt := range tests {
+		ids, missing, err := ownerNamed(t, tt.owner).resolveOwnerArgs(client, []string{tt.arg})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ids) != 1 || !strings.HasPrefix(ids[0], tt.gid) || len(missing) != 0 {
+			t.Errorf("%s resolveOwnerArgs(%s) = %v, %v, want a %s", tt.owner, tt.arg, ids, missing, tt.gid)
+		}
+	}
+}
+
+func TestMissingSkus(t *testing.T) {
+	client := mockClient(t, mock.Seed{
+		Products: []map[string]interface{}{
+			{"title": "A", "handle": "a", "variants": []interface{}{map[string]interface{}{"sku": "A"}}},
+			{"title": "B", "handle": "b", "variants": []interface{}{map[string]interface{}{"sku": "B"}}},
+		},
+	})
+
+	tests := []struct {
+		name      string
+		requested []string
+		want      []string
+	}{
+		{name: "all found", requested: []string{"sku:B", "sku:A"}},
+		{name: "some missing", requested: []string{"sku:A", "sku:C", "sku:D"}, want: []string{"sku:C", "sku:D"}},
+		{name: "all missing", requested: []string{"sku:C", "sku:D"}, want: []string{"sku:C", "sku:D"}},
+		{name: "duplicate requested", requested: []string{"sku:A", "sku:A"}},
+		{name: "empty requested"},
+	}
+
+	for _, owner := range []string{"product", "variant"} {
+		for _, tt := range tests {
+			_, missing, err := ownerNamed(t, owner).resolveOwnerArgs(client, tt.requested)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(missing, tt.want) {
+				t.Errorf("%s %s: missing = %v, want %v", owner, tt.name, missing, tt.want)
+			}
+		}
+	}
+}
+
+func ownerNamed(t *testing.T, name string) metafieldOwner {
+	for _, owner := range metafieldOwners {
+		if owner.Name == name {
+			return owner
+		}
+	}
+
+	t.Fatalf("no %s metafield owner", name)
+	return metafieldOwner{}
+}
@@ -0,0 +1,267 @@
+package metafields
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/metafield"
+)
+
+// splitNamespaceKey splits a namespace.key argument
+func splitNamespaceKey(arg string) (string, string, error) {
+	nk := strings.SplitN(arg, ".", 2)
+	if len(nk) != 2 || nk[0] == "" || nk[1] == "" {
+		return "", "", fmt.Errorf("Metafield %q invalid: must be in namespace.key format", arg)
+	}
+
+	return nk[0], nk[1], nil
+}
+
+// Number of SKUs or handles searched for at once
+const ownerLookupBatchSize = 50
+
+// parseOwner checks the owner is a GID, 'sku:VALUE' for a variant, 'handle:VALUE' for a product,
+// 'shop', or 'app' for the app installation associated with the credentials. For the prefixed
+// forms the prefix, lowercase, and value are returned.
+func parseOwner(owner string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(owner, "gid://shopify/"), owner == "shop", owner == "app":
+		return "", owner, nil
+	}
+
+	for _, prefix := range []string{"sku:", "handle:"} {
+		if strings.HasPrefix(strings.ToLower(owner), prefix) {
+			value := owner[len(prefix):]
+			if value == "" {
+				return "", "", fmt.Errorf("Owner '%s' invalid: value missing after '%s'", owner, prefix)
+			}
+
+			return prefix, value, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("Owner '%s' invalid: must be a GID, 'sku:VALUE', 'handle:VALUE', 'shop', or 'app'", owner)
+}
+
+// resolveOwners returns the GIDs of the owners, see parseOwner, by owner. Owners that
+// aren't found are not returned.
+func resolveOwners(client *gql.Client, owners []string) (map[string]string, error) {
+	ids := make(map[string]string)
+	// Owners by prefix and their values
+	lookups := map[string][]string{}
+	values := map[string][]string{}
+
+	for _, owner := range owners {
+		if _, ok := ids[owner]; ok {
+			continue
+		}
+
+		prefix, value, err := parseOwner(owner)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case prefix != "":
+			lookups[prefix] = append(lookups[prefix], owner)
+			values[prefix] = append(values[prefix], value)
+		case value == "shop" || value == "app":
+			id, err := lookupOwnerID(client, value)
+			if err != nil {
+				return nil, err
+			}
+
+			ids[owner] = id
+			continue
+		default:
+			ids[owner] = owner
+			continue
+		}
+
+		ids[owner] = ""
+	}
+
+	for prefix, lookup := range map[string]func(*gql.Client, []string) (map[string]string, error){
+		"sku:":    lookupVariantIDsBySku,
+		"handle:": lookupProductIDsByHandle,
+	} {
+		if len(values[prefix]) == 0 {
+			continue
+		}
+
+		found := map[string]string{}
+		for start := 0; start < len(values[prefix]); start += ownerLookupBatchSize {
+			batch, err := lookup(client, values[prefix][start:min(start+ownerLookupBatchSize, len(values[prefix]))])
+			if err != nil {
+				return nil, err
+			}
+
+			for value, id := range batch {
+				found[value] = id
+			}
+		}
+
+		for i, owner := range lookups[prefix] {
+			if id, ok := found[values[prefix][i]]; ok {
+				ids[owner] = id
+			} else {
+				delete(ids, owner)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// definitionOwnerType returns the metafield definition owner type of the owner's GID, e.g.,
+// PRODUCTVARIANT, or "" if it doesn't have one
+func definitionOwnerType(ownerID string) string {
+	parts := strings.Split(strings.TrimPrefix(ownerID, "gid://shopify/"), "/")
+	if len(parts) != 2 || parts[0] == "AppInstallation" {
+		return ""
+	}
+
+	return strings.ToUpper(parts[0])
+}
+
+// typeFromDefinitions sets the Type of the inputs without one to the type of their metafield's
+// definition, if it has one, and returns the error of each input whose value isn't valid for it
+func typeFromDefinitions(client *gql.Client, inputs []metafieldSetInput) ([]error, error) {
+	errs := make([]error, len(inputs))
+	// Definitions' types by owner type and namespace.key
+	types := map[string]map[string]string{}
+
+	for i := range inputs {
+		input := &inputs[i]
+
+		ownerType := definitionOwnerType(input.OwnerID)
+		if input.Type != "" || ownerType == "" {
+			continue
+		}
+
+		if _, ok := types[ownerType]; !ok {
+			definitions, err := ListMetafieldDefinitions(client, ownerType, "")
+			if err != nil {
+				return nil, err
+			}
+
+			types[ownerType] = map[string]string{}
+			for _, definition := range definitions {
+				types[ownerType][definition.Namespace+"."+definition.Key] = definition.Type
+			}
+		}
+
+		input.Type = types[ownerType][input.Namespace+"."+input.Key]
+		if input.Type == "" {
+			continue
+		}
+
+		if err := metafield.ValidateValue(input.Type, input.Value); err != nil {
+			errs[i] = fmt.Errorf("Invalid value for %s.%s: %s", input.Namespace, input.Key, err)
+		}
+	}
+
+	return errs, nil
+}
+
+// printSetErrors outputs the metafields that weren't set and returns the ones that were
+func printSetErrors(results []SetMetafield) []Metafield {
+	var metafields []Metafield
+
+	for _, mf := range results {
+		if mf.Error != "" {
+			fmt.Fprintf(os.Stderr, "Cannot set %s@%s.%s: %s\n", mf.OwnerID, mf.Namespace, mf.Key, mf.Error)
+			continue
+		}
+
+		metafields = append(metafields, mf.Metafield)
+	}
+
+	return metafields
+}
+
+func setAction(c *cli.Context) error {
+	if c.NArg() == 0 || c.NArg()%3 != 0 {
+		return errors.New("Owner, namespace.key, and value required")
+	}
+
+	metafieldType := c.String("type")
+	args := c.Args().Slice()
+
+	var owners []string
+	for i := 0; i < len(args); i += 3 {
+		owners = append(owners, args[i])
+	}
+
+	var inputs []metafieldSetInput
+	for i := 0; i < len(args); i += 3 {
+		namespace, key, err := splitNamespaceKey(args[i+1])
+		if err != nil {
+			return err
+		}
+
+		if metafieldType != "" {
+			if err := metafield.ValidateValue(metafieldType, args[i+2]); err != nil {
+				return fmt.Errorf("Invalid value for %s.%s: %s", namespace, key, err)
+			}
+		}
+
+		inputs = append(inputs, metafieldSetInput{Namespace: namespace, Key: key, Type: metafieldType, Value: args[i+2]})
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	ids, err := resolveOwners(client, owners)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for i := range inputs {
+		id, ok := ids[owners[i]]
+		if !ok {
+			missing = append(missing, owners[i])
+		}
+
+		inputs[i].OwnerID = id
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Owner not found: %s", strings.Join(missing, ", "))
+	}
+
+	// Without --type values are checked against their definitions
+	errs, err := typeFromDefinitions(client, inputs)
+	if err != nil {
+		return err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	results, err := setMetafields(client, inputs)
+	if err != nil {
+		return err
+	}
+
+	metafields := printSetErrors(results)
+	printMetafields(metafields, contextToOptions(c))
+
+	if failures := len(results) - len(metafields); failures > 0 {
+		return fmt.Errorf("Cannot set %d metafield(s)", failures)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package metafields
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+var setSeed = mock.Seed{
+	Products: []map[string]interface{}{
+		{"title": "Hat", "handle": "hat", "variants": []interface{}{map[string]interface{}{"sku": "HAT"}}},
+	},
+}
+
+func mockClient(t *testing.T, seed mock.Seed) *gql.Client {
+	t.Helper()
+
+	server := mock.NewServer()
+	if err := server.Load(seed); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	old := gql.AdminURL
+	gql.AdminURL = httpServer.URL
+	t.Cleanup(func() { gql.AdminURL = old })
+
+	return gql.NewClient("acme", "shpat_test")
+}
+
+func TestResolveOwners(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	ids, err := resolveOwners(client, []string{"shop", "sku:HAT", "SKU:HAT", "handle:hat", "gid://shopify/Product/1", "sku:NOPE", "handle:nope"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids["shop"] != "gid://shopify/Shop/1" || ids["gid://shopify/Product/1"] != "gid://shopify/Product/1" {
+		t.Errorf("ids = %v", ids)
+	}
+
+	if got := ids["sku:HAT"]; !strings.HasPrefix(got, "gid://shopify/ProductVariant/") || got != ids["SKU:HAT"] {
+		t.Errorf("sku:HAT = %q, SKU:HAT = %q, want a variant GID", got, ids["SKU:HAT"])
+	}
+
+	if got := ids["handle:hat"]; !strings.HasPrefix(got, "gid://shopify/Product/") {
+		t.Errorf("handle:hat = %q, want a product GID", got)
+	}
+
+	for _, owner := range []string{"sku:NOPE", "handle:nope"} {
+		if id, ok := ids[owner]; ok {
+			t.Errorf("%s = %q, want it not found", owner, id)
+		}
+	}
+
+	for _, owner := range []string{"sku:", "handle:", "hat"} {
+		if _, err := resolveOwners(client, []string{owner}); err == nil {
+			t.Errorf("resolveOwners(%q) did not fail", owner)
+		}
+	}
+}
+
+func TestSetMetafields(t *testing.T) {
+	client := mockClient(t, setSeed)
+
+	var inputs []metafieldSetInput
+	for i := 0; i < metafieldsSetBatchSize+2; i++ {
+		inputs = append(inputs, metafieldSetInput{OwnerID: "gid://shopify/Shop/1", Namespace: "custom", Key: fmt.Sprintf("key%d", i), Type: "number_integer", Value: fmt.Sprint(i)})
+	}
+
+	// One metafield in the second batch has an owner that doesn't exist, the others are retried
+	inputs = append(inputs, metafieldSetInput{OwnerID: "gid://shopify/Product/999", Namespace: "custom", Key: "missing", Type: "number_integer", Value: "1"})
+
+	results, err := setMetafields(client, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := len(results) - 1
+
+	for i, r := range results[:last] {
+		if r.Error != "" || r.ID == "" || r.Key != fmt.Sprintf("key%d", i) || r.Value != fmt.Sprint(i) {
+			t.Errorf("result %d = %+v", i, r)
+		}
+	}
+
+	if got, want := results[last].Error, "Owner does not exist."; got != want || results[last].ID != "" {
+		t.Errorf("result %d = %+v, want error %q", last, results[last], want)
+	}
+
+	metafields, err := listShopMetafields(client, "custom", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metafields) != last {
+		t.Errorf("len(metafields) = %d, want %d", len(metafields), last)
+	}
+}
+
+func TestTypeFromDefinitions(t *testing.T) {
+	client := mockClient(t, definitionsSeed)
+
+	ids, err := resolveOwners(client, []string{"handle:hat", "sku:HAT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inputs := []metafieldSetInput{
+		{OwnerID: ids["handle:hat"], Namespace: "custom", Key: "weight", Value: "5"},
+		{OwnerID: ids["handle:hat"], Namespace: "custom", Key: "weight", Value: "heavy"},
+		{OwnerID: ids["handle:hat"], Namespace: "custom", Key: "fit", Type: "multi_line_text_field", Value: "Snug"},
+		{OwnerID: ids["handle:hat"], Namespace: "custom", Key: "other", Value: "x"},
+		{OwnerID: ids["sku:HAT"], Namespace: "custom", Key: "weight", Value: "heavy"},
+	}
+
+	errs, err := typeFromDefinitions(client, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTypes := []string{"number_integer", "number_integer", "multi_line_text_field", "", ""}
+	wantErrors := []string{"", "Invalid value for custom.weight: must be an integer", "", "", ""}
+
+	for i := range inputs {
+		var got string
+		if errs[i] != nil {
+			got = errs[i].Error()
+		}
+
+		if inputs[i].Type != wantTypes[i] || got != wantErrors[i] {
+			t.Errorf("input %d type = %q, error = %q, want %q, %q", i, inputs[i].Type, got, wantTypes[i], wantErrors[i])
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ScreenStaring/shopify-dev-tools/cmd"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/metaobjects/gql"
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
 	"github.com/urfave/cli/v2"
 )
 
@@ -24,16 +25,27 @@ func exportAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	moType := c.Args().Get(0)
 	verbose := c.Bool("verbose")
 	query := c.String("query")
 
+	fetch := func(fn func(gql.Metaobject) error) error {
+		if c.Bool("bulk") {
+			return gql.FetchAllMetaobjectsBulk(shop, token, moType, query, verbose, fn, gqlclient.PrintBulkProgress)
+		}
+
+		return gql.FetchAllMetaobjects(shop, token, moType, query, verbose, fn)
+	}
+
 	if c.Bool("jsonl") {
-		return exportJSONL(shop, token, moType, query, verbose)
+		return exportJSONL(shop, moType, fetch)
 	}
 
-	return exportCSV(shop, token, moType, query, verbose)
+	return exportCSV(shop, moType, fetch)
 }
 
 func metaobjectFieldMap(m gql.Metaobject) map[string]string {
@@ -44,7 +56,7 @@ func metaobjectFieldMap(m gql.Metaobject) map[string]string {
 	return fields
 }
 
-func exportJSONL(shop, token, moType, query string, verbose bool) error {
+func exportJSONL(shop, moType string, fetch func(func(gql.Metaobject) error) error) error {
 	filename := exportBaseName(shop, moType) + ".jsonl"
 
 	file, err := os.Create(filename)
@@ -54,7 +66,7 @@ func exportJSONL(shop, token, moType, query string, verbose bool) error {
 	defer file.Close()
 
 	count := 0
-	err = gql.FetchAllMetaobjects(shop, token, moType, query, verbose, func(m gql.Metaobject) error {
+	err = fetch(func(m gql.Metaobject) error {
 		record := map[string]interface{}{
 			"id":           strings.TrimPrefix(m.ID, "gid://shopify/Metaobject/"),
 			"handle":       m.Handle,
@@ -88,10 +100,10 @@ func exportJSONL(shop, token, moType, query string, verbose bool) error {
 	return nil
 }
 
-func exportCSV(shop, token, moType, query string, verbose bool) error {
+func exportCSV(shop, moType string, fetch func(func(gql.Metaobject) error) error) error {
 	var metaobjects []gql.Metaobject
 
-	err := gql.FetchAllMetaobjects(shop, token, moType, query, verbose, func(m gql.Metaobject) error {
+	err := fetch(func(m gql.Metaobject) error {
 		metaobjects = append(metaobjects, m)
 		fmt.Fprintf(os.Stderr, "\rFetched %d", len(metaobjects))
 		return nil
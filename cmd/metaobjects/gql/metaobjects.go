@@ -1,8 +1,8 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
@@ -37,10 +37,13 @@ query($first: Int!, $after: String) {
       id
       name
       type
+      description
       displayNameKey
       fieldDefinitions {
         key
         name
+        description
+        required
         type {
           name
         }
@@ -64,10 +67,13 @@ query($id: ID!) {
     id
     name
     type
+    description
     displayNameKey
     fieldDefinitions {
       key
       name
+      description
+      required
       type {
         name
       }
@@ -102,7 +108,9 @@ type MetaobjectFieldValidation struct {
 type MetaobjectFieldDefinition struct {
 	Key         string
 	Name        string
+	Description string
 	Type        string
+	Required    bool
 	Validations []MetaobjectFieldValidation
 }
 
@@ -110,6 +118,7 @@ type MetaobjectDefinition struct {
 	ID             string
 	Name           string
 	Type           string
+	Description    string
 	DisplayNameKey string
 	Fields         []MetaobjectFieldDefinition
 }
@@ -130,11 +139,14 @@ type metaobjectDefinitionJSON struct {
 	ID               string `json:"id"`
 	Name             string `json:"name"`
 	Type             string `json:"type"`
+	Description      string `json:"description"`
 	DisplayNameKey   string `json:"displayNameKey"`
 	FieldDefinitions []struct {
-		Key  string `json:"key"`
-		Name string `json:"name"`
-		Type struct {
+		Key         string `json:"key"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Required    bool   `json:"required"`
+		Type        struct {
 			Name string `json:"name"`
 		} `json:"type"`
 		Validations []struct {
@@ -175,13 +187,14 @@ func jsonToMetaobjectDefinition(n metaobjectDefinitionJSON) MetaobjectDefinition
 			validations[j] = MetaobjectFieldValidation{Name: v.Name, Value: v.Value}
 		}
 
-		fields[i] = MetaobjectFieldDefinition{Key: f.Key, Name: f.Name, Type: f.Type.Name, Validations: validations}
+		fields[i] = MetaobjectFieldDefinition{Key: f.Key, Name: f.Name, Description: f.Description, Type: f.Type.Name, Required: f.Required, Validations: validations}
 	}
 
 	return MetaobjectDefinition{
 		ID:             n.ID,
 		Name:           n.Name,
 		Type:           n.Type,
+		Description:    n.Description,
 		DisplayNameKey: n.DisplayNameKey,
 		Fields:         fields,
 	}
@@ -195,15 +208,13 @@ func ListMetaobjects(shop, token, moType string, limit, page int, query string,
 	}
 
 	var response struct {
-		Data struct {
-			Metaobjects struct {
-				Nodes    []metaobjectJSON `json:"nodes"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metaobjects"`
-		} `json:"data"`
+		Metaobjects struct {
+			Nodes    []metaobjectJSON `json:"nodes"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"metaobjects"`
 	}
 
 	var after string
@@ -213,30 +224,20 @@ func ListMetaobjects(shop, token, moType string, limit, page int, query string,
 			vars["after"] = after
 		}
 
-		data, err := client.Execute(metaobjectsQuery, vars)
-		if err != nil {
+		response.Metaobjects.Nodes = nil
+		if err := client.ExecuteInto(metaobjectsQuery, vars, &response); err != nil {
 			return nil, fmt.Errorf("Cannot list metaobjects: %s", err)
 		}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot re-encode metaobjects response: %s", err)
-		}
-
-		response.Data.Metaobjects.Nodes = nil
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot parse metaobjects response: %s", err)
-		}
-
-		if !response.Data.Metaobjects.PageInfo.HasNextPage && i < page-1 {
+		if !response.Metaobjects.PageInfo.HasNextPage && i < page-1 {
 			break
 		}
 
-		after = response.Data.Metaobjects.PageInfo.EndCursor
+		after = response.Metaobjects.PageInfo.EndCursor
 	}
 
 	var result []Metaobject
-	for _, n := range response.Data.Metaobjects.Nodes {
+	for _, n := range response.Metaobjects.Nodes {
 		result = append(result, jsonToMetaobject(n))
 	}
 
@@ -246,51 +247,54 @@ func ListMetaobjects(shop, token, moType string, limit, page int, query string,
 func FetchAllMetaobjects(shop, token, moType, query string, verbose bool, fn func(Metaobject) error) error {
 	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
 
-	var response struct {
-		Data struct {
-			Metaobjects struct {
-				Nodes    []metaobjectJSON `json:"nodes"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metaobjects"`
-		} `json:"data"`
-	}
+	vars := map[string]interface{}{"type": moType, "first": 250, "query": query}
 
-	var after string
-	for {
-		vars := map[string]interface{}{"type": moType, "first": 250, "query": query}
-		if after != "" {
-			vars["after"] = after
-		}
+	err := gqlclient.Paginate(client, metaobjectsQuery, vars, "metaobjects", func(n metaobjectJSON) error {
+		return fn(jsonToMetaobject(n))
+	})
 
-		data, err := client.Execute(metaobjectsQuery, vars)
-		if err != nil {
-			return fmt.Errorf("Cannot list metaobjects: %s", err)
-		}
+	if err != nil {
+		return fmt.Errorf("Cannot list metaobjects: %s", err)
+	}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return fmt.Errorf("Cannot re-encode metaobjects response: %s", err)
-		}
+	return nil
+}
 
-		response.Data.Metaobjects.Nodes = nil
-		if err := json.Unmarshal(b, &response); err != nil {
-			return fmt.Errorf("Cannot parse metaobjects response: %s", err)
-		}
+const metaobjectsBulkQuery = `
+{
+  metaobjects(%s) {
+    edges {
+      node {
+        id
+        handle
+        type
+        displayName
+        updatedAt
+        fields {
+          key
+          value
+        }
+      }
+    }
+  }
+}
+`
 
-		for _, n := range response.Data.Metaobjects.Nodes {
-			if err := fn(jsonToMetaobject(n)); err != nil {
-				return err
-			}
-		}
+// FetchAllMetaobjectsBulk is FetchAllMetaobjects using a bulk query
+func FetchAllMetaobjectsBulk(shop, token, moType, query string, verbose bool, fn func(Metaobject) error, progress func(gqlclient.BulkOperationResult)) error {
+	args := "type: " + strconv.Quote(moType)
+	if query != "" {
+		args += ", query: " + strconv.Quote(query)
+	}
 
-		if !response.Data.Metaobjects.PageInfo.HasNextPage {
-			break
-		}
+	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
+
+	err := gqlclient.BulkQuery(client, fmt.Sprintf(metaobjectsBulkQuery, args), nil, func(n metaobjectJSON) error {
+		return fn(jsonToMetaobject(n))
+	}, progress)
 
-		after = response.Data.Metaobjects.PageInfo.EndCursor
+	if err != nil {
+		return fmt.Errorf("Cannot list metaobjects: %s", err)
 	}
 
 	return nil
@@ -299,31 +303,19 @@ func FetchAllMetaobjects(shop, token, moType, query string, verbose bool, fn fun
 func GetMetaobjectDefinition(shop, token, id string, verbose bool) (*MetaobjectDefinition, error) {
 	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
 
-	data, err := client.Execute(metaobjectDefinitionQuery, map[string]interface{}{"id": ToDefinitionGID(id)})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get metaobject definition: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode metaobject definition response: %s", err)
-	}
-
 	var response struct {
-		Data struct {
-			MetaobjectDefinition *metaobjectDefinitionJSON `json:"metaobjectDefinition"`
-		} `json:"data"`
+		MetaobjectDefinition *metaobjectDefinitionJSON `json:"metaobjectDefinition"`
 	}
 
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse metaobject definition response: %s", err)
+	if err := client.ExecuteInto(metaobjectDefinitionQuery, map[string]interface{}{"id": ToDefinitionGID(id)}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get metaobject definition: %s", err)
 	}
 
-	if response.Data.MetaobjectDefinition == nil {
+	if response.MetaobjectDefinition == nil {
 		return nil, fmt.Errorf("Metaobject definition not found")
 	}
 
-	d := jsonToMetaobjectDefinition(*response.Data.MetaobjectDefinition)
+	d := jsonToMetaobjectDefinition(*response.MetaobjectDefinition)
 	return &d, nil
 }
 
@@ -335,15 +327,13 @@ func ListMetaobjectDefinitions(shop, token string, limit, page int, verbose bool
 	}
 
 	var response struct {
-		Data struct {
-			MetaobjectDefinitions struct {
-				Nodes    []metaobjectDefinitionJSON `json:"nodes"`
-				PageInfo struct {
-					HasNextPage bool   `json:"hasNextPage"`
-					EndCursor   string `json:"endCursor"`
-				} `json:"pageInfo"`
-			} `json:"metaobjectDefinitions"`
-		} `json:"data"`
+		MetaobjectDefinitions struct {
+			Nodes    []metaobjectDefinitionJSON `json:"nodes"`
+			PageInfo struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"metaobjectDefinitions"`
 	}
 
 	var after string
@@ -353,32 +343,103 @@ func ListMetaobjectDefinitions(shop, token string, limit, page int, verbose bool
 			vars["after"] = after
 		}
 
-		data, err := client.Execute(metaobjectDefinitionsQuery, vars)
-		if err != nil {
+		response.MetaobjectDefinitions.Nodes = nil
+		if err := client.ExecuteInto(metaobjectDefinitionsQuery, vars, &response); err != nil {
 			return nil, fmt.Errorf("Cannot list metaobject definitions: %s", err)
 		}
 
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot re-encode metaobject definitions response: %s", err)
-		}
-
-		response.Data.MetaobjectDefinitions.Nodes = nil
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot parse metaobject definitions response: %s", err)
-		}
-
-		if !response.Data.MetaobjectDefinitions.PageInfo.HasNextPage && i < page-1 {
+		if !response.MetaobjectDefinitions.PageInfo.HasNextPage && i < page-1 {
 			break
 		}
 
-		after = response.Data.MetaobjectDefinitions.PageInfo.EndCursor
+		after = response.MetaobjectDefinitions.PageInfo.EndCursor
 	}
 
 	var result []MetaobjectDefinition
-	for _, n := range response.Data.MetaobjectDefinitions.Nodes {
+	for _, n := range response.MetaobjectDefinitions.Nodes {
 		result = append(result, jsonToMetaobjectDefinition(n))
 	}
 
 	return result, nil
 }
+
+// FetchAllMetaobjectDefinitions returns all of the shop's metaobject definitions
+func FetchAllMetaobjectDefinitions(shop, token string, verbose bool) ([]MetaobjectDefinition, error) {
+	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
+
+	var result []MetaobjectDefinition
+
+	err := gqlclient.Paginate(client, metaobjectDefinitionsQuery, map[string]interface{}{"first": 250}, "metaobjectDefinitions", func(n metaobjectDefinitionJSON) error {
+		result = append(result, jsonToMetaobjectDefinition(n))
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list metaobject definitions: %s", err)
+	}
+
+	return result, nil
+}
+
+const metaobjectDefinitionCreateMutation = `
+mutation($definition: MetaobjectDefinitionCreateInput!) {
+  metaobjectDefinitionCreate(definition: $definition) {
+    metaobjectDefinition {
+      id
+      type
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}
+`
+
+const metaobjectDefinitionUpdateMutation = `
+mutation($id: ID!, $definition: MetaobjectDefinitionUpdateInput!) {
+  metaobjectDefinitionUpdate(id: $id, definition: $definition) {
+    metaobjectDefinition {
+      id
+      type
+    }
+    userErrors {
+      field
+      message
+      code
+    }
+  }
+}
+`
+
+// CreateMetaobjectDefinition creates the definition in input, a MetaobjectDefinitionCreateInput, and returns its GID
+func CreateMetaobjectDefinition(shop, token string, input map[string]interface{}, verbose bool) (string, error) {
+	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
+
+	var response struct {
+		MetaobjectDefinitionCreate struct {
+			MetaobjectDefinition struct {
+				ID string `json:"id"`
+			} `json:"metaobjectDefinition"`
+		} `json:"metaobjectDefinitionCreate"`
+	}
+
+	if err := client.ExecuteInto(metaobjectDefinitionCreateMutation, map[string]interface{}{"definition": input}, &response); err != nil {
+		return "", fmt.Errorf("Cannot create metaobject definition %s: %s", input["type"], err)
+	}
+
+	return response.MetaobjectDefinitionCreate.MetaobjectDefinition.ID, nil
+}
+
+// UpdateMetaobjectDefinition updates the definition with the GID using input, a MetaobjectDefinitionUpdateInput
+func UpdateMetaobjectDefinition(shop, token, id string, input map[string]interface{}, verbose bool) error {
+	client := gqlclient.NewClient(shop, token, map[string]interface{}{"verbose": verbose})
+
+	vars := map[string]interface{}{"id": ToDefinitionGID(id), "definition": input}
+	if err := client.ExecuteInto(metaobjectDefinitionUpdateMutation, vars, nil); err != nil {
+		return fmt.Errorf("Cannot update metaobject definition %s: %s", id, err)
+	}
+
+	return nil
+}
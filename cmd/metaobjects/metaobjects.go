@@ -19,7 +19,10 @@ func listAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	metaobjects, err := gql.ListMetaobjects(shop, token, c.Args().Get(0), c.Int("limit"), c.Int("page"), c.String("query"), c.Bool("verbose"))
 	if err != nil {
@@ -54,7 +57,10 @@ func printMetaobjects(metaobjects []gql.Metaobject) {
 
 func defListAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	if c.NArg() > 0 {
 		var definitions []gql.MetaobjectDefinition
@@ -164,6 +170,11 @@ func init() {
 			Aliases: []string{"j"},
 			Usage:   "Export as JSONL (one record per line) instead of CSV",
 		},
+		&cli.BoolFlag{
+			Name:    "bulk",
+			Aliases: []string{"b"},
+			Usage:   "Fetch the metaobjects with a bulk operation; faster for many metaobjects",
+		},
 	}
 
 	Cmd = cli.Command{
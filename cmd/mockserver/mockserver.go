@@ -0,0 +1,70 @@
+package mockserver
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+var Cmd cli.Command
+
+func serve(c *cli.Context) error {
+	server := mock.NewServer()
+	server.AccessToken = c.String("access-token")
+	server.BulkOperationPolls = c.Int("bulk-operation-polls")
+
+	if path := c.String("data"); path != "" {
+		if err := server.LoadFile(path); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", c.String("host"), c.Int("port")))
+	if err != nil {
+		return fmt.Errorf("Cannot start mock server: %s", err)
+	}
+
+	url := "http://" + listener.Addr().String()
+
+	fmt.Printf("Mock Admin API listening on %s\n", url)
+	fmt.Printf("Use it with: SDT_ADMIN_URL=%s sdt COMMAND --shop mock --access-token TOKEN\n", url)
+
+	return http.Serve(listener, server)
+}
+
+func init() {
+	Cmd = cli.Command{
+		Name:  "mock-server",
+		Usage: "Run a fake Shopify Admin API with in-memory data for testing",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Address to listen on",
+				Value: "127.0.0.1",
+			},
+			&cli.IntFlag{
+				Name:    "port",
+				Aliases: []string{"p"},
+				Usage:   "Port to listen on",
+				Value:   3457,
+			},
+			&cli.StringFlag{
+				Name:  "data",
+				Usage: "JSON `FILE` with the products, orders, webhooks, metafields, and locations to start with",
+			},
+			&cli.StringFlag{
+				Name:  "access-token",
+				Usage: "Only accept requests with this access token; by default any token is accepted",
+			},
+			&cli.IntFlag{
+				Name:  "bulk-operation-polls",
+				Usage: "Number of status requests before a bulk operation completes",
+			},
+		},
+		Action: serve,
+	}
+}
@@ -1,7 +1,6 @@
 package orders
 
 import (
-	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -207,13 +206,11 @@ type orderJSON struct {
 }
 
 type ordersResponse struct {
-	Data struct {
-		Orders struct {
-			Edges []struct {
-				Node orderJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"orders"`
-	} `json:"data"`
+	Orders struct {
+		Edges []struct {
+			Node orderJSON `json:"node"`
+		} `json:"edges"`
+	} `json:"orders"`
 }
 
 var orderSortKeys = map[string]string{
@@ -278,23 +275,13 @@ func listOrders(shop, token string, filter OrderFilter, limit int, sortKey strin
 
 	query := buildQuery(filter)
 
-	data, err := client.Execute(ordersQuery, map[string]interface{}{"query": query, "first": limit, "sortKey": sortKey})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list orders: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode orders response: %s", err)
-	}
-
 	var response ordersResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse orders response: %s", err)
+	if err := client.ExecuteInto(ordersQuery, map[string]interface{}{"query": query, "first": limit, "sortKey": sortKey}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list orders: %s", err)
 	}
 
 	var result []Order
-	for _, edge := range response.Data.Orders.Edges {
+	for _, edge := range response.Orders.Edges {
 		n := edge.Node
 		order := Order{
 			ID:                       n.LegacyResourceId,
@@ -340,11 +327,9 @@ type Attribute struct {
 }
 
 type attributesResponse struct {
-	Data struct {
-		Order struct {
-			CustomAttributes []Attribute `json:"customAttributes"`
-		} `json:"order"`
-	} `json:"data"`
+	Order struct {
+		CustomAttributes []Attribute `json:"customAttributes"`
+	} `json:"order"`
 }
 
 func listOrderAttributes(shop, token, orderID string) ([]Attribute, error) {
@@ -354,22 +339,12 @@ func listOrderAttributes(shop, token, orderID string) ([]Attribute, error) {
 		orderID = "gid://shopify/Order/" + orderID
 	}
 
-	data, err := client.Execute(attributesQuery, map[string]interface{}{"id": orderID})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list order attributes: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode order attributes response: %s", err)
-	}
-
 	var response attributesResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse order attributes response: %s", err)
+	if err := client.ExecuteInto(attributesQuery, map[string]interface{}{"id": orderID}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list order attributes: %s", err)
 	}
 
-	return response.Data.Order.CustomAttributes, nil
+	return response.Order.CustomAttributes, nil
 }
 
 func updateOrderAttributes(shop, token, orderID string, attributes []Attribute) ([]Attribute, error) {
@@ -389,43 +364,19 @@ func updateOrderAttributes(shop, token, orderID string, attributes []Attribute)
 		"customAttributes": custom,
 	}
 
-	data, err := client.Execute(orderUpdateMutation, map[string]interface{}{"input": input})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot update order attributes: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode order update response: %s", err)
+	var response struct {
+		OrderUpdate struct {
+			Order *struct {
+				CustomAttributes []Attribute `json:"customAttributes"`
+			} `json:"order"`
+		} `json:"orderUpdate"`
 	}
 
-	var response struct {
-		Data struct {
-			OrderUpdate struct {
-				Order *struct {
-					CustomAttributes []Attribute `json:"customAttributes"`
-				} `json:"order"`
-				UserErrors []struct {
-					Field   []string `json:"field"`
-					Message string   `json:"message"`
-				} `json:"userErrors"`
-			} `json:"orderUpdate"`
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse order update response: %s", err)
-	}
-
-	if errs := response.Data.OrderUpdate.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return nil, fmt.Errorf("Cannot update order attributes: %s", strings.Join(messages, ", "))
+	if err := client.ExecuteInto(orderUpdateMutation, map[string]interface{}{"input": input}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot update order attributes: %s", err)
 	}
 
-	return response.Data.OrderUpdate.Order.CustomAttributes, nil
+	return response.OrderUpdate.Order.CustomAttributes, nil
 }
 
 func setOrderAttribute(shop, token, orderID, key, value string) ([]Attribute, error) {
@@ -519,11 +470,9 @@ type fulfillmentJSON struct {
 }
 
 type fulfillmentsResponse struct {
-	Data struct {
-		Order struct {
-			Fulfillments []fulfillmentJSON `json:"fulfillments"`
-		} `json:"order"`
-	} `json:"data"`
+	Order struct {
+		Fulfillments []fulfillmentJSON `json:"fulfillments"`
+	} `json:"order"`
 }
 
 func listFulfillments(shop, token, orderID string) ([]Fulfillment, error) {
@@ -533,23 +482,13 @@ func listFulfillments(shop, token, orderID string) ([]Fulfillment, error) {
 		orderID = "gid://shopify/Order/" + orderID
 	}
 
-	data, err := client.Execute(fulfillmentsQuery, map[string]interface{}{"id": orderID})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list fulfillments: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode fulfillments response: %s", err)
-	}
-
 	var response fulfillmentsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse fulfillments response: %s", err)
+	if err := client.ExecuteInto(fulfillmentsQuery, map[string]interface{}{"id": orderID}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list fulfillments: %s", err)
 	}
 
 	var result []Fulfillment
-	for _, f := range response.Data.Order.Fulfillments {
+	for _, f := range response.Order.Fulfillments {
 		ff := Fulfillment{
 			ID:            f.ID,
 			Name:          f.Name,
@@ -650,15 +589,13 @@ type fulfillmentOrderJSON struct {
 }
 
 type fulfillmentOrdersResponse struct {
-	Data struct {
-		Order struct {
-			FulfillmentOrders struct {
-				Edges []struct {
-					Node fulfillmentOrderJSON `json:"node"`
-				} `json:"edges"`
-			} `json:"fulfillmentOrders"`
-		} `json:"order"`
-	} `json:"data"`
+	Order struct {
+		FulfillmentOrders struct {
+			Edges []struct {
+				Node fulfillmentOrderJSON `json:"node"`
+			} `json:"edges"`
+		} `json:"fulfillmentOrders"`
+	} `json:"order"`
 }
 
 func listFulfillmentOrders(shop, token, orderID string) ([]FulfillmentOrder, error) {
@@ -668,23 +605,13 @@ func listFulfillmentOrders(shop, token, orderID string) ([]FulfillmentOrder, err
 		orderID = "gid://shopify/Order/" + orderID
 	}
 
-	data, err := client.Execute(fulfillmentOrdersQuery, map[string]interface{}{"id": orderID})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list fulfillment orders: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode fulfillment orders response: %s", err)
-	}
-
 	var response fulfillmentOrdersResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse fulfillment orders response: %s", err)
+	if err := client.ExecuteInto(fulfillmentOrdersQuery, map[string]interface{}{"id": orderID}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list fulfillment orders: %s", err)
 	}
 
 	var result []FulfillmentOrder
-	for _, edge := range response.Data.Order.FulfillmentOrders.Edges {
+	for _, edge := range response.Order.FulfillmentOrders.Edges {
 		n := edge.Node
 		fo := FulfillmentOrder{
 			ID:            n.ID,
@@ -773,41 +700,17 @@ func createFulfillmentDeliveredEvent(shop, token, fulfillmentID, happenedAt, mes
 		event["message"] = message
 	}
 
-	data, err := client.Execute(fulfillmentEventCreateMutation, map[string]interface{}{"fulfillmentEvent": event})
-	if err != nil {
-		return "", fmt.Errorf("Cannot create fulfillment event: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("Cannot re-encode fulfillment event response: %s", err)
+	var response struct {
+		FulfillmentEventCreate struct {
+			FulfillmentEvent *struct {
+				ID string `json:"id"`
+			} `json:"fulfillmentEvent"`
+		} `json:"fulfillmentEventCreate"`
 	}
 
-	var response struct {
-		Data struct {
-			FulfillmentEventCreate struct {
-				FulfillmentEvent *struct {
-					ID string `json:"id"`
-				} `json:"fulfillmentEvent"`
-				UserErrors []struct {
-					Field   []string `json:"field"`
-					Message string   `json:"message"`
-				} `json:"userErrors"`
-			} `json:"fulfillmentEventCreate"`
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(b, &response); err != nil {
-		return "", fmt.Errorf("Cannot parse fulfillment event response: %s", err)
-	}
-
-	if errs := response.Data.FulfillmentEventCreate.UserErrors; len(errs) > 0 {
-		var messages []string
-		for _, e := range errs {
-			messages = append(messages, e.Message)
-		}
-		return "", fmt.Errorf("Cannot create fulfillment event: %s", strings.Join(messages, ", "))
+	if err := client.ExecuteInto(fulfillmentEventCreateMutation, map[string]interface{}{"fulfillmentEvent": event}, &response); err != nil {
+		return "", fmt.Errorf("Cannot create fulfillment event: %s", err)
 	}
 
-	return response.Data.FulfillmentEventCreate.FulfillmentEvent.ID, nil
+	return response.FulfillmentEventCreate.FulfillmentEvent.ID, nil
 }
@@ -18,7 +18,10 @@ func fulfillmentsAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	for _, orderID := range c.Args().Slice() {
 		fulfillments, err := listFulfillments(shop, token, orderID)
@@ -38,7 +41,10 @@ func fulfillmentOrdersAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	for _, orderID := range c.Args().Slice() {
 		fulfillmentOrders, err := listFulfillmentOrders(shop, token, orderID)
@@ -67,7 +73,12 @@ func deliveredAction(c *cli.Context) error {
 	message := c.Args().Get(1)
 
 	shop := c.String("shop")
-	id, err := createFulfillmentDeliveredEvent(shop, cmd.LookupAccessToken(shop, c.String("access-token")), fulfillmentID, happenedAt, message)
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	id, err := createFulfillmentDeliveredEvent(shop, token, fulfillmentID, happenedAt, message)
 	if err != nil {
 		return err
 	}
@@ -83,7 +94,10 @@ func attributesAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	showID := c.Args().Len() > 1
 
@@ -110,7 +124,10 @@ func setAttributeAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	key := c.Args().Get(1)
 	value := c.Args().Get(2)
@@ -130,7 +147,10 @@ func deleteAttributeAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	key := c.Args().Get(1)
 
@@ -190,7 +210,12 @@ func listAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	orders, err := listOrders(shop, cmd.LookupAccessToken(shop, c.String("access-token")), filter, c.Int("limit"), sortKey)
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := listOrders(shop, token, filter, c.Int("limit"), sortKey)
 	if err != nil {
 		return err
 	}
@@ -8,29 +8,35 @@ import (
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/cheynewallace/tabby"
 	"github.com/urfave/cli/v2"
 
 	"github.com/ScreenStaring/shopify-dev-tools/cmd"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
 )
 
-type importError struct {
-	row     int
-	message string
-}
-
 type bulkResultLine struct {
 	Data struct {
 		ProductSet struct {
+			Product *struct {
+				ID string `json:"id"`
+			} `json:"product"`
 			UserErrors []struct {
 				Message string   `json:"message"`
 				Field   []string `json:"field"`
 			} `json:"userErrors"`
 		} `json:"productSet"`
 	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
 	LineNumber int `json:"__lineNumber"`
 }
 
@@ -76,7 +82,7 @@ func buildJSONL(products []importProductInput) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func uploadFile(target *gql.StagedTarget, data []byte) error {
+func uploadFile(target *gql.StagedTarget, filename string, data []byte) error {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 
@@ -86,7 +92,7 @@ func uploadFile(target *gql.StagedTarget, data []byte) error {
 		}
 	}
 
-	part, err := writer.CreateFormFile("file", "bulk_import.jsonl")
+	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
 		return fmt.Errorf("Cannot create multipart file field: %s", err)
 	}
@@ -105,7 +111,7 @@ func uploadFile(target *gql.StagedTarget, data []byte) error {
 	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := gqlclient.HTTPClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("Upload request failed: %s", err)
 	}
@@ -121,53 +127,95 @@ func uploadFile(target *gql.StagedTarget, data []byte) error {
 
 func importProducts(c *cli.Context) error {
 	if c.NArg() < 1 {
-		return fmt.Errorf("CSV file path required")
+		return fmt.Errorf("File path required")
+	}
+
+	filename := c.Args().First()
+	format, err := productFileFormat(filename, c.String("format"))
+	if err != nil {
+		return err
 	}
 
-	csvFile := c.Args().First()
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{}
+	jsonOutput := c.Bool("json")
+
+	if jsonOutput && !c.Bool("wait") && !c.Bool("dry-run") {
+		return fmt.Errorf("--json requires --wait or --dry-run")
+	}
+
+	out := os.Stdout
+	if jsonOutput {
+		out = os.Stderr
+	}
 
 	locations, err := gql.FetchLocations(shop, token, options)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("Parsing %s...\n", csvFile)
+	fmt.Fprintf(out, "Parsing %s...\n", filename)
 
-	products, err := parseCSV(csvFile, locations)
+	products, err := parseProductFile(filename, format, locations)
 	if err != nil {
 		return err
 	}
 
 	if len(products) == 0 {
+		if format != formatCSV {
+			return fmt.Errorf("No products found in %s", filename)
+		}
+
 		return fmt.Errorf("No products found in CSV. Does the identifier column exist?")
 	}
 
 	setProductIdentifiers(products, c.String("identify-by"))
 
-	fmt.Printf("Found %d products\n", len(products))
+	if !c.Bool("wait") && !c.Bool("dry-run") {
+		for _, p := range products {
+			if len(p.Publications) > 0 {
+				return fmt.Errorf("Publishing products requires --wait")
+			}
+		}
+	}
+
+	if err := resolveImportReferences(shop, token, products, c.Bool("dry-run"), out, options); err != nil {
+		return err
+	}
+
+	if c.Bool("dry-run") {
+		return dryRunImport(shop, token, products, locations, c.Bool("match-media-by-name"), jsonOutput, out, options)
+	}
+
+	if err := resolveMedia(shop, token, products, filepath.Dir(filename), c.Bool("match-media-by-name"), out, options); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Found %d products\n", len(products))
 
 	jsonlData, err := buildJSONL(products)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Creating staged upload...")
+	fmt.Fprintln(out, "Creating staged upload...")
 
 	target, err := gql.StagedUpload(shop, token, len(jsonlData), options)
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Uploading JSONL file...")
+	fmt.Fprintln(out, "Uploading JSONL file...")
 
-	if err := uploadFile(target, jsonlData); err != nil {
+	if err := uploadFile(target, "bulk_import.jsonl", jsonlData); err != nil {
 		return err
 	}
 
-	fmt.Println("Starting bulk operation...")
+	fmt.Fprintln(out, "Starting bulk operation...")
 
 	var stagedUploadPath string
 	for _, param := range target.Parameters {
@@ -186,41 +234,104 @@ func importProducts(c *cli.Context) error {
 		return err
 	}
 
-	fmt.Printf("Bulk operation started\n")
-	fmt.Printf("  Operation ID: %s\n", operationID)
-	fmt.Printf("  Status: %s\n", status)
-	fmt.Printf("\nCheck status with: sdt products bulk status %s\n", operationID)
+	fmt.Fprintf(out, "Bulk operation started\n")
+	fmt.Fprintf(out, "  Operation ID: %s\n", operationID)
+	fmt.Fprintf(out, "  Status: %s\n", status)
 
-	return nil
+	if !c.Bool("wait") {
+		fmt.Fprintf(out, "\nCheck status with: sdt products bulk status %s\n", operationID)
+		return nil
+	}
+
+	result, err := gqlclient.WaitForBulkOperation(gqlclient.NewClient(shop, token, options), operationID, func(result gqlclient.BulkOperationResult) {
+		fmt.Fprintf(out, "\r%s: %d/%d products", result.Status, result.ObjectCount, len(products))
+	})
+
+	fmt.Fprintln(out)
+
+	if err != nil {
+		return err
+	}
+
+	lines := map[int]importResult{}
+	if result.URL != "" {
+		lines, err = downloadBulkResults(result.URL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Results are by JSONL line, one line per product
+	results := make([]importResult, len(products))
+	for i, product := range products {
+		r, ok := lines[i]
+		if !ok {
+			r.Err = fmt.Errorf("No result from bulk operation")
+		}
+
+		r.Row = product.Row
+		r.Handle = product.Input.Handle
+
+		// Publishing isn't part of productSet so it's done once the product exists
+		publishProduct(shop, token, product, &r, options)
+		results[i] = r
+	}
+
+	return printImportResults(results, jsonOutput, out)
 }
 
-func downloadBulkResults(url string) ([]importError, error) {
-	resp, err := http.Get(url)
+// downloadBulkResults returns the result of each productSet in the bulk
+// operation by its line number in the JSONL file, starting from 0.
+func downloadBulkResults(url string) (map[int]importResult, error) {
+	resp, err := gqlclient.HTTPClient.Get(url)
 	if err != nil {
 		return nil, fmt.Errorf("Cannot download result file: %s", err)
 	}
 	defer resp.Body.Close()
 
-	var errors []importError
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Download of result file failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	results := map[int]importResult{}
 	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	n := 0
 	for scanner.Scan() {
+		n++
+
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
 		var line bulkResultLine
 		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
-			continue
+			return nil, fmt.Errorf("Cannot parse line %d of result file: %s", n, err)
+		}
+
+		var r importResult
+		if product := line.Data.ProductSet.Product; product != nil {
+			r.ID = strings.TrimPrefix(product.ID, "gid://shopify/Product/")
 		}
+
+		for _, e := range line.Errors {
+			r.Errors = append(r.Errors, e.Message)
+		}
+
 		for _, ue := range line.Data.ProductSet.UserErrors {
-			errors = append(errors, importError{
-				row:     line.LineNumber + 1,
-				message: ue.Message,
-			})
+			r.Errors = append(r.Errors, ue.Message)
 		}
+
+		results[line.LineNumber] = r
 	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("Error reading result file: %s", err)
 	}
 
-	return errors, nil
+	return results, nil
 }
 
 func importStatus(c *cli.Context) error {
@@ -233,11 +344,12 @@ func importStatus(c *cli.Context) error {
 		operationID = "gid://shopify/BulkOperation/" + operationID
 	}
 
-	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
-	options := map[string]interface{}{}
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
-	result, err := gql.FetchBulkOperationStatus(shop, token, operationID, options)
+	result, err := gqlclient.FetchBulkOperationStatus(client, operationID)
 	if err != nil {
 		return err
 	}
@@ -260,17 +372,28 @@ func importStatus(c *cli.Context) error {
 	t.Print()
 
 	if result.Status == "COMPLETED" && result.URL != "" {
-		errors, err := downloadBulkResults(result.URL)
+		results, err := downloadBulkResults(result.URL)
 		if err != nil {
 			return err
 		}
 
-		if len(errors) > 0 {
+		lineNumbers := make([]int, 0, len(results))
+		for n, r := range results {
+			if len(r.Errors) > 0 {
+				lineNumbers = append(lineNumbers, n)
+			}
+		}
+
+		sort.Ints(lineNumbers)
+
+		if len(lineNumbers) > 0 {
 			fmt.Println("Errors")
 			et := tabby.New()
 			et.AddHeader("Row", "Message")
-			for _, e := range errors {
-				et.AddLine(e.row, e.message)
+			for _, n := range lineNumbers {
+				for _, message := range results[n].Errors {
+					et.AddLine(n+1, message)
+				}
 			}
 			et.Print()
 		}
@@ -289,11 +412,12 @@ func cancelBulkOperation(c *cli.Context) error {
 		operationID = "gid://shopify/BulkOperation/" + operationID
 	}
 
-	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
-	options := map[string]interface{}{}
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
-	id, status, err := gql.CancelBulkOperation(shop, token, operationID, options)
+	id, status, err := gqlclient.CancelBulkOperation(client, operationID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,58 @@
+package products
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDownloadBulkResults(t *testing.T) {
+	jsonl := `{"data":{"productSet":{"product":{"id":"gid://shopify/Product/1"},"userErrors":[]}},"__lineNumber":0}
+{"data":{"productSet":{"product":null,"userErrors":[{"message":"Title can't be blank","field":["input","title"]}]}},"__lineNumber":1}
+{"errors":[{"message":"Internal error"}],"__lineNumber":2}
+`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(jsonl))
+	}))
+	defer server.Close()
+
+	results, err := downloadBulkResults(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]importResult{
+		0: {ID: "1"},
+		1: {Errors: []string{"Title can't be blank"}},
+		2: {Errors: []string{"Internal error"}},
+	}
+
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+}
+
+func TestDownloadBulkResultsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"status", http.StatusForbidden, "Request has expired"},
+		{"unparsable line", http.StatusOK, `{"data":{"productSet":{"product":{"id":"gid://shopify/Product/1"}}},"__lineNumber":0}` + "\n{\"data\":\n"},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		if _, err := downloadBulkResults(server.URL); err == nil {
+			t.Errorf("%s: downloadBulkResults did not fail", tt.name)
+		}
+
+		server.Close()
+	}
+}
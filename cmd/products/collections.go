@@ -0,0 +1,56 @@
+package products
+
+import (
+	"fmt"
+	"io"
+
+	collectionsgql "github.com/ScreenStaring/shopify-dev-tools/cmd/collections/gql"
+)
+
+// resolveCollections sets the collections of each product's productSet input
+// to the IDs of its Collections, given by handle or title. Collections that
+// don't exist are created as custom collections, unless dryRun, in which
+// case they're reported but not set.
+func resolveCollections(shop, token string, products []importProductInput, dryRun bool, out io.Writer, options map[string]interface{}) error {
+	ids := map[string]string{}
+
+	for i := range products {
+		p := &products[i]
+		p.Input.Collections = nil
+
+		for _, name := range p.Collections {
+			id, ok := ids[name]
+			if !ok {
+				collection, err := collectionsgql.FindCollection(shop, token, name, options)
+				if err != nil {
+					return err
+				}
+
+				switch {
+				case collection != nil && collection.Type == "Smart":
+					return fmt.Errorf("Cannot add products to smart collection %q", name)
+				case collection != nil:
+					id = collection.ID
+				case dryRun:
+					fmt.Fprintf(out, "Collection %q does not exist, it will be created\n", name)
+				default:
+					collection, err = collectionsgql.CreateCollection(shop, token, name, options)
+					if err != nil {
+						return err
+					}
+
+					fmt.Fprintf(out, "Created collection %q\n", name)
+					id = collection.ID
+				}
+
+				ids[name] = id
+			}
+
+			if id != "" {
+				p.Input.Collections = appendUnique(p.Input.Collections, id)
+			}
+		}
+	}
+
+	return nil
+}
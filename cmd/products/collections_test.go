@@ -0,0 +1,111 @@
+package products
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+
+	collectionsgql "github.com/ScreenStaring/shopify-dev-tools/cmd/collections/gql"
+)
+
+var collectionsSeed = mock.Seed{
+	Collections: []mock.SeedCollection{
+		{Title: "Winter Hats", Handle: "hats"},
+		{Title: "Best Sellers", Smart: true},
+	},
+}
+
+func TestResolveCollections(t *testing.T) {
+	mockShop(t, collectionsSeed)
+
+	products := []importProductInput{
+		{Input: importProduct{Handle: "beanie"}, Collections: []string{"hats", "winter hats", "Scarves"}},
+		{Input: importProduct{Handle: "cap"}, Collections: []string{"Scarves"}},
+		{Input: importProduct{Handle: "glove"}},
+	}
+
+	options := map[string]interface{}{}
+
+	var out bytes.Buffer
+	if err := resolveCollections("acme", "shpat_test", products, true, &out, options); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := out.String(), "Collection \"Scarves\" does not exist, it will be created\n"; got != want {
+		t.Errorf("dry run output = %q, want %q", got, want)
+	}
+
+	hats, err := collectionsgql.FindCollection("acme", "shpat_test", "hats")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := products[0].Input.Collections, []string{hats.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dry run collections = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := resolveCollections("acme", "shpat_test", products, false, &out, options); err != nil {
+		t.Fatal(err)
+	}
+
+	scarves, err := collectionsgql.FindCollection("acme", "shpat_test", "scarves")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scarves == nil || scarves.Type != "Custom" {
+		t.Fatalf("created collection = %+v, want custom Scarves", scarves)
+	}
+
+	if got, want := out.String(), "Created collection \"Scarves\"\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+
+	wantCollections := [][]string{{hats.ID, scarves.ID}, {scarves.ID}, nil}
+	for i, want := range wantCollections {
+		if got := products[i].Input.Collections; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s collections = %q, want %q", products[i].Input.Handle, got, want)
+		}
+	}
+
+	smart := []importProductInput{{Input: importProduct{Handle: "beanie"}, Collections: []string{"best-sellers"}}}
+	err = resolveCollections("acme", "shpat_test", smart, false, &out, options)
+	if err == nil || err.Error() != `Cannot add products to smart collection "best-sellers"` {
+		t.Errorf("smart collection error = %v", err)
+	}
+}
+
+func TestImportCollections(t *testing.T) {
+	mockShop(t, collectionsSeed)
+
+	importCSV(t, "handle,title,collection\nbeanie,Beanie,hats\n", "handle")
+
+	hats, err := collectionsgql.FindCollection("acme", "shpat_test", "hats")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hats.ProductsCount != 1 {
+		t.Errorf("products in hats = %d, want 1", hats.ProductsCount)
+	}
+
+	// Collections are set, products are removed from those that aren't given
+	mockShop(t, collectionsSeed)
+
+	importCSV(t, "handle,title,collection\nbeanie,Beanie,hats\n", "handle")
+	importCSV(t, "handle,title,collection\nbeanie,Beanie,Scarves\n", "handle")
+
+	for handle, want := range map[string]int{"hats": 0, "scarves": 1} {
+		c, err := collectionsgql.FindCollection("acme", "shpat_test", handle)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if c.ProductsCount != want {
+			t.Errorf("products in %s = %d, want %d", handle, c.ProductsCount, want)
+		}
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"math"
+	"net/url"
 	"os"
+	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -46,12 +50,17 @@ type importVariant struct {
 	InventoryPolicy     string                   `json:"inventoryPolicy,omitempty"`
 	InventoryItem       *inventoryItemInput      `json:"inventoryItem,omitempty"`
 	InventoryQuantities []inventoryQuantityInput `json:"inventoryQuantities,omitempty"`
-	Metafields          []metafieldInput         `json:"metafields,omitempty"`
+	// One of the product's files
+	File       *fileInput       `json:"file,omitempty"`
+	Metafields []metafieldInput `json:"metafields,omitempty"`
 }
 
 type fileInput struct {
-	OriginalSource string `json:"originalSource"`
-	ContentType    string `json:"contentType"`
+	// Of a file the product has, instead of OriginalSource
+	ID             string `json:"id,omitempty"`
+	OriginalSource string `json:"originalSource,omitempty"`
+	ContentType    string `json:"contentType,omitempty"`
+	Alt            string `json:"alt,omitempty"`
 }
 
 type metafieldInput struct {
@@ -74,6 +83,8 @@ type importProduct struct {
 	ProductOptions  []optionCreateInput `json:"productOptions,omitempty"`
 	Variants        []importVariant     `json:"variants,omitempty"`
 	Metafields      []metafieldInput    `json:"metafields,omitempty"`
+	// IDs, set from importProductInput.Collections
+	Collections []string `json:"collections,omitempty"`
 }
 
 type productSetIdentifier struct {
@@ -84,6 +95,58 @@ type productSetIdentifier struct {
 type importProductInput struct {
 	Input      importProduct         `json:"input"`
 	Identifier *productSetIdentifier `json:"identifier,omitempty"`
+	// Line number of the product's first row in the CSV file, or its row in
+	// other import files
+	Row int `json:"-"`
+	// Handles or titles of the custom collections the product belongs to
+	Collections []string `json:"-"`
+	// Names of the publications, or sales channels, to publish the product to
+	Publications []string `json:"-"`
+	// Rows of the product's variants and metafields when they're on their own
+	rows importRows
+	// Set from Publications before importing
+	publicationIDs []string
+}
+
+// importRows are the CSV line numbers of a product's parts, to report errors
+type importRows struct {
+	variants []int
+	// Per variant, additional locations can be on their own rows
+	quantities        [][]int
+	metafields        []int
+	variantMetafields [][]int
+}
+
+func (p importProductInput) variantRow(i int) int {
+	if i < len(p.rows.variants) {
+		return p.rows.variants[i]
+	}
+
+	return p.Row
+}
+
+func (p importProductInput) quantityRow(variant, i int) int {
+	if variant < len(p.rows.quantities) && i < len(p.rows.quantities[variant]) {
+		return p.rows.quantities[variant][i]
+	}
+
+	return p.variantRow(variant)
+}
+
+func (p importProductInput) metafieldRow(i int) int {
+	if i < len(p.rows.metafields) {
+		return p.rows.metafields[i]
+	}
+
+	return p.Row
+}
+
+func (p importProductInput) variantMetafieldRow(variant, i int) int {
+	if variant < len(p.rows.variantMetafields) && i < len(p.rows.variantMetafields[variant]) {
+		return p.rows.variantMetafields[variant][i]
+	}
+
+	return p.variantRow(variant)
 }
 
 var optionColumnRE = regexp.MustCompile(`\b(option)\s+(\d)\b`)
@@ -124,6 +187,100 @@ func buildProductOptions(optionNames []string, optionValues [][]string) []option
 	return opts
 }
 
+// appendUnique appends value to values if it's not empty or already in values
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+
+	return append(values, value)
+}
+
+// appendListValues appends the comma separated values in s that are not in values
+func appendListValues(values []string, s string) []string {
+	for _, value := range strings.Split(s, ",") {
+		values = appendUnique(values, strings.TrimSpace(value))
+	}
+
+	return values
+}
+
+// mediaContentType returns the productSet content type of the media at
+// source: mediaType, e.g., "external video", if given, otherwise based on
+// source's host or extension.
+func mediaContentType(source, mediaType string) string {
+	if mediaType != "" {
+		return strings.ReplaceAll(strings.ToUpper(strings.TrimSpace(mediaType)), " ", "_")
+	}
+
+	if u, err := url.Parse(source); err == nil {
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		if host == "youtube.com" || host == "youtu.be" || host == "vimeo.com" {
+			return "EXTERNAL_VIDEO"
+		}
+
+		source = u.Path
+	}
+
+	switch strings.ToLower(path.Ext(source)) {
+	case ".mp4", ".mov", ".m4v", ".webm":
+		return "VIDEO"
+	case ".glb", ".usdz":
+		return "MODEL_3D"
+	}
+
+	return "IMAGE"
+}
+
+// addFile adds the file to files unless one with the same source is there, in
+// which case its alt text is set if file has one. The file's index is returned.
+func addFile(files *[]fileInput, file fileInput) int {
+	for i := range *files {
+		if (*files)[i].OriginalSource == file.OriginalSource {
+			if file.Alt != "" {
+				(*files)[i].Alt = file.Alt
+			}
+
+			return i
+		}
+	}
+
+	*files = append(*files, file)
+
+	return len(*files) - 1
+}
+
+// sortFiles orders files by their positions, those without one, 0, go last
+func sortFiles(files []fileInput, positions []int) {
+	indexes := make([]int, len(files))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	position := func(i int) int {
+		if i < len(positions) && positions[i] > 0 {
+			return positions[i]
+		}
+
+		return math.MaxInt
+	}
+
+	sort.SliceStable(indexes, func(a, b int) bool { return position(indexes[a]) < position(indexes[b]) })
+
+	sorted := make([]fileInput, len(files))
+	for i, index := range indexes {
+		sorted[i] = files[index]
+	}
+
+	copy(files, sorted)
+}
+
 func parseBoolPtr(s string) *bool {
 	if s == "" {
 		return nil
@@ -132,6 +289,55 @@ func parseBoolPtr(s string) *bool {
 	return &v
 }
 
+// newImportProductInput sets the product's options, using Shopify's default
+// "Title" option when there are none, and its identifier if it has an ID.
+func newImportProductInput(product importProduct, optionNames []string, optionValues [][]string, row int) importProductInput {
+	if len(optionNames) > 0 {
+		product.ProductOptions = buildProductOptions(optionNames, optionValues)
+	} else {
+		product.ProductOptions = []optionCreateInput{
+			{Name: "Title", Values: []optionValueInput{{Name: "Default Title"}}},
+		}
+
+		if len(product.Variants) == 0 {
+			product.Variants = []importVariant{
+				{OptionValues: []variantOptionValue{{OptionName: "Title", Name: "Default Title"}}},
+			}
+		} else {
+			for i := range product.Variants {
+				product.Variants[i].OptionValues = append(
+					[]variantOptionValue{{OptionName: "Title", Name: "Default Title"}},
+					product.Variants[i].OptionValues...,
+				)
+			}
+		}
+	}
+
+	pip := importProductInput{Input: product, Row: row}
+	if product.ID != "" {
+		id := product.ID
+		if matched, _ := regexp.MatchString(`^\d+$`, id); matched {
+			id = "gid://shopify/Product/" + id
+		}
+		pip.Identifier = &productSetIdentifier{ID: id}
+	}
+
+	return pip
+}
+
+func parseImagePosition(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	position, err := strconv.Atoi(s)
+	if err != nil || position < 1 {
+		return 0, fmt.Errorf("Invalid image position %q", s)
+	}
+
+	return position, nil
+}
+
 func parseCSV(filename string, locations map[string]string) ([]importProductInput, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -157,6 +363,11 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 
 	var products []importProductInput
 	var current *importProduct
+	var currentRow int
+	var currentRows importRows
+	var collections []string
+	var publications []string
+	var filePositions []int
 	var optionNames []string
 	var optionValueCols []string
 	var optionValues [][]string
@@ -173,36 +384,13 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 			return
 		}
 
-		if len(optionNames) > 0 {
-			current.ProductOptions = buildProductOptions(optionNames, optionValues)
-		} else {
-			current.ProductOptions = []optionCreateInput{
-				{Name: "Title", Values: []optionValueInput{{Name: "Default Title"}}},
-			}
+		sortFiles(current.Files, filePositions)
 
-			if len(current.Variants) == 0 {
-				current.Variants = []importVariant{
-					{OptionValues: []variantOptionValue{{OptionName: "Title", Name: "Default Title"}}},
-				}
-			} else {
-				for i := range current.Variants {
-					current.Variants[i].OptionValues = append(
-						[]variantOptionValue{{OptionName: "Title", Name: "Default Title"}},
-						current.Variants[i].OptionValues...,
-					)
-				}
-			}
-		}
-
-		pip := importProductInput{Input: *current}
-		if current.ID != "" {
-			id := current.ID
-			if matched, _ := regexp.MatchString(`^\d+$`, id); matched {
-				id = "gid://shopify/Product/" + id
-			}
-			pip.Identifier = &productSetIdentifier{ID: id}
-		}
-		products = append(products, pip)
+		product := newImportProductInput(*current, optionNames, optionValues, currentRow)
+		product.rows = currentRows
+		product.Collections = collections
+		product.Publications = publications
+		products = append(products, product)
 	}
 
 	for {
@@ -214,6 +402,7 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 			return nil, fmt.Errorf("Cannot read CSV row: %s", err)
 		}
 
+		line, _ := reader.FieldPos(0)
 		handle := get(row, "handle")
 		id := get(row, "product id")
 
@@ -223,7 +412,7 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 		newProduct := handle != "" || id != ""
 		if !newProduct && !hasIDColumns {
 			for _, name := range []string{
-				"title", "vendor", "body (html)", "body", "status", "product image url",
+				"title", "vendor", "body (html)", "body", "status", "image src", "product image url", "variant image",
 				"variant sku", "variant price", "variant compare at price", "variant barcode", "unit cost",
 			} {
 				if get(row, name) != "" {
@@ -244,6 +433,12 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 		if newProduct {
 			finalize()
 
+			currentRow = line
+			currentRows = importRows{}
+			collections = nil
+			publications = nil
+			filePositions = nil
+
 			status := strings.ToUpper(get(row, "status"))
 
 			var tags []string
@@ -270,11 +465,6 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 				}
 			}
 
-			var files []fileInput
-			if u := get(row, "product image url"); u != "" {
-				files = append(files, fileInput{OriginalSource: u, ContentType: "IMAGE"})
-			}
-
 			description := get(row, "body (html)")
 			if description == "" {
 				description = get(row, "body")
@@ -289,15 +479,45 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 				ProductType:     get(row, "type"),
 				Tags:            tags,
 				Status:          status,
-				Files:           files,
 			}
-
 		}
 
 		if current == nil {
 			continue
 		}
 
+		// Additional images and other media follow the product's first row
+		image := get(row, "image src")
+		if image == "" {
+			image = get(row, "product image url")
+		}
+
+		if image != "" {
+			position, err := parseImagePosition(get(row, "image position"))
+			if err != nil {
+				return nil, err
+			}
+
+			file := fileInput{OriginalSource: image, ContentType: mediaContentType(image, get(row, "media type")), Alt: get(row, "image alt text")}
+			if i := addFile(&current.Files, file); i == len(filePositions) {
+				filePositions = append(filePositions, position)
+			} else if position > 0 {
+				filePositions[i] = position
+			}
+		}
+
+		// One collection per row, additional ones follow the product's first row
+		collections = appendUnique(collections, get(row, "collection"))
+
+		publications = appendListValues(publications, get(row, "publications"))
+		publications = appendListValues(publications, get(row, "sales channels"))
+
+		for _, column := range []string{"published", "published on online store"} {
+			if strings.EqualFold(get(row, column), "true") {
+				publications = appendListValues(publications, onlineStorePublication)
+			}
+		}
+
 		// Collect option values for dedup and build variant
 		var variantOpts []variantOptionValue
 		for i, valCol := range optionValueCols {
@@ -346,9 +566,15 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 		var inventoryQuantities []inventoryQuantityInput
 		location := get(row, "location")
 		if location != "" {
-			locationID, ok := locations[location]
-			if !ok {
-				return nil, fmt.Errorf("Unknown location %q", location)
+			// Without locations names are kept, e.g., to validate them later
+			locationID := location
+			if locations != nil {
+				id, ok := locations[location]
+				if !ok {
+					return nil, fmt.Errorf("Unknown location %q", location)
+				}
+
+				locationID = id
 			}
 
 			for _, iqType := range []struct {
@@ -396,7 +622,40 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 				InventoryItem:       inventoryItem,
 				InventoryQuantities: inventoryQuantities,
 			}
+
+			// Variant images are also the product's
+			if u := get(row, "variant image"); u != "" {
+				file := fileInput{OriginalSource: u, ContentType: mediaContentType(u, "")}
+				if i := addFile(&current.Files, file); i == len(filePositions) {
+					filePositions = append(filePositions, 0)
+				}
+
+				v.File = &fileInput{OriginalSource: u, ContentType: file.ContentType}
+			}
+
 			current.Variants = append(current.Variants, v)
+
+			var quantityRows []int
+			if len(inventoryQuantities) > 0 {
+				quantityRows = []int{line}
+			}
+
+			currentRows.variants = append(currentRows.variants, line)
+			currentRows.quantities = append(currentRows.quantities, quantityRows)
+			currentRows.variantMetafields = append(currentRows.variantMetafields, nil)
+		} else if len(inventoryQuantities) > 0 && len(current.Variants) > 0 {
+			// Inventory at another location for the preceding variant
+			last := &current.Variants[len(current.Variants)-1]
+			last.InventoryQuantities = append(last.InventoryQuantities, inventoryQuantities...)
+
+			n := len(current.Variants) - 1
+			currentRows.quantities[n] = append(currentRows.quantities[n], line)
+
+			tracked := true
+			if last.InventoryItem == nil {
+				last.InventoryItem = &inventoryItemInput{}
+			}
+			last.InventoryItem.Tracked = &tracked
 		}
 
 		// Metafield row: single group of metafield columns, one metafield per
@@ -413,25 +672,27 @@ func parseCSV(filename string, locations map[string]string) ([]importProductInpu
 
 		if strings.EqualFold(get(row, "metafield owner"), "variant") {
 			sku = get(row, "variant sku")
-			var target *importVariant
+			target := -1
 			if sku != "" {
 				for i := range current.Variants {
 					if current.Variants[i].SKU == sku {
-						target = &current.Variants[i]
+						target = i
 						break
 					}
 				}
-				if target == nil {
+				if target < 0 {
 					return nil, fmt.Errorf("Variant metafield references unknown variant SKU %q (place the metafield row after the variant row)", sku)
 				}
 			} else if len(current.Variants) > 0 {
-				target = &current.Variants[len(current.Variants)-1]
+				target = len(current.Variants) - 1
 			} else {
 				return nil, fmt.Errorf("Variant metafield on a row with no variant defined yet")
 			}
-			target.Metafields = append(target.Metafields, mf)
+			current.Variants[target].Metafields = append(current.Variants[target].Metafields, mf)
+			currentRows.variantMetafields[target] = append(currentRows.variantMetafields[target], line)
 		} else {
 			current.Metafields = append(current.Metafields, mf)
+			currentRows.metafields = append(currentRows.metafields, line)
 		}
 	}
 
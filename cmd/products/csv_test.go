@@ -265,6 +265,33 @@ func TestParseCSVInventory(t *testing.T) {
 	}
 }
 
+func TestParseCSVInventoryAdditionalLocations(t *testing.T) {
+	locations := map[string]string{"Main": "gid://shopify/Location/1", "Second": "gid://shopify/Location/2"}
+	csv := "handle,Variant SKU,Location,Available\n" +
+		"chair,CHAIR-BLK,Main,5\n" +
+		",,Second,2\n" +
+		",CHAIR-WHT,Main,1\n"
+	prods, err := parseCSV(writeCSV(t, csv), locations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variants := prods[0].Input.Variants
+	if len(variants) != 2 {
+		t.Fatalf("len(variants) = %d, want 2", len(variants))
+	}
+
+	wantQty := []inventoryQuantityInput{
+		{LocationID: "gid://shopify/Location/1", Name: "available", Quantity: 5},
+		{LocationID: "gid://shopify/Location/2", Name: "available", Quantity: 2},
+	}
+	if got := variants[0].InventoryQuantities; !reflect.DeepEqual(got, wantQty) {
+		t.Errorf("variant1 quantities = %+v, want %+v", got, wantQty)
+	}
+	if got := len(variants[1].InventoryQuantities); got != 1 {
+		t.Errorf("len(variant2 quantities) = %d, want 1", got)
+	}
+}
+
 func TestParseCSVProductImage(t *testing.T) {
 	csv := "handle,Product Image URL\n" +
 		"chair,https://example.com/chair.png\n"
@@ -278,6 +305,26 @@ func TestParseCSVProductImage(t *testing.T) {
 	}
 }
 
+func TestParseCSVAdditionalProductImages(t *testing.T) {
+	csv := "handle,Variant SKU,Product Image URL\n" +
+		"chair,CHAIR-BLK,https://example.com/chair.png\n" +
+		",,https://example.com/chair-side.png\n"
+	prods, err := parseCSV(writeCSV(t, csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFiles := []fileInput{
+		{OriginalSource: "https://example.com/chair.png", ContentType: "IMAGE"},
+		{OriginalSource: "https://example.com/chair-side.png", ContentType: "IMAGE"},
+	}
+	if got := prods[0].Input.Files; !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("files = %+v, want %+v", got, wantFiles)
+	}
+	if got := len(prods[0].Input.Variants); got != 1 {
+		t.Errorf("len(variants) = %d, want 1", got)
+	}
+}
+
 func TestParseCSVUnknownLocation(t *testing.T) {
 	csv := "handle,Location,Available,Variant SKU\n" +
 		"chair,Nowhere,5,CHAIR-BLK\n"
@@ -419,3 +466,115 @@ func TestParseCSVMetafields(t *testing.T) {
 		t.Errorf("variant2 metafields = %+v, want %+v", got, wantVmf2)
 	}
 }
+
+func TestParseCSVRows(t *testing.T) {
+	csv := "handle,Title,Body (HTML),Variant SKU\n" +
+		"chair,Chair,\"<p>Line 1\nLine 2</p>\",CHAIR-BLK\n" +
+		",,,CHAIR-WHT\n" +
+		"table,Table,,TABLE\n"
+	prods, err := parseCSV(writeCSV(t, csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []int
+	for _, p := range prods {
+		rows = append(rows, p.Row)
+	}
+
+	if want := []int{2, 5}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
+
+func TestParseCSVCollectionsAndPublications(t *testing.T) {
+	csv := "handle,Variant SKU,Collection,Publications,Published\n" +
+		"chair,CHAIR-BLK,Furniture,\"Point of Sale, Shop\",TRUE\n" +
+		",CHAIR-RED,\"Chairs, Stools & Benches\",Shop,\n" +
+		",,furniture,,\n" +
+		"stool,STOOL,,,FALSE\n"
+	prods, err := parseCSV(writeCSV(t, csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCollections := []string{"Furniture", "Chairs, Stools & Benches", "furniture"}
+	if got := prods[0].Collections; !reflect.DeepEqual(got, wantCollections) {
+		t.Errorf("collections = %q, want %q", got, wantCollections)
+	}
+
+	wantPublications := []string{"Point of Sale", "Shop", "Online Store"}
+	if got := prods[0].Publications; !reflect.DeepEqual(got, wantPublications) {
+		t.Errorf("publications = %q, want %q", got, wantPublications)
+	}
+
+	if prods[1].Collections != nil || prods[1].Publications != nil {
+		t.Errorf("stool collections = %q, publications = %q, want none", prods[1].Collections, prods[1].Publications)
+	}
+}
+
+func TestParseCSVMedia(t *testing.T) {
+	csv := "handle,option1 name,option1 value,variant sku,image src,image position,image alt text,variant image,media type\n" +
+		"chair,Color,Black,CHAIR-BLK,https://example.com/side.png,2,Side,https://example.com/black.png,\n" +
+		",,White,CHAIR-WHT,https://example.com/front.png,1,Front,https://example.com/white.png,\n" +
+		",,,,https://example.com/black.png,3,Black chair,,\n" +
+		",,,,https://youtu.be/abc123,,,,\n" +
+		",,,,https://example.com/spin,,,,video\n"
+	prods, err := parseCSV(writeCSV(t, csv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []fileInput{
+		{OriginalSource: "https://example.com/front.png", ContentType: "IMAGE", Alt: "Front"},
+		{OriginalSource: "https://example.com/side.png", ContentType: "IMAGE", Alt: "Side"},
+		{OriginalSource: "https://example.com/black.png", ContentType: "IMAGE", Alt: "Black chair"},
+		{OriginalSource: "https://example.com/white.png", ContentType: "IMAGE"},
+		{OriginalSource: "https://youtu.be/abc123", ContentType: "EXTERNAL_VIDEO"},
+		{OriginalSource: "https://example.com/spin", ContentType: "VIDEO"},
+	}
+	if got := prods[0].Input.Files; !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("files = %+v, want %+v", got, wantFiles)
+	}
+
+	variants := prods[0].Input.Variants
+	if len(variants) != 2 {
+		t.Fatalf("len(variants) = %d, want 2", len(variants))
+	}
+	for i, want := range []string{"https://example.com/black.png", "https://example.com/white.png"} {
+		if variants[i].File == nil || variants[i].File.OriginalSource != want {
+			t.Errorf("variant %d file = %+v, want %s", i, variants[i].File, want)
+		}
+	}
+}
+
+func TestParseCSVInvalidImagePosition(t *testing.T) {
+	csv := "handle,image src,image position\n" +
+		"chair,https://example.com/chair.png,first\n"
+	if _, err := parseCSV(writeCSV(t, csv), nil); err == nil {
+		t.Error("parseCSV did not fail")
+	}
+}
+
+func TestMediaContentType(t *testing.T) {
+	tests := []struct {
+		source    string
+		mediaType string
+		want      string
+	}{
+		{"https://example.com/chair.png", "", "IMAGE"},
+		{"https://example.com/chair.MP4?v=1", "", "VIDEO"},
+		{"images/chair.webm", "", "VIDEO"},
+		{"https://example.com/chair.glb", "", "MODEL_3D"},
+		{"https://www.youtube.com/watch?v=abc123", "", "EXTERNAL_VIDEO"},
+		{"https://vimeo.com/123", "", "EXTERNAL_VIDEO"},
+		{"https://example.com/chair", "external video", "EXTERNAL_VIDEO"},
+		{"https://example.com/chair", "Model 3d", "MODEL_3D"},
+	}
+
+	for _, tt := range tests {
+		if got := mediaContentType(tt.source, tt.mediaType); got != tt.want {
+			t.Errorf("mediaContentType(%q, %q) = %q, want %q", tt.source, tt.mediaType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package products
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+// Number of selectors searched for at once
+const productSelectorBatchSize = 50
+
+// productSelector selects products by ID, SKU, or handle
+type productSelector struct {
+	// Row of the selector in the CSV file, or its position in the arguments
+	Row    int
+	ID     int64
+	SKU    string
+	Handle string
+}
+
+func (s productSelector) String() string {
+	switch {
+	case s.SKU != "":
+		return "sku:" + s.SKU
+	case s.Handle != "":
+		return "handle:" + s.Handle
+	}
+
+	return strconv.FormatInt(s.ID, 10)
+}
+
+func (s productSelector) query() string {
+	switch {
+	case s.SKU != "":
+		return fmt.Sprintf("sku:%q", s.SKU)
+	case s.Handle != "":
+		return fmt.Sprintf("handle:%q", s.Handle)
+	}
+
+	return fmt.Sprintf("id:%d", s.ID)
+}
+
+func (s productSelector) matches(product gql.Product) bool {
+	switch {
+	case s.SKU != "":
+		for _, v := range product.Variants {
+			if v.SKU == s.SKU {
+				return true
+			}
+		}
+
+		return false
+	case s.Handle != "":
+		return product.Handle == s.Handle
+	}
+
+	return product.ID == s.ID
+}
+
+// parseProductSelector parses an ID, product GID, 'sku:VALUE', or 'handle:VALUE' argument
+func parseProductSelector(arg string, row int) (productSelector, error) {
+	selector := productSelector{Row: row}
+	lower := strings.ToLower(arg)
+
+	switch {
+	case strings.HasPrefix(lower, "sku:"):
+		selector.SKU = arg[4:]
+		if selector.SKU == "" {
+			return selector, fmt.Errorf("SKU value missing after 'sku:'")
+		}
+	case strings.HasPrefix(lower, "handle:"):
+		selector.Handle = arg[7:]
+		if selector.Handle == "" {
+			return selector, fmt.Errorf("Handle value missing after 'handle:'")
+		}
+	default:
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "gid://shopify/Product/"), 10, 64)
+		if err != nil {
+			return selector, fmt.Errorf("Argument '%s' invalid: must be a product id, 'sku:VALUE', or 'handle:VALUE'", arg)
+		}
+
+		selector.ID = id
+	}
+
+	return selector, nil
+}
+
+// readSelectorsCSV reads the products to select from a CSV file with a Product ID, ID, Handle,
+// Variant SKU, or SKU column, such as an import file. Each row uses the first of these that has a value.
+func readSelectorsCSV(filename string) ([]productSelector, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open CSV file: %s", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read CSV header: %s", err)
+	}
+
+	ci := buildColumnIndex(header)
+	columns := []string{"product id", "id", "handle", "variant sku", "sku"}
+
+	found := false
+	for _, column := range columns {
+		if _, ok := ci[column]; ok {
+			found = true
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("CSV file must have a Product ID, ID, Handle, Variant SKU, or SKU column")
+	}
+
+	var selectors []productSelector
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CSV row: %s", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		for _, column := range columns {
+			idx, ok := ci[column]
+			if !ok || colVal(row, idx) == "" {
+				continue
+			}
+
+			value := colVal(row, idx)
+			selector := productSelector{Row: line}
+
+			switch column {
+			case "product id", "id":
+				selector, err = parseProductSelector(value, line)
+				if err != nil || selector.ID == 0 {
+					return nil, fmt.Errorf("Invalid product ID %q on row %d", value, line)
+				}
+			case "handle":
+				selector.Handle = value
+			default:
+				selector.SKU = value
+			}
+
+			selectors = append(selectors, selector)
+			break
+		}
+	}
+
+	return selectors, nil
+}
+
+type selectedProduct struct {
+	Row     int
+	Product gql.Product
+}
+
+// findProducts returns the products matching the selectors, in their order, followed by those matching
+// query. Each product is returned once. Selectors that match no products are also returned.
+func findProducts(shop, token string, selectors []productSelector, query string, options map[string]interface{}) ([]selectedProduct, []productSelector, error) {
+	var products []selectedProduct
+	var unmatched []productSelector
+	seen := map[int64]bool{}
+
+	add := func(row int, product gql.Product) {
+		if !seen[product.ID] {
+			seen[product.ID] = true
+			products = append(products, selectedProduct{Row: row, Product: product})
+		}
+	}
+
+	for start := 0; start < len(selectors); start += productSelectorBatchSize {
+		batch := selectors[start:min(start+productSelectorBatchSize, len(selectors))]
+
+		terms := make([]string, len(batch))
+		for i, selector := range batch {
+			terms[i] = selector.query()
+		}
+
+		var found []gql.Product
+		err := gql.SearchProducts(shop, token, strings.Join(terms, " OR "), func(product gql.Product) error {
+			found = append(found, product)
+			return nil
+		}, options)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, selector := range batch {
+			matched := false
+
+			for _, product := range found {
+				if selector.matches(product) {
+					matched = true
+					add(selector.Row, product)
+				}
+			}
+
+			if !matched {
+				unmatched = append(unmatched, selector)
+			}
+		}
+	}
+
+	if query != "" {
+		row := len(selectors)
+
+		err := gql.SearchProducts(shop, token, query, func(product gql.Product) error {
+			row++
+			add(row, product)
+			return nil
+		}, options)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return products, unmatched, nil
+}
+
+// deleteSelectedProducts deletes, or archives, the products, parallel at a time
+func deleteSelectedProducts(shop, token string, products []selectedProduct, archive bool, parallel int, options map[string]interface{}) []importResult {
+	results := make([]importResult, len(products))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, p := range products {
+		wg.Add(1)
+		go func(idx int, selected selectedProduct) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			id := strconv.FormatInt(selected.Product.ID, 10)
+			results[idx] = importResult{Row: selected.Row, Handle: selected.Product.Handle, ID: id}
+
+			if archive {
+				userErrors, err := gql.ProductArchive(shop, token, toProductGID(id), options)
+				if err != nil {
+					results[idx].Err = err
+					return
+				}
+
+				for _, ue := range userErrors {
+					results[idx].Errors = append(results[idx].Errors, ue.Message)
+				}
+
+				return
+			}
+
+			result, err := gql.ProductDelete(shop, token, toProductGID(id), options)
+			if err != nil {
+				results[idx].Err = err
+				return
+			}
+
+			for _, ue := range result.UserErrors {
+				results[idx].Errors = append(results[idx].Errors, ue.Message)
+			}
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func deleteProducts(c *cli.Context) error {
+	var selectors []productSelector
+
+	for i, arg := range c.Args().Slice() {
+		selector, err := parseProductSelector(arg, i+1)
+		if err != nil {
+			return err
+		}
+
+		selectors = append(selectors, selector)
+	}
+
+	if filename := c.String("from-csv"); filename != "" {
+		csvSelectors, err := readSelectorsCSV(filename)
+		if err != nil {
+			return err
+		}
+
+		selectors = append(selectors, csvSelectors...)
+	}
+
+	query := c.String("query")
+
+	if c.NArg() == 0 && c.String("from-csv") == "" && query == "" {
+		// The answer to the confirmation would also be read from stdin
+		if !c.Bool("yes") {
+			return fmt.Errorf("--yes is required when reading products from stdin")
+		}
+
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("Cannot read from stdin: %s", err)
+		}
+
+		row := 0
+		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			row++
+			selector, err := parseProductSelector(line, row)
+			if err != nil {
+				return err
+			}
+
+			selectors = append(selectors, selector)
+		}
+	}
+
+	shop := c.String("shop")
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+	options := map[string]interface{}{}
+	archive := c.Bool("archive")
+	jsonOutput := c.Bool("json")
+
+	out := os.Stdout
+	if jsonOutput {
+		out = os.Stderr
+	}
+
+	products, unmatched, err := findProducts(shop, token, selectors, query, options)
+	if err != nil {
+		return err
+	}
+
+	for _, selector := range unmatched {
+		fmt.Fprintf(os.Stderr, "No products match %s\n", selector)
+	}
+
+	if len(products) == 0 {
+		return fmt.Errorf("No products to delete")
+	}
+
+	action := "Delete"
+	if archive {
+		action = "Archive"
+	}
+
+	// Same as tabby.New() but to out, stderr with --json
+	t := tabby.NewCustom(tabwriter.NewWriter(out, 0, 0, 2, ' ', 0))
+	t.AddHeader("Row", "ID", "Handle", "Title")
+	for _, p := range products {
+		t.AddLine(p.Row, p.Product.ID, p.Product.Handle, p.Product.Title)
+	}
+	t.Print()
+
+	if !c.Bool("yes") {
+		ok, err := cmd.Confirm(os.Stdin, out, fmt.Sprintf("%s %d products?", action, len(products)))
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("Nothing was changed")
+		}
+	}
+
+	results := deleteSelectedProducts(shop, token, products, archive, c.Int("parallel"), options)
+
+	return printImportResults(results, jsonOutput, out)
+}
@@ -0,0 +1,192 @@
+package products
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+var deleteSeed = mock.Seed{
+	Products: []map[string]interface{}{
+		{"title": "Hat", "handle": "hat", "vendor": "Acme", "variants": []interface{}{map[string]interface{}{"sku": "HAT"}}},
+		{"title": "Scarf", "handle": "scarf", "vendor": "Acme", "variants": []interface{}{map[string]interface{}{"sku": "SCARF"}}},
+		{"title": "Glove", "handle": "glove", "vendor": "Other", "variants": []interface{}{map[string]interface{}{"sku": "GLOVE"}}},
+	},
+}
+
+func TestParseProductSelector(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    productSelector
+		wantErr bool
+	}{
+		{"123", productSelector{Row: 1, ID: 123}, false},
+		{"gid://shopify/Product/123", productSelector{Row: 1, ID: 123}, false},
+		{"sku:HAT-S", productSelector{Row: 1, SKU: "HAT-S"}, false},
+		{"SKU:HAT-S", productSelector{Row: 1, SKU: "HAT-S"}, false},
+		{"handle:blue-hat", productSelector{Row: 1, Handle: "blue-hat"}, false},
+		{"sku:", productSelector{}, true},
+		{"handle:", productSelector{}, true},
+		{"hat", productSelector{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseProductSelector(tt.arg, 1)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseProductSelector(%q) did not fail", tt.arg)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("parseProductSelector(%q) failed: %s", tt.arg, err)
+		} else if got != tt.want {
+			t.Errorf("parseProductSelector(%q) = %+v, want %+v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestReadSelectorsCSV(t *testing.T) {
+	csv := "Product ID,Handle,Variant SKU\n" +
+		"123,hat,HAT-S\n" +
+		",,HAT-M\n" +
+		",scarf,\n" +
+		",,\n"
+
+	got, err := readSelectorsCSV(writeCSV(t, csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []productSelector{{Row: 2, ID: 123}, {Row: 3, SKU: "HAT-M"}, {Row: 4, Handle: "scarf"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectors = %+v, want %+v", got, want)
+	}
+
+	if _, err := readSelectorsCSV(writeCSV(t, "Title\nHat\n")); err == nil {
+		t.Error("readSelectorsCSV without an identifier column did not fail")
+	}
+
+	if _, err := readSelectorsCSV(writeCSV(t, "Product ID\nhat\n")); err == nil {
+		t.Error("readSelectorsCSV with an invalid ID did not fail")
+	}
+}
+
+func TestDeleteProducts(t *testing.T) {
+	mockShop(t, deleteSeed)
+
+	options := map[string]interface{}{}
+
+	all, err := gql.FetchProducts("acme", "shpat_test", nil, nil, "", 10, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]int64{}
+	for _, p := range all {
+		ids[p.Handle] = p.ID
+	}
+
+	selectors := []productSelector{
+		{Row: 1, SKU: "SCARF"},
+		{Row: 2, Handle: "hat"},
+		{Row: 3, ID: ids["scarf"]},
+		{Row: 4, Handle: "nope"},
+	}
+
+	products, unmatched, err := findProducts("acme", "shpat_test", selectors, "vendor:Other", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handles []string
+	var rows []int
+	for _, p := range products {
+		handles = append(handles, p.Product.Handle)
+		rows = append(rows, p.Row)
+	}
+
+	if want := []string{"scarf", "hat", "glove"}; !reflect.DeepEqual(handles, want) {
+		t.Errorf("handles = %v, want %v", handles, want)
+	}
+
+	if want := []int{1, 2, 5}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+
+	if want := []productSelector{{Row: 4, Handle: "nope"}}; !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("unmatched = %+v, want %+v", unmatched, want)
+	}
+
+	results := deleteSelectedProducts("acme", "shpat_test", products[2:], true, 2, options)
+	results = append(results, deleteSelectedProducts("acme", "shpat_test", products[:2], false, 2, options)...)
+
+	for i, want := range []string{"glove", "scarf", "hat"} {
+		r := results[i]
+		if r.Err != nil || len(r.Errors) > 0 {
+			t.Errorf("%s failed: %v %v", want, r.Err, r.Errors)
+		}
+
+		if r.Handle != want || r.ID != strconv.FormatInt(ids[want], 10) {
+			t.Errorf("result %d = %+v, want %s", i, r, want)
+		}
+	}
+
+	remaining, err := gql.FetchProducts("acme", "shpat_test", nil, nil, "", 10, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(remaining) != 1 || remaining[0].Handle != "glove" || remaining[0].Status != "ARCHIVED" {
+		t.Errorf("remaining products = %+v, want archived glove", remaining)
+	}
+
+	results = deleteSelectedProducts("acme", "shpat_test", products[:1], false, 1, options)
+	if len(results[0].Errors) == 0 {
+		t.Error("deleting a deleted product did not fail")
+	}
+}
+
+func TestFindProductsBySkuPastFirstVariantsPage(t *testing.T) {
+	var values, variants []interface{}
+	for i := 1; i <= 260; i++ {
+		size := strconv.Itoa(i)
+		values = append(values, map[string]interface{}{"name": size})
+		variants = append(variants, map[string]interface{}{
+			"sku":          "SOCK-" + size,
+			"optionValues": []interface{}{map[string]interface{}{"optionName": "Size", "name": size}},
+		})
+	}
+
+	mockShop(t, mock.Seed{
+		Products: []map[string]interface{}{
+			{
+				"title":          "Sock",
+				"handle":         "sock",
+				"productOptions": []interface{}{map[string]interface{}{"name": "Size", "values": values}},
+				"variants":       variants,
+			},
+		},
+	})
+
+	selectors := []productSelector{{Row: 1, SKU: "SOCK-260"}}
+
+	products, unmatched, err := findProducts("acme", "shpat_test", selectors, "", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unmatched) > 0 || len(products) != 1 || products[0].Product.Handle != "sock" {
+		t.Fatalf("products = %+v, unmatched = %+v, want sock", products, unmatched)
+	}
+
+	if got := len(products[0].Product.Variants); got != 260 {
+		t.Errorf("variants = %d, want 260", got)
+	}
+}
@@ -0,0 +1,455 @@
+package products
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cheynewallace/tabby"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+const (
+	diffCreate    = "create"
+	diffUpdate    = "update"
+	diffDelete    = "delete"
+	diffUnchanged = "unchanged"
+	// Identified by an ID that doesn't exist; productSet will fail
+	diffNotFound = "not found"
+)
+
+// Longest value shown in the dry run table
+const maxDiffValueLength = 40
+
+type fieldChange struct {
+	Field  string `json:"field"`
+	Action string `json:"action"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+type productDiff struct {
+	Row     int           `json:"row"`
+	Handle  string        `json:"handle"`
+	ID      string        `json:"id,omitempty"`
+	Action  string        `json:"action"`
+	Changes []fieldChange `json:"changes,omitempty"`
+}
+
+// dryRunImport outputs what importing products would change without changing anything.
+// See matchMedia for mediaByName.
+func dryRunImport(shop, token string, products []importProductInput, locations map[string]string, mediaByName, jsonOutput bool, out io.Writer, options map[string]interface{}) error {
+	var identifiers []gql.ProductIdentifier
+	var indexes []int
+
+	for i, p := range products {
+		if p.Identifier != nil {
+			identifiers = append(identifiers, gql.ProductIdentifier{ID: p.Identifier.ID, Handle: p.Identifier.Handle})
+			indexes = append(indexes, i)
+		}
+	}
+
+	fmt.Fprintf(out, "Fetching %d existing products...\n", len(identifiers))
+
+	found, err := gql.FetchFullProductsByIdentifier(shop, token, identifiers, options)
+	if err != nil {
+		return err
+	}
+
+	existing := make([]*gql.FullProduct, len(products))
+	for i, index := range indexes {
+		existing[index] = found[i]
+	}
+
+	locationNames := make(map[string]string, len(locations))
+	for name, id := range locations {
+		locationNames[id] = name
+	}
+
+	diffs := make([]productDiff, len(products))
+	for i, p := range products {
+		diffs[i] = diffProduct(p, existing[i], locationNames, mediaByName)
+	}
+
+	if jsonOutput {
+		for _, d := range diffs {
+			line, err := json.Marshal(d)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(string(line))
+		}
+
+		return nil
+	}
+
+	printDiffs(diffs, out)
+
+	return nil
+}
+
+func printDiffs(diffs []productDiff, out io.Writer) {
+	counts := map[string]int{}
+
+	t := tabby.New()
+	t.AddHeader("Row", "Handle", "Product", "Action", "Change")
+
+	for _, d := range diffs {
+		counts[d.Action]++
+
+		if len(d.Changes) == 0 {
+			t.AddLine(d.Row, d.Handle, d.ID, d.Action, "")
+			continue
+		}
+
+		for i, change := range d.Changes {
+			if i == 0 {
+				t.AddLine(d.Row, d.Handle, d.ID, d.Action, formatChange(change))
+			} else {
+				t.AddLine("", "", "", "", formatChange(change))
+			}
+		}
+	}
+
+	t.Print()
+
+	fmt.Fprintf(out, "\n%d to create, %d to update, %d unchanged", counts[diffCreate], counts[diffUpdate], counts[diffUnchanged])
+	if counts[diffNotFound] > 0 {
+		fmt.Fprintf(out, ", %d not found", counts[diffNotFound])
+	}
+
+	fmt.Fprintln(out)
+}
+
+func formatChange(change fieldChange) string {
+	switch change.Action {
+	case diffCreate:
+		if change.New != "" {
+			return fmt.Sprintf("+ %s: %s", change.Field, truncateDiffValue(change.New))
+		}
+
+		return "+ " + change.Field
+	case diffDelete:
+		return "- " + change.Field
+	default:
+		return fmt.Sprintf("%s: %s → %s", change.Field, truncateDiffValue(change.Old), truncateDiffValue(change.New))
+	}
+}
+
+func truncateDiffValue(value string) string {
+	runes := []rune(value)
+	if len(runes) > maxDiffValueLength {
+		value = string(runes[:maxDiffValueLength-3]) + "..."
+	}
+
+	return strconv.Quote(value)
+}
+
+type productDiffer struct {
+	changes []fieldChange
+}
+
+func (d *productDiffer) update(field, old, new string) {
+	if !sameValue(old, new) {
+		d.changes = append(d.changes, fieldChange{Field: field, Action: diffUpdate, Old: old, New: new})
+	}
+}
+
+// updateIfSet is update for optional input, where empty means not to change the value
+func (d *productDiffer) updateIfSet(field, old, new string) {
+	if new != "" {
+		d.update(field, old, new)
+	}
+}
+
+func (d *productDiffer) metafields(prefix string, existing []gql.Metafield, input []metafieldInput) {
+	for _, mf := range input {
+		field := prefix + "metafield " + mf.Namespace + "." + mf.Key
+
+		var current *gql.Metafield
+		for i := range existing {
+			if existing[i].Namespace == mf.Namespace && existing[i].Key == mf.Key {
+				current = &existing[i]
+				break
+			}
+		}
+
+		if current == nil {
+			d.changes = append(d.changes, fieldChange{Field: field, Action: diffCreate, New: mf.Value})
+			continue
+		}
+
+		d.update(field, current.Value, mf.Value)
+		d.updateIfSet(field+" type", current.Type, mf.Type)
+	}
+}
+
+// diffProduct compares the product as imported with the existing one, which
+// is nil if it doesn't exist. See matchMedia for mediaByName.
+func diffProduct(input importProductInput, existing *gql.FullProduct, locationNames map[string]string, mediaByName bool) productDiff {
+	result := productDiff{Row: input.Row, Handle: input.Input.Handle}
+
+	if existing == nil {
+		result.Action = diffCreate
+		if input.Identifier != nil && input.Identifier.ID != "" {
+			result.Action = diffNotFound
+		}
+
+		return result
+	}
+
+	result.ID = strconv.FormatInt(existing.ID, 10)
+	if result.Handle == "" {
+		result.Handle = existing.Handle
+	}
+
+	p := input.Input
+	d := &productDiffer{}
+
+	d.updateIfSet("handle", existing.Handle, p.Handle)
+	d.updateIfSet("title", existing.Title, p.Title)
+	d.updateIfSet("body", existing.DescriptionHTML, p.DescriptionHTML)
+	d.updateIfSet("vendor", existing.Vendor, p.Vendor)
+	d.updateIfSet("type", existing.ProductType, p.ProductType)
+	d.updateIfSet("status", existing.Status, p.Status)
+
+	if len(p.Tags) > 0 {
+		d.update("tags", joinSorted(existing.Tags), joinSorted(p.Tags))
+	}
+
+	if len(p.ProductOptions) > 0 {
+		var inputOptions []gql.ProductOption
+		for _, option := range p.ProductOptions {
+			o := gql.ProductOption{Name: option.Name}
+			for _, value := range option.Values {
+				o.Values = append(o.Values, value.Name)
+			}
+
+			inputOptions = append(inputOptions, o)
+		}
+
+		d.update("options", formatOptions(existing.Options), formatOptions(inputOptions))
+	}
+
+	// Files that are existing media are shown by the media's URL
+	mediaURLs := map[string]string{}
+	for _, media := range existing.Media {
+		mediaURLs[media.ID] = media.URL
+	}
+
+	fileURLs := map[string]string{}
+
+	if len(p.Files) > 0 {
+		var old, sources []string
+		for _, media := range existing.Media {
+			old = append(old, media.URL)
+		}
+
+		matches := matchMedia(existing.Media, p.Files, mediaByName)
+		for i, file := range p.Files {
+			source := file.OriginalSource
+			if matches[i] >= 0 {
+				media := existing.Media[matches[i]]
+				source = media.URL
+				// Show that it won't be uploaded or downloaded in case it's a different file
+				if !sameMedia(file, media) {
+					source += " (same name as " + file.OriginalSource + ")"
+				}
+			}
+
+			fileURLs[file.OriginalSource] = source
+			sources = append(sources, source)
+		}
+
+		d.update("media", strings.Join(old, " "), strings.Join(sources, " "))
+	}
+
+	d.metafields("", existing.Metafields, p.Metafields)
+
+	// productSet deletes variants that aren't given
+	if len(p.Variants) > 0 {
+		matched := make([]bool, len(existing.Variants))
+
+		for _, v := range p.Variants {
+			i := matchExistingVariant(existing.Variants, matched, v)
+			label := "variant " + inputVariantLabel(v)
+
+			if i < 0 {
+				d.changes = append(d.changes, fieldChange{Field: label, Action: diffCreate})
+				continue
+			}
+
+			matched[i] = true
+			diffVariant(d, label, existing.Variants[i], v, locationNames)
+
+			if v.File != nil {
+				d.update(label+" image", mediaURLs[existing.Variants[i].MediaID], fileURLs[v.File.OriginalSource])
+			}
+		}
+
+		for i, v := range existing.Variants {
+			if !matched[i] {
+				d.changes = append(d.changes, fieldChange{Field: "variant " + existingVariantLabel(v), Action: diffDelete})
+			}
+		}
+	}
+
+	result.Changes = d.changes
+	result.Action = diffUnchanged
+	if len(d.changes) > 0 {
+		result.Action = diffUpdate
+	}
+
+	return result
+}
+
+func diffVariant(d *productDiffer, label string, existing gql.FullVariant, v importVariant, locationNames map[string]string) {
+	d.updateIfSet(label+" sku", existing.SKU, v.SKU)
+	d.updateIfSet(label+" price", existing.Price, v.Price)
+	d.updateIfSet(label+" compare at price", existing.CompareAtPrice, v.CompareAtPrice)
+	d.updateIfSet(label+" barcode", existing.Barcode, v.Barcode)
+	d.updateIfSet(label+" inventory policy", existing.InventoryPolicy, v.InventoryPolicy)
+
+	if v.Taxable != nil {
+		d.update(label+" taxable", strconv.FormatBool(existing.Taxable), strconv.FormatBool(*v.Taxable))
+	}
+
+	if item := v.InventoryItem; item != nil {
+		if item.Tracked != nil {
+			d.update(label+" tracked", strconv.FormatBool(existing.Tracked), strconv.FormatBool(*item.Tracked))
+		}
+
+		if item.RequiresShipping != nil {
+			d.update(label+" requires shipping", strconv.FormatBool(existing.RequiresShipping), strconv.FormatBool(*item.RequiresShipping))
+		}
+
+		d.updateIfSet(label+" unit cost", existing.UnitCost, item.Cost)
+	}
+
+	for _, quantity := range v.InventoryQuantities {
+		location := locationNames[quantity.LocationID]
+		if location == "" {
+			location = quantity.LocationID
+		}
+
+		var old string
+		for _, level := range existing.InventoryLevels {
+			if level.Location != location {
+				continue
+			}
+
+			if quantity.Name == "on_hand" {
+				old = strconv.Itoa(level.OnHand)
+			} else {
+				old = strconv.Itoa(level.Available)
+			}
+		}
+
+		d.update(fmt.Sprintf("%s %s at %s", label, strings.ReplaceAll(quantity.Name, "_", " "), location), old, strconv.Itoa(quantity.Quantity))
+	}
+
+	d.metafields(label+" ", existing.Metafields, v.Metafields)
+}
+
+// matchExistingVariant returns the index of the unmatched existing variant
+// with v's SKU or, failing that, its option values; -1 if there's none.
+func matchExistingVariant(variants []gql.FullVariant, matched []bool, v importVariant) int {
+	if v.SKU != "" {
+		for i, existing := range variants {
+			if !matched[i] && existing.SKU == v.SKU {
+				return i
+			}
+		}
+	}
+
+	options := map[string]string{}
+	for _, option := range v.OptionValues {
+		options[option.OptionName] = option.Name
+	}
+
+	for i, existing := range variants {
+		if matched[i] || len(existing.SelectedOptions) != len(options) {
+			continue
+		}
+
+		same := true
+		for _, selected := range existing.SelectedOptions {
+			if options[selected.Name] != selected.Value {
+				same = false
+				break
+			}
+		}
+
+		if same {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func inputVariantLabel(v importVariant) string {
+	if v.SKU != "" {
+		return v.SKU
+	}
+
+	var values []string
+	for _, option := range v.OptionValues {
+		values = append(values, option.Name)
+	}
+
+	return strings.Join(values, " / ")
+}
+
+func existingVariantLabel(v gql.FullVariant) string {
+	if v.SKU != "" {
+		return v.SKU
+	}
+
+	var values []string
+	for _, option := range v.SelectedOptions {
+		values = append(values, option.Value)
+	}
+
+	return strings.Join(values, " / ")
+}
+
+func formatOptions(options []gql.ProductOption) string {
+	var result []string
+	for _, option := range options {
+		result = append(result, option.Name+": "+strings.Join(option.Values, ", "))
+	}
+
+	return strings.Join(result, "; ")
+}
+
+func joinSorted(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+
+	return strings.Join(sorted, ", ")
+}
+
+// sameValue compares values as numbers when both are, e.g., prices of "10" and "10.00"
+func sameValue(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	x, err := strconv.ParseFloat(a, 64)
+	if err != nil {
+		return false
+	}
+
+	y, err := strconv.ParseFloat(b, 64)
+	if err != nil {
+		return false
+	}
+
+	return x == y
+}
@@ -0,0 +1,179 @@
+package products
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+func TestDiffProductExported(t *testing.T) {
+	mockShop(t, exportSeed)
+
+	options := map[string]interface{}{}
+	locations, err := gql.FetchLocations("acme", "shpat_test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "products.csv")
+	if err := os.WriteFile(filename, []byte(exportCSV(t)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	products, err := parseCSV(filename, locations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setProductIdentifiers(products, "handle")
+
+	identifiers := []gql.ProductIdentifier{{Handle: "blue-hat"}, {Handle: "red-scarf"}, {Handle: "nothing"}}
+	existing, err := gql.FetchFullProductsByIdentifier("acme", "shpat_test", identifiers, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if existing[2] != nil {
+		t.Errorf("existing[2] = %+v, want nil", existing[2])
+	}
+
+	locationNames := map[string]string{}
+	for name, id := range locations {
+		locationNames[id] = name
+	}
+
+	for i, p := range products {
+		d := diffProduct(p, existing[i], locationNames, false)
+		if d.Action != diffUnchanged {
+			t.Errorf("%s action = %q, want %q; changes: %+v", p.Input.Handle, d.Action, diffUnchanged, d.Changes)
+		}
+	}
+}
+
+func TestDiffProduct(t *testing.T) {
+	tracked := true
+	existing := &gql.FullProduct{
+		ID:     1,
+		Handle: "hat",
+		Title:  "Hat",
+		Tags:   []string{"b", "a"},
+		Status: "ACTIVE",
+		Options: []gql.ProductOption{
+			{Name: "Size", Values: []string{"S", "M"}},
+		},
+		Metafields: []gql.Metafield{{Namespace: "custom", Key: "care", Value: "Wash", Type: "single_line_text_field"}},
+		Variants: []gql.FullVariant{
+			{
+				SKU:             "HAT-S",
+				Price:           "10.00",
+				SelectedOptions: []gql.SelectedOption{{Name: "Size", Value: "S"}},
+				Tracked:         true,
+				InventoryLevels: []gql.InventoryLevel{{Location: "Main", Available: 5, OnHand: 5}},
+			},
+			{SKU: "HAT-M", Price: "10.00", SelectedOptions: []gql.SelectedOption{{Name: "Size", Value: "M"}}},
+		},
+	}
+
+	input := importProductInput{
+		Row:        2,
+		Identifier: &productSetIdentifier{Handle: "hat"},
+		Input: importProduct{
+			Handle: "hat",
+			Title:  "Blue Hat",
+			Tags:   []string{"a", "b"},
+			Status: "ACTIVE",
+			ProductOptions: []optionCreateInput{
+				{Name: "Size", Values: []optionValueInput{{Name: "S"}, {Name: "L"}}},
+			},
+			Metafields: []metafieldInput{
+				{Namespace: "custom", Key: "care", Value: "Wash", Type: "single_line_text_field"},
+				{Namespace: "custom", Key: "fit", Value: "Snug", Type: "single_line_text_field"},
+			},
+			Variants: []importVariant{
+				{
+					SKU:                 "HAT-S",
+					Price:               "10",
+					OptionValues:        []variantOptionValue{{OptionName: "Size", Name: "S"}},
+					InventoryItem:       &inventoryItemInput{Tracked: &tracked},
+					InventoryQuantities: []inventoryQuantityInput{{LocationID: "gid://shopify/Location/1", Name: "available", Quantity: 3}},
+				},
+				{SKU: "HAT-L", Price: "12.00", OptionValues: []variantOptionValue{{OptionName: "Size", Name: "L"}}},
+			},
+		},
+	}
+
+	got := diffProduct(input, existing, map[string]string{"gid://shopify/Location/1": "Main"}, false)
+
+	want := productDiff{
+		Row:    2,
+		Handle: "hat",
+		ID:     "1",
+		Action: diffUpdate,
+		Changes: []fieldChange{
+			{Field: "title", Action: diffUpdate, Old: "Hat", New: "Blue Hat"},
+			{Field: "options", Action: diffUpdate, Old: "Size: S, M", New: "Size: S, L"},
+			{Field: "metafield custom.fit", Action: diffCreate, New: "Snug"},
+			{Field: "variant HAT-S available at Main", Action: diffUpdate, Old: "5", New: "3"},
+			{Field: "variant HAT-L", Action: diffCreate},
+			{Field: "variant HAT-M", Action: diffDelete},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffProduct() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffProductMissing(t *testing.T) {
+	tests := []struct {
+		identifier *productSetIdentifier
+		want       string
+	}{
+		{nil, diffCreate},
+		{&productSetIdentifier{Handle: "hat"}, diffCreate},
+		{&productSetIdentifier{ID: "gid://shopify/Product/1"}, diffNotFound},
+	}
+
+	for _, tt := range tests {
+		input := importProductInput{Identifier: tt.identifier, Input: importProduct{Handle: "hat"}}
+		if got := diffProduct(input, nil, nil, false).Action; got != tt.want {
+			t.Errorf("diffProduct(%+v) action = %q, want %q", tt.identifier, got, tt.want)
+		}
+	}
+}
+
+func TestDiffProductMediaByName(t *testing.T) {
+	existing := &gql.FullProduct{
+		ID:     1,
+		Handle: "chair",
+		Media:  []gql.ProductMedia{{ID: "gid://shopify/MediaImage/1", ContentType: "IMAGE", URL: "https://cdn.shopify.com/files/chair.png?v=1"}},
+	}
+
+	input := importProductInput{
+		Identifier: &productSetIdentifier{Handle: "chair"},
+		Input: importProduct{
+			Handle: "chair",
+			Files:  []fileInput{{OriginalSource: "images/chair.png", ContentType: "IMAGE"}},
+		},
+	}
+
+	tests := []struct {
+		byName bool
+		want   string
+	}{
+		{false, "images/chair.png"},
+		{true, "https://cdn.shopify.com/files/chair.png?v=1 (same name as images/chair.png)"},
+	}
+
+	for _, tt := range tests {
+		d := diffProduct(input, existing, nil, tt.byName)
+
+		want := []fieldChange{{Field: "media", Action: diffUpdate, Old: existing.Media[0].URL, New: tt.want}}
+		if !reflect.DeepEqual(d.Changes, want) {
+			t.Errorf("diffProduct(byName %t) changes = %+v, want %+v", tt.byName, d.Changes, want)
+		}
+	}
+}
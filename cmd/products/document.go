@@ -0,0 +1,466 @@
+package products
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatCSV   = "csv"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+	formatYAML  = "yaml"
+)
+
+var productFileFormats = map[string]string{
+	".csv":    formatCSV,
+	".json":   formatJSON,
+	".jsonl":  formatJSONL,
+	".ndjson": formatJSONL,
+	".yaml":   formatYAML,
+	".yml":    formatYAML,
+}
+
+// documentString is a string that can also be given as a JSON number, e.g., a price of 10.5
+type documentString string
+
+func (s *documentString) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var v string
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+
+		*s = documentString(v)
+		return nil
+	}
+
+	if string(b) == "null" {
+		*s = ""
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("expected a string or number, got %s", b)
+	}
+
+	*s = documentString(n)
+	return nil
+}
+
+// mediaDocument is an image or other media, given as its URL or path or as
+// an object with its src, alt, and type
+type mediaDocument struct {
+	Src  string `json:"src"`
+	Alt  string `json:"alt"`
+	Type string `json:"type"`
+}
+
+func (m *mediaDocument) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		*m = mediaDocument{}
+		return json.Unmarshal(b, &m.Src)
+	}
+
+	// Without the method, to not recurse
+	type media mediaDocument
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode((*media)(m))
+}
+
+type inventoryDocument struct {
+	Location  string `json:"location"`
+	Available *int   `json:"available"`
+	OnHand    *int   `json:"onHand"`
+}
+
+type metafieldDocument struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Type      string `json:"type"`
+	// Values that aren't strings, e.g., for json metafields, are converted to JSON
+	Value interface{} `json:"value"`
+}
+
+type variantDocument struct {
+	// Values of the product's options, in the same order
+	Options          []string            `json:"options"`
+	SKU              documentString      `json:"sku"`
+	Price            documentString      `json:"price"`
+	CompareAtPrice   documentString      `json:"compareAtPrice"`
+	Barcode          documentString      `json:"barcode"`
+	Taxable          *bool               `json:"taxable"`
+	InventoryPolicy  string              `json:"inventoryPolicy"`
+	RequiresShipping *bool               `json:"requiresShipping"`
+	UnitCost         documentString      `json:"unitCost"`
+	Image            string              `json:"image"`
+	Inventory        []inventoryDocument `json:"inventory"`
+	Metafields       []metafieldDocument `json:"metafields"`
+}
+
+// productDocument is a product in a JSON, JSONL, or YAML import file. It
+// describes the same things as the CSV columns.
+type productDocument struct {
+	ID           documentString      `json:"id"`
+	Handle       string              `json:"handle"`
+	Title        string              `json:"title"`
+	Body         string              `json:"body"`
+	Vendor       string              `json:"vendor"`
+	Type         string              `json:"type"`
+	Tags         []string            `json:"tags"`
+	Status       string              `json:"status"`
+	Images       []mediaDocument     `json:"images"`
+	Collections  []string            `json:"collections"`
+	Publications []string            `json:"publications"`
+	Options      []string            `json:"options"`
+	Variants     []variantDocument   `json:"variants"`
+	Metafields   []metafieldDocument `json:"metafields"`
+}
+
+// productFileFormat returns format if given, otherwise the format of filename based on its extension
+func productFileFormat(filename, format string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		for _, f := range productFileFormats {
+			if f == format {
+				return format, nil
+			}
+		}
+
+		return "", fmt.Errorf("Unknown format %q: must be csv, json, jsonl, or yaml", format)
+	}
+
+	format, ok := productFileFormats[strings.ToLower(filepath.Ext(filename))]
+	if !ok {
+		return "", fmt.Errorf("Cannot determine the format of %s: use --format", filename)
+	}
+
+	return format, nil
+}
+
+// parseProductFile reads the products to import from filename in the given format
+func parseProductFile(filename, format string, locations map[string]string) ([]importProductInput, error) {
+	if format == formatCSV {
+		return parseCSV(filename, locations)
+	}
+
+	return parseProductDocuments(filename, format, locations)
+}
+
+// parseProductDocuments reads products from a JSON array, JSONL, or YAML
+// list. A product's row is its line number in JSONL files and its position
+// in the list for others.
+func parseProductDocuments(filename, format string, locations map[string]string) ([]importProductInput, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open product file: %s", err)
+	}
+
+	var documents [][]byte
+	var rows []int
+
+	switch format {
+	case formatJSONL:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+		line := 0
+		for scanner.Scan() {
+			line++
+
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+
+			documents = append(documents, append([]byte(nil), scanner.Bytes()...))
+			rows = append(rows, line)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("Cannot read product file: %s", err)
+		}
+	case formatJSON:
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("Cannot parse product file, it must contain a list of products: %s", err)
+		}
+
+		for i, document := range list {
+			documents = append(documents, document)
+			rows = append(rows, i+1)
+		}
+	case formatYAML:
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("Cannot parse product file, it must contain a list of products: %s", err)
+		}
+
+		var list []*yaml.Node
+		if len(root.Content) > 0 {
+			if root.Content[0].Kind != yaml.SequenceNode {
+				return nil, fmt.Errorf("Cannot parse product file, it must contain a list of products")
+			}
+
+			list = root.Content[0].Content
+		}
+
+		for i, document := range list {
+			v, err := yamlToJSON(document)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot parse product %d: %s", i+1, err)
+			}
+
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("Cannot parse product %d: %s", i+1, err)
+			}
+
+			documents = append(documents, b)
+			rows = append(rows, i+1)
+		}
+	default:
+		return nil, fmt.Errorf("Unknown format %q", format)
+	}
+
+	var products []importProductInput
+
+	for i, document := range documents {
+		var doc productDocument
+
+		decoder := json.NewDecoder(bytes.NewReader(document))
+		decoder.DisallowUnknownFields()
+		decoder.UseNumber()
+
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("Cannot parse product on row %d: %s", rows[i], err)
+		}
+
+		product, err := doc.importProductInput(rows[i], locations)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid product on row %d: %s", rows[i], err)
+		}
+
+		products = append(products, product)
+	}
+
+	return products, nil
+}
+
+func (doc productDocument) importProductInput(row int, locations map[string]string) (importProductInput, error) {
+	product := importProduct{
+		ID:              string(doc.ID),
+		Handle:          doc.Handle,
+		Title:           doc.Title,
+		DescriptionHTML: doc.Body,
+		Vendor:          doc.Vendor,
+		ProductType:     doc.Type,
+		Tags:            doc.Tags,
+		Status:          strings.ToUpper(doc.Status),
+	}
+
+	for _, media := range doc.Images {
+		if media.Src == "" {
+			return importProductInput{}, fmt.Errorf("Image src is required")
+		}
+
+		addFile(&product.Files, fileInput{OriginalSource: media.Src, ContentType: mediaContentType(media.Src, media.Type), Alt: media.Alt})
+	}
+
+	optionValues := make([][]string, len(doc.Options))
+	optionSeen := make([]map[string]bool, len(doc.Options))
+	for i := range optionSeen {
+		optionSeen[i] = map[string]bool{}
+	}
+
+	for n, v := range doc.Variants {
+		if len(v.Options) != len(doc.Options) {
+			return importProductInput{}, fmt.Errorf("Variant %d has %d option values but the product has %d options", n+1, len(v.Options), len(doc.Options))
+		}
+
+		variant := importVariant{
+			SKU:             string(v.SKU),
+			Price:           string(v.Price),
+			CompareAtPrice:  string(v.CompareAtPrice),
+			Barcode:         string(v.Barcode),
+			Taxable:         v.Taxable,
+			InventoryPolicy: strings.ToUpper(v.InventoryPolicy),
+		}
+
+		for i, value := range v.Options {
+			if !optionSeen[i][value] {
+				optionSeen[i][value] = true
+				optionValues[i] = append(optionValues[i], value)
+			}
+
+			variant.OptionValues = append(variant.OptionValues, variantOptionValue{OptionName: doc.Options[i], Name: value})
+		}
+
+		// Variant images are also the product's
+		if v.Image != "" {
+			file := fileInput{OriginalSource: v.Image, ContentType: mediaContentType(v.Image, "")}
+			addFile(&product.Files, file)
+			variant.File = &file
+		}
+
+		if v.RequiresShipping != nil || v.UnitCost != "" {
+			variant.InventoryItem = &inventoryItemInput{RequiresShipping: v.RequiresShipping, Cost: string(v.UnitCost)}
+		}
+
+		for _, inventory := range v.Inventory {
+			locationID := inventory.Location
+			if locations != nil {
+				id, ok := locations[inventory.Location]
+				if !ok {
+					return importProductInput{}, fmt.Errorf("Unknown location %q", inventory.Location)
+				}
+
+				locationID = id
+			}
+
+			quantity := inventoryQuantityInput{LocationID: locationID}
+
+			switch {
+			case inventory.Available != nil && inventory.OnHand != nil:
+				return importProductInput{}, fmt.Errorf("Only one of available and onHand can be set for location %q", inventory.Location)
+			case inventory.Available != nil:
+				quantity.Name = "available"
+				quantity.Quantity = *inventory.Available
+			case inventory.OnHand != nil:
+				quantity.Name = "on_hand"
+				quantity.Quantity = *inventory.OnHand
+			default:
+				return importProductInput{}, fmt.Errorf("One of available or onHand is required for location %q", inventory.Location)
+			}
+
+			variant.InventoryQuantities = append(variant.InventoryQuantities, quantity)
+		}
+
+		if len(variant.InventoryQuantities) > 0 {
+			tracked := true
+			if variant.InventoryItem == nil {
+				variant.InventoryItem = &inventoryItemInput{}
+			}
+			variant.InventoryItem.Tracked = &tracked
+		}
+
+		metafields, err := documentMetafields(v.Metafields)
+		if err != nil {
+			return importProductInput{}, err
+		}
+
+		variant.Metafields = metafields
+		product.Variants = append(product.Variants, variant)
+	}
+
+	metafields, err := documentMetafields(doc.Metafields)
+	if err != nil {
+		return importProductInput{}, err
+	}
+
+	product.Metafields = metafields
+
+	input := newImportProductInput(product, doc.Options, optionValues, row)
+	for _, c := range doc.Collections {
+		input.Collections = appendUnique(input.Collections, strings.TrimSpace(c))
+	}
+
+	for _, p := range doc.Publications {
+		input.Publications = appendUnique(input.Publications, strings.TrimSpace(p))
+	}
+
+	return input, nil
+}
+
+func documentMetafields(documents []metafieldDocument) ([]metafieldInput, error) {
+	var metafields []metafieldInput
+
+	for _, doc := range documents {
+		mf := metafieldInput{Namespace: doc.Namespace, Key: doc.Key, Type: doc.Type}
+
+		switch value := doc.Value.(type) {
+		case nil:
+		case string:
+			mf.Value = value
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid value for metafield %s.%s: %s", doc.Namespace, doc.Key, err)
+			}
+
+			mf.Value = string(b)
+		}
+
+		metafields = append(metafields, mf)
+	}
+
+	return metafields, nil
+}
+
+// yamlToJSON converts node to a value that can be encoded as JSON. Numbers keep their text, so
+// string fields get what was written, e.g., a barcode of 0123 and not 83. Numbers JSON can't
+// represent, e.g., 0123 or 0x1F, are strings.
+func yamlToJSON(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+
+		return yamlToJSON(node.Content[0])
+	case yaml.AliasNode:
+		return yamlToJSON(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v, err := yamlToJSON(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			m[node.Content[i].Value] = v
+		}
+
+		return m, nil
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i := range node.Content {
+			v, err := yamlToJSON(node.Content[i])
+			if err != nil {
+				return nil, err
+			}
+
+			list[i] = v
+		}
+
+		return list, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var b bool
+		if err := node.Decode(&b); err != nil {
+			return nil, err
+		}
+
+		return b, nil
+	case "!!int", "!!float":
+		if json.Valid([]byte(node.Value)) {
+			return json.Number(node.Value), nil
+		}
+	}
+
+	return node.Value, nil
+}
@@ -0,0 +1,233 @@
+package products
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeProductFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+var documentLocations = map[string]string{"Main": "gid://shopify/Location/1"}
+
+const documentCSV = "handle,title,body (html),vendor,type,tags,status,product image url,option1 name,option1 value,variant sku,variant price,variant taxable,requires shipping,unit cost,location,available,metafield owner,metafield namespace,metafield key,metafield type,metafield value\n" +
+	"hat,Hat,<p>Warm</p>,Acme,Hats,\"hats, winter\",active,https://example.com/hat.png,Size,S,HAT-S,10.00,false,true,4.5,Main,3,,,,,\n" +
+	",,,,,,,,,,,,,,,,,Variant,custom,fit,single_line_text_field,Snug\n" +
+	",,,,,,,,,M,HAT-M,12,,,,,,,,,,\n" +
+	",,,,,,,https://example.com/hat-side.png,,,,,,,,,,,,,,\n" +
+	",,,,,,,,,,,,,,,,,Product,custom,dimensions,json,\"{\"\"width\"\":10}\"\n" +
+	"scarf,Scarf,,,,,draft,,,,SCARF,5.00,,,,,,,,,,\n"
+
+const documentHatJSON = `{"handle": "hat", "title": "Hat", "body": "<p>Warm</p>", "vendor": "Acme", "type": "Hats", "tags": ["hats", "winter"], "status": "active", "images": ["https://example.com/hat.png", "https://example.com/hat-side.png"], "options": ["Size"], "variants": [{"options": ["S"], "sku": "HAT-S", "price": "10.00", "taxable": false, "requiresShipping": true, "unitCost": 4.5, "inventory": [{"location": "Main", "available": 3}], "metafields": [{"namespace": "custom", "key": "fit", "type": "single_line_text_field", "value": "Snug"}]}, {"options": ["M"], "sku": "HAT-M", "price": 12}], "metafields": [{"namespace": "custom", "key": "dimensions", "type": "json", "value": {"width": 10}}]}`
+
+const documentScarfJSON = `{"handle": "scarf", "title": "Scarf", "status": "draft", "variants": [{"sku": "SCARF", "price": "5.00"}]}`
+
+const documentYAML = `
+- handle: hat
+  title: Hat
+  body: <p>Warm</p>
+  vendor: Acme
+  type: Hats
+  tags: [hats, winter]
+  status: active
+  images:
+    - https://example.com/hat.png
+    - https://example.com/hat-side.png
+  options: [Size]
+  variants:
+    - options: [S]
+      sku: HAT-S
+      price: "10.00"
+      taxable: false
+      requiresShipping: true
+      unitCost: 4.5
+      inventory:
+        - location: Main
+          available: 3
+      metafields:
+        - namespace: custom
+          key: fit
+          type: single_line_text_field
+          value: Snug
+    - options: [M]
+      sku: HAT-M
+      price: 12
+  metafields:
+    - namespace: custom
+      key: dimensions
+      type: json
+      value:
+        width: 10
+- handle: scarf
+  title: Scarf
+  status: draft
+  variants:
+    - sku: SCARF
+      price: "5.00"
+`
+
+func TestParseProductDocumentsMatchesCSV(t *testing.T) {
+	want, err := parseCSV(writeProductFile(t, "products.csv", documentCSV), documentLocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		rows    []int
+	}{
+		{"products.jsonl", documentHatJSON + "\n\n" + documentScarfJSON + "\n", formatJSONL, []int{1, 3}},
+		{"products.json", "[" + documentHatJSON + ",\n" + documentScarfJSON + "]", formatJSON, []int{1, 2}},
+		{"products.yaml", documentYAML, formatYAML, []int{1, 2}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseProductDocuments(writeProductFile(t, tt.name, tt.content), tt.format, documentLocations)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("%s: len(products) = %d, want %d", tt.name, len(got), len(want))
+		}
+
+		for i := range got {
+			if got[i].Row != tt.rows[i] {
+				t.Errorf("%s: product %d row = %d, want %d", tt.name, i, got[i].Row, tt.rows[i])
+			}
+
+			expected := want[i]
+			expected.Row = got[i].Row
+			// Everything in a document is on its row
+			expected.rows = importRows{}
+
+			if !reflect.DeepEqual(got[i], expected) {
+				t.Errorf("%s: product %d = %+v, want %+v", tt.name, i, got[i], expected)
+			}
+		}
+	}
+}
+
+func TestParseProductDocumentsMedia(t *testing.T) {
+	content := `{"handle": "hat", "options": ["Color"], "images": ["https://example.com/hat.png", {"src": "hat.mp4", "alt": "Spinning"}, {"src": "https://example.com/hat", "type": "model 3d"}], "variants": [{"options": ["Red"], "image": "https://example.com/red.png"}, {"options": ["Blue"], "image": "https://example.com/hat.png"}]}`
+
+	products, err := parseProductDocuments(writeProductFile(t, "products.jsonl", content), formatJSONL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantFiles := []fileInput{
+		{OriginalSource: "https://example.com/hat.png", ContentType: "IMAGE"},
+		{OriginalSource: "hat.mp4", ContentType: "VIDEO", Alt: "Spinning"},
+		{OriginalSource: "https://example.com/hat", ContentType: "MODEL_3D"},
+		{OriginalSource: "https://example.com/red.png", ContentType: "IMAGE"},
+	}
+	if got := products[0].Input.Files; !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("files = %+v, want %+v", got, wantFiles)
+	}
+
+	for i, want := range []string{"https://example.com/red.png", "https://example.com/hat.png"} {
+		if file := products[0].Input.Variants[i].File; file == nil || file.OriginalSource != want {
+			t.Errorf("variant %d file = %+v, want %s", i, file, want)
+		}
+	}
+}
+
+func TestParseProductDocumentsYAMLScalars(t *testing.T) {
+	content := `
+- handle: hat
+  title: 0123
+  options: [Size]
+  variants:
+    - options: [yes]
+      sku: 007
+      barcode: 0123
+      price: 10.50
+      unitCost: 0x1F
+`
+
+	products, err := parseProductDocuments(writeProductFile(t, "products.yaml", content), formatYAML, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := products[0].Input.Title; got != "0123" {
+		t.Errorf("title = %q, want 0123", got)
+	}
+
+	variant := products[0].Input.Variants[0]
+	got := []string{variant.OptionValues[0].Name, variant.SKU, variant.Barcode, variant.Price, variant.InventoryItem.Cost}
+	if want := []string{"yes", "007", "0123", "10.50", "0x1F"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("option, sku, barcode, price, cost = %q, want %q", got, want)
+	}
+
+	filename := writeProductFile(t, "products.yaml", "- handle: hat\n  variants:\n    - taxable: yes\n")
+	if _, err := parseProductDocuments(filename, formatYAML, nil); err == nil {
+		t.Error("parseProductDocuments did not fail on taxable: yes")
+	}
+}
+
+func TestParseProductDocumentsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"handle": "hat", "titel": "Hat"}`},
+		{"option count", `{"handle": "hat", "options": ["Size"], "variants": [{"sku": "HAT"}]}`},
+		{"unknown location", `{"handle": "hat", "variants": [{"inventory": [{"location": "Mars", "available": 1}]}]}`},
+		{"both quantities", `{"handle": "hat", "variants": [{"inventory": [{"location": "Main", "available": 1, "onHand": 2}]}]}`},
+		{"invalid price", `{"handle": "hat", "variants": [{"price": true}]}`},
+		{"image without src", `{"handle": "hat", "images": [{"alt": "Hat"}]}`},
+		{"unknown image field", `{"handle": "hat", "images": [{"src": "hat.png", "position": 1}]}`},
+	}
+
+	for _, tt := range tests {
+		filename := writeProductFile(t, "products.jsonl", tt.content)
+		if _, err := parseProductDocuments(filename, formatJSONL, documentLocations); err == nil {
+			t.Errorf("%s: parseProductDocuments did not fail", tt.name)
+		}
+	}
+}
+
+func TestProductFileFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		format   string
+		want     string
+		wantErr  bool
+	}{
+		{"products.csv", "", formatCSV, false},
+		{"products.CSV", "", formatCSV, false},
+		{"products.json", "", formatJSON, false},
+		{"products.jsonl", "", formatJSONL, false},
+		{"products.ndjson", "", formatJSONL, false},
+		{"products.yml", "", formatYAML, false},
+		{"products.yaml", "", formatYAML, false},
+		{"products.txt", "", "", true},
+		{"products.txt", "JSONL", formatJSONL, false},
+		{"products.csv", "xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := productFileFormat(tt.filename, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("productFileFormat(%q, %q) error = %v, want error %v", tt.filename, tt.format, err, tt.wantErr)
+		}
+
+		if got != tt.want {
+			t.Errorf("productFileFormat(%q, %q) = %q, want %q", tt.filename, tt.format, got, tt.want)
+		}
+	}
+}
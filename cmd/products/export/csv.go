@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/exportformat"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// CSV exports products in the format read by "products import"
+func CSV(c *cli.Context) error {
+	shop := c.String("shop")
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+	status := c.String("status")
+	options := map[string]interface{}{}
+
+	total, err := gql.FetchProductCount(shop, token, status, options)
+	if err != nil {
+		return err
+	}
+
+	filename := shopBaseName(shop) + "-products.csv"
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("Failed to create CSV file: %s", err)
+	}
+	defer file.Close()
+
+	fmt.Fprintf(os.Stderr, "Exporting %d products to %s...\n", total, filename)
+
+	d := exportformat.NewShopifyCSV(file)
+
+	count := 0
+	fn := func(product gql.FullProduct) error {
+		if err := d.Dump(product); err != nil {
+			return fmt.Errorf("Cannot write product %d: %s", product.ID, err)
+		}
+
+		count++
+		fmt.Fprintf(os.Stderr, "\rProcessing %d/%d", count, total)
+
+		return nil
+	}
+
+	if c.Bool("bulk") {
+		err = gql.FetchAllFullProductsBulk(shop, token, status, fn, gqlclient.PrintBulkProgress, options)
+	} else {
+		err = gql.FetchAllFullProducts(shop, token, status, fn, options)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := d.Close(); err != nil {
+		return fmt.Errorf("Cannot write CSV file: %s", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "\nComplete!")
+
+	return nil
+}
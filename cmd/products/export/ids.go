@@ -10,6 +10,7 @@ import (
 	"github.com/ScreenStaring/shopify-dev-tools/cmd"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/exportformat"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
 )
 
 type dumper interface {
@@ -23,7 +24,10 @@ func shopBaseName(shop string) string {
 
 func IDs(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	status := c.String("status")
 	baseName := shopBaseName(shop)
 
@@ -54,7 +58,7 @@ func IDs(c *cli.Context) error {
 	}
 
 	count := 0
-	err = gql.FetchAllProducts(shop, token, status, func(product gql.Product) error {
+	fn := func(product gql.Product) error {
 		if err := d.Dump(product); err != nil {
 			return fmt.Errorf("Cannot write product %d: %s", product.ID, err)
 		}
@@ -63,7 +67,13 @@ func IDs(c *cli.Context) error {
 		fmt.Fprintf(os.Stderr, "\rProcessing %d/%d", count, total)
 
 		return nil
-	}, options)
+	}
+
+	if c.Bool("bulk") {
+		err = gql.FetchAllProductsBulk(shop, token, status, fn, gqlclient.PrintBulkProgress, options)
+	} else {
+		err = gql.FetchAllProducts(shop, token, status, fn, options)
+	}
 
 	if err != nil {
 		return err
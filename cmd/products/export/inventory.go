@@ -12,6 +12,7 @@ import (
 
 	"github.com/ScreenStaring/shopify-dev-tools/cmd"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
 )
 
 var validIdentifyBy = []string{"id", "sku", "barcode"}
@@ -34,7 +35,10 @@ func readIdentifiers() ([]string, error) {
 
 func Inventory(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{}
 	baseName := shopBaseName(shop)
 
@@ -100,16 +104,18 @@ func Inventory(c *cli.Context) error {
 	}
 
 	count := 0
+	bulk := c.Bool("bulk")
 
 	if identifyBy != "" {
-		identifiers, err := readIdentifiers()
+		var identifiers []string
+		identifiers, err = readIdentifiers()
 		if err != nil {
 			return err
 		}
 
 		fmt.Fprintf(os.Stderr, "Exporting inventory for %d identifiers to CSV...\n", len(identifiers))
 
-		err = gql.FetchInventoryByIdentifiers(shop, token, identifyBy, identifiers, func(pi gql.ProductInventory) error {
+		fn := func(pi gql.ProductInventory) error {
 			if err := writeRow(pi); err != nil {
 				return err
 			}
@@ -118,16 +124,23 @@ func Inventory(c *cli.Context) error {
 			fmt.Fprintf(os.Stderr, "\rProcessing %d", count)
 
 			return nil
-		}, options)
+		}
+
+		if bulk {
+			err = gql.FetchInventoryByIdentifiersBulk(shop, token, identifyBy, identifiers, fn, gqlclient.PrintBulkProgress, options)
+		} else {
+			err = gql.FetchInventoryByIdentifiers(shop, token, identifyBy, identifiers, fn, options)
+		}
 	} else {
-		total, err := gql.FetchProductCount(shop, token, "", options)
+		var total int
+		total, err = gql.FetchProductCount(shop, token, "", options)
 		if err != nil {
 			return err
 		}
 
 		fmt.Fprintf(os.Stderr, "Exporting inventory for %d products to CSV...\n", total)
 
-		err = gql.FetchAllInventory(shop, token, func(pi gql.ProductInventory) error {
+		fn := func(pi gql.ProductInventory) error {
 			if err := writeRow(pi); err != nil {
 				return err
 			}
@@ -136,7 +149,13 @@ func Inventory(c *cli.Context) error {
 			fmt.Fprintf(os.Stderr, "\rProcessing %d/%d", count, total)
 
 			return nil
-		}, options)
+		}
+
+		if bulk {
+			err = gql.FetchAllInventoryBulk(shop, token, fn, gqlclient.PrintBulkProgress, options)
+		} else {
+			err = gql.FetchAllInventory(shop, token, fn, options)
+		}
 	}
 
 	if err != nil {
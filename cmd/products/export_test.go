@@ -0,0 +1,202 @@
+package products
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/exportformat"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+var exportSeed = mock.Seed{
+	Locations:    []string{"Warehouse"},
+	Publications: []string{"Point of Sale"},
+	Collections:  []mock.SeedCollection{{Title: "Hats", Handle: "hats"}, {Title: "Winter", Handle: "winter"}, {Title: "Sale", Handle: "sale", Smart: true}},
+	Products: []map[string]interface{}{
+		{
+			"title":           "Blue Hat",
+			"handle":          "blue-hat",
+			"descriptionHtml": "<p>Warm, \"woolly\"</p>",
+			"vendor":          "Acme",
+			"productType":     "Hats",
+			"tags":            []interface{}{"hats", "winter"},
+			"status":          "ACTIVE",
+			"collections":     []interface{}{"hats", "winter"},
+			"publications":    []interface{}{"Online Store", "Point of Sale"},
+			"files": []interface{}{
+				map[string]interface{}{"originalSource": "https://example.com/hat.png", "contentType": "IMAGE", "alt": "Blue hat"},
+				map[string]interface{}{"originalSource": "https://example.com/hat-side.png", "contentType": "IMAGE"},
+				map[string]interface{}{"originalSource": "https://example.com/hat.mp4", "contentType": "VIDEO", "alt": "Hat on a head"},
+			},
+			"productOptions": []interface{}{
+				map[string]interface{}{"name": "Size", "values": []interface{}{map[string]interface{}{"name": "S"}, map[string]interface{}{"name": "M"}}},
+			},
+			"variants": []interface{}{
+				map[string]interface{}{
+					"sku":             "HAT-S",
+					"price":           "20.00",
+					"compareAtPrice":  "25.00",
+					"barcode":         "0001",
+					"inventoryPolicy": "CONTINUE",
+					"optionValues":    []interface{}{map[string]interface{}{"optionName": "Size", "name": "S"}},
+					"inventoryItem":   map[string]interface{}{"cost": "8.50", "tracked": true},
+					"inventoryQuantities": []interface{}{
+						map[string]interface{}{"locationId": "Main", "name": "available", "quantity": 5},
+						map[string]interface{}{"locationId": "Warehouse", "name": "available", "quantity": 2},
+					},
+					"metafields": []interface{}{
+						map[string]interface{}{"namespace": "custom", "key": "fit", "type": "single_line_text_field", "value": "Snug"},
+					},
+				},
+				map[string]interface{}{
+					"sku":           "HAT-M",
+					"price":         "22.00",
+					"file":          map[string]interface{}{"originalSource": "https://example.com/hat-side.png"},
+					"taxable":       false,
+					"optionValues":  []interface{}{map[string]interface{}{"optionName": "Size", "name": "M"}},
+					"inventoryItem": map[string]interface{}{"requiresShipping": false},
+				},
+			},
+			"metafields": []interface{}{
+				map[string]interface{}{"namespace": "custom", "key": "care", "type": "multi_line_text_field", "value": "Hand wash\nDry flat"},
+			},
+		},
+		{"title": "Red Scarf", "status": "DRAFT", "variants": []interface{}{map[string]interface{}{"sku": "SCARF", "price": "15.00"}}},
+	},
+}
+
+func mockShop(t *testing.T, seed mock.Seed) {
+	t.Helper()
+
+	server := mock.NewServer()
+	if err := server.Load(seed); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	old := gqlclient.AdminURL
+	gqlclient.AdminURL = httpServer.URL
+	t.Cleanup(func() { gqlclient.AdminURL = old })
+}
+
+func exportCSV(t *testing.T) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	d := exportformat.NewShopifyCSV(&buf)
+
+	err := gql.FetchAllFullProducts("acme", "shpat_test", "", d.Dump, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+// importCSV imports the CSV as products import does
+func importCSV(t *testing.T, content, identifyBy string) {
+	t.Helper()
+	importCSVMatchingMedia(t, content, identifyBy, false)
+}
+
+// importCSVMatchingMedia is importCSV with products import's --match-media-by-name
+func importCSVMatchingMedia(t *testing.T, content, identifyBy string, mediaByName bool) {
+	t.Helper()
+
+	options := map[string]interface{}{}
+	locations, err := gql.FetchLocations("acme", "shpat_test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "products.csv")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	products, err := parseProductFile(filename, formatCSV, locations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setProductIdentifiers(products, identifyBy)
+
+	results, err := setProducts("acme", "shpat_test", products, filepath.Dir(filename), mediaByName, 1, nil, io.Discard, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range results {
+		if r.Err != nil || len(r.Errors) > 0 {
+			t.Fatalf("import %s: %v %v", r.Handle, r.Err, r.Errors)
+		}
+	}
+}
+
+func TestExportCSVReimportIsNoOp(t *testing.T) {
+	mockShop(t, exportSeed)
+
+	exported := exportCSV(t)
+	importCSV(t, exported, "handle")
+
+	count, err := gql.FetchProductCount("acme", "shpat_test", "", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("product count = %d, want 2", count)
+	}
+
+	if got := exportCSV(t); got != exported {
+		t.Errorf("re-export = \n%s\nwant\n%s", got, exported)
+	}
+}
+
+func TestExportCSVImportIntoEmptyShop(t *testing.T) {
+	mockShop(t, exportSeed)
+	exported := exportCSV(t)
+
+	mockShop(t, mock.Seed{Locations: exportSeed.Locations, Publications: exportSeed.Publications})
+	importCSV(t, exported, "")
+
+	if got := exportCSV(t); got != exported {
+		t.Errorf("export = \n%s\nwant\n%s", got, exported)
+	}
+}
+
+func TestExportCSVBulk(t *testing.T) {
+	mockShop(t, exportSeed)
+
+	old := gqlclient.BulkPollInterval
+	gqlclient.BulkPollInterval = 0
+	t.Cleanup(func() { gqlclient.BulkPollInterval = old })
+
+	var buf bytes.Buffer
+	d := exportformat.NewShopifyCSV(&buf)
+
+	err := gql.FetchAllFullProductsBulk("acme", "shpat_test", "", d.Dump, nil, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := buf.String(), exportCSV(t); got != want {
+		t.Errorf("bulk export = \n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+package exportformat
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+// ShopifyCSV writes products in the CSV format read by "products import".
+//
+// A product's first row has its properties, first collection, publications,
+// option names, first media, and first variant. Each of its other variants has
+// a row with only variant columns. Rows for inventory at additional locations,
+// additional media, additional collections, and metafields follow with only
+// their columns set. Media have their positions so that variant images, which
+// are given by URL, don't change their order when imported.
+type ShopifyCSV struct {
+	out           *csv.Writer
+	headerWritten bool
+}
+
+var ShopifyCSVHeader = []string{
+	"Handle",
+	"Title",
+	"Body (HTML)",
+	"Vendor",
+	"Type",
+	"Tags",
+	"Status",
+	"Collection",
+	"Publications",
+	"Option1 Name",
+	"Option1 Value",
+	"Option2 Name",
+	"Option2 Value",
+	"Option3 Name",
+	"Option3 Value",
+	"Variant SKU",
+	"Variant Price",
+	"Variant Compare At Price",
+	"Variant Barcode",
+	"Variant Taxable",
+	"Variant Inventory Policy",
+	"Requires Shipping",
+	"Unit Cost",
+	"Variant Image",
+	"Location",
+	"Available",
+	"Product Image URL",
+	"Image Alt Text",
+	"Image Position",
+	"Media Type",
+	"Metafield Owner",
+	"Metafield Namespace",
+	"Metafield Key",
+	"Metafield Value",
+	"Metafield Type",
+}
+
+// Column positions
+const (
+	colHandle = iota
+	colTitle
+	colBody
+	colVendor
+	colType
+	colTags
+	colStatus
+	colCollection
+	colPublications
+	colOption1Name
+	colOption1Value
+	colOption2Name
+	colOption2Value
+	colOption3Name
+	colOption3Value
+	colSKU
+	colPrice
+	colCompareAtPrice
+	colBarcode
+	colTaxable
+	colInventoryPolicy
+	colRequiresShipping
+	colUnitCost
+	colVariantImage
+	colLocation
+	colAvailable
+	colImageURL
+	colImageAlt
+	colImagePosition
+	colMediaType
+	colMetafieldOwner
+	colMetafieldNamespace
+	colMetafieldKey
+	colMetafieldValue
+	colMetafieldType
+)
+
+func NewShopifyCSV(w io.Writer) *ShopifyCSV {
+	return &ShopifyCSV{out: csv.NewWriter(w)}
+}
+
+func (c *ShopifyCSV) Dump(product gql.FullProduct) error {
+	if !c.headerWritten {
+		if err := c.out.Write(ShopifyCSVHeader); err != nil {
+			return err
+		}
+
+		c.headerWritten = true
+	}
+
+	row := newRow()
+	row[colHandle] = product.Handle
+	row[colTitle] = product.Title
+	row[colBody] = product.DescriptionHTML
+	row[colVendor] = product.Vendor
+	row[colType] = product.ProductType
+	row[colTags] = strings.Join(product.Tags, ", ")
+	row[colStatus] = strings.ToLower(product.Status)
+	row[colPublications] = strings.Join(product.Publications, ", ")
+
+	if len(product.Collections) > 0 {
+		row[colCollection] = product.Collections[0]
+	}
+
+	// Import adds the default option when there are none
+	var options []gql.ProductOption
+	if !product.HasOnlyDefaultVariant {
+		options = product.Options
+	}
+
+	for i, option := range options {
+		if i < 3 {
+			row[colOption1Name+i*2] = option.Name
+		}
+	}
+
+	mediaURLs := map[string]string{}
+	for i, media := range product.Media {
+		mediaURLs[media.ID] = media.URL
+
+		if i == 0 {
+			setMedia(row, media, i)
+		}
+	}
+
+	for i, variant := range product.Variants {
+		if i > 0 {
+			row = newRow()
+		}
+
+		if err := c.dumpVariant(row, options, variant, mediaURLs[variant.MediaID]); err != nil {
+			return err
+		}
+	}
+
+	if len(product.Variants) == 0 {
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for i := 1; i < len(product.Media); i++ {
+		row = newRow()
+		setMedia(row, product.Media[i], i)
+
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	for _, handle := range product.Collections[min(1, len(product.Collections)):] {
+		row = newRow()
+		row[colCollection] = handle
+
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return c.dumpMetafields("Product", product.Metafields)
+}
+
+// setMedia sets the media columns of row to the product's media at index i
+func setMedia(row []string, media gql.ProductMedia, i int) {
+	row[colImageURL] = media.URL
+	row[colImageAlt] = media.Alt
+	row[colImagePosition] = strconv.Itoa(i + 1)
+	row[colMediaType] = media.ContentType
+}
+
+func (c *ShopifyCSV) dumpVariant(row []string, options []gql.ProductOption, variant gql.FullVariant, imageURL string) error {
+	values := map[string]string{}
+	for _, selected := range variant.SelectedOptions {
+		values[selected.Name] = selected.Value
+	}
+
+	for i, option := range options {
+		if i < 3 {
+			row[colOption1Value+i*2] = values[option.Name]
+		}
+	}
+
+	row[colSKU] = variant.SKU
+	row[colPrice] = variant.Price
+	row[colCompareAtPrice] = variant.CompareAtPrice
+	row[colBarcode] = variant.Barcode
+	row[colTaxable] = strconv.FormatBool(variant.Taxable)
+	row[colInventoryPolicy] = variant.InventoryPolicy
+	row[colRequiresShipping] = strconv.FormatBool(variant.RequiresShipping)
+	row[colUnitCost] = variant.UnitCost
+	row[colVariantImage] = imageURL
+
+	// Quantities can't be set for untracked inventory
+	var levels []gql.InventoryLevel
+	if variant.Tracked {
+		levels = variant.InventoryLevels
+	}
+
+	for i, level := range levels {
+		if i > 0 {
+			row = newRow()
+		}
+
+		row[colLocation] = level.Location
+		row[colAvailable] = strconv.Itoa(level.Available)
+
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	if len(levels) == 0 {
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	// Variant metafields apply to the preceding variant
+	return c.dumpMetafields("Variant", variant.Metafields)
+}
+
+func (c *ShopifyCSV) dumpMetafields(owner string, metafields []gql.Metafield) error {
+	for _, mf := range metafields {
+		row := newRow()
+		row[colMetafieldOwner] = owner
+		row[colMetafieldNamespace] = mf.Namespace
+		row[colMetafieldKey] = mf.Key
+		row[colMetafieldValue] = mf.Value
+		row[colMetafieldType] = mf.Type
+
+		if err := c.out.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *ShopifyCSV) Close() error {
+	c.out.Flush()
+
+	return c.out.Error()
+}
+
+func newRow() []string {
+	return make([]string, len(ShopifyCSVHeader))
+}
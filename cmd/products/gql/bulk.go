@@ -1,8 +1,8 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
+	"strconv"
 
 	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
 )
@@ -56,38 +56,6 @@ mutation bulkOperationRunMutation($mutation: String!, $stagedUploadPath: String!
 }
 `
 
-const bulkOperationStatusQuery = `
-query($id: ID!) {
-  node(id: $id) {
-    ... on BulkOperation {
-      id
-      status
-      errorCode
-      objectCount
-      rootObjectCount
-      url
-      createdAt
-      completedAt
-    }
-  }
-}
-`
-
-const bulkOperationCancelMutation = `
-mutation bulkOperationCancel($id: ID!) {
-  bulkOperationCancel(id: $id) {
-    bulkOperation {
-      id
-      status
-    }
-    userErrors {
-      field
-      message
-    }
-  }
-}
-`
-
 type StagedUploadParameter struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
@@ -100,182 +68,75 @@ type StagedTarget struct {
 }
 
 type stagedUploadsResponse struct {
-	Data struct {
-		StagedUploadsCreate struct {
-			StagedTargets []StagedTarget `json:"stagedTargets"`
-			UserErrors    []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"stagedUploadsCreate"`
-	} `json:"data"`
+	StagedUploadsCreate struct {
+		StagedTargets []StagedTarget `json:"stagedTargets"`
+	} `json:"stagedUploadsCreate"`
 }
 
 type bulkOperationRunResponse struct {
-	Data struct {
-		BulkOperationRunMutation struct {
-			BulkOperation struct {
-				ID     string `json:"id"`
-				Status string `json:"status"`
-				URL    string `json:"url"`
-			} `json:"bulkOperation"`
-			UserErrors []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"bulkOperationRunMutation"`
-	} `json:"data"`
+	BulkOperationRunMutation struct {
+		BulkOperation struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+			URL    string `json:"url"`
+		} `json:"bulkOperation"`
+	} `json:"bulkOperationRunMutation"`
 }
 
-type BulkOperationResult struct {
-	ID              string `json:"id"`
-	Status          string `json:"status"`
-	ErrorCode       string `json:"errorCode"`
-	ObjectCount     int    `json:"objectCount,string"`
-	RootObjectCount int    `json:"rootObjectCount,string"`
-	URL             string `json:"url"`
-	CreatedAt       string `json:"createdAt"`
-	CompletedAt     string `json:"completedAt"`
-}
-
-type bulkOperationStatusResponse struct {
-	Data struct {
-		Node BulkOperationResult `json:"node"`
-	} `json:"data"`
+func StagedUpload(shop, token string, fileSize int, options map[string]interface{}) (*StagedTarget, error) {
+	return StagedUploadFile(shop, token, "BULK_MUTATION_VARIABLES", "bulk_import.jsonl", "text/jsonl", int64(fileSize), options)
 }
 
-func StagedUpload(shop, token string, fileSize int, options map[string]interface{}) (*StagedTarget, error) {
+// StagedUploadFile returns the target to upload a file to, e.g., an IMAGE or
+// VIDEO resource, before it's used by a mutation.
+func StagedUploadFile(shop, token, resource, filename, mimeType string, fileSize int64, options map[string]interface{}) (*StagedTarget, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
 	input := []map[string]interface{}{
 		{
-			"resource":   "BULK_MUTATION_VARIABLES",
-			"filename":   "bulk_import.jsonl",
-			"mimeType":   "text/jsonl",
+			"resource":   resource,
+			"filename":   filename,
+			"mimeType":   mimeType,
 			"httpMethod": "POST",
 			"fileSize":   fmt.Sprintf("%d", fileSize),
 		},
 	}
 
-	data, err := client.Execute(stagedUploadsCreateMutation, map[string]interface{}{
+	var response stagedUploadsResponse
+	if err := client.ExecuteInto(stagedUploadsCreateMutation, map[string]interface{}{
 		"input": input,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return nil, fmt.Errorf("Cannot create staged upload: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode staged upload response: %s", err)
-	}
-
-	var response stagedUploadsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse staged upload response: %s", err)
-	}
-
-	if len(response.Data.StagedUploadsCreate.UserErrors) > 0 {
-		return nil, fmt.Errorf("Staged upload error: %s", response.Data.StagedUploadsCreate.UserErrors[0].Message)
-	}
-
-	if len(response.Data.StagedUploadsCreate.StagedTargets) == 0 {
+	if len(response.StagedUploadsCreate.StagedTargets) == 0 {
 		return nil, fmt.Errorf("No staged upload targets returned")
 	}
 
-	return &response.Data.StagedUploadsCreate.StagedTargets[0], nil
+	return &response.StagedUploadsCreate.StagedTargets[0], nil
 }
 
 func StartBulkMutation(shop, token, stagedUploadPath string, options map[string]interface{}) (string, string, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
-	data, err := client.Execute(bulkOperationRunMutationQuery, map[string]interface{}{
+	var response bulkOperationRunResponse
+	if err := client.ExecuteInto(bulkOperationRunMutationQuery, map[string]interface{}{
 		"mutation":         productSetMutation,
 		"stagedUploadPath": stagedUploadPath,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return "", "", fmt.Errorf("Cannot start bulk mutation: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return "", "", fmt.Errorf("Cannot re-encode bulk mutation response: %s", err)
-	}
-
-	var response bulkOperationRunResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return "", "", fmt.Errorf("Cannot parse bulk mutation response: %s", err)
-	}
-
-	if len(response.Data.BulkOperationRunMutation.UserErrors) > 0 {
-		return "", "", fmt.Errorf("Bulk mutation error: %s", response.Data.BulkOperationRunMutation.UserErrors[0].Message)
-	}
-
-	op := response.Data.BulkOperationRunMutation.BulkOperation
+	op := response.BulkOperationRunMutation.BulkOperation
 	return op.ID, op.Status, nil
 }
 
-func FetchBulkOperationStatus(shop, token, operationID string, options map[string]interface{}) (*BulkOperationResult, error) {
-	client := gqlclient.NewClient(shop, token, options)
-
-	data, err := client.Execute(bulkOperationStatusQuery, map[string]interface{}{
-		"id": operationID,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("Cannot fetch bulk operation status: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode bulk operation status response: %s", err)
-	}
-
-	var response bulkOperationStatusResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse bulk operation status response: %s", err)
-	}
-
-	return &response.Data.Node, nil
-}
-
-type bulkOperationCancelResponse struct {
-	Data struct {
-		BulkOperationCancel struct {
-			BulkOperation struct {
-				ID     string `json:"id"`
-				Status string `json:"status"`
-			} `json:"bulkOperation"`
-			UserErrors []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"bulkOperationCancel"`
-	} `json:"data"`
-}
-
-func CancelBulkOperation(shop, token, operationID string, options map[string]interface{}) (string, string, error) {
-	client := gqlclient.NewClient(shop, token, options)
-
-	data, err := client.Execute(bulkOperationCancelMutation, map[string]interface{}{
-		"id": operationID,
-	})
-	if err != nil {
-		return "", "", fmt.Errorf("Cannot cancel bulk operation: %s", err)
+// bulkQueryArguments returns the arguments for a bulk query's connection
+// that filters by the search query, if any.
+func bulkQueryArguments(query string) string {
+	if query == "" {
+		return ""
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return "", "", fmt.Errorf("Cannot re-encode bulk operation cancel response: %s", err)
-	}
-
-	var response bulkOperationCancelResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return "", "", fmt.Errorf("Cannot parse bulk operation cancel response: %s", err)
-	}
-
-	if len(response.Data.BulkOperationCancel.UserErrors) > 0 {
-		return "", "", fmt.Errorf("Bulk operation cancel error: %s", response.Data.BulkOperationCancel.UserErrors[0].Message)
-	}
-
-	op := response.Data.BulkOperationCancel.BulkOperation
-	return op.ID, op.Status, nil
+	return fmt.Sprintf("(query: %s)", strconv.Quote(query))
 }
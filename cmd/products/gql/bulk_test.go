@@ -0,0 +1,53 @@
+package gql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+func TestReadInventoryBulkQueryResults(t *testing.T) {
+	jsonl := `{"id":"gid://shopify/Product/1","legacyResourceId":"1","title":"Hat"}
+{"id":"gid://shopify/ProductVariant/10","legacyResourceId":"10","title":"Small","sku":"HAT-S","inventoryItem":{},"__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/InventoryLevel/100?inventory_item_id=11","location":{"name":"Main"},"quantities":[{"name":"available","quantity":5}],"__parentId":"gid://shopify/ProductVariant/10"}
+{"id":"gid://shopify/Video/5","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/ProductVariant/12","legacyResourceId":"12","title":"Large","sku":"HAT-L","__parentId":"gid://shopify/Product/1"}
+{"id":"gid://shopify/InventoryLevel/101?inventory_item_id=13","location":{"name":"Main"},"quantities":[{"name":"available","quantity":2}],"__parentId":"gid://shopify/ProductVariant/12"}
+
+{"id":"gid://shopify/Product/2","legacyResourceId":"2","title":"Scarf"}
+`
+
+	var products []ProductInventory
+	err := gqlclient.ReadBulkQueryResults(strings.NewReader(jsonl), inventoryBulkPaths, func(object json.RawMessage) error {
+		var n productInventoryJSON
+		if err := json.Unmarshal(object, &n); err != nil {
+			return err
+		}
+
+		products = append(products, toProductInventory(n))
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("ReadBulkQueryResults failed: %s", err)
+	}
+
+	want := []ProductInventory{
+		{
+			ProductID:    1,
+			ProductTitle: "Hat",
+			Variants: []VariantInventory{
+				{VariantID: 10, VariantTitle: "Small", SKU: "HAT-S", InventoryLevels: []InventoryLevel{{Location: "Main", Available: 5}}},
+				{VariantID: 12, VariantTitle: "Large", SKU: "HAT-L", InventoryLevels: []InventoryLevel{{Location: "Main", Available: 2}}},
+			},
+		},
+		{ProductID: 2, ProductTitle: "Scarf"},
+	}
+
+	if !reflect.DeepEqual(products, want) {
+		t.Errorf("products = %+v, want %+v", products, want)
+	}
+}
@@ -1,7 +1,7 @@
 package gql
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 
 	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
@@ -21,46 +21,64 @@ mutation($id: ID!) {
 
 type ProductDeleteResult struct {
 	DeletedProductID string
-	UserErrors       []string
+	UserErrors       gqlclient.UserErrors
 }
 
 type productDeleteResponse struct {
-	Data struct {
-		ProductDelete struct {
-			DeletedProductID string `json:"deletedProductId"`
-			UserErrors       []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"productDelete"`
-	} `json:"data"`
+	ProductDelete struct {
+		DeletedProductID string `json:"deletedProductId"`
+	} `json:"productDelete"`
 }
 
 func ProductDelete(shop, token, id string, options map[string]interface{}) (*ProductDeleteResult, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
-	data, err := client.Execute(productDeleteMutation, map[string]interface{}{"id": id})
-	if err != nil {
+	var response productDeleteResponse
+
+	// Validation errors are reported in the result rather than as a failure
+	err := client.ExecuteInto(productDeleteMutation, map[string]interface{}{"id": id}, &response)
+
+	var userErrors gqlclient.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
 		return nil, fmt.Errorf("productDelete mutation failed: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode productDelete response: %s", err)
+	result := &ProductDeleteResult{
+		DeletedProductID: response.ProductDelete.DeletedProductID,
+		UserErrors:       userErrors,
 	}
 
-	var response productDeleteResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse productDelete response: %s", err)
-	}
+	return result, nil
+}
 
-	result := &ProductDeleteResult{
-		DeletedProductID: response.Data.ProductDelete.DeletedProductID,
+const productArchiveMutation = `
+mutation($product: ProductUpdateInput!) {
+  productUpdate(product: $product) {
+    product {
+      id
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+// ProductArchive sets the product's status to ARCHIVED
+func ProductArchive(shop, token, id string, options map[string]interface{}) (gqlclient.UserErrors, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	variables := map[string]interface{}{
+		"product": map[string]interface{}{"id": id, "status": "ARCHIVED"},
 	}
 
-	for _, ue := range response.Data.ProductDelete.UserErrors {
-		result.UserErrors = append(result.UserErrors, ue.Message)
+	err := client.ExecuteInto(productArchiveMutation, variables, nil)
+
+	var userErrors gqlclient.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
+		return nil, fmt.Errorf("productUpdate mutation failed: %s", err)
 	}
 
-	return result, nil
+	return userErrors, nil
 }
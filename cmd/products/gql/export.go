@@ -0,0 +1,1028 @@
+package gql
+
+import (
+	"fmt"
+	"strings"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+// Nested connections are given small page sizes to keep the cost of queries selecting
+// these fragments under Shopify's limit of 1000 points. The pages that don't fit are
+// fetched by completeFullProduct.
+const (
+	productMediaPageSize          = 10
+	productMetafieldsPageSize     = 25
+	productCollectionsPageSize    = 10
+	productVariantsPageSize       = 20
+	variantLevelsPageSize         = 5
+	variantMetafieldsPageSize     = 10
+	nestedMediaPageSize           = 50
+	nestedMetafieldsPageSize      = 250
+	nestedCollectionsPageSize     = 250
+	nestedInventoryLevelsPageSize = 50
+)
+
+// Everything import can set
+var fullProductFragment = fmt.Sprintf(`
+fragment FullProduct on Product {
+  id
+  legacyResourceId
+  handle
+  title
+  descriptionHtml
+  vendor
+  productType
+  tags
+  status
+  hasOnlyDefaultVariant
+  options {
+    name
+    values
+  }
+  media(first: %d) {
+    nodes {
+      ...Media
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+  metafields(first: %d) {
+    nodes {
+      namespace
+      key
+      value
+      type
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+  collections(first: %d) {
+    nodes {
+      handle
+      ruleSet {
+        appliedDisjunctively
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+  variants(first: %d) {
+    nodes {
+      ...FullVariant
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`, productMediaPageSize, productMetafieldsPageSize, productCollectionsPageSize, productVariantsPageSize) + fullVariantFragment
+
+var fullVariantFragment = fmt.Sprintf(`
+fragment FullVariant on ProductVariant {
+  id
+  legacyResourceId
+  media(first: 1) {
+    nodes {
+      id
+    }
+  }
+  sku
+  price
+  compareAtPrice
+  barcode
+  taxable
+  inventoryPolicy
+  selectedOptions {
+    name
+    value
+  }
+  inventoryItem {
+    tracked
+    requiresShipping
+    unitCost {
+      amount
+    }
+    inventoryLevels(first: %d) {
+      edges {
+        node {
+          ...FullInventoryLevel
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+  metafields(first: %d) {
+    nodes {
+      namespace
+      key
+      value
+      type
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`, variantLevelsPageSize, variantMetafieldsPageSize) + inventoryLevelFragment
+
+const inventoryLevelFragment = `
+fragment FullInventoryLevel on InventoryLevel {
+  location {
+    name
+  }
+  quantities(names: ["available", "on_hand"]) {
+    name
+    quantity
+  }
+}
+`
+
+const mediaFragment = `
+fragment Media on Media {
+  id
+  alt
+  mediaContentType
+  ... on MediaImage {
+    image {
+      url
+    }
+  }
+  ... on Video {
+    originalSource {
+      url
+    }
+  }
+  ... on ExternalVideo {
+    originUrl
+  }
+  ... on Model3d {
+    originalSource {
+      url
+    }
+  }
+}
+`
+
+var productMediaFragment = fmt.Sprintf(`
+fragment ProductMedia on Product {
+  id
+  media(first: %d) {
+    nodes {
+      ...Media
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+`, productMediaPageSize) + mediaFragment
+
+// Products are fetched one at a time as each includes its variants,
+// inventory, media, and metafields.
+var productsFullExportQuery = `
+query($first: Int!, $after: String, $query: String) {
+  products(first: $first, after: $after, query: $query) {
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+    edges {
+      node {
+        ...FullProduct
+      }
+    }
+  }
+}
+` + fullProductFragment + mediaFragment
+
+// Queries for the rest of the pages of a product's nested connections
+const productMediaPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  product(id: $id) {
+    media(first: $first, after: $after) {
+      nodes {
+        ...Media
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+` + mediaFragment
+
+const productMetafieldsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  product(id: $id) {
+    metafields(first: $first, after: $after) {
+      nodes {
+        namespace
+        key
+        value
+        type
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+`
+
+const productCollectionsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  product(id: $id) {
+    collections(first: $first, after: $after) {
+      nodes {
+        handle
+        ruleSet {
+          appliedDisjunctively
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+`
+
+var productVariantsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  product(id: $id) {
+    variants(first: $first, after: $after) {
+      nodes {
+        ...FullVariant
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+` + fullVariantFragment
+
+const variantInventoryLevelsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  productVariant(id: $id) {
+    inventoryItem {
+      inventoryLevels(first: $first, after: $after) {
+        edges {
+          node {
+            ...FullInventoryLevel
+          }
+        }
+        pageInfo {
+          hasNextPage
+          endCursor
+        }
+      }
+    }
+  }
+}
+` + inventoryLevelFragment
+
+const variantMetafieldsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  productVariant(id: $id) {
+    metafields(first: $first, after: $after) {
+      nodes {
+        namespace
+        key
+        value
+        type
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+`
+
+// FullProduct is a product with everything needed to recreate it.
+type FullProduct struct {
+	ID                    int64
+	Handle                string
+	Title                 string
+	DescriptionHTML       string
+	Vendor                string
+	ProductType           string
+	Tags                  []string
+	Status                string
+	HasOnlyDefaultVariant bool
+	Options               []ProductOption
+	Media                 []ProductMedia
+	Metafields            []Metafield
+	// Handles of the custom collections the product is in
+	Collections []string
+	// Names of the publications the product is published on
+	Publications []string
+	Variants     []FullVariant
+}
+
+// ProductMedia is an image, video, external video, or 3D model of a product
+type ProductMedia struct {
+	ID          string
+	Alt         string
+	ContentType string
+	// Of the image, the video or 3D model's original source, or the external video's origin
+	URL string
+}
+
+type mediaJSON struct {
+	ID               string `json:"id"`
+	Alt              string `json:"alt"`
+	MediaContentType string `json:"mediaContentType"`
+	Image            *struct {
+		URL string `json:"url"`
+	} `json:"image"`
+	OriginalSource *struct {
+		URL string `json:"url"`
+	} `json:"originalSource"`
+	OriginURL string `json:"originUrl"`
+}
+
+func toProductMedia(n mediaJSON) ProductMedia {
+	media := ProductMedia{ID: n.ID, Alt: n.Alt, ContentType: n.MediaContentType, URL: n.OriginURL}
+
+	if n.Image != nil {
+		media.URL = n.Image.URL
+	} else if n.OriginalSource != nil {
+		media.URL = n.OriginalSource.URL
+	}
+
+	return media
+}
+
+type SelectedOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type FullVariant struct {
+	ID               int64
+	SKU              string
+	Price            string
+	CompareAtPrice   string
+	Barcode          string
+	Taxable          bool
+	InventoryPolicy  string
+	SelectedOptions  []SelectedOption
+	Tracked          bool
+	RequiresShipping bool
+	UnitCost         string
+	// ID of the variant's media, if any
+	MediaID         string
+	InventoryLevels []InventoryLevel
+	Metafields      []Metafield
+}
+
+type Metafield struct {
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Type      string `json:"type"`
+}
+
+type pageInfoJSON struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type metafieldsJSON struct {
+	Nodes    []Metafield  `json:"nodes"`
+	PageInfo pageInfoJSON `json:"pageInfo"`
+}
+
+// The IDs of a variant's media
+type variantMediaJSON struct {
+	Nodes []struct {
+		ID string `json:"id"`
+	} `json:"nodes"`
+}
+
+type fullVariantJSON struct {
+	ID               string           `json:"id"`
+	LegacyResourceId int64            `json:"legacyResourceId,string"`
+	SKU              string           `json:"sku"`
+	Price            string           `json:"price"`
+	CompareAtPrice   string           `json:"compareAtPrice"`
+	Barcode          string           `json:"barcode"`
+	Taxable          bool             `json:"taxable"`
+	InventoryPolicy  string           `json:"inventoryPolicy"`
+	SelectedOptions  []SelectedOption `json:"selectedOptions"`
+	Media            variantMediaJSON `json:"media"`
+	InventoryItem    struct {
+		Tracked          bool `json:"tracked"`
+		RequiresShipping bool `json:"requiresShipping"`
+		UnitCost         *struct {
+			Amount string `json:"amount"`
+		} `json:"unitCost"`
+		InventoryLevels inventoryLevelsJSON `json:"inventoryLevels"`
+	} `json:"inventoryItem"`
+	Metafields metafieldsJSON `json:"metafields"`
+}
+
+type mediaConnectionJSON struct {
+	Nodes    []mediaJSON  `json:"nodes"`
+	PageInfo pageInfoJSON `json:"pageInfo"`
+}
+
+type collectionJSON struct {
+	Handle string `json:"handle"`
+	// nil for custom collections
+	RuleSet *struct{} `json:"ruleSet"`
+}
+
+type collectionsJSON struct {
+	Nodes    []collectionJSON `json:"nodes"`
+	PageInfo pageInfoJSON     `json:"pageInfo"`
+}
+
+type fullProductJSON struct {
+	ID                    string   `json:"id"`
+	LegacyResourceId      int64    `json:"legacyResourceId,string"`
+	Handle                string   `json:"handle"`
+	Title                 string   `json:"title"`
+	DescriptionHTML       string   `json:"descriptionHtml"`
+	Vendor                string   `json:"vendor"`
+	ProductType           string   `json:"productType"`
+	Tags                  []string `json:"tags"`
+	Status                string   `json:"status"`
+	HasOnlyDefaultVariant bool     `json:"hasOnlyDefaultVariant"`
+	Options               []struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values"`
+	} `json:"options"`
+	Media       mediaConnectionJSON `json:"media"`
+	Metafields  metafieldsJSON      `json:"metafields"`
+	Collections collectionsJSON     `json:"collections"`
+	Variants    struct {
+		Nodes    []fullVariantJSON `json:"nodes"`
+		PageInfo pageInfoJSON      `json:"pageInfo"`
+	} `json:"variants"`
+}
+
+// completeFullProduct fetches the pages of the product's nested connections that were not
+// in the response
+func completeFullProduct(client *gqlclient.Client, n *fullProductJSON) error {
+	if n.Media.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": n.ID, "first": nestedMediaPageSize, "after": n.Media.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, productMediaPageQuery, vars, "product.media", func(m mediaJSON) error {
+			n.Media.Nodes = append(n.Media.Nodes, m)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch media of product %s: %s", n.ID, err)
+		}
+	}
+
+	if n.Metafields.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": n.ID, "first": nestedMetafieldsPageSize, "after": n.Metafields.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, productMetafieldsPageQuery, vars, "product.metafields", func(m Metafield) error {
+			n.Metafields.Nodes = append(n.Metafields.Nodes, m)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch metafields of product %s: %s", n.ID, err)
+		}
+	}
+
+	if n.Collections.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": n.ID, "first": nestedCollectionsPageSize, "after": n.Collections.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, productCollectionsPageQuery, vars, "product.collections", func(c collectionJSON) error {
+			n.Collections.Nodes = append(n.Collections.Nodes, c)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch collections of product %s: %s", n.ID, err)
+		}
+	}
+
+	if n.Variants.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": n.ID, "first": productVariantsPageSize, "after": n.Variants.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, productVariantsPageQuery, vars, "product.variants", func(v fullVariantJSON) error {
+			n.Variants.Nodes = append(n.Variants.Nodes, v)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch variants of product %s: %s", n.ID, err)
+		}
+	}
+
+	for i := range n.Variants.Nodes {
+		if err := completeFullVariant(client, &n.Variants.Nodes[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func completeFullVariant(client *gqlclient.Client, v *fullVariantJSON) error {
+	levels := &v.InventoryItem.InventoryLevels
+	if levels.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": v.ID, "first": nestedInventoryLevelsPageSize, "after": levels.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, variantInventoryLevelsPageQuery, vars, "productVariant.inventoryItem.inventoryLevels", func(l inventoryLevelJSON) error {
+			levels.Edges = append(levels.Edges, inventoryLevelEdgeJSON{Node: l})
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch inventory levels of variant %s: %s", v.ID, err)
+		}
+	}
+
+	if v.Metafields.PageInfo.HasNextPage {
+		vars := map[string]interface{}{"id": v.ID, "first": nestedMetafieldsPageSize, "after": v.Metafields.PageInfo.EndCursor}
+		err := gqlclient.Paginate(client, variantMetafieldsPageQuery, vars, "productVariant.metafields", func(m Metafield) error {
+			v.Metafields.Nodes = append(v.Metafields.Nodes, m)
+			return nil
+		})
+
+		if err != nil {
+			return fmt.Errorf("Cannot fetch metafields of variant %s: %s", v.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func toFullProduct(n fullProductJSON) FullProduct {
+	product := FullProduct{
+		ID:                    n.LegacyResourceId,
+		Handle:                n.Handle,
+		Title:                 n.Title,
+		DescriptionHTML:       n.DescriptionHTML,
+		Vendor:                n.Vendor,
+		ProductType:           n.ProductType,
+		Tags:                  n.Tags,
+		Status:                n.Status,
+		HasOnlyDefaultVariant: n.HasOnlyDefaultVariant,
+		Metafields:            n.Metafields.Nodes,
+	}
+
+	for _, c := range n.Collections.Nodes {
+		if c.RuleSet == nil {
+			product.Collections = append(product.Collections, c.Handle)
+		}
+	}
+
+	for i, opt := range n.Options {
+		product.Options = append(product.Options, ProductOption{Name: opt.Name, Position: i + 1, Values: opt.Values})
+	}
+
+	for _, media := range n.Media.Nodes {
+		product.Media = append(product.Media, toProductMedia(media))
+	}
+
+	for _, v := range n.Variants.Nodes {
+		variant := FullVariant{
+			ID:               v.LegacyResourceId,
+			SKU:              v.SKU,
+			Price:            v.Price,
+			CompareAtPrice:   v.CompareAtPrice,
+			Barcode:          v.Barcode,
+			Taxable:          v.Taxable,
+			InventoryPolicy:  v.InventoryPolicy,
+			SelectedOptions:  v.SelectedOptions,
+			Tracked:          v.InventoryItem.Tracked,
+			RequiresShipping: v.InventoryItem.RequiresShipping,
+			InventoryLevels:  toInventoryLevels(v.InventoryItem.InventoryLevels),
+			Metafields:       v.Metafields.Nodes,
+		}
+
+		if v.InventoryItem.UnitCost != nil {
+			variant.UnitCost = v.InventoryItem.UnitCost.Amount
+		}
+
+		if len(v.Media.Nodes) > 0 {
+			variant.MediaID = v.Media.Nodes[0].ID
+		}
+
+		product.Variants = append(product.Variants, variant)
+	}
+
+	return product
+}
+
+// FetchAllFullProducts calls fn with each of the shop's products, optionally
+// only those with the given status.
+func FetchAllFullProducts(shop, token, status string, fn func(FullProduct) error, options map[string]interface{}) error {
+	client := gqlclient.NewClient(shop, token, options)
+
+	publications, err := fetchProductPublications(client)
+	if err != nil {
+		return err
+	}
+
+	vars := map[string]interface{}{"first": 1}
+	if len(status) > 0 {
+		vars["query"] = "status:" + status
+	}
+
+	err = gqlclient.Paginate(client, productsFullExportQuery, vars, "products", func(n fullProductJSON) error {
+		if err := completeFullProduct(client, &n); err != nil {
+			return err
+		}
+
+		product := toFullProduct(n)
+		product.Publications = publications[n.ID]
+
+		return fn(product)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
+	}
+
+	return nil
+}
+
+// ProductIdentifier identifies a product by its ID or handle, as productSet does
+type ProductIdentifier struct {
+	ID     string `json:"id,omitempty"`
+	Handle string `json:"handle,omitempty"`
+}
+
+// Number of products looked up per request by FetchFullProductsByIdentifier and FetchProductMedia,
+// keeping the queries' cost under Shopify's limit
+const (
+	fullProductsBatchSize = 1
+	productMediaBatchSize = 10
+)
+
+// FetchFullProductsByIdentifier returns the products with the given
+// identifiers in the same order, or nil for those that don't exist.
+func FetchFullProductsByIdentifier(shop, token string, identifiers []ProductIdentifier, options map[string]interface{}) ([]*FullProduct, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	nodes, err := fetchByIdentifier[fullProductJSON](client, identifiers, fullProductsBatchSize, "FullProduct", fullProductFragment+mediaFragment)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch products: %s", err)
+	}
+
+	products := make([]*FullProduct, len(identifiers))
+	for i, n := range nodes {
+		if n == nil {
+			continue
+		}
+
+		if err := completeFullProduct(client, n); err != nil {
+			return nil, fmt.Errorf("Cannot fetch products: %s", err)
+		}
+
+		product := toFullProduct(*n)
+		products[i] = &product
+	}
+
+	return products, nil
+}
+
+// FetchProductMedia returns the media of the products with the given
+// identifiers in the same order, or nil for those that don't exist.
+func FetchProductMedia(shop, token string, identifiers []ProductIdentifier, options map[string]interface{}) ([][]ProductMedia, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	type productMediaJSON struct {
+		ID    string              `json:"id"`
+		Media mediaConnectionJSON `json:"media"`
+	}
+
+	nodes, err := fetchByIdentifier[productMediaJSON](client, identifiers, productMediaBatchSize, "ProductMedia", productMediaFragment)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch product media: %s", err)
+	}
+
+	media := make([][]ProductMedia, len(identifiers))
+	for i, n := range nodes {
+		if n == nil {
+			continue
+		}
+
+		if n.Media.PageInfo.HasNextPage {
+			vars := map[string]interface{}{"id": n.ID, "first": nestedMediaPageSize, "after": n.Media.PageInfo.EndCursor}
+			err := gqlclient.Paginate(client, productMediaPageQuery, vars, "product.media", func(m mediaJSON) error {
+				n.Media.Nodes = append(n.Media.Nodes, m)
+				return nil
+			})
+
+			if err != nil {
+				return nil, fmt.Errorf("Cannot fetch product media: %s", err)
+			}
+		}
+
+		media[i] = []ProductMedia{}
+		for _, m := range n.Media.Nodes {
+			media[i] = append(media[i], toProductMedia(m))
+		}
+	}
+
+	return media, nil
+}
+
+// identifierQuery returns a query looking up the products with the given identifiers, selecting
+// the named fragment, and its variables. Products are aliased by their index in identifiers
+// plus offset, e.g., p3.
+func identifierQuery(identifiers []ProductIdentifier, offset int, fragmentName, fragment string) (string, map[string]interface{}) {
+	var params, fields []string
+	vars := map[string]interface{}{}
+
+	for i, identifier := range identifiers {
+		n := i + offset
+		params = append(params, fmt.Sprintf("$i%d: ProductIdentifierInput!", n))
+		fields = append(fields, fmt.Sprintf("p%d: productByIdentifier(identifier: $i%d) { ...%s }", n, n, fragmentName))
+		vars[fmt.Sprintf("i%d", n)] = identifier
+	}
+
+	return fmt.Sprintf("query(%s) {\n  %s\n}\n", strings.Join(params, ", "), strings.Join(fields, "\n  ")) + fragment, vars
+}
+
+// fetchByIdentifier returns the products with the given identifiers, batchSize at a time,
+// selecting the named fragment, in the same order, or nil for those that don't exist.
+func fetchByIdentifier[T any](client *gqlclient.Client, identifiers []ProductIdentifier, batchSize int, fragmentName, fragment string) ([]*T, error) {
+	products := make([]*T, len(identifiers))
+
+	for start := 0; start < len(identifiers); start += batchSize {
+		end := min(start+batchSize, len(identifiers))
+		query, vars := identifierQuery(identifiers[start:end], start, fragmentName, fragment)
+
+		var response map[string]*T
+		if err := client.ExecuteInto(query, vars, &response); err != nil {
+			return nil, err
+		}
+
+		for i := start; i < end; i++ {
+			products[i] = response[fmt.Sprintf("p%d", i)]
+		}
+	}
+
+	return products, nil
+}
+
+// Shopify allows at most 5 connections, nested 2 deep, in a bulk query so products are exported
+// with two: productsFullExportBulkQuery and productsVariantsBulkQuery, whose results are joined
+// by product and variant GID.
+const productsFullExportBulkQuery = `
+{
+  products%s {
+    edges {
+      node {
+        id
+        legacyResourceId
+        handle
+        title
+        descriptionHtml
+        vendor
+        productType
+        tags
+        status
+        hasOnlyDefaultVariant
+        options {
+          name
+          values
+        }
+        media {
+          edges {
+            node {
+              id
+              alt
+              mediaContentType
+              ... on MediaImage {
+                image {
+                  url
+                }
+              }
+              ... on Video {
+                originalSource {
+                  url
+                }
+              }
+              ... on ExternalVideo {
+                originUrl
+              }
+              ... on Model3d {
+                originalSource {
+                  url
+                }
+              }
+            }
+          }
+        }
+        metafields {
+          edges {
+            node {
+              id
+              namespace
+              key
+              value
+              type
+            }
+          }
+        }
+        variants {
+          edges {
+            node {
+              id
+              legacyResourceId
+              sku
+              price
+              compareAtPrice
+              barcode
+              taxable
+              inventoryPolicy
+              selectedOptions {
+                name
+                value
+              }
+              inventoryItem {
+                tracked
+                requiresShipping
+                unitCost {
+                  amount
+                }
+              }
+              metafields {
+                edges {
+                  node {
+                    id
+                    namespace
+                    key
+                    value
+                    type
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+// The products' collections and their variants' media and inventory levels
+const productsVariantsBulkQuery = `
+{
+  products%s {
+    edges {
+      node {
+        id
+        collections {
+          edges {
+            node {
+              id
+              handle
+              ruleSet {
+                appliedDisjunctively
+              }
+            }
+          }
+        }
+        variants {
+          edges {
+            node {
+              id
+              media {
+                edges {
+                  node {
+                    id
+                  }
+                }
+              }
+              inventoryItem {
+                inventoryLevels {
+                  edges {
+                    node {
+                      id
+                      location {
+                        name
+                      }
+                      quantities(names: ["available", "on_hand"]) {
+                        name
+                        quantity
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+type bulkVariantsJSON struct {
+	ID          string          `json:"id"`
+	Collections collectionsJSON `json:"collections"`
+	Variants    struct {
+		Nodes []struct {
+			ID            string           `json:"id"`
+			Media         variantMediaJSON `json:"media"`
+			InventoryItem struct {
+				InventoryLevels inventoryLevelsJSON `json:"inventoryLevels"`
+			} `json:"inventoryItem"`
+		} `json:"nodes"`
+	} `json:"variants"`
+}
+
+// FetchAllFullProductsBulk is FetchAllFullProducts using bulk queries
+func FetchAllFullProductsBulk(shop, token, status string, fn func(FullProduct) error, progress func(gqlclient.BulkOperationResult), options map[string]interface{}) error {
+	var filter string
+	if len(status) > 0 {
+		filter = "status:" + status
+	}
+
+	client := gqlclient.NewClient(shop, token, options)
+
+	publications, err := fetchProductPublications(client)
+	if err != nil {
+		return err
+	}
+
+	// Collections by product GID, media and inventory levels by variant GID
+	collections := map[string]collectionsJSON{}
+	media := map[string]variantMediaJSON{}
+	levels := map[string]inventoryLevelsJSON{}
+
+	query := fmt.Sprintf(productsVariantsBulkQuery, bulkQueryArguments(filter))
+	paths := map[string]string{
+		"Collection":     "collections",
+		"ProductVariant": "variants",
+		"MediaImage":     "media",
+		"InventoryLevel": "inventoryItem.inventoryLevels",
+	}
+
+	err = gqlclient.BulkQuery(client, query, paths, func(n bulkVariantsJSON) error {
+		collections[n.ID] = n.Collections
+
+		for _, v := range n.Variants.Nodes {
+			media[v.ID] = v.Media
+			levels[v.ID] = v.InventoryItem.InventoryLevels
+		}
+
+		return nil
+	}, progress)
+
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
+	}
+
+	query = fmt.Sprintf(productsFullExportBulkQuery, bulkQueryArguments(filter))
+	paths = map[string]string{
+		"ProductVariant": "variants",
+		"MediaImage":     "media",
+		"Video":          "media",
+		"ExternalVideo":  "media",
+		"Model3d":        "media",
+		"Metafield":      "metafields",
+	}
+
+	err = gqlclient.BulkQuery(client, query, paths, func(n fullProductJSON) error {
+		n.Collections = collections[n.ID]
+
+		for i := range n.Variants.Nodes {
+			v := &n.Variants.Nodes[i]
+			v.Media = media[v.ID]
+			v.InventoryItem.InventoryLevels = levels[v.ID]
+		}
+
+		product := toFullProduct(n)
+		product.Publications = publications[n.ID]
+
+		return fn(product)
+	}, progress)
+
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package gql
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+// Shopify's limit on the requested cost of a query
+const maxQueryCost = 1000
+
+// requestedCost returns the worst case cost of the query as Shopify calculates it: objects cost 1,
+// scalars 0, and connections 2 plus the cost of their nodes times the page size. Both sides
+// of inline fragments are counted.
+func requestedCost(t *testing.T, query string, vars map[string]interface{}) float64 {
+	t.Helper()
+
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		t.Fatalf("Cannot parse query: %s", err)
+	}
+
+	var cost float64
+	for _, op := range doc.Operations {
+		cost += selectionCost(t, doc, op.SelectionSet, vars)
+	}
+
+	return cost
+}
+
+func selectionCost(t *testing.T, doc *ast.QueryDocument, selections ast.SelectionSet, vars map[string]interface{}) float64 {
+	var cost float64
+
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			cost += fieldCost(t, doc, s, vars)
+		case *ast.InlineFragment:
+			cost += selectionCost(t, doc, s.SelectionSet, vars)
+		case *ast.FragmentSpread:
+			fragment := doc.Fragments.ForName(s.Name)
+			if fragment == nil {
+				t.Fatalf("Fragment %s not defined", s.Name)
+			}
+
+			cost += selectionCost(t, doc, fragment.SelectionSet, vars)
+		}
+	}
+
+	return cost
+}
+
+func fieldCost(t *testing.T, doc *ast.QueryDocument, field *ast.Field, vars map[string]interface{}) float64 {
+	if len(field.SelectionSet) == 0 {
+		return 0
+	}
+
+	first := field.Arguments.ForName("first")
+	if first == nil {
+		return 1 + selectionCost(t, doc, field.SelectionSet, vars)
+	}
+
+	value, err := first.Value.Value(vars)
+	if err != nil {
+		t.Fatalf("Cannot get %s's page size: %s", field.Name, err)
+	}
+
+	var size float64
+	switch n := value.(type) {
+	case int:
+		size = float64(n)
+	case int64:
+		size = float64(n)
+	default:
+		t.Fatalf("%s's page size = %v, want an int", field.Name, value)
+	}
+
+	var perNode float64
+	for _, selection := range field.SelectionSet {
+		child, ok := selection.(*ast.Field)
+		if !ok {
+			continue
+		}
+
+		switch child.Name {
+		case "nodes":
+			perNode += 1 + selectionCost(t, doc, child.SelectionSet, vars)
+		case "edges":
+			perNode += selectionCost(t, doc, child.SelectionSet, vars)
+		}
+	}
+
+	return 2 + size*perNode
+}
+
+func TestRequestedCost(t *testing.T) {
+	query := `
+query($first: Int!) {
+  shop {
+    name
+  }
+  products(first: $first) {
+    nodes {
+      title
+      ...Variants
+    }
+  }
+}
+fragment Variants on Product {
+  variants(first: 3) {
+    edges {
+      node {
+        sku
+        inventoryItem {
+          id
+        }
+      }
+    }
+  }
+}
+`
+	// shop 1 + products (2 + 10 * (product 1 + variants (2 + 3 * (variant 1 + inventoryItem 1))))
+	if cost := requestedCost(t, query, map[string]interface{}{"first": 10}); cost != 93 {
+		t.Errorf("cost = %v, want 93", cost)
+	}
+}
+
+func TestQueryCostsUnderLimit(t *testing.T) {
+	identifiers := func(n int) []ProductIdentifier {
+		return make([]ProductIdentifier, n)
+	}
+
+	byIdentifierQuery := func(n int, name, fragment string) string {
+		query, _ := identifierQuery(identifiers(n), 0, name, fragment)
+		return query
+	}
+
+	page := func(size int) map[string]interface{} {
+		return map[string]interface{}{"id": "gid://shopify/Product/1", "first": size}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+	}{
+		{"products export", productsFullExportQuery, map[string]interface{}{"first": 1}},
+		{"full products by identifier", byIdentifierQuery(fullProductsBatchSize, "FullProduct", fullProductFragment+mediaFragment), nil},
+		{"product media by identifier", byIdentifierQuery(productMediaBatchSize, "ProductMedia", productMediaFragment), nil},
+		{"product media page", productMediaPageQuery, page(nestedMediaPageSize)},
+		{"product metafields page", productMetafieldsPageQuery, page(nestedMetafieldsPageSize)},
+		{"product variants page", productVariantsPageQuery, page(productVariantsPageSize)},
+		{"variant inventory levels page", variantInventoryLevelsPageQuery, page(nestedInventoryLevelsPageSize)},
+		{"variant metafields page", variantMetafieldsPageQuery, page(nestedMetafieldsPageSize)},
+	}
+
+	for _, tt := range tests {
+		if cost := requestedCost(t, tt.query, tt.vars); cost >= maxQueryCost {
+			t.Errorf("%s query cost = %v, want less than %d", tt.name, cost, maxQueryCost)
+		}
+	}
+}
+
+func TestFetchFullProductsNestedPages(t *testing.T) {
+	seed := mock.Seed{}
+
+	var values, variants, metafields []interface{}
+	for i := 1; i <= productVariantsPageSize+5; i++ {
+		values = append(values, map[string]interface{}{"name": fmt.Sprint(i)})
+		variants = append(variants, map[string]interface{}{
+			"sku":          fmt.Sprintf("HAT-%d", i),
+			"optionValues": []interface{}{map[string]interface{}{"optionName": "Size", "name": fmt.Sprint(i)}},
+		})
+	}
+
+	for i := 1; i <= productMetafieldsPageSize+5; i++ {
+		metafields = append(metafields, map[string]interface{}{"namespace": "custom", "key": fmt.Sprintf("k%d", i), "type": "single_line_text_field", "value": "v"})
+	}
+
+	// The last variant has more metafields and inventory levels than fit in the first page
+	var levels []interface{}
+	for i := 1; i <= variantLevelsPageSize+2; i++ {
+		name := fmt.Sprintf("Warehouse %d", i)
+		seed.Locations = append(seed.Locations, name)
+		levels = append(levels, map[string]interface{}{"locationId": name, "name": "available", "quantity": i})
+	}
+
+	last := variants[len(variants)-1].(map[string]interface{})
+	last["inventoryQuantities"] = levels
+	last["metafields"] = metafields[:variantMetafieldsPageSize+3]
+
+	seed.Products = []map[string]interface{}{
+		{
+			"title":          "Hat",
+			"handle":         "hat",
+			"productOptions": []interface{}{map[string]interface{}{"name": "Size", "values": values}},
+			"variants":       variants,
+			"metafields":     metafields,
+		},
+	}
+
+	server := mock.NewServer()
+	if err := server.Load(seed); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	old := gqlclient.AdminURL
+	gqlclient.AdminURL = httpServer.URL
+	t.Cleanup(func() { gqlclient.AdminURL = old })
+
+	check := func(name string, product FullProduct) {
+		if len(product.Variants) != len(variants) || len(product.Metafields) != len(metafields) {
+			t.Fatalf("%s: %d variants and %d metafields, want %d and %d", name, len(product.Variants), len(product.Metafields), len(variants), len(metafields))
+		}
+
+		variant := product.Variants[len(product.Variants)-1]
+		if len(variant.InventoryLevels) != len(levels) || len(variant.Metafields) != variantMetafieldsPageSize+3 {
+			t.Errorf("%s: last variant has %d inventory levels and %d metafields, want %d and %d", name, len(variant.InventoryLevels), len(variant.Metafields), len(levels), variantMetafieldsPageSize+3)
+		}
+	}
+
+	var exported []FullProduct
+	err := FetchAllFullProducts("acme", "shpat_test", "", func(p FullProduct) error {
+		exported = append(exported, p)
+		return nil
+	}, map[string]interface{}{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(exported) != 1 {
+		t.Fatalf("exported %d products, want 1", len(exported))
+	}
+
+	check("FetchAllFullProducts", exported[0])
+
+	found, err := FetchFullProductsByIdentifier("acme", "shpat_test", []ProductIdentifier{{Handle: "hat"}}, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check("FetchFullProductsByIdentifier", *found[0])
+}
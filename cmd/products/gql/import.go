@@ -1,7 +1,7 @@
 package gql
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 
 	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
@@ -9,47 +9,33 @@ import (
 
 type ProductSetResult struct {
 	ProductID  string
-	UserErrors []string
+	UserErrors gqlclient.UserErrors
 }
 
 type productSetResponse struct {
-	Data struct {
-		ProductSet struct {
-			Product struct {
-				ID string `json:"id"`
-			} `json:"product"`
-			UserErrors []struct {
-				Field   []string `json:"field"`
-				Message string   `json:"message"`
-			} `json:"userErrors"`
-		} `json:"productSet"`
-	} `json:"data"`
+	ProductSet struct {
+		Product struct {
+			ID string `json:"id"`
+		} `json:"product"`
+	} `json:"productSet"`
 }
 
 func ProductSet(shop, token string, variables map[string]interface{}, options map[string]interface{}) (*ProductSetResult, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
-	data, err := client.Execute(productSetMutation, variables)
-	if err != nil {
-		return nil, fmt.Errorf("productSet mutation failed: %s", err)
-	}
+	var response productSetResponse
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode productSet response: %s", err)
-	}
+	// Validation errors are reported in the result rather than as a failure
+	err := client.ExecuteInto(productSetMutation, variables, &response)
 
-	var response productSetResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse productSet response: %s", err)
+	var userErrors gqlclient.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
+		return nil, fmt.Errorf("productSet mutation failed: %s", err)
 	}
 
 	result := &ProductSetResult{
-		ProductID: response.Data.ProductSet.Product.ID,
-	}
-
-	for _, ue := range response.Data.ProductSet.UserErrors {
-		result.UserErrors = append(result.UserErrors, ue.Message)
+		ProductID:  response.ProductSet.Product.ID,
+		UserErrors: userErrors,
 	}
 
 	return result, nil
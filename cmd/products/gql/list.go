@@ -1,7 +1,6 @@
 package gql
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -17,11 +16,9 @@ query($query: String) {
 `
 
 type productsCountResponse struct {
-	Data struct {
-		ProductsCount struct {
-			Count int `json:"count"`
-		} `json:"productsCount"`
-	} `json:"data"`
+	ProductsCount struct {
+		Count int `json:"count"`
+	} `json:"productsCount"`
 }
 
 func FetchProductCount(shop, token, status string, options map[string]interface{}) (int, error) {
@@ -32,22 +29,12 @@ func FetchProductCount(shop, token, status string, options map[string]interface{
 		vars["query"] = "status:" + status
 	}
 
-	data, err := client.Execute(productsCountQuery, vars)
-	if err != nil {
-		return 0, fmt.Errorf("Cannot fetch product count: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return 0, fmt.Errorf("Cannot re-encode product count response: %s", err)
-	}
-
 	var response productsCountResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return 0, fmt.Errorf("Cannot parse product count response: %s", err)
+	if err := client.ExecuteInto(productsCountQuery, vars, &response); err != nil {
+		return 0, fmt.Errorf("Cannot fetch product count: %s", err)
 	}
 
-	return response.Data.ProductsCount.Count, nil
+	return response.ProductsCount.Count, nil
 }
 
 const productsExportQuery = `
@@ -72,6 +59,10 @@ query($first: Int!, $after: String, $query: String) {
               barcode
             }
           }
+          pageInfo {
+            hasNextPage
+            endCursor
+          }
         }
       }
     }
@@ -79,33 +70,94 @@ query($first: Int!, $after: String, $query: String) {
 }
 `
 
-type productsExportResponse struct {
-	Data struct {
-		Products struct {
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-			Edges []struct {
-				Node struct {
-					LegacyResourceId int64  `json:"legacyResourceId,string"`
-					Title            string `json:"title"`
-					ProductType      string `json:"productType"`
-					Handle           string `json:"handle"`
-					Variants         struct {
-						Edges []struct {
-							Node struct {
-								LegacyResourceId int64  `json:"legacyResourceId,string"`
-								Title            string `json:"title"`
-								SKU              string `json:"sku"`
-								Barcode          string `json:"barcode"`
-							} `json:"node"`
-						} `json:"edges"`
-					} `json:"variants"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"products"`
-	} `json:"data"`
+const productExportVariantsPageQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  product(id: $id) {
+    variants(first: $first, after: $after) {
+      edges {
+        node {
+          legacyResourceId
+          title
+          sku
+          barcode
+        }
+      }
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+    }
+  }
+}
+`
+
+type exportVariantJSON struct {
+	LegacyResourceId int64  `json:"legacyResourceId,string"`
+	Title            string `json:"title"`
+	SKU              string `json:"sku"`
+	Barcode          string `json:"barcode"`
+}
+
+type productExportJSON struct {
+	LegacyResourceId int64  `json:"legacyResourceId,string"`
+	Title            string `json:"title"`
+	ProductType      string `json:"productType"`
+	Handle           string `json:"handle"`
+	Variants         struct {
+		Edges []struct {
+			Node exportVariantJSON `json:"node"`
+		} `json:"edges"`
+		PageInfo pageInfoJSON `json:"pageInfo"`
+	} `json:"variants"`
+}
+
+func toExportProduct(n productExportJSON) Product {
+	product := Product{
+		ID:          n.LegacyResourceId,
+		Title:       n.Title,
+		ProductType: n.ProductType,
+		Handle:      n.Handle,
+	}
+
+	for _, vEdge := range n.Variants.Edges {
+		product.Variants = append(product.Variants, toExportVariant(vEdge.Node))
+	}
+
+	return product
+}
+
+func toExportVariant(v exportVariantJSON) Variant {
+	return Variant{
+		ID:      v.LegacyResourceId,
+		Title:   v.Title,
+		SKU:     v.SKU,
+		Barcode: v.Barcode,
+	}
+}
+
+// fetchExportProduct converts n to a Product, fetching the variants past the first page
+func fetchExportProduct(client *gqlclient.Client, n productExportJSON) (Product, error) {
+	product := toExportProduct(n)
+	if !n.Variants.PageInfo.HasNextPage {
+		return product, nil
+	}
+
+	vars := map[string]interface{}{
+		"id":    fmt.Sprintf("gid://shopify/Product/%d", n.LegacyResourceId),
+		"first": 250,
+		"after": n.Variants.PageInfo.EndCursor,
+	}
+
+	err := gqlclient.Paginate(client, productExportVariantsPageQuery, vars, "product.variants", func(v exportVariantJSON) error {
+		product.Variants = append(product.Variants, toExportVariant(v))
+		return nil
+	})
+
+	if err != nil {
+		return product, fmt.Errorf("Cannot fetch variants of product %d: %s", n.LegacyResourceId, err)
+	}
+
+	return product, nil
 }
 
 func FetchAllProducts(shop, token, status string, fn func(Product) error, options map[string]interface{}) error {
@@ -116,51 +168,87 @@ func FetchAllProducts(shop, token, status string, fn func(Product) error, option
 		vars["query"] = "status:" + status
 	}
 
-	for {
-		data, err := client.Execute(productsExportQuery, vars)
+	err := gqlclient.Paginate(client, productsExportQuery, vars, "products", func(n productExportJSON) error {
+		product, err := fetchExportProduct(client, n)
 		if err != nil {
-			return fmt.Errorf("Cannot fetch products: %s", err)
+			return err
 		}
 
-		b, err := json.Marshal(data)
+		return fn(product)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
+	}
+
+	return nil
+}
+
+// SearchProducts calls fn with each product matching the search query, e.g., "sku:ABC OR handle:hat"
+func SearchProducts(shop, token, query string, fn func(Product) error, options map[string]interface{}) error {
+	client := gqlclient.NewClient(shop, token, options)
+
+	vars := map[string]interface{}{"first": 250, "query": query}
+
+	err := gqlclient.Paginate(client, productsExportQuery, vars, "products", func(n productExportJSON) error {
+		product, err := fetchExportProduct(client, n)
 		if err != nil {
-			return fmt.Errorf("Cannot re-encode products response: %s", err)
+			return err
 		}
 
-		var response productsExportResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return fmt.Errorf("Cannot parse products response: %s", err)
-		}
+		return fn(product)
+	})
 
-		for _, edge := range response.Data.Products.Edges {
-			n := edge.Node
-			product := Product{
-				ID:          n.LegacyResourceId,
-				Title:       n.Title,
-				ProductType: n.ProductType,
-				Handle:      n.Handle,
-			}
+	if err != nil {
+		return fmt.Errorf("Cannot search products: %s", err)
+	}
 
-			for _, vEdge := range n.Variants.Edges {
-				v := vEdge.Node
-				product.Variants = append(product.Variants, Variant{
-					ID:      v.LegacyResourceId,
-					Title:   v.Title,
-					SKU:     v.SKU,
-					Barcode: v.Barcode,
-				})
-			}
+	return nil
+}
 
-			if err := fn(product); err != nil {
-				return err
-			}
-		}
+const productsBulkExportQuery = `
+{
+  products%s {
+    edges {
+      node {
+        id
+        legacyResourceId
+        title
+        productType
+        handle
+        variants {
+          edges {
+            node {
+              id
+              legacyResourceId
+              title
+              sku
+              barcode
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
 
-		if !response.Data.Products.PageInfo.HasNextPage {
-			break
-		}
+// FetchAllProductsBulk is FetchAllProducts using a bulk query
+func FetchAllProductsBulk(shop, token, status string, fn func(Product) error, progress func(gqlclient.BulkOperationResult), options map[string]interface{}) error {
+	var filter string
+	if len(status) > 0 {
+		filter = "status:" + status
+	}
+
+	query := fmt.Sprintf(productsBulkExportQuery, bulkQueryArguments(filter))
+	paths := map[string]string{"ProductVariant": "variants"}
 
-		vars["after"] = response.Data.Products.PageInfo.EndCursor
+	err := gqlclient.BulkQuery(gqlclient.NewClient(shop, token, options), query, paths, func(n productExportJSON) error {
+		return fn(toExportProduct(n))
+	}, progress)
+
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
 	}
 
 	return nil
@@ -259,19 +347,7 @@ type variantJSON struct {
 	Position          int    `json:"position"`
 	InventoryQuantity int    `json:"inventoryQuantity"`
 	InventoryItem     struct {
-		InventoryLevels struct {
-			Edges []struct {
-				Node struct {
-					Location struct {
-						Name string `json:"name"`
-					} `json:"location"`
-					Quantities []struct {
-						Name     string `json:"name"`
-						Quantity int    `json:"quantity"`
-					} `json:"quantities"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"inventoryLevels"`
+		InventoryLevels inventoryLevelsJSON `json:"inventoryLevels"`
 	} `json:"inventoryItem"`
 }
 
@@ -302,13 +378,11 @@ type productJSON struct {
 }
 
 type productsResponse struct {
-	Data struct {
-		Products struct {
-			Edges []struct {
-				Node productJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"products"`
-	} `json:"data"`
+	Products struct {
+		Edges []struct {
+			Node productJSON `json:"node"`
+		} `json:"edges"`
+	} `json:"products"`
 }
 
 func buildQuery(ids []int64, skus []string, status string) (string, int) {
@@ -393,46 +467,85 @@ type ProductInventory struct {
 	Variants     []VariantInventory
 }
 
-type productsInventoryResponse struct {
-	Data struct {
-		Products struct {
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-			Edges []struct {
-				Node struct {
-					LegacyResourceId int64  `json:"legacyResourceId,string"`
-					Title            string `json:"title"`
-					Variants         struct {
-						Edges []struct {
-							Node struct {
-								LegacyResourceId int64  `json:"legacyResourceId,string"`
-								Title            string `json:"title"`
-								SKU              string `json:"sku"`
-								Barcode          string `json:"barcode"`
-								InventoryItem    struct {
-									InventoryLevels struct {
-										Edges []struct {
-											Node struct {
-												Location struct {
-													Name string `json:"name"`
-												} `json:"location"`
-												Quantities []struct {
-													Name     string `json:"name"`
-													Quantity int    `json:"quantity"`
-												} `json:"quantities"`
-											} `json:"node"`
-										} `json:"edges"`
-									} `json:"inventoryLevels"`
-								} `json:"inventoryItem"`
-							} `json:"node"`
-						} `json:"edges"`
-					} `json:"variants"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"products"`
-	} `json:"data"`
+type inventoryLevelJSON struct {
+	Location struct {
+		Name string `json:"name"`
+	} `json:"location"`
+	Quantities []struct {
+		Name     string `json:"name"`
+		Quantity int    `json:"quantity"`
+	} `json:"quantities"`
+}
+
+type inventoryLevelEdgeJSON struct {
+	Node inventoryLevelJSON `json:"node"`
+}
+
+type inventoryLevelsJSON struct {
+	Edges    []inventoryLevelEdgeJSON `json:"edges"`
+	PageInfo pageInfoJSON             `json:"pageInfo"`
+}
+
+type productInventoryJSON struct {
+	LegacyResourceId int64  `json:"legacyResourceId,string"`
+	Title            string `json:"title"`
+	Variants         struct {
+		Edges []struct {
+			Node struct {
+				LegacyResourceId int64  `json:"legacyResourceId,string"`
+				Title            string `json:"title"`
+				SKU              string `json:"sku"`
+				Barcode          string `json:"barcode"`
+				InventoryItem    struct {
+					InventoryLevels inventoryLevelsJSON `json:"inventoryLevels"`
+				} `json:"inventoryItem"`
+			} `json:"node"`
+		} `json:"edges"`
+	} `json:"variants"`
+}
+
+// toInventoryLevels converts inventoryLevels to InventoryLevels, using
+// whichever of the available, committed, and on_hand quantities were selected.
+func toInventoryLevels(levels inventoryLevelsJSON) []InventoryLevel {
+	var result []InventoryLevel
+
+	for _, levelEdge := range levels.Edges {
+		level := levelEdge.Node
+		il := InventoryLevel{Location: level.Location.Name}
+		for _, q := range level.Quantities {
+			switch q.Name {
+			case "available":
+				il.Available = q.Quantity
+			case "committed":
+				il.Committed = q.Quantity
+			case "on_hand":
+				il.OnHand = q.Quantity
+			}
+		}
+		result = append(result, il)
+	}
+
+	return result
+}
+
+func toProductInventory(n productInventoryJSON) ProductInventory {
+	pi := ProductInventory{
+		ProductID:    n.LegacyResourceId,
+		ProductTitle: n.Title,
+	}
+
+	for _, vEdge := range n.Variants.Edges {
+		v := vEdge.Node
+		pi.Variants = append(pi.Variants, VariantInventory{
+			VariantID:       v.LegacyResourceId,
+			VariantTitle:    v.Title,
+			SKU:             v.SKU,
+			Barcode:         v.Barcode,
+			InventoryLevels: toInventoryLevels(v.InventoryItem.InventoryLevels),
+		})
+	}
+
+	return pi
 }
 
 func FetchAllInventory(shop, token string, fn func(ProductInventory) error, options map[string]interface{}) error {
@@ -440,65 +553,71 @@ func FetchAllInventory(shop, token string, fn func(ProductInventory) error, opti
 
 	vars := map[string]interface{}{"first": 10}
 
-	for {
-		data, err := client.Execute(productsInventoryQuery, vars)
-		if err != nil {
-			return fmt.Errorf("Cannot fetch products: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return fmt.Errorf("Cannot re-encode products response: %s", err)
-		}
+	err := gqlclient.Paginate(client, productsInventoryQuery, vars, "products", func(n productInventoryJSON) error {
+		return fn(toProductInventory(n))
+	})
 
-		var response productsInventoryResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return fmt.Errorf("Cannot parse products response: %s", err)
-		}
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
+	}
 
-		for _, edge := range response.Data.Products.Edges {
-			n := edge.Node
-			pi := ProductInventory{
-				ProductID:    n.LegacyResourceId,
-				ProductTitle: n.Title,
-			}
+	return nil
+}
 
-			for _, vEdge := range n.Variants.Edges {
-				v := vEdge.Node
-				vi := VariantInventory{
-					VariantID:    v.LegacyResourceId,
-					VariantTitle: v.Title,
-					SKU:          v.SKU,
-					Barcode:      v.Barcode,
-				}
-
-				for _, levelEdge := range v.InventoryItem.InventoryLevels.Edges {
-					level := levelEdge.Node
-					il := InventoryLevel{Location: level.Location.Name}
-					for _, q := range level.Quantities {
-						switch q.Name {
-						case "available":
-							il.Available = q.Quantity
-						case "on_hand":
-							il.OnHand = q.Quantity
-						}
-					}
-					vi.InventoryLevels = append(vi.InventoryLevels, il)
-				}
-
-				pi.Variants = append(pi.Variants, vi)
-			}
+const productsInventoryBulkQuery = `
+{
+  products {
+    edges {
+      node {
+        id
+        legacyResourceId
+        title
+        variants {
+          edges {
+            node {
+              id
+              legacyResourceId
+              title
+              sku
+              barcode
+              inventoryItem {
+                inventoryLevels {
+                  edges {
+                    node {
+                      id
+                      location {
+                        name
+                      }
+                      quantities(names: ["available", "on_hand"]) {
+                        name
+                        quantity
+                      }
+                    }
+                  }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
 
-			if err := fn(pi); err != nil {
-				return err
-			}
-		}
+var inventoryBulkPaths = map[string]string{
+	"ProductVariant": "variants",
+	"InventoryLevel": "inventoryItem.inventoryLevels",
+}
 
-		if !response.Data.Products.PageInfo.HasNextPage {
-			break
-		}
+// FetchAllInventoryBulk is FetchAllInventory using a bulk query
+func FetchAllInventoryBulk(shop, token string, fn func(ProductInventory) error, progress func(gqlclient.BulkOperationResult), options map[string]interface{}) error {
+	err := gqlclient.BulkQuery(gqlclient.NewClient(shop, token, options), productsInventoryBulkQuery, inventoryBulkPaths, func(n productInventoryJSON) error {
+		return fn(toProductInventory(n))
+	}, progress)
 
-		vars["after"] = response.Data.Products.PageInfo.EndCursor
+	if err != nil {
+		return fmt.Errorf("Cannot fetch products: %s", err)
 	}
 
 	return nil
@@ -542,167 +661,148 @@ query($first: Int!, $after: String, $query: String!) {
 }
 `
 
-type variantsInventoryResponse struct {
-	Data struct {
-		ProductVariants struct {
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-			Edges []struct {
-				Node struct {
-					LegacyResourceId int64  `json:"legacyResourceId,string"`
-					Title            string `json:"title"`
-					SKU              string `json:"sku"`
-					Barcode          string `json:"barcode"`
-					Product          struct {
-						LegacyResourceId int64  `json:"legacyResourceId,string"`
-						Title            string `json:"title"`
-					} `json:"product"`
-					InventoryItem struct {
-						InventoryLevels struct {
-							Edges []struct {
-								Node struct {
-									Location struct {
-										Name string `json:"name"`
-									} `json:"location"`
-									Quantities []struct {
-										Name     string `json:"name"`
-										Quantity int    `json:"quantity"`
-									} `json:"quantities"`
-								} `json:"node"`
-							} `json:"edges"`
-						} `json:"inventoryLevels"`
-					} `json:"inventoryItem"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"productVariants"`
-	} `json:"data"`
+type variantInventoryJSON struct {
+	LegacyResourceId int64  `json:"legacyResourceId,string"`
+	Title            string `json:"title"`
+	SKU              string `json:"sku"`
+	Barcode          string `json:"barcode"`
+	Product          struct {
+		LegacyResourceId int64  `json:"legacyResourceId,string"`
+		Title            string `json:"title"`
+	} `json:"product"`
+	InventoryItem struct {
+		InventoryLevels inventoryLevelsJSON `json:"inventoryLevels"`
+	} `json:"inventoryItem"`
 }
 
-func FetchInventoryByIdentifiers(shop, token, identifyBy string, identifiers []string, fn func(ProductInventory) error, options map[string]interface{}) error {
-	client := gqlclient.NewClient(shop, token, options)
+func toVariantProductInventory(v variantInventoryJSON) ProductInventory {
+	return ProductInventory{
+		ProductID:    v.Product.LegacyResourceId,
+		ProductTitle: v.Product.Title,
+		Variants: []VariantInventory{
+			{
+				VariantID:       v.LegacyResourceId,
+				VariantTitle:    v.Title,
+				SKU:             v.SKU,
+				Barcode:         v.Barcode,
+				InventoryLevels: toInventoryLevels(v.InventoryItem.InventoryLevels),
+			},
+		},
+	}
+}
 
+func identifiersQuery(identifyBy string, identifiers []string) string {
 	parts := make([]string, len(identifiers))
 	for i, id := range identifiers {
 		parts[i] = identifyBy + ":\"" + id + "\""
 	}
 
+	return strings.Join(parts, " OR ")
+}
+
+func FetchInventoryByIdentifiers(shop, token, identifyBy string, identifiers []string, fn func(ProductInventory) error, options map[string]interface{}) error {
+	client := gqlclient.NewClient(shop, token, options)
+
 	vars := map[string]interface{}{
 		"first": 50,
-		"query": strings.Join(parts, " OR "),
+		"query": identifiersQuery(identifyBy, identifiers),
 	}
 
-	for {
-		data, err := client.Execute(variantsInventoryQuery, vars)
-		if err != nil {
-			return fmt.Errorf("Cannot fetch variants: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return fmt.Errorf("Cannot re-encode variants response: %s", err)
-		}
-
-		var response variantsInventoryResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return fmt.Errorf("Cannot parse variants response: %s", err)
-		}
+	err := gqlclient.Paginate(client, variantsInventoryQuery, vars, "productVariants", func(v variantInventoryJSON) error {
+		return fn(toVariantProductInventory(v))
+	})
 
-		for _, edge := range response.Data.ProductVariants.Edges {
-			v := edge.Node
-			vi := VariantInventory{
-				VariantID:    v.LegacyResourceId,
-				VariantTitle: v.Title,
-				SKU:          v.SKU,
-				Barcode:      v.Barcode,
-			}
+	if err != nil {
+		return fmt.Errorf("Cannot fetch variants: %s", err)
+	}
 
-			for _, levelEdge := range v.InventoryItem.InventoryLevels.Edges {
-				level := levelEdge.Node
-				il := InventoryLevel{Location: level.Location.Name}
-				for _, q := range level.Quantities {
-					switch q.Name {
-					case "available":
-						il.Available = q.Quantity
-					case "committed":
-						il.Committed = q.Quantity
-					case "on_hand":
-						il.OnHand = q.Quantity
-					}
-				}
-				vi.InventoryLevels = append(vi.InventoryLevels, il)
-			}
+	return nil
+}
 
-			pi := ProductInventory{
-				ProductID:    v.Product.LegacyResourceId,
-				ProductTitle: v.Product.Title,
-				Variants:     []VariantInventory{vi},
-			}
+const variantsInventoryBulkQuery = `
+{
+  productVariants%s {
+    edges {
+      node {
+        id
+        legacyResourceId
+        title
+        sku
+        barcode
+        product {
+          legacyResourceId
+          title
+        }
+        inventoryItem {
+          inventoryLevels {
+            edges {
+              node {
+                id
+                location {
+                  name
+                }
+                quantities(names: ["available", "on_hand"]) {
+                  name
+                  quantity
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
 
-			if err := fn(pi); err != nil {
-				return err
-			}
-		}
+// FetchInventoryByIdentifiersBulk is FetchInventoryByIdentifiers using a bulk query
+func FetchInventoryByIdentifiersBulk(shop, token, identifyBy string, identifiers []string, fn func(ProductInventory) error, progress func(gqlclient.BulkOperationResult), options map[string]interface{}) error {
+	query := fmt.Sprintf(variantsInventoryBulkQuery, bulkQueryArguments(identifiersQuery(identifyBy, identifiers)))
 
-		if !response.Data.ProductVariants.PageInfo.HasNextPage {
-			break
-		}
+	err := gqlclient.BulkQuery(gqlclient.NewClient(shop, token, options), query, inventoryBulkPaths, func(v variantInventoryJSON) error {
+		return fn(toVariantProductInventory(v))
+	}, progress)
 
-		vars["after"] = response.Data.ProductVariants.PageInfo.EndCursor
+	if err != nil {
+		return fmt.Errorf("Cannot fetch variants: %s", err)
 	}
 
 	return nil
 }
 
 const locationsQuery = `
-{
-  locations(first: 250, includeLegacy: false, includeInactive: false) {
+query($first: Int!, $after: String) {
+  locations(first: $first, after: $after, includeLegacy: false, includeInactive: false) {
     edges {
       node {
         id
         name
       }
     }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
   }
 }
 `
 
-type locationsResponse struct {
-	Data struct {
-		Locations struct {
-			Edges []struct {
-				Node struct {
-					ID   string `json:"id"`
-					Name string `json:"name"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"locations"`
-	} `json:"data"`
-}
-
 // FetchLocations returns a map of location name to GID for all active locations.
 func FetchLocations(shop, token string, options map[string]interface{}) (map[string]string, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
-	data, err := client.Execute(locationsQuery)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot fetch locations: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode locations response: %s", err)
-	}
+	locations := make(map[string]string)
 
-	var response locationsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse locations response: %s", err)
-	}
+	err := gqlclient.Paginate(client, locationsQuery, nil, "locations", func(n struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}) error {
+		locations[n.Name] = n.ID
+		return nil
+	})
 
-	locations := make(map[string]string)
-	for _, edge := range response.Data.Locations.Edges {
-		locations[edge.Node.Name] = edge.Node.ID
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch locations: %s", err)
 	}
 
 	return locations, nil
@@ -721,23 +821,13 @@ func FetchProducts(shop, token string, ids []int64, skus []string, status string
 		vars["query"] = query
 	}
 
-	data, err := client.Execute(productsQuery, vars)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list products: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode products response: %s", err)
-	}
-
 	var response productsResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse products response: %s", err)
+	if err := client.ExecuteInto(productsQuery, vars, &response); err != nil {
+		return nil, fmt.Errorf("Cannot list products: %s", err)
 	}
 
 	var result []Product
-	for _, edge := range response.Data.Products.Edges {
+	for _, edge := range response.Products.Edges {
 		result = append(result, toProduct(edge.Node))
 	}
 
@@ -785,21 +875,7 @@ func toProduct(n productJSON) Product {
 			InventoryQuantity: v.InventoryQuantity,
 		}
 
-		for _, levelEdge := range v.InventoryItem.InventoryLevels.Edges {
-			level := levelEdge.Node
-			il := InventoryLevel{Location: level.Location.Name}
-			for _, q := range level.Quantities {
-				switch q.Name {
-				case "available":
-					il.Available = q.Quantity
-				case "committed":
-					il.Committed = q.Quantity
-				case "on_hand":
-					il.OnHand = q.Quantity
-				}
-			}
-			variant.InventoryLevels = append(variant.InventoryLevels, il)
-		}
+		variant.InventoryLevels = toInventoryLevels(v.InventoryItem.InventoryLevels)
 
 		product.Variants = append(product.Variants, variant)
 	}
@@ -862,9 +938,7 @@ query($id: ID!, $first: Int!) {
 `
 
 type productInventoryResponse struct {
-	Data struct {
-		Product productJSON `json:"product"`
-	} `json:"data"`
+	Product productJSON `json:"product"`
 }
 
 // FetchProductInventory fetches a single product by ID with each variant's
@@ -872,24 +946,14 @@ type productInventoryResponse struct {
 func FetchProductInventory(shop, token string, id int64, options map[string]interface{}) (*Product, error) {
 	client := gqlclient.NewClient(shop, token, options)
 
-	data, err := client.Execute(productInventoryQuery, map[string]interface{}{
+	var response productInventoryResponse
+	if err := client.ExecuteInto(productInventoryQuery, map[string]interface{}{
 		"id":    fmt.Sprintf("gid://shopify/Product/%d", id),
 		"first": 250,
-	})
-	if err != nil {
+	}, &response); err != nil {
 		return nil, fmt.Errorf("Cannot fetch product inventory: %s", err)
 	}
 
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode product inventory response: %s", err)
-	}
-
-	var response productInventoryResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse product inventory response: %s", err)
-	}
-
-	product := toProduct(response.Data.Product)
+	product := toProduct(response.Product)
 	return &product, nil
 }
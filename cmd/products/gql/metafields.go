@@ -0,0 +1,75 @@
+package gql
+
+import (
+	"fmt"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const metafieldDefinitionsQuery = `
+query($ownerType: MetafieldOwnerType!, $first: Int!, $after: String) {
+  metafieldDefinitions(ownerType: $ownerType, first: $first, after: $after) {
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+    edges {
+      node {
+        namespace
+        key
+        type {
+          name
+        }
+        validations {
+          name
+          value
+        }
+      }
+    }
+  }
+}
+`
+
+type MetafieldValidation struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type MetafieldDefinition struct {
+	Namespace   string
+	Key         string
+	Type        string
+	Validations []MetafieldValidation
+}
+
+// FetchMetafieldDefinitions returns the metafield definitions for ownerType, e.g., PRODUCT
+func FetchMetafieldDefinitions(shop, token, ownerType string, options map[string]interface{}) ([]MetafieldDefinition, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	vars := map[string]interface{}{"ownerType": ownerType, "first": 250}
+
+	var definitions []MetafieldDefinition
+
+	err := gqlclient.Paginate(client, metafieldDefinitionsQuery, vars, "metafieldDefinitions", func(n struct {
+		Namespace string `json:"namespace"`
+		Key       string `json:"key"`
+		Type      struct {
+			Name string `json:"name"`
+		} `json:"type"`
+		Validations []MetafieldValidation `json:"validations"`
+	}) error {
+		definitions = append(definitions, MetafieldDefinition{
+			Namespace:   n.Namespace,
+			Key:         n.Key,
+			Type:        n.Type.Name,
+			Validations: n.Validations,
+		})
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch metafield definitions: %s", err)
+	}
+
+	return definitions, nil
+}
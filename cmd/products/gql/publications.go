@@ -0,0 +1,153 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const publicationsQuery = `
+query($first: Int!, $after: String) {
+  publications(first: $first, after: $after) {
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+    nodes {
+      id
+      name
+      catalog {
+        title
+      }
+    }
+  }
+}
+`
+
+const publishablePublishMutation = `
+mutation($id: ID!, $input: [PublicationInput!]!) {
+  publishablePublish(id: $id, input: $input) {
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+// FetchPublications returns the shop's publications, which include its sales
+// channels, by name to ID. Publications are also included by their catalog's
+// title when it differs from their name.
+func FetchPublications(shop, token string, options map[string]interface{}) (map[string]string, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	publications := make(map[string]string)
+
+	err := gqlclient.Paginate(client, publicationsQuery, nil, "publications", func(n struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Catalog *struct {
+			Title string `json:"title"`
+		} `json:"catalog"`
+	}) error {
+		publications[n.Name] = n.ID
+		if n.Catalog != nil && n.Catalog.Title != "" {
+			if _, ok := publications[n.Catalog.Title]; !ok {
+				publications[n.Catalog.Title] = n.ID
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch publications: %s", err)
+	}
+
+	return publications, nil
+}
+
+// PublishProduct publishes the product to the publications. Validation errors
+// are returned as UserErrors.
+func PublishProduct(shop, token, productID string, publicationIDs []string, options map[string]interface{}) (gqlclient.UserErrors, error) {
+	client := gqlclient.NewClient(shop, token, options)
+
+	var input []map[string]interface{}
+	for _, id := range publicationIDs {
+		input = append(input, map[string]interface{}{"publicationId": id})
+	}
+
+	var response struct{}
+
+	err := client.ExecuteInto(publishablePublishMutation, map[string]interface{}{"id": productID, "input": input}, &response)
+
+	var userErrors gqlclient.UserErrors
+	if err != nil && !errors.As(err, &userErrors) {
+		return nil, fmt.Errorf("Cannot publish product %s: %s", productID, err)
+	}
+
+	return userErrors, nil
+}
+
+const publicationProductsQuery = `
+query($id: ID!, $first: Int!, $after: String) {
+  publication(id: $id) {
+    products(first: $first, after: $after) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      nodes {
+        id
+      }
+    }
+  }
+}
+`
+
+// fetchProductPublications returns the names of the publications each product is published on
+// by product GID. A publication's name is its catalog's title when it has no name.
+func fetchProductPublications(client *gqlclient.Client) (map[string][]string, error) {
+	type publicationJSON struct {
+		ID      string `json:"id"`
+		Name    string `json:"name"`
+		Catalog *struct {
+			Title string `json:"title"`
+		} `json:"catalog"`
+	}
+
+	var publications []publicationJSON
+
+	err := gqlclient.Paginate(client, publicationsQuery, nil, "publications", func(n publicationJSON) error {
+		publications = append(publications, n)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot fetch publications: %s", err)
+	}
+
+	products := make(map[string][]string)
+
+	for _, pub := range publications {
+		name := pub.Name
+		if name == "" && pub.Catalog != nil {
+			name = pub.Catalog.Title
+		}
+
+		vars := map[string]interface{}{"id": pub.ID, "first": 250}
+		err := gqlclient.Paginate(client, publicationProductsQuery, vars, "publication.products", func(n struct {
+			ID string `json:"id"`
+		}) error {
+			products[n.ID] = append(products[n.ID], name)
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("Cannot fetch products published on %s: %s", name, err)
+		}
+	}
+
+	return products, nil
+}
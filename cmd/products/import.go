@@ -3,7 +3,9 @@ package products
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -16,12 +18,28 @@ import (
 
 func syncImportProducts(c *cli.Context) error {
 	if c.NArg() < 1 {
-		return fmt.Errorf("CSV file path required")
+		return fmt.Errorf("File path required")
+	}
+
+	if c.Bool("retry-failed") && c.String("resume") == "" {
+		return fmt.Errorf("--retry-failed requires --resume")
+	}
+
+	filename := c.Args().First()
+	format, err := productFileFormat(filename, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	if c.String("failed-csv") != "" && format != formatCSV {
+		return fmt.Errorf("--failed-csv requires a CSV file")
 	}
 
-	csvFile := c.Args().First()
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{}
 	parallel := c.Int("parallel")
 	jsonOutput := c.Bool("json")
@@ -36,26 +54,114 @@ func syncImportProducts(c *cli.Context) error {
 		return err
 	}
 
-	fmt.Fprintf(out, "Parsing %s...\n", csvFile)
+	fmt.Fprintf(out, "Parsing %s...\n", filename)
 
-	products, err := parseCSV(csvFile, locations)
+	products, err := parseProductFile(filename, format, locations)
 	if err != nil {
 		return err
 	}
 
 	if len(products) == 0 {
+		if format != formatCSV {
+			return fmt.Errorf("No products found in %s", filename)
+		}
+
 		return fmt.Errorf("No products found in CSV. Does the identifier column exist?")
 	}
 
 	setProductIdentifiers(products, c.String("identify-by"))
 
-	fmt.Fprintf(out, "Importing %d products...\n", len(products))
+	all := products
+	resume := c.String("resume")
+
+	if resume != "" {
+		previous, err := readJournal(resume)
+		if err != nil {
+			return err
+		}
+
+		products, err = pendingProducts(products, previous, c.Bool("retry-failed"))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Skipping %d products already in %s\n", len(all)-len(products), resume)
 
-	type importResult struct {
-		Row    int
-		ID     string
-		Errors []string
-		Err    error
+		if len(products) == 0 {
+			fmt.Fprintln(out, "Nothing to import")
+			return nil
+		}
+	}
+
+	if c.Bool("dry-run") {
+		if err := resolveImportReferences(shop, token, products, true, out, options); err != nil {
+			return err
+		}
+
+		return dryRunImport(shop, token, products, locations, c.Bool("match-media-by-name"), jsonOutput, out, options)
+	}
+
+	journalFile := c.String("journal")
+	if journalFile == "" {
+		journalFile = resume
+	}
+	if journalFile == "" {
+		journalFile = filename + ".journal"
+	}
+
+	journal, err := openJournal(journalFile, resume != "")
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+
+	fmt.Fprintf(out, "Importing %d products, writing journal to %s...\n", len(products), journalFile)
+
+	results, err := setProducts(shop, token, products, filepath.Dir(filename), c.Bool("match-media-by-name"), parallel, journal, out, options)
+	if err != nil {
+		return err
+	}
+
+	if failedCSV := c.String("failed-csv"); failedCSV != "" {
+		failed := map[int]bool{}
+		for _, r := range results {
+			if r.Err != nil || len(r.Errors) > 0 {
+				failed[r.Row] = true
+			}
+		}
+
+		if len(failed) > 0 {
+			if err := writeFailedRows(filename, failedCSV, all, failed); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, "Wrote %d failed products to %s\n", len(failed), failedCSV)
+		}
+	}
+
+	return printImportResults(results, jsonOutput, out)
+}
+
+// resolveImportReferences replaces the products' collection and publication names with IDs.
+// Collections that don't exist are created unless dryRun.
+func resolveImportReferences(shop, token string, products []importProductInput, dryRun bool, out io.Writer, options map[string]interface{}) error {
+	if err := resolveCollections(shop, token, products, dryRun, out, options); err != nil {
+		return err
+	}
+
+	return resolvePublications(shop, token, products, options)
+}
+
+// setProducts creates or updates the products, parallel at a time, after resolving their
+// collections, publications, and media. Local media files are relative to dir, see matchMedia
+// for mediaByName. Each result is recorded in journal, if given.
+func setProducts(shop, token string, products []importProductInput, dir string, mediaByName bool, parallel int, journal *importJournal, out io.Writer, options map[string]interface{}) ([]importResult, error) {
+	if err := resolveImportReferences(shop, token, products, false, out, options); err != nil {
+		return nil, err
+	}
+
+	if err := resolveMedia(shop, token, products, dir, mediaByName, out, options); err != nil {
+		return nil, err
 	}
 
 	results := make([]importResult, len(products))
@@ -64,12 +170,20 @@ func syncImportProducts(c *cli.Context) error {
 
 	for i, p := range products {
 		wg.Add(1)
+		// Acquired here so products are started in order
+		sem <- struct{}{}
 		go func(idx int, product importProductInput) {
 			defer wg.Done()
-			sem <- struct{}{}
 			defer func() { <-sem }()
 
-			results[idx] = importResult{Row: idx + 1}
+			results[idx] = importResult{Row: product.Row, Handle: product.Input.Handle}
+			if journal != nil {
+				defer func() {
+					if err := journal.record(results[idx]); err != nil {
+						fmt.Fprintln(os.Stderr, err)
+					}
+				}()
+			}
 
 			b, err := json.Marshal(product)
 			if err != nil {
@@ -90,19 +204,39 @@ func syncImportProducts(c *cli.Context) error {
 			}
 
 			results[idx].ID = strings.TrimPrefix(result.ProductID, "gid://shopify/Product/")
-			results[idx].Errors = result.UserErrors
+			for _, ue := range result.UserErrors {
+				results[idx].Errors = append(results[idx].Errors, ue.Message)
+			}
+
+			publishProduct(shop, token, product, &results[idx], options)
 		}(i, p)
 	}
 
 	wg.Wait()
 
+	return results, nil
+}
+
+type importResult struct {
+	// Row of the product in the import file
+	Row    int
+	Handle string
+	ID     string
+	// User errors from productSet
+	Errors []string
+	Err    error
+}
+
+// printImportResults outputs the status of each product as a table, or JSON,
+// and exits with an error status if any failed.
+func printImportResults(results []importResult, jsonOutput bool, out io.Writer) error {
 	var failures int
 
 	if jsonOutput {
 		jsonResults := make([]map[string]interface{}, 0, len(results))
 
 		for _, r := range results {
-			item := map[string]interface{}{"row": r.Row, "id": r.ID, "status": "ok"}
+			item := map[string]interface{}{"row": r.Row, "handle": r.Handle, "id": r.ID, "status": "ok"}
 
 			if r.Err != nil {
 				failures++
@@ -127,17 +261,17 @@ func syncImportProducts(c *cli.Context) error {
 		fmt.Fprintln(out, "Done!")
 
 		t := tabby.New()
-		t.AddHeader("Row", "Product", "Status")
+		t.AddHeader("Row", "Handle", "Product", "Status")
 
 		for _, r := range results {
 			if r.Err != nil {
 				failures++
-				t.AddLine(r.Row, "", "Error: "+r.Err.Error())
+				t.AddLine(r.Row, r.Handle, "", "Error: "+r.Err.Error())
 			} else if len(r.Errors) > 0 {
 				failures++
-				t.AddLine(r.Row, r.ID, "Error: "+strings.Join(r.Errors, "; "))
+				t.AddLine(r.Row, r.Handle, r.ID, "Error: "+strings.Join(r.Errors, "; "))
 			} else {
-				t.AddLine(r.Row, r.ID, "OK")
+				t.AddLine(r.Row, r.Handle, r.ID, "OK")
 			}
 		}
 		t.Print()
@@ -0,0 +1,231 @@
+package products
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// journalEntry is the outcome of importing the product at a CSV row. A row
+// can have many entries, the last is its current status.
+type journalEntry struct {
+	Row    int      `json:"row"`
+	Handle string   `json:"handle"`
+	ID     string   `json:"id,omitempty"`
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+func newJournalEntry(r importResult) journalEntry {
+	entry := journalEntry{Row: r.Row, Handle: r.Handle, ID: r.ID, Status: "ok"}
+
+	if r.Err != nil {
+		entry.Status = "error"
+		entry.Errors = []string{r.Err.Error()}
+	} else if len(r.Errors) > 0 {
+		entry.Status = "error"
+		entry.Errors = r.Errors
+	}
+
+	return entry
+}
+
+// importJournal records the outcome of each product as it's imported so an
+// interrupted or partially failed import can be resumed.
+type importJournal struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+// openJournal creates the journal at path, or appends to it when resuming.
+// An incomplete last line, see readJournal, is removed before appending.
+func openJournal(path string, resume bool) (*importJournal, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+
+		if err := truncateIncompleteLine(path); err != nil {
+			return nil, fmt.Errorf("Cannot open journal: %s", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open journal: %s", err)
+	}
+
+	return &importJournal{file: file}, nil
+}
+
+// truncateIncompleteLine removes the text after the last newline in the file at path, if it exists
+func truncateIncompleteLine(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 || data[len(data)-1] == '\n' {
+		return nil
+	}
+
+	return os.Truncate(path, int64(bytes.LastIndexByte(data, '\n')+1))
+}
+
+// record writes the result, safe to call from many goroutines
+func (j *importJournal) record(r importResult) error {
+	line, err := json.Marshal(newJournalEntry(r))
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Cannot write journal: %s", err)
+	}
+
+	return nil
+}
+
+func (j *importJournal) Close() error {
+	return j.file.Close()
+}
+
+// readJournal returns the current status of each row in the journal at path
+func readJournal(path string) (map[int]journalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open journal: %s", err)
+	}
+	defer file.Close()
+
+	entries := map[int]journalEntry{}
+	scanner := bufio.NewScanner(file)
+
+	line := 0
+	// The line that couldn't be parsed, only the last line can be
+	var invalid error
+
+	for scanner.Scan() {
+		line++
+
+		if invalid != nil {
+			return nil, invalid
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// The last line is incomplete if the import was killed while writing it
+			invalid = fmt.Errorf("Invalid journal %s: line %d is not valid JSON: %s", path, line, err)
+			continue
+		}
+
+		if entry.Row == 0 {
+			return nil, fmt.Errorf("Invalid journal %s: line %d has no row", path, line)
+		}
+
+		entries[entry.Row] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Cannot read journal: %s", err)
+	}
+
+	return entries, nil
+}
+
+// pendingProducts returns the products to import given the journal of a
+// previous import: those that have not succeeded or, when retryFailed, only
+// those that failed.
+func pendingProducts(products []importProductInput, journal map[int]journalEntry, retryFailed bool) ([]importProductInput, error) {
+	var pending []importProductInput
+
+	for _, p := range products {
+		entry, ok := journal[p.Row]
+		if ok && entry.Handle != p.Input.Handle {
+			return nil, fmt.Errorf("Journal does not match CSV: row %d is %q in the journal but %q in the CSV", p.Row, entry.Handle, p.Input.Handle)
+		}
+
+		if retryFailed {
+			if ok && entry.Status != "ok" {
+				pending = append(pending, p)
+			}
+		} else if !ok || entry.Status != "ok" {
+			pending = append(pending, p)
+		}
+	}
+
+	return pending, nil
+}
+
+// writeFailedRows copies the header and the rows of the failed products,
+// given by their row, from csvFile to output so they can be fixed and
+// imported again. products must be every product in csvFile.
+func writeFailedRows(csvFile, output string, products []importProductInput, failed map[int]bool) error {
+	in, err := os.Open(csvFile)
+	if err != nil {
+		return fmt.Errorf("Cannot open CSV file: %s", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("Cannot create CSV file: %s", err)
+	}
+	defer out.Close()
+
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(out)
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("Cannot read CSV header: %s", err)
+	}
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("Cannot write CSV header: %s", err)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Cannot read CSV row: %s", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		i := productIndexAt(products, line)
+		if i < 0 || !failed[products[i].Row] {
+			continue
+		}
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("Cannot write CSV row: %s", err)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// productIndexAt returns the index of the product in products, which are in
+// CSV order, whose rows include line; -1 if it's before the first product.
+// A product's rows are from its first row up to the next product's.
+func productIndexAt(products []importProductInput, line int) int {
+	return sort.Search(len(products), func(i int) bool { return products[i].Row > line }) - 1
+}
@@ -0,0 +1,209 @@
+package products
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func journalProducts(handles ...string) []importProductInput {
+	products := make([]importProductInput, len(handles))
+	for i, handle := range handles {
+		products[i].Row = i + 2
+		products[i].Input.Handle = handle
+	}
+
+	return products
+}
+
+func TestJournalRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv.journal")
+
+	journal, err := openJournal(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal.record(importResult{Row: 2, Handle: "hat", ID: "1"})
+	journal.record(importResult{Row: 3, Handle: "scarf", Errors: []string{"Title can't be blank"}})
+	journal.record(importResult{Row: 4, Handle: "sock", Err: errors.New("timeout")})
+	journal.Close()
+
+	// Resuming appends, a row's last entry wins
+	journal, err = openJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal.record(importResult{Row: 3, Handle: "scarf", ID: "2"})
+	journal.Close()
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]journalEntry{
+		2: {Row: 2, Handle: "hat", ID: "1", Status: "ok"},
+		3: {Row: 3, Handle: "scarf", ID: "2", Status: "ok"},
+		4: {Row: 4, Handle: "sock", Status: "error", Errors: []string{"timeout"}},
+	}
+
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+}
+
+func TestReadJournalIncompleteLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv.journal")
+	content := `{"row":2,"handle":"hat","id":"1","status":"ok"}` + "\n" + `{"row":3,"handle":"sc`
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[2].Status != "ok" {
+		t.Errorf("entries = %+v, want only row 2", entries)
+	}
+}
+
+func TestReadJournalInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv.journal")
+	content := `{"row":2,"handle":"hat","id":"1","status":"ok"}` + "\n" + `{"row":3,"handle":"sc` + "\n" + `{"row":4,"handle":"sock","status":"ok"}` + "\n"
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readJournal(path)
+	if err == nil {
+		t.Fatal("err = nil, want error for line 2")
+	}
+
+	want := "Invalid journal " + path + ": line 2 is not valid JSON: unexpected end of JSON input"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestJournalResumeAfterIncompleteLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv.journal")
+	content := `{"row":2,"handle":"hat","id":"1","status":"ok"}` + "\n" + `{"row":3,"handle":"sc`
+
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	journal, err := openJournal(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	journal.record(importResult{Row: 3, Handle: "scarf", ID: "2"})
+	journal.Close()
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int]journalEntry{
+		2: {Row: 2, Handle: "hat", ID: "1", Status: "ok"},
+		3: {Row: 3, Handle: "scarf", ID: "2", Status: "ok"},
+	}
+
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+}
+
+func TestPendingProducts(t *testing.T) {
+	products := journalProducts("hat", "scarf", "sock", "glove")
+	journal := map[int]journalEntry{
+		2: {Row: 2, Handle: "hat", Status: "ok"},
+		3: {Row: 3, Handle: "scarf", Status: "error"},
+		4: {Row: 4, Handle: "sock", Status: "ok"},
+	}
+
+	tests := []struct {
+		retryFailed bool
+		want        []string
+	}{
+		{false, []string{"scarf", "glove"}},
+		{true, []string{"scarf"}},
+	}
+
+	for _, tt := range tests {
+		pending, err := pendingProducts(products, journal, tt.retryFailed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var handles []string
+		for _, p := range pending {
+			handles = append(handles, p.Input.Handle)
+		}
+
+		if !reflect.DeepEqual(handles, tt.want) {
+			t.Errorf("pendingProducts(retryFailed = %v) = %v, want %v", tt.retryFailed, handles, tt.want)
+		}
+	}
+}
+
+func TestPendingProductsJournalMismatch(t *testing.T) {
+	products := journalProducts("hat", "scarf")
+	journal := map[int]journalEntry{3: {Row: 3, Handle: "sock", Status: "ok"}}
+
+	if _, err := pendingProducts(products, journal, false); err == nil {
+		t.Error("pendingProducts with a different CSV did not fail")
+	}
+}
+
+func TestWriteFailedRows(t *testing.T) {
+	content := "handle,title,sku,option1 name,option1 value,body (html)\n" +
+		"hat,Hat,HAT-S,Size,S,\"<p>Warm\nand soft</p>\"\n" +
+		",,HAT-M,,M,\n" +
+		"scarf,Scarf,SCARF,,,\n" +
+		"sock,Sock,SOCK-S,Size,S,\n" +
+		",,SOCK-M,,M,\n"
+
+	csvFile := writeCSV(t, content)
+
+	products, err := parseCSV(csvFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(products) != 3 {
+		t.Fatalf("len(products) = %d, want 3", len(products))
+	}
+
+	output := filepath.Join(t.TempDir(), "failed.csv")
+	failed := map[int]bool{products[0].Row: true, products[2].Row: true}
+
+	if err := writeFailedRows(csvFile, output, products, failed); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "handle,title,sku,option1 name,option1 value,body (html)\n" +
+		"hat,Hat,HAT-S,Size,S,\"<p>Warm\nand soft</p>\"\n" +
+		",,HAT-M,,M,\n" +
+		"sock,Sock,SOCK-S,Size,S,\n" +
+		",,SOCK-M,,M,\n"
+
+	if string(got) != want {
+		t.Errorf("failed CSV = \n%s\nwant\n%s", got, want)
+	}
+}
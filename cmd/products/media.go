@@ -0,0 +1,224 @@
+package products
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+// Staged upload resources by file content type
+var stagedUploadResources = map[string]string{
+	"IMAGE":    "IMAGE",
+	"VIDEO":    "VIDEO",
+	"MODEL_3D": "MODEL_3D",
+}
+
+// isLocalFile reports whether the file's source is a path rather than a URL
+func isLocalFile(source string) bool {
+	return !strings.Contains(source, "://") || strings.HasPrefix(source, "file://")
+}
+
+// mediaURL returns source without its query string, e.g., Shopify's ?v=123
+func mediaURL(source string) string {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" {
+		return source
+	}
+
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// mediaFileName returns the lowercase name of the file at source
+func mediaFileName(source string) string {
+	if isLocalFile(source) {
+		return strings.ToLower(filepath.Base(strings.TrimPrefix(source, "file://")))
+	}
+
+	u, err := url.Parse(source)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(path.Base(u.Path))
+}
+
+// sameMedia reports whether file is the existing media: it's given by the media's ID or has the
+// same URL, ignoring any query string.
+func sameMedia(file fileInput, media gql.ProductMedia) bool {
+	return (file.ID != "" && file.ID == media.ID) || mediaURL(file.OriginalSource) == mediaURL(media.URL)
+}
+
+// sameMediaName reports whether file and the existing media have the same file name. Shopify keeps
+// the names of files it downloads or that are uploaded but different files can have the same name,
+// so this is only used when asked for.
+func sameMediaName(file fileInput, media gql.ProductMedia) bool {
+	if media.ContentType == "EXTERNAL_VIDEO" {
+		return false
+	}
+
+	name := mediaFileName(file.OriginalSource)
+	return name != "" && name != "." && name == mediaFileName(media.URL)
+}
+
+// matchMedia returns, for each file, the index of the existing media that's the same file or -1.
+// See sameMedia. If byName files with the same file name as media that isn't otherwise matched
+// are also the same, see sameMediaName. Each media matches at most one file.
+func matchMedia(existing []gql.ProductMedia, files []fileInput, byName bool) []int {
+	matches := make([]int, len(files))
+	used := make([]bool, len(existing))
+
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	passes := []func(fileInput, gql.ProductMedia) bool{sameMedia}
+	// URLs first so a file with the same name doesn't match another's media
+	if byName {
+		passes = append(passes, sameMediaName)
+	}
+
+	for _, same := range passes {
+		for i, file := range files {
+			if matches[i] >= 0 {
+				continue
+			}
+
+			for j, media := range existing {
+				if used[j] || (file.ContentType != "" && media.ContentType != file.ContentType) {
+					continue
+				}
+
+				if same(file, media) {
+					matches[i] = j
+					used[j] = true
+					break
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// resolveMedia prepares the products' files for productSet. Files a product
+// already has are given by their ID so they're not downloaded or uploaded
+// again, and local files are uploaded using staged uploads. Relative paths
+// are relative to dir. See matchMedia for byName.
+func resolveMedia(shop, token string, products []importProductInput, dir string, byName bool, out io.Writer, options map[string]interface{}) error {
+	var identifiers []gql.ProductIdentifier
+	var indexes []int
+
+	for i, p := range products {
+		if p.Identifier != nil && len(p.Input.Files) > 0 {
+			identifiers = append(identifiers, gql.ProductIdentifier{ID: p.Identifier.ID, Handle: p.Identifier.Handle})
+			indexes = append(indexes, i)
+		}
+	}
+
+	existing := make([][]gql.ProductMedia, len(products))
+	if len(identifiers) > 0 {
+		found, err := gql.FetchProductMedia(shop, token, identifiers, options)
+		if err != nil {
+			return err
+		}
+
+		for i, index := range indexes {
+			existing[index] = found[i]
+		}
+	}
+
+	// Local paths to their staged upload URLs, files are only uploaded once
+	uploads := map[string]string{}
+
+	for i := range products {
+		p := &products[i]
+		if len(p.Input.Files) == 0 {
+			continue
+		}
+
+		resolved := map[string]fileInput{}
+		matches := matchMedia(existing[i], p.Input.Files, byName)
+
+		for j, file := range p.Input.Files {
+			source := file.OriginalSource
+
+			if matches[j] >= 0 {
+				file = fileInput{ID: existing[i][matches[j]].ID, Alt: file.Alt}
+			} else if isLocalFile(source) {
+				filename := strings.TrimPrefix(source, "file://")
+				if !filepath.IsAbs(filename) {
+					filename = filepath.Join(dir, filename)
+				}
+
+				resourceURL, ok := uploads[filename]
+				if !ok {
+					var err error
+					resourceURL, err = uploadMedia(shop, token, filename, file.ContentType, options)
+					if err != nil {
+						return err
+					}
+
+					fmt.Fprintf(out, "Uploaded %s\n", source)
+					uploads[filename] = resourceURL
+				}
+
+				file.OriginalSource = resourceURL
+			}
+
+			p.Input.Files[j] = file
+			resolved[source] = file
+		}
+
+		for j := range p.Input.Variants {
+			if v := &p.Input.Variants[j]; v.File != nil {
+				if file, ok := resolved[v.File.OriginalSource]; ok {
+					v.File = &file
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// uploadMedia uploads the local file using a staged upload and returns the
+// URL to give productSet.
+func uploadMedia(shop, token, filename, contentType string, options map[string]interface{}) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("Cannot read media file: %s", err)
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(filename))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	resource, ok := stagedUploadResources[contentType]
+	if !ok {
+		resource = "FILE"
+	}
+
+	name := filepath.Base(filename)
+
+	target, err := gql.StagedUploadFile(shop, token, resource, name, mimeType, int64(len(data)), options)
+	if err != nil {
+		return "", err
+	}
+
+	if err := uploadFile(target, name, data); err != nil {
+		return "", fmt.Errorf("Cannot upload %s: %s", filename, err)
+	}
+
+	return target.ResourceURL, nil
+}
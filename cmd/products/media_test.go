@@ -0,0 +1,118 @@
+package products
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+func TestMatchMedia(t *testing.T) {
+	existing := []gql.ProductMedia{
+		{ID: "gid://shopify/MediaImage/1", ContentType: "IMAGE", URL: "https://cdn.shopify.com/files/chair.png?v=123"},
+		{ID: "gid://shopify/MediaImage/2", ContentType: "IMAGE", URL: "https://cdn.shopify.com/files/side.png?v=123"},
+		{ID: "gid://shopify/ExternalVideo/3", ContentType: "EXTERNAL_VIDEO", URL: "https://youtu.be/abc123"},
+		{ID: "gid://shopify/Video/4", ContentType: "VIDEO", URL: "https://cdn.shopify.com/videos/spin.mp4"},
+	}
+
+	tests := []struct {
+		name   string
+		files  []fileInput
+		byName bool
+		want   []int
+	}{
+		{"url", []fileInput{{OriginalSource: "https://cdn.shopify.com/files/side.png"}}, false, []int{1}},
+		{"file name", []fileInput{{OriginalSource: "https://example.com/images/CHAIR.png", ContentType: "IMAGE"}}, true, []int{0}},
+		{"file name not by name", []fileInput{{OriginalSource: "https://example.com/images/chair.png", ContentType: "IMAGE"}}, false, []int{-1}},
+		{"local path", []fileInput{{OriginalSource: "images/spin.mp4", ContentType: "VIDEO"}}, true, []int{3}},
+		{"local path not by name", []fileInput{{OriginalSource: "images/spin.mp4", ContentType: "VIDEO"}}, false, []int{-1}},
+		{"id", []fileInput{{ID: "gid://shopify/MediaImage/2"}}, false, []int{1}},
+		{"url before name", []fileInput{{OriginalSource: "https://example.com/side.png"}, {OriginalSource: "https://cdn.shopify.com/files/side.png"}}, true, []int{-1, 1}},
+		{"content type", []fileInput{{OriginalSource: "https://example.com/chair.png", ContentType: "VIDEO"}}, true, []int{-1}},
+		{"external video", []fileInput{{OriginalSource: "https://vimeo.com/abc123", ContentType: "EXTERNAL_VIDEO"}}, true, []int{-1}},
+		{"new", []fileInput{{OriginalSource: "https://example.com/table.png"}}, true, []int{-1}},
+	}
+
+	for _, tt := range tests {
+		if got := matchMedia(existing, tt.files, tt.byName); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: matchMedia = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImportMedia(t *testing.T) {
+	mockShop(t, mock.Seed{})
+
+	image := filepath.Join(t.TempDir(), "chair.png")
+	if err := os.WriteFile(image, []byte("PNG"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "handle,title,option1 name,option1 value,variant sku,image src,image alt text,variant image\n" +
+		"chair,Chair,Color,Black,CHAIR-BLK," + image + ",Chair,https://example.com/black.png\n" +
+		",,,White,CHAIR-WHT,https://youtu.be/abc123,,https://example.com/white.png\n"
+
+	identifiers := []gql.ProductIdentifier{{Handle: "chair"}}
+
+	fetchMedia := func() []gql.ProductMedia {
+		found, err := gql.FetchProductMedia("acme", "shpat_test", identifiers, map[string]interface{}{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return found[0]
+	}
+
+	importCSV(t, csv, "handle")
+	first := fetchMedia()
+
+	if len(first) != 4 {
+		t.Fatalf("len(media) = %d, want 4", len(first))
+	}
+
+	// The local file's media has a different URL so it's uploaded again unless matched by name
+	importCSV(t, csv, "handle")
+	media := fetchMedia()
+
+	if len(media) != len(first) {
+		t.Fatalf("len(media) after reimport = %d, want %d", len(media), len(first))
+	}
+
+	if media[0].ID == first[0].ID {
+		t.Errorf("local file media after reimport = %s, want new media", media[0].ID)
+	}
+
+	if !reflect.DeepEqual(media[1:], first[1:]) {
+		t.Errorf("media after reimport = %+v, want %+v", media[1:], first[1:])
+	}
+
+	importCSVMatchingMedia(t, csv, "handle", true)
+	if found := fetchMedia(); !reflect.DeepEqual(found, media) {
+		t.Errorf("media after reimport matching by name = %+v, want %+v", found, media)
+	}
+
+	wantTypes := []string{"IMAGE", "IMAGE", "EXTERNAL_VIDEO", "IMAGE"}
+	for i, want := range wantTypes {
+		if media[i].ContentType != want {
+			t.Errorf("media %d content type = %s, want %s", i, media[i].ContentType, want)
+		}
+	}
+
+	if got := mediaFileName(media[0].URL); got != "chair.png" || media[0].Alt != "Chair" {
+		t.Errorf("uploaded media = %+v, want chair.png with alt Chair", media[0])
+	}
+
+	products, err := gql.FetchFullProductsByIdentifier("acme", "shpat_test", identifiers, map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{media[1].ID, media[3].ID} {
+		if got := products[0].Variants[i].MediaID; got != want {
+			t.Errorf("variant %d media = %s, want %s", i, got, want)
+		}
+	}
+}
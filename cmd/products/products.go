@@ -3,7 +3,6 @@ package products
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"reflect"
 	"strings"
@@ -177,49 +176,6 @@ func toProductGID(id string) string {
 	return "gid://shopify/Product/" + id
 }
 
-func deleteProducts(c *cli.Context) error {
-	var ids []string
-
-	if c.NArg() > 0 {
-		for i := 0; i < c.NArg(); i++ {
-			ids = append(ids, c.Args().Get(i))
-		}
-	} else {
-		data, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			return fmt.Errorf("Cannot read from stdin: %s", err)
-		}
-
-		for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
-			if line != "" {
-				ids = append(ids, line)
-			}
-		}
-	}
-
-	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
-	options := map[string]interface{}{}
-
-	for _, id := range ids {
-		gid := toProductGID(id)
-		result, err := gql.ProductDelete(shop, token, gid, options)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error deleting product %s: %s\n", id, err)
-			continue
-		}
-
-		if len(result.UserErrors) > 0 {
-			fmt.Fprintf(os.Stderr, "Error deleting product %s: %s\n", id, strings.Join(result.UserErrors, ", "))
-			continue
-		}
-
-		fmt.Printf("Deleted %s\n", result.DeletedProductID)
-	}
-
-	return nil
-}
-
 func listProducts(c *cli.Context) error {
 	ids, skus, err := cmd.ParseIDArgs(c.Args().Slice(), "a product id")
 	if err != nil {
@@ -234,7 +190,12 @@ func listProducts(c *cli.Context) error {
 
 	shop := c.String("shop")
 	options := map[string]interface{}{}
-	products, err := gql.FetchProducts(shop, cmd.LookupAccessToken(shop, c.String("access-token")), ids, skus, c.String("status"), int(c.Int64("limit")), options)
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	products, err := gql.FetchProducts(shop, token, ids, skus, c.String("status"), int(c.Int64("limit")), options)
 	if err != nil {
 		return err
 	}
@@ -259,7 +220,10 @@ func inventoryProducts(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{}
 
 	// Resolve sku: arguments to product IDs first; the inventory query is per product.
@@ -322,12 +286,34 @@ func init() {
 		apiVersionFlag,
 	}
 
+	bulkExportFlag := &cli.BoolFlag{
+		Name:    "bulk",
+		Aliases: []string{"b"},
+		Usage:   "Fetch the products with a bulk operation; faster for large shops",
+	}
+
 	identifyByFlag := &cli.StringFlag{
 		Name:    "identify-by",
 		Aliases: []string{"i"},
 		Usage:   "Identifier property for productSet: 'id' or 'handle'",
 	}
 
+	dryRunFlag := &cli.BoolFlag{
+		Name:    "dry-run",
+		Aliases: []string{"n"},
+		Usage:   "Output what would be created or updated without importing anything",
+	}
+
+	matchMediaByNameFlag := &cli.BoolFlag{
+		Name:  "match-media-by-name",
+		Usage: "Treat files with the same name as a product's media as already uploaded",
+	}
+
+	formatFlag := &cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the import file: csv, json, jsonl, or yaml, defaults to the file's extension",
+	}
+
 	Cmd = cli.Command{
 		Name:    "products",
 		Aliases: []string{"p"},
@@ -352,19 +338,55 @@ func init() {
 			{
 				Name:        "delete",
 				Aliases:     []string{"d"},
-				Usage:       "Delete products by ID",
-				ArgsUsage:   "[ID [ID ...]]",
-				Description: "If IDs are not given they're read from stdin one per line",
-				Flags:       append(cmd.Flags, apiVersionFlag),
-				Action:      deleteProducts,
+				Usage:       "Delete or archive products by ID, SKU, handle, search query, or CSV file",
+				ArgsUsage:   "[ID|sku:VALUE|handle:VALUE [ID|sku:VALUE|handle:VALUE ...]]",
+				Description: "The products are listed and must be confirmed before they're deleted. If no products, --query, or --from-csv are given they're read from stdin one per line, which requires --yes",
+				Flags: append(cmd.Flags,
+					&cli.StringFlag{
+						Name:    "query",
+						Aliases: []string{"q"},
+						Usage:   "Delete the products matching the search query, e.g., 'vendor:Acme AND status:draft'",
+					},
+					&cli.StringFlag{
+						Name:    "from-csv",
+						Aliases: []string{"f"},
+						Usage:   "Delete the products in the CSV file's Product ID, ID, Handle, Variant SKU, or SKU column",
+					},
+					&cli.BoolFlag{
+						Name:    "archive",
+						Aliases: []string{"a"},
+						Usage:   "Set the products' status to archived instead of deleting them",
+					},
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Don't ask for confirmation",
+					},
+					&cli.IntFlag{
+						Name:    "parallel",
+						Aliases: []string{"p"},
+						Value:   5,
+						Usage:   "Number of parallel API calls to make",
+					},
+					&cli.BoolFlag{
+						Name:    "json",
+						Aliases: []string{"j"},
+						Usage:   "Output the results in JSON format",
+					},
+					apiVersionFlag,
+				),
+				Action: deleteProducts,
 			},
 			{
 				Name:      "import",
 				Aliases:   []string{"i"},
-				Usage:     "Import products synchronously from a Shopify CSV file",
+				Usage:     "Import products synchronously from a Shopify CSV, JSON, JSONL, or YAML file",
 				ArgsUsage: "products.csv",
 				Flags: append(cmd.Flags,
 					identifyByFlag,
+					dryRunFlag,
+					formatFlag,
+					matchMediaByNameFlag,
 					&cli.IntFlag{
 						Name:    "parallel",
 						Aliases: []string{"p"},
@@ -376,10 +398,37 @@ func init() {
 						Aliases: []string{"j"},
 						Usage:   "Output the results in JSON format",
 					},
+					&cli.StringFlag{
+						Name:  "journal",
+						Usage: "File to record each product's result in, defaults to the --resume journal or the CSV file name with a .journal extension",
+					},
+					&cli.StringFlag{
+						Name:    "resume",
+						Aliases: []string{"r"},
+						Usage:   "Skip products that were imported successfully according to the given journal",
+					},
+					&cli.BoolFlag{
+						Name:  "retry-failed",
+						Usage: "Only import products that failed according to the --resume journal",
+					},
+					&cli.StringFlag{
+						Name:    "failed-csv",
+						Aliases: []string{"f"},
+						Usage:   "Write the rows of products that failed to import to the given CSV file",
+					},
 					apiVersionFlag,
 				),
 				Action: syncImportProducts,
 			},
+			{
+				Name:        "validate",
+				Aliases:     []string{"v"},
+				Usage:       "Check a product import file for errors without importing it",
+				ArgsUsage:   "products.csv",
+				Description: "Checks for duplicate handles and SKUs, option and variant limits, invalid prices and weights, unknown locations, and metafields that don't match the shop's definitions",
+				Flags:       append(cmd.Flags, formatFlag, apiVersionFlag),
+				Action:      validateProducts,
+			},
 			{
 				Name:    "export",
 				Aliases: []string{"e", "x"},
@@ -391,6 +440,7 @@ func init() {
 						Usage:   "Export product and variant IDs, and other identifiers, to a CSV or JSON file",
 						Flags: append(cmd.Flags,
 							apiVersionFlag,
+							bulkExportFlag,
 							&cli.StringFlag{
 								Name:    "status",
 								Aliases: []string{"s"},
@@ -414,6 +464,7 @@ func init() {
 						Usage:   "Export inventory quantities by variant and location to a CSV file",
 						Flags: append(cmd.Flags,
 							apiVersionFlag,
+							bulkExportFlag,
 							&cli.StringFlag{
 								Name:    "identify-by",
 								Aliases: []string{"i"},
@@ -422,20 +473,50 @@ func init() {
 						),
 						Action: export.Inventory,
 					},
+					{
+						Name:    "csv",
+						Aliases: []string{"c"},
+						Usage:   "Export products, variants, inventory, images, and metafields to a CSV file that can be imported",
+						Flags: append(cmd.Flags,
+							apiVersionFlag,
+							bulkExportFlag,
+							&cli.StringFlag{
+								Name:    "status",
+								Aliases: []string{"s"},
+							},
+						),
+						Action: export.CSV,
+					},
 				},
 			},
 			{
 				Name:    "bulk",
 				Aliases: []string{"b"},
-				Usage:   "Import products from a Shopify CSV file using the Bulk API",
+				Usage:   "Import products from a Shopify CSV, JSON, JSONL, or YAML file using the Bulk API",
 				Subcommands: []*cli.Command{
 					{
 						Name:      "import",
 						Aliases:   []string{"i"},
-						Usage:     "Import a Shopify CSV file",
+						Usage:     "Import a Shopify CSV, JSON, JSONL, or YAML file",
 						ArgsUsage: "products.csv",
-						Flags:     append(cmd.Flags, identifyByFlag, apiVersionFlag),
-						Action:    importProducts,
+						Flags: append(cmd.Flags,
+							identifyByFlag,
+							dryRunFlag,
+							formatFlag,
+							matchMediaByNameFlag,
+							&cli.BoolFlag{
+								Name:    "wait",
+								Aliases: []string{"w"},
+								Usage:   "Wait for the bulk operation to finish and output the result of each product",
+							},
+							&cli.BoolFlag{
+								Name:    "json",
+								Aliases: []string{"j"},
+								Usage:   "Output the results in JSON format; requires --wait or --dry-run",
+							},
+							apiVersionFlag,
+						),
+						Action: importProducts,
 					},
 					{
 						Name:      "status",
@@ -0,0 +1,68 @@
+package products
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+// Publication used by the CSV Published column
+const onlineStorePublication = "Online Store"
+
+// resolvePublications sets the IDs of each product's Publications, which are
+// matched by name, ignoring case if there's no exact match.
+func resolvePublications(shop, token string, products []importProductInput, options map[string]interface{}) error {
+	var publications map[string]string
+
+	for i := range products {
+		p := &products[i]
+		p.publicationIDs = nil
+
+		for _, name := range p.Publications {
+			if publications == nil {
+				var err error
+				publications, err = gql.FetchPublications(shop, token, options)
+				if err != nil {
+					return err
+				}
+			}
+
+			id, ok := publications[name]
+			if !ok {
+				for other, otherID := range publications {
+					if strings.EqualFold(other, name) {
+						id, ok = otherID, true
+						break
+					}
+				}
+			}
+
+			if !ok {
+				return fmt.Errorf("Unknown publication %q", name)
+			}
+
+			p.publicationIDs = appendUnique(p.publicationIDs, id)
+		}
+	}
+
+	return nil
+}
+
+// publishProduct publishes the imported product to its publications, adding
+// any errors to its result.
+func publishProduct(shop, token string, product importProductInput, result *importResult, options map[string]interface{}) {
+	if len(product.publicationIDs) == 0 || result.ID == "" || result.Err != nil || len(result.Errors) > 0 {
+		return
+	}
+
+	userErrors, err := gql.PublishProduct(shop, token, "gid://shopify/Product/"+result.ID, product.publicationIDs, options)
+	if err != nil {
+		result.Err = err
+		return
+	}
+
+	for _, ue := range userErrors {
+		result.Errors = append(result.Errors, "Cannot publish: "+ue.Message)
+	}
+}
@@ -0,0 +1,63 @@
+package products
+
+import (
+	"testing"
+
+	gqlclient "github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+func TestImportPublications(t *testing.T) {
+	mockShop(t, mock.Seed{Publications: []string{"Point of Sale", "Shop"}})
+
+	importCSV(t, "handle,title,publications,published\nbeanie,Beanie,point of sale,TRUE\ncap,Cap,,\n", "")
+
+	publications, err := gql.FetchPublications("acme", "shpat_test", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := gqlclient.NewClient("acme", "shpat_test")
+
+	tests := []struct {
+		handle      string
+		publication string
+		want        bool
+	}{
+		{"beanie", "Point of Sale", true},
+		{"beanie", "Online Store", true},
+		{"beanie", "Shop", false},
+		{"cap", "Online Store", false},
+	}
+
+	for _, tt := range tests {
+		var response struct {
+			ProductByIdentifier struct {
+				PublishedOnPublication bool `json:"publishedOnPublication"`
+			} `json:"productByIdentifier"`
+		}
+
+		query := `query($handle: String!, $id: ID!) { productByIdentifier(identifier: { handle: $handle }) { publishedOnPublication(publicationId: $id) } }`
+		vars := map[string]interface{}{"handle": tt.handle, "id": publications[tt.publication]}
+		if err := client.ExecuteInto(query, vars, &response); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := response.ProductByIdentifier.PublishedOnPublication; got != tt.want {
+			t.Errorf("%s published on %s = %v, want %v", tt.handle, tt.publication, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePublicationsUnknown(t *testing.T) {
+	mockShop(t, mock.Seed{})
+
+	products := []importProductInput{{Input: importProduct{Handle: "beanie"}, Publications: []string{"Online Store", "Wholesale"}}}
+
+	err := resolvePublications("acme", "shpat_test", products, map[string]interface{}{})
+	if err == nil || err.Error() != `Unknown publication "Wholesale"` {
+		t.Errorf("error = %v, want unknown publication", err)
+	}
+}
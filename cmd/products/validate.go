@@ -0,0 +1,427 @@
+package products
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/metafield"
+)
+
+// Shopify's limits for products created with productSet
+const (
+	maxProductOptions  = 3
+	maxProductVariants = 2048
+)
+
+var weightUnits = map[string]bool{"g": true, "kg": true, "lb": true, "oz": true}
+
+type validationError struct {
+	Row     int
+	Handle  string
+	Message string
+}
+
+// productValidator checks products against each other and the shop
+type productValidator struct {
+	locations map[string]bool
+	// Owner type to namespace.key to definition
+	definitions map[string]map[string]gql.MetafieldDefinition
+	handles     map[string]int
+	skus        map[string]int
+	errors      []validationError
+}
+
+func newProductValidator(locations map[string]string, definitions map[string][]gql.MetafieldDefinition) *productValidator {
+	v := &productValidator{
+		locations:   map[string]bool{},
+		definitions: map[string]map[string]gql.MetafieldDefinition{},
+		handles:     map[string]int{},
+		skus:        map[string]int{},
+	}
+
+	for name := range locations {
+		v.locations[name] = true
+	}
+
+	for ownerType, list := range definitions {
+		v.definitions[ownerType] = map[string]gql.MetafieldDefinition{}
+		for _, d := range list {
+			v.definitions[ownerType][d.Namespace+"."+d.Key] = d
+		}
+	}
+
+	return v
+}
+
+func (v *productValidator) errorf(row int, handle, format string, args ...interface{}) {
+	v.errors = append(v.errors, validationError{Row: row, Handle: handle, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks products parsed without locations, so that they have location names instead of IDs
+func (v *productValidator) validate(products []importProductInput) []validationError {
+	for _, p := range products {
+		v.validateProduct(p)
+	}
+
+	sort.SliceStable(v.errors, func(i, j int) bool { return v.errors[i].Row < v.errors[j].Row })
+
+	return v.errors
+}
+
+func (v *productValidator) validateProduct(p importProductInput) {
+	input := p.Input
+	handle := input.Handle
+
+	if handle != "" {
+		if row, ok := v.handles[handle]; ok {
+			v.errorf(p.Row, handle, "Duplicate handle, first used on row %d", row)
+		} else {
+			v.handles[handle] = p.Row
+		}
+	}
+
+	switch input.Status {
+	case "", "ACTIVE", "ARCHIVED", "DRAFT":
+	default:
+		v.errorf(p.Row, handle, "Invalid status %q: must be active, archived, or draft", input.Status)
+	}
+
+	if len(input.ProductOptions) > maxProductOptions {
+		v.errorf(p.Row, handle, "Product has %d options, the limit is %d", len(input.ProductOptions), maxProductOptions)
+	}
+
+	if len(input.Variants) > maxProductVariants {
+		v.errorf(p.Row, handle, "Product has %d variants, the limit is %d", len(input.Variants), maxProductVariants)
+	}
+
+	combinations := map[string]int{}
+
+	for i, variant := range input.Variants {
+		row := p.variantRow(i)
+
+		if variant.SKU != "" {
+			if first, ok := v.skus[variant.SKU]; ok {
+				v.errorf(row, handle, "Duplicate SKU %q, first used on row %d", variant.SKU, first)
+			} else {
+				v.skus[variant.SKU] = row
+			}
+		}
+
+		values := map[string]string{}
+		for _, option := range variant.OptionValues {
+			values[option.OptionName] = option.Name
+		}
+
+		var combination []string
+		for _, option := range input.ProductOptions {
+			value, ok := values[option.Name]
+			if !ok {
+				v.errorf(row, handle, "Variant has no value for option %q", option.Name)
+			}
+
+			combination = append(combination, value)
+		}
+
+		key := strings.Join(combination, " / ")
+		if first, ok := combinations[key]; ok {
+			v.errorf(row, handle, "Duplicate variant %q, first on row %d", key, first)
+		} else {
+			combinations[key] = row
+		}
+
+		v.validatePrice(row, handle, "price", variant.Price)
+		v.validatePrice(row, handle, "compare at price", variant.CompareAtPrice)
+		if variant.InventoryItem != nil {
+			v.validatePrice(row, handle, "unit cost", variant.InventoryItem.Cost)
+		}
+
+		switch variant.InventoryPolicy {
+		case "", "CONTINUE", "DENY":
+		default:
+			v.errorf(row, handle, "Invalid inventory policy %q: must be continue or deny", variant.InventoryPolicy)
+		}
+
+		for j, quantity := range variant.InventoryQuantities {
+			if !v.locations[quantity.LocationID] {
+				v.errorf(p.quantityRow(i, j), handle, "Unknown location %q", quantity.LocationID)
+			}
+		}
+
+		for j, mf := range variant.Metafields {
+			v.validateMetafield(p.variantMetafieldRow(i, j), handle, "PRODUCTVARIANT", mf)
+		}
+	}
+
+	for i, mf := range input.Metafields {
+		v.validateMetafield(p.metafieldRow(i), handle, "PRODUCT", mf)
+	}
+}
+
+func (v *productValidator) validatePrice(row int, handle, name, value string) {
+	if value == "" {
+		return
+	}
+
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		v.errorf(row, handle, "Invalid %s %q", name, value)
+	}
+}
+
+func (v *productValidator) validateMetafield(row int, handle, ownerType string, mf metafieldInput) {
+	if mf.Namespace == "" || mf.Key == "" {
+		v.errorf(row, handle, "Metafield requires a namespace and key")
+		return
+	}
+
+	name := mf.Namespace + "." + mf.Key
+	valueType := mf.Type
+
+	definition, defined := v.definitions[ownerType][name]
+	if defined {
+		if valueType != "" && valueType != definition.Type {
+			v.errorf(row, handle, "Metafield %s has type %s but its definition's type is %s", name, valueType, definition.Type)
+			return
+		}
+
+		valueType = definition.Type
+	} else if valueType == "" {
+		v.errorf(row, handle, "Metafield %s requires a type, it has no definition", name)
+		return
+	}
+
+	if err := metafield.ValidateValue(valueType, mf.Value); err != nil {
+		v.errorf(row, handle, "Invalid value for metafield %s: %s", name, err)
+		return
+	}
+
+	if defined {
+		if err := validateMetafieldValidations(valueType, mf.Value, definition.Validations); err != nil {
+			v.errorf(row, handle, "Invalid value for metafield %s: %s", name, err)
+		}
+	}
+}
+
+// validateMetafieldValidations checks value against the definition's
+// choices, regex, min, and max validations. Others are left to Shopify.
+func validateMetafieldValidations(valueType, value string, validations []gql.MetafieldValidation) error {
+	values := []string{value}
+	if strings.HasPrefix(valueType, "list.") {
+		// Already checked to be a list
+		var elements []json.RawMessage
+		json.Unmarshal([]byte(value), &elements)
+
+		values = nil
+		for _, raw := range elements {
+			element := string(raw)
+
+			var s string
+			if json.Unmarshal(raw, &s) == nil {
+				element = s
+			}
+
+			values = append(values, element)
+		}
+
+		valueType = strings.TrimPrefix(valueType, "list.")
+	}
+
+	numeric := valueType == "number_integer" || valueType == "number_decimal"
+	text := valueType == "single_line_text_field" || valueType == "multi_line_text_field"
+
+	for _, validation := range validations {
+		for _, value := range values {
+			switch validation.Name {
+			case "choices":
+				var choices []string
+				if json.Unmarshal([]byte(validation.Value), &choices) != nil {
+					continue
+				}
+
+				found := false
+				for _, choice := range choices {
+					if choice == value {
+						found = true
+						break
+					}
+				}
+
+				if !found {
+					return fmt.Errorf("%q is not one of %s", value, strings.Join(choices, ", "))
+				}
+			case "regex":
+				re, err := regexp.Compile(validation.Value)
+				if err == nil && !re.MatchString(value) {
+					return fmt.Errorf("%q does not match %s", value, validation.Value)
+				}
+			case "min", "max":
+				limit, err := strconv.ParseFloat(validation.Value, 64)
+				if err != nil {
+					continue
+				}
+
+				var n float64
+				if numeric {
+					n, _ = strconv.ParseFloat(value, 64)
+				} else if text {
+					n = float64(utf8.RuneCountInString(value))
+				} else {
+					continue
+				}
+
+				if validation.Name == "min" && n < limit {
+					return fmt.Errorf("%q is less than the minimum of %s", value, validation.Value)
+				}
+
+				if validation.Name == "max" && n > limit {
+					return fmt.Errorf("%q is more than the maximum of %s", value, validation.Value)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCSVWeights checks the weight columns, which aren't imported and so aren't parsed
+func validateCSVWeights(filename string, products []importProductInput) ([]validationError, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open CSV file: %s", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read CSV header: %s", err)
+	}
+
+	ci := buildColumnIndex(header)
+
+	var errors []validationError
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Cannot read CSV row: %s", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		var handle string
+		if i := productIndexAt(products, line); i >= 0 {
+			handle = products[i].Input.Handle
+		}
+
+		for _, column := range []string{"variant grams", "variant weight"} {
+			idx, ok := ci[column]
+			if !ok {
+				continue
+			}
+
+			if value := colVal(row, idx); value != "" {
+				if weight, err := strconv.ParseFloat(value, 64); err != nil || weight < 0 {
+					errors = append(errors, validationError{Row: line, Handle: handle, Message: fmt.Sprintf("Invalid %s %q", column, value)})
+				}
+			}
+		}
+
+		if idx, ok := ci["variant weight unit"]; ok {
+			if unit := colVal(row, idx); unit != "" && !weightUnits[strings.ToLower(unit)] {
+				errors = append(errors, validationError{Row: line, Handle: handle, Message: fmt.Sprintf("Invalid weight unit %q: must be g, kg, lb, or oz", unit)})
+			}
+		}
+	}
+
+	return errors, nil
+}
+
+func validateProducts(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return fmt.Errorf("File path required")
+	}
+
+	filename := c.Args().First()
+	format, err := productFileFormat(filename, c.String("format"))
+	if err != nil {
+		return err
+	}
+
+	shop := c.String("shop")
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+	options := map[string]interface{}{}
+
+	// Parsed without locations so that all unknown ones are reported
+	products, err := parseProductFile(filename, format, nil)
+	if err != nil {
+		return err
+	}
+
+	if len(products) == 0 {
+		return fmt.Errorf("No products found in %s", filename)
+	}
+
+	locations, err := gql.FetchLocations(shop, token, options)
+	if err != nil {
+		return err
+	}
+
+	definitions := map[string][]gql.MetafieldDefinition{}
+	for _, ownerType := range []string{"PRODUCT", "PRODUCTVARIANT"} {
+		definitions[ownerType], err = gql.FetchMetafieldDefinitions(shop, token, ownerType, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	errors := newProductValidator(locations, definitions).validate(products)
+
+	if format == formatCSV {
+		weightErrors, err := validateCSVWeights(filename, products)
+		if err != nil {
+			return err
+		}
+
+		errors = append(errors, weightErrors...)
+		sort.SliceStable(errors, func(i, j int) bool { return errors[i].Row < errors[j].Row })
+	}
+
+	if len(errors) == 0 {
+		fmt.Printf("%d products are valid\n", len(products))
+		return nil
+	}
+
+	t := tabby.New()
+	t.AddHeader("Row", "Handle", "Error")
+
+	for _, e := range errors {
+		t.AddLine(e.Row, e.Handle, e.Message)
+	}
+
+	t.Print()
+
+	return cli.Exit(fmt.Sprintf("\n%d errors found in %d products", len(errors), len(products)), 1)
+}
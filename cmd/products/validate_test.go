@@ -0,0 +1,133 @@
+package products
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/products/gql"
+)
+
+func TestValidateProducts(t *testing.T) {
+	mockShop(t, mock.Seed{
+		Locations: []string{"Warehouse"},
+		MetafieldDefinitions: []map[string]interface{}{
+			{"ownerType": "PRODUCT", "namespace": "custom", "key": "material", "type": "single_line_text_field",
+				"validations": []interface{}{map[string]interface{}{"name": "choices", "value": `["wool","cotton"]`}}},
+			{"ownerType": "PRODUCTVARIANT", "namespace": "custom", "key": "size_cm", "type": "number_integer"},
+		},
+	})
+
+	content := "handle,title,status,option1 name,option1 value,variant sku,variant price,variant compare at price,location,available,variant grams,variant weight unit,metafield owner,metafield namespace,metafield key,metafield type,metafield value\n" +
+		"hat,Hat,active,Size,S,HAT-S,10.00,,Main,1,100,g,,,,,\n" +
+		",,,,,,,,Mars,2,,,,,,,\n" +
+		",,,,M,HAT-M,-1,abc,,,heavy,stone,,,,,\n" +
+		",,,,,,,,,,,,Variant,custom,size_cm,,big\n" +
+		",,,,M,HAT-S,10.00,,,,,,,,,,\n" +
+		",,,,,,,,,,,,Product,custom,material,,silk\n" +
+		",,,,,,,,,,,,Product,custom,care,,Hand wash\n" +
+		",,,,,,,,,,,,Product,custom,since,date,2024-13-01\n" +
+		"hat,Hat Again,bogus,,,,,,,,,,,,,,\n"
+
+	filename := writeCSV(t, content)
+
+	products, err := parseCSV(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := map[string]interface{}{}
+
+	locations, err := gql.FetchLocations("acme", "shpat_test", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	definitions := map[string][]gql.MetafieldDefinition{}
+	for _, ownerType := range []string{"PRODUCT", "PRODUCTVARIANT"} {
+		definitions[ownerType], err = gql.FetchMetafieldDefinitions("acme", "shpat_test", ownerType, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := newProductValidator(locations, definitions).validate(products)
+
+	weightErrors, err := validateCSVWeights(filename, products)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = append(got, weightErrors...)
+
+	want := []validationError{
+		{3, "hat", `Unknown location "Mars"`},
+		{4, "hat", `Invalid price "-1"`},
+		{4, "hat", `Invalid compare at price "abc"`},
+		{5, "hat", `Invalid value for metafield custom.size_cm: must be an integer`},
+		{6, "hat", `Duplicate SKU "HAT-S", first used on row 2`},
+		{6, "hat", `Duplicate variant "M", first on row 4`},
+		{7, "hat", `Invalid value for metafield custom.material: "silk" is not one of wool, cotton`},
+		{8, "hat", `Metafield custom.care requires a type, it has no definition`},
+		{9, "hat", `Invalid value for metafield custom.since: must be a date in YYYY-MM-DD format`},
+		{10, "hat", `Duplicate handle, first used on row 2`},
+		{10, "hat", `Invalid status "BOGUS": must be active, archived, or draft`},
+		{4, "hat", `Invalid variant grams "heavy"`},
+		{4, "hat", `Invalid weight unit "stone": must be g, kg, lb, or oz`},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("errors = \n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestValidateProductsLimits(t *testing.T) {
+	var variants []importVariant
+	for i := 0; i <= maxProductVariants; i++ {
+		variants = append(variants, importVariant{OptionValues: []variantOptionValue{{OptionName: "Size", Name: string(rune('A' + i%26))}}})
+	}
+
+	products := []importProductInput{
+		{Row: 2, Input: importProduct{
+			Handle:         "hat",
+			ProductOptions: []optionCreateInput{{Name: "Size"}, {Name: "Color"}, {Name: "Fit"}, {Name: "Brim"}},
+		}},
+		{Row: 3, Input: importProduct{Handle: "scarf", ProductOptions: []optionCreateInput{{Name: "Size"}}, Variants: variants}},
+	}
+
+	var messages []string
+	for _, e := range newProductValidator(nil, nil).validate(products) {
+		if e.Message == "Product has 4 options, the limit is 3" || e.Message == "Product has 2049 variants, the limit is 2048" {
+			messages = append(messages, e.Message)
+		}
+	}
+
+	if len(messages) != 2 {
+		t.Errorf("limit errors = %v, want options and variants", messages)
+	}
+}
+
+func TestValidateMetafieldValidations(t *testing.T) {
+	tests := []struct {
+		valueType   string
+		value       string
+		validations []gql.MetafieldValidation
+		valid       bool
+	}{
+		{"number_integer", "5", []gql.MetafieldValidation{{Name: "min", Value: "1"}, {Name: "max", Value: "10"}}, true},
+		{"number_integer", "11", []gql.MetafieldValidation{{Name: "min", Value: "1"}, {Name: "max", Value: "10"}}, false},
+		{"single_line_text_field", "abcdef", []gql.MetafieldValidation{{Name: "max", Value: "5"}}, false},
+		{"single_line_text_field", "ABC-1", []gql.MetafieldValidation{{Name: "regex", Value: `^[A-Z]+-\d$`}}, true},
+		{"single_line_text_field", "abc", []gql.MetafieldValidation{{Name: "regex", Value: `^[A-Z]+-\d$`}}, false},
+		{"list.single_line_text_field", `["wool", "silk"]`, []gql.MetafieldValidation{{Name: "choices", Value: `["wool", "cotton"]`}}, false},
+		{"list.single_line_text_field", `["wool"]`, []gql.MetafieldValidation{{Name: "choices", Value: `["wool", "cotton"]`}}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateMetafieldValidations(tt.valueType, tt.value, tt.validations)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateMetafieldValidations(%q, %q, %v) = %v, want valid %v", tt.valueType, tt.value, tt.validations, err, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package profile
+
+import (
+	"fmt"
+
+	"github.com/cheynewallace/tabby"
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+)
+
+var Cmd cli.Command
+
+func listAction(c *cli.Context) error {
+	config, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	current := currentProfileName(c, config)
+
+	t := tabby.New()
+	t.AddHeader("", "Name", "Shop", "API Version", "Token", "Read-Only")
+
+	for _, name := range config.ProfileNames() {
+		profile := config.Profiles[name]
+
+		marker := ""
+		if name == current {
+			marker = "*"
+		}
+
+		token := ""
+		if profile.AccessTokenCommand != "" {
+			token = "command"
+		} else if profile.AccessToken != "" {
+			token = "yes"
+		}
+
+		readOnly := ""
+		if profile.ReadOnly {
+			readOnly = "yes"
+		}
+
+		t.AddLine(marker, name, profile.Shop, profile.APIVersion, token, readOnly)
+	}
+
+	t.Print()
+
+	return nil
+}
+
+func addAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("You must supply a profile name")
+	}
+
+	if c.String("access-token") != "" && c.String("access-token-command") != "" {
+		return fmt.Errorf("Only one of --access-token or --access-token-command can be given")
+	}
+
+	config, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	name := c.Args().Get(0)
+	if _, ok := config.Profiles[name]; ok && !c.Bool("force") {
+		return fmt.Errorf("Profile '%s' already exists, use --force to replace it", name)
+	}
+
+	config.Profiles[name] = &cmd.Profile{
+		Shop:               c.String("shop"),
+		AccessToken:        c.String("access-token"),
+		AccessTokenCommand: c.String("access-token-command"),
+		APIVersion:         c.String("api-version"),
+		ReadOnly:           c.Bool("read-only"),
+	}
+
+	if config.Default == "" || c.Bool("default") {
+		config.Default = name
+	}
+
+	return config.Save()
+}
+
+func removeAction(c *cli.Context) error {
+	if c.NArg() == 0 {
+		return fmt.Errorf("You must supply a profile name")
+	}
+
+	config, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range c.Args().Slice() {
+		if _, ok := config.Profiles[name]; !ok {
+			return fmt.Errorf("Profile '%s' not found", name)
+		}
+
+		delete(config.Profiles, name)
+
+		if config.Default == name {
+			config.Default = ""
+		}
+	}
+
+	return config.Save()
+}
+
+func currentAction(c *cli.Context) error {
+	config, err := cmd.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() == 0 {
+		name := currentProfileName(c, config)
+		if name == "" {
+			return fmt.Errorf("No profile selected")
+		}
+
+		fmt.Println(name)
+		return nil
+	}
+
+	name := c.Args().Get(0)
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("Profile '%s' not found", name)
+	}
+
+	config.Default = name
+
+	return config.Save()
+}
+
+// currentProfileName returns the name given by --profile or, if not given, the
+// config file's default.
+func currentProfileName(c *cli.Context, config *cmd.Config) string {
+	if name := c.String(cmd.ProfileFlag.Name); name != "" {
+		return name
+	}
+
+	return config.Default
+}
+
+func init() {
+	Cmd = cli.Command{
+		Name:    "profile",
+		Aliases: []string{"profiles", "pr"},
+		Usage:   "Manage shop profiles in the config file",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "ls",
+				Usage:  "List profiles, the current one is marked with a *",
+				Action: listAction,
+			},
+			{
+				Name:      "add",
+				Usage:     "Add a profile",
+				ArgsUsage: "NAME",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "shop",
+						Usage:    "Shopify domain or shop name",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "access-token",
+						Usage: "Shopify access token for shop",
+					},
+					&cli.StringFlag{
+						Name:  "access-token-command",
+						Usage: "Command to run to get the access token; it's given the shop as its argument",
+					},
+					&cli.StringFlag{
+						Name:  "api-version",
+						Usage: "API version to use; default is a versionless call",
+					},
+					&cli.BoolFlag{
+						Name:  "read-only",
+						Usage: "Do not allow mutations to be executed",
+					},
+					&cli.BoolFlag{
+						Name:  "default",
+						Usage: "Make this the default profile",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Replace the profile if it exists",
+					},
+				},
+				Action: addAction,
+			},
+			{
+				Name:      "rm",
+				Usage:     "Remove profiles",
+				ArgsUsage: "NAME [NAME ...]",
+				Action:    removeAction,
+			},
+			{
+				Name:      "current",
+				Usage:     "Output the current profile or, if NAME is given, make it the default",
+				ArgsUsage: "[NAME]",
+				Action:    currentAction,
+			},
+		},
+	}
+}
@@ -0,0 +1,342 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/metafields"
+	metaobjects "github.com/ScreenStaring/shopify-dev-tools/cmd/metaobjects/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const (
+	formatJSON = "json"
+	formatYAML = "yaml"
+)
+
+// Validations whose values are metaobject definition GIDs, a JSON list of them for the latter.
+// In schema files the definitions' types are used instead, as GIDs differ between shops.
+const (
+	referenceValidation  = "metaobject_definition_id"
+	referencesValidation = "metaobject_definition_ids"
+)
+
+// Metafield owner types dumped when none are given
+var defaultOwnerTypes = []string{
+	"PRODUCT",
+	"PRODUCTVARIANT",
+	"COLLECTION",
+	"CUSTOMER",
+	"ORDER",
+	"DRAFTORDER",
+	"COMPANY",
+	"COMPANY_LOCATION",
+	"LOCATION",
+	"MARKET",
+	"PAGE",
+	"BLOG",
+	"ARTICLE",
+	"SHOP",
+}
+
+// Schema is the metaobject and metafield definitions in a schema file
+type Schema struct {
+	MetaobjectDefinitions []metaobjectDocument            `json:"metaobjectDefinitions" yaml:"metaobjectDefinitions"`
+	MetafieldDefinitions  []metafields.DefinitionDocument `json:"metafieldDefinitions" yaml:"metafieldDefinitions"`
+}
+
+type metaobjectDocument struct {
+	Type           string                    `json:"type" yaml:"type"`
+	Name           string                    `json:"name" yaml:"name"`
+	Description    string                    `json:"description,omitempty" yaml:"description,omitempty"`
+	DisplayNameKey string                    `json:"displayNameKey,omitempty" yaml:"displayNameKey,omitempty"`
+	Fields         []metaobjectFieldDocument `json:"fields" yaml:"fields"`
+}
+
+type metaobjectFieldDocument struct {
+	Key         string                           `json:"key" yaml:"key"`
+	Name        string                           `json:"name" yaml:"name"`
+	Description string                           `json:"description,omitempty" yaml:"description,omitempty"`
+	Type        string                           `json:"type" yaml:"type"`
+	Required    bool                             `json:"required,omitempty" yaml:"required,omitempty"`
+	Validations []metafields.MetafieldValidation `json:"validations,omitempty" yaml:"validations,omitempty"`
+}
+
+func (d metaobjectDocument) field(key string) *metaobjectFieldDocument {
+	for i := range d.Fields {
+		if d.Fields[i].Key == key {
+			return &d.Fields[i]
+		}
+	}
+
+	return nil
+}
+
+// shopSchema is a shop's schema and the GIDs of its metaobject definitions by type
+type shopSchema struct {
+	Schema
+	MetaobjectIDs map[string]string
+}
+
+// fileFormat returns format if given, otherwise the format of filename based on its extension
+func fileFormat(filename, format string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return formatJSON, nil
+	case "yaml", "yml":
+		return formatYAML, nil
+	}
+
+	return "", fmt.Errorf("Unknown format for %s: must be json or yaml, use --format", filename)
+}
+
+// readSchema reads a schema in format. Unknown properties are an error.
+func readSchema(r io.Reader, format string) (Schema, error) {
+	var schema Schema
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return schema, fmt.Errorf("Cannot read schema: %s", err)
+	}
+
+	if format == formatYAML {
+		err = yaml.UnmarshalStrict(data, &schema)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&schema)
+	}
+
+	if err != nil {
+		return schema, fmt.Errorf("Cannot parse schema: %s", err)
+	}
+
+	types := map[string]bool{}
+
+	for i := range schema.MetaobjectDefinitions {
+		doc := &schema.MetaobjectDefinitions[i]
+		if doc.Type == "" {
+			return schema, fmt.Errorf("Metaobject definition %d invalid: type is required", i+1)
+		}
+
+		if types[doc.Type] {
+			return schema, fmt.Errorf("Metaobject definition %d invalid: %s is given more than once", i+1, doc.Type)
+		}
+
+		types[doc.Type] = true
+
+		if doc.Name == "" {
+			doc.Name = doc.Type
+		}
+
+		keys := map[string]bool{}
+
+		for j := range doc.Fields {
+			field := &doc.Fields[j]
+			if field.Key == "" || field.Type == "" {
+				return schema, fmt.Errorf("Metaobject definition %s invalid: field %d requires a key and type", doc.Type, j+1)
+			}
+
+			if keys[field.Key] {
+				return schema, fmt.Errorf("Metaobject definition %s invalid: field %s is given more than once", doc.Type, field.Key)
+			}
+
+			keys[field.Key] = true
+
+			if field.Name == "" {
+				field.Name = field.Key
+			}
+
+			if _, err := referencedTypes(field.Validations); err != nil {
+				return schema, fmt.Errorf("Metaobject definition %s invalid: field %s: %s", doc.Type, field.Key, err)
+			}
+		}
+	}
+
+	if err := metafields.ValidateDefinitions(schema.MetafieldDefinitions); err != nil {
+		return schema, err
+	}
+
+	for _, doc := range schema.MetafieldDefinitions {
+		if _, err := referencedTypes(doc.Validations); err != nil {
+			return schema, fmt.Errorf("Metafield definition %s invalid: %s", doc.String(), err)
+		}
+	}
+
+	return schema, nil
+}
+
+func writeSchema(w io.Writer, schema Schema, format string) error {
+	var data []byte
+	var err error
+
+	if format == formatYAML {
+		data, err = yaml.Marshal(schema)
+	} else {
+		data, err = json.MarshalIndent(schema, "", "  ")
+		data = append(data, '\n')
+	}
+
+	if err != nil {
+		return fmt.Errorf("Cannot encode schema: %s", err)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// mapReferences returns the validations with the values of metaobject definition references replaced
+// using mapping, from GIDs to types or types to GIDs
+func mapReferences(validations []metafields.MetafieldValidation, mapping map[string]string) ([]metafields.MetafieldValidation, error) {
+	if len(validations) == 0 {
+		return validations, nil
+	}
+
+	result := make([]metafields.MetafieldValidation, len(validations))
+
+	for i, v := range validations {
+		result[i] = v
+
+		switch v.Name {
+		case referenceValidation:
+			value, ok := mapping[v.Value]
+			if !ok {
+				return nil, fmt.Errorf("Unknown metaobject definition %s", v.Value)
+			}
+
+			result[i].Value = value
+		case referencesValidation:
+			var values []string
+			if err := json.Unmarshal([]byte(v.Value), &values); err != nil {
+				return nil, fmt.Errorf("Validation %s must be a JSON list: %s", v.Name, err)
+			}
+
+			for j, value := range values {
+				mapped, ok := mapping[value]
+				if !ok {
+					return nil, fmt.Errorf("Unknown metaobject definition %s", value)
+				}
+
+				values[j] = mapped
+			}
+
+			data, _ := json.Marshal(values)
+			result[i].Value = string(data)
+		}
+	}
+
+	return result, nil
+}
+
+// referencedTypes returns the metaobject types referred to by the validations of a schema file
+func referencedTypes(validations []metafields.MetafieldValidation) ([]string, error) {
+	var types []string
+
+	for _, v := range validations {
+		switch v.Name {
+		case referenceValidation:
+			types = append(types, v.Value)
+		case referencesValidation:
+			var values []string
+			if err := json.Unmarshal([]byte(v.Value), &values); err != nil {
+				return nil, fmt.Errorf("Validation %s must be a JSON list: %s", v.Name, err)
+			}
+
+			types = append(types, values...)
+		}
+	}
+
+	return types, nil
+}
+
+func metaobjectToDocument(d metaobjects.MetaobjectDefinition, types map[string]string) (metaobjectDocument, error) {
+	doc := metaobjectDocument{
+		Type:           d.Type,
+		Name:           d.Name,
+		Description:    d.Description,
+		DisplayNameKey: d.DisplayNameKey,
+		Fields:         []metaobjectFieldDocument{},
+	}
+
+	for _, f := range d.Fields {
+		var validations []metafields.MetafieldValidation
+		for _, v := range f.Validations {
+			validations = append(validations, metafields.MetafieldValidation{Name: v.Name, Value: v.Value})
+		}
+
+		validations, err := mapReferences(validations, types)
+		if err != nil {
+			return doc, fmt.Errorf("Cannot dump metaobject definition %s field %s: %s", d.Type, f.Key, err)
+		}
+
+		doc.Fields = append(doc.Fields, metaobjectFieldDocument{
+			Key:         f.Key,
+			Name:        f.Name,
+			Description: f.Description,
+			Type:        f.Type,
+			Required:    f.Required,
+			Validations: validations,
+		})
+	}
+
+	return doc, nil
+}
+
+// fetchSchema returns the shop's metaobject definitions and its metafield definitions for the owner types
+func fetchSchema(client *gql.Client, shop, token string, ownerTypes []string, verbose bool) (shopSchema, error) {
+	schema := shopSchema{
+		Schema:        Schema{MetaobjectDefinitions: []metaobjectDocument{}, MetafieldDefinitions: []metafields.DefinitionDocument{}},
+		MetaobjectIDs: map[string]string{},
+	}
+
+	definitions, err := metaobjects.FetchAllMetaobjectDefinitions(shop, token, verbose)
+	if err != nil {
+		return schema, err
+	}
+
+	// GIDs to types
+	types := map[string]string{}
+	for _, d := range definitions {
+		types[d.ID] = d.Type
+		schema.MetaobjectIDs[d.Type] = d.ID
+	}
+
+	for _, d := range definitions {
+		doc, err := metaobjectToDocument(d, types)
+		if err != nil {
+			return schema, err
+		}
+
+		schema.MetaobjectDefinitions = append(schema.MetaobjectDefinitions, doc)
+	}
+
+	for _, ownerType := range ownerTypes {
+		definitions, err := metafields.ListMetafieldDefinitions(client, ownerType, "")
+		if err != nil {
+			return schema, err
+		}
+
+		for _, d := range definitions {
+			doc := metafields.DefinitionToDocument(d)
+
+			doc.Validations, err = mapReferences(doc.Validations, types)
+			if err != nil {
+				return schema, fmt.Errorf("Cannot dump metafield definition %s: %s", doc, err)
+			}
+
+			schema.MetafieldDefinitions = append(schema.MetafieldDefinitions, doc)
+		}
+	}
+
+	return schema, nil
+}
@@ -0,0 +1,480 @@
+package schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/metafields"
+	metaobjects "github.com/ScreenStaring/shopify-dev-tools/cmd/metaobjects/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const (
+	kindMetaobject = "metaobject definition"
+	kindMetafield  = "metafield definition"
+)
+
+// change is a definition to create or update
+type change struct {
+	// kindMetaobject or kindMetafield
+	Kind string
+	// Type of a metaobject definition or owner type and namespace.key of a metafield definition
+	Name string
+	// create or update
+	Action string
+	// Lines describing the changes
+	Changes []string
+	// Changes that cannot be made, e.g., to a type
+	Breaking []string
+
+	metaobject *metaobjectDocument
+	// The metaobject definition that is updated
+	current   *metaobjectDocument
+	metafield *metafields.DefinitionDocument
+}
+
+func formatValidations(validations []metafields.MetafieldValidation) string {
+	parts := make([]string, len(validations))
+	for i, v := range validations {
+		parts[i] = v.Name + "=" + v.Value
+	}
+
+	sort.Strings(parts)
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// diff appends "name: from -> to" to lines if from and to differ
+func diff(lines []string, name string, from, to interface{}) []string {
+	if validations, ok := from.([]metafields.MetafieldValidation); ok {
+		from, to = formatValidations(validations), formatValidations(to.([]metafields.MetafieldValidation))
+		if from != to {
+			return append(lines, fmt.Sprintf("%s: %s -> %s", name, from, to))
+		}
+
+		return lines
+	}
+
+	if from == to {
+		return lines
+	}
+
+	if _, ok := from.(string); ok {
+		return append(lines, fmt.Sprintf("%s: %q -> %q", name, from, to))
+	}
+
+	return append(lines, fmt.Sprintf("%s: %v -> %v", name, from, to))
+}
+
+func diffField(current, field metaobjectFieldDocument) []string {
+	prefix := "field " + field.Key + " "
+
+	var lines []string
+	lines = diff(lines, prefix+"name", current.Name, field.Name)
+	lines = diff(lines, prefix+"description", current.Description, field.Description)
+	lines = diff(lines, prefix+"required", current.Required, field.Required)
+	lines = diff(lines, prefix+"validations", current.Validations, field.Validations)
+
+	return lines
+}
+
+// diffMetaobject returns the changes and breaking changes needed for current to match doc.
+// Fields not in doc are not changed.
+func diffMetaobject(current, doc metaobjectDocument) ([]string, []string) {
+	var changes, breaking []string
+
+	changes = diff(changes, "name", current.Name, doc.Name)
+	changes = diff(changes, "description", current.Description, doc.Description)
+	changes = diff(changes, "displayNameKey", current.DisplayNameKey, doc.DisplayNameKey)
+
+	for _, field := range doc.Fields {
+		existing := current.field(field.Key)
+		if existing == nil {
+			changes = append(changes, fmt.Sprintf("+ field %s (%s)", field.Key, field.Type))
+			continue
+		}
+
+		if existing.Type != field.Type {
+			breaking = diff(breaking, "field "+field.Key+" type", existing.Type, field.Type)
+		}
+
+		changes = append(changes, diffField(*existing, field)...)
+	}
+
+	return changes, breaking
+}
+
+// diffMetafield returns the changes and breaking changes needed for current to match doc
+func diffMetafield(current, doc metafields.DefinitionDocument) ([]string, []string) {
+	var changes, breaking []string
+
+	breaking = diff(breaking, "type", current.Type, doc.Type)
+
+	changes = diff(changes, "name", current.Name, doc.Name)
+	changes = diff(changes, "description", current.Description, doc.Description)
+	changes = diff(changes, "validations", current.Validations, doc.Validations)
+
+	if doc.Access != nil {
+		currentAccess := metafields.DefinitionAccess{}
+		if current.Access != nil {
+			currentAccess = *current.Access
+		}
+
+		// Storefront access is omitted from dumps when it's NONE
+		if currentAccess.Storefront == "" {
+			currentAccess.Storefront = "NONE"
+		}
+
+		if doc.Access.Admin != "" {
+			changes = diff(changes, "admin access", currentAccess.Admin, doc.Access.Admin)
+		}
+
+		if doc.Access.Storefront != "" {
+			changes = diff(changes, "storefront access", currentAccess.Storefront, doc.Access.Storefront)
+		}
+	}
+
+	changes = diff(changes, "pinned", current.Pinned, doc.Pinned)
+
+	return changes, breaking
+}
+
+// sortMetaobjects orders the definitions so those referred to by others' fields come first. Only
+// references to definitions that don't exist, the types not in existing, are considered.
+func sortMetaobjects(docs []metaobjectDocument, existing map[string]string) ([]metaobjectDocument, error) {
+	index := map[string]int{}
+	for i, doc := range docs {
+		index[doc.Type] = i
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	state := make([]int, len(docs))
+	sorted := make([]metaobjectDocument, 0, len(docs))
+
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		path = append(path, docs[i].Type)
+
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("Metaobject definitions that don't exist cannot refer to each other: %s", strings.Join(path, " -> "))
+		}
+
+		state[i] = visiting
+
+		for _, field := range docs[i].Fields {
+			types, err := referencedTypes(field.Validations)
+			if err != nil {
+				return fmt.Errorf("Metaobject definition %s field %s invalid: %s", docs[i].Type, field.Key, err)
+			}
+
+			for _, t := range types {
+				j, ok := index[t]
+				if _, exists := existing[t]; !ok || exists {
+					continue
+				}
+
+				if err := visit(j, path); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[i] = visited
+		sorted = append(sorted, docs[i])
+
+		return nil
+	}
+
+	for i := range docs {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// checkReferences checks that the metaobject types referred to by the schema are in it or the shop
+func checkReferences(schema Schema, existing map[string]string) error {
+	types := map[string]bool{}
+	for t := range existing {
+		types[t] = true
+	}
+
+	for _, doc := range schema.MetaobjectDefinitions {
+		types[doc.Type] = true
+	}
+
+	check := func(name string, validations []metafields.MetafieldValidation) error {
+		referenced, err := referencedTypes(validations)
+		if err != nil {
+			return fmt.Errorf("%s invalid: %s", name, err)
+		}
+
+		for _, t := range referenced {
+			if !types[t] {
+				return fmt.Errorf("%s refers to metaobject type %q, which is not in the schema or the shop", name, t)
+			}
+		}
+
+		return nil
+	}
+
+	for _, doc := range schema.MetaobjectDefinitions {
+		for _, field := range doc.Fields {
+			if err := check(fmt.Sprintf("Metaobject definition %s field %s", doc.Type, field.Key), field.Validations); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, doc := range schema.MetafieldDefinitions {
+		if err := check("Metafield definition "+doc.String(), doc.Validations); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planSchema returns the changes needed for current to match schema, in the order they must be made:
+// metaobject definitions, those referred to first, then metafield definitions. Definitions that
+// match are not returned and definitions not in schema are not changed.
+func planSchema(schema Schema, current shopSchema) ([]change, error) {
+	if err := checkReferences(schema, current.MetaobjectIDs); err != nil {
+		return nil, err
+	}
+
+	ordered, err := sortMetaobjects(schema.MetaobjectDefinitions, current.MetaobjectIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	currentMetaobjects := map[string]*metaobjectDocument{}
+	for i, doc := range current.MetaobjectDefinitions {
+		currentMetaobjects[doc.Type] = &current.MetaobjectDefinitions[i]
+	}
+
+	var changes []change
+
+	for i := range ordered {
+		doc := &ordered[i]
+		ch := change{Kind: kindMetaobject, Name: doc.Type, Action: "create", metaobject: doc}
+
+		existing, ok := currentMetaobjects[doc.Type]
+		if !ok {
+			for _, field := range doc.Fields {
+				ch.Changes = append(ch.Changes, fmt.Sprintf("+ field %s (%s)", field.Key, field.Type))
+			}
+
+			changes = append(changes, ch)
+			continue
+		}
+
+		ch.Action = "update"
+		ch.current = existing
+		ch.Changes, ch.Breaking = diffMetaobject(*existing, *doc)
+
+		if len(ch.Changes) > 0 || len(ch.Breaking) > 0 {
+			changes = append(changes, ch)
+		}
+	}
+
+	currentMetafields := map[string]metafields.DefinitionDocument{}
+	for _, doc := range current.MetafieldDefinitions {
+		currentMetafields[doc.String()] = doc
+	}
+
+	for i := range schema.MetafieldDefinitions {
+		doc := &schema.MetafieldDefinitions[i]
+		ch := change{Kind: kindMetafield, Name: doc.String(), Action: "create", metafield: doc}
+
+		existing, ok := currentMetafields[doc.String()]
+		if !ok {
+			ch.Changes = []string{"type: " + doc.Type}
+			changes = append(changes, ch)
+			continue
+		}
+
+		ch.Action = "update"
+		ch.Changes, ch.Breaking = diffMetafield(existing, *doc)
+
+		if len(ch.Changes) > 0 || len(ch.Breaking) > 0 {
+			changes = append(changes, ch)
+		}
+	}
+
+	return changes, nil
+}
+
+// countBreaking returns the number of changes with breaking changes
+func countBreaking(changes []change) int {
+	n := 0
+	for _, ch := range changes {
+		if len(ch.Breaking) > 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+func printPlan(out io.Writer, changes []change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No changes, the shop matches the schema")
+		return
+	}
+
+	adds, updates := 0, 0
+
+	for _, ch := range changes {
+		symbol := "+"
+
+		switch {
+		case len(ch.Breaking) > 0:
+			symbol = "!"
+		case ch.Action == "update":
+			symbol = "~"
+			updates++
+		default:
+			adds++
+		}
+
+		fmt.Fprintf(out, "%s %s %s\n", symbol, ch.Kind, ch.Name)
+
+		for _, line := range ch.Changes {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+
+		for _, line := range ch.Breaking {
+			fmt.Fprintf(out, "    %s (breaking)\n", line)
+		}
+	}
+
+	fmt.Fprintf(out, "\nPlan: %d to add, %d to change, %d breaking\n", adds, updates, countBreaking(changes))
+}
+
+func fieldInput(field metaobjectFieldDocument, ids map[string]string) (map[string]interface{}, error) {
+	validations, err := mapReferences(field.Validations, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if validations == nil {
+		validations = []metafields.MetafieldValidation{}
+	}
+
+	return map[string]interface{}{
+		"key":         field.Key,
+		"name":        field.Name,
+		"description": field.Description,
+		"type":        field.Type,
+		"required":    field.Required,
+		"validations": validations,
+	}, nil
+}
+
+// metaobjectInput returns the MetaobjectDefinitionCreateInput for doc or, when current is given, the
+// MetaobjectDefinitionUpdateInput that creates and updates its fields. ids are the GIDs of metaobject
+// definitions by type.
+func metaobjectInput(doc metaobjectDocument, current *metaobjectDocument, ids map[string]string) (map[string]interface{}, error) {
+	input := map[string]interface{}{
+		"name":           doc.Name,
+		"description":    doc.Description,
+		"displayNameKey": doc.DisplayNameKey,
+	}
+
+	var fields []map[string]interface{}
+
+	for _, field := range doc.Fields {
+		fi, err := fieldInput(field, ids)
+		if err != nil {
+			return nil, fmt.Errorf("Field %s: %s", field.Key, err)
+		}
+
+		if current == nil {
+			fields = append(fields, fi)
+			continue
+		}
+
+		existing := current.field(field.Key)
+		if existing == nil {
+			fields = append(fields, map[string]interface{}{"create": fi})
+			continue
+		}
+
+		if len(diffField(*existing, field)) > 0 {
+			// The type can't be updated
+			delete(fi, "type")
+			fields = append(fields, map[string]interface{}{"update": fi})
+		}
+	}
+
+	if current == nil {
+		input["type"] = doc.Type
+	}
+
+	if fields != nil {
+		input["fieldDefinitions"] = fields
+	}
+
+	return input, nil
+}
+
+// applyChanges makes the changes in order, stopping at the first that fails. ids are the GIDs of the shop's
+// metaobject definitions by type, those created are added.
+func applyChanges(client *gql.Client, shop, token string, verbose bool, changes []change, ids map[string]string, out io.Writer) error {
+	for _, ch := range changes {
+		var err error
+
+		switch {
+		case ch.metaobject != nil:
+			var input map[string]interface{}
+			input, err = metaobjectInput(*ch.metaobject, ch.current, ids)
+			if err != nil {
+				return fmt.Errorf("Cannot %s %s %s: %s", ch.Action, ch.Kind, ch.Name, err)
+			}
+
+			if ch.Action == "create" {
+				var id string
+				id, err = metaobjects.CreateMetaobjectDefinition(shop, token, input, verbose)
+				ids[ch.Name] = id
+			} else {
+				err = metaobjects.UpdateMetaobjectDefinition(shop, token, ids[ch.Name], input, verbose)
+			}
+		default:
+			doc := *ch.metafield
+			doc.Validations, err = mapReferences(doc.Validations, ids)
+			if err != nil {
+				return fmt.Errorf("Cannot %s %s %s: %s", ch.Action, ch.Kind, ch.Name, err)
+			}
+
+			if ch.Action == "create" {
+				_, err = metafields.CreateMetafieldDefinition(client, metafields.DefinitionInput(doc, false))
+			} else {
+				_, err = metafields.UpdateMetafieldDefinition(client, metafields.DefinitionInput(doc, true))
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		action := "Created"
+		if ch.Action == "update" {
+			action = "Updated"
+		}
+
+		fmt.Fprintf(out, "%s %s %s\n", action, ch.Kind, ch.Name)
+	}
+
+	return nil
+}
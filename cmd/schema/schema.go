@@ -0,0 +1,221 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+var Cmd cli.Command
+
+// readSchemaFile reads the schema file given by the first argument
+func readSchemaFile(c *cli.Context) (Schema, error) {
+	if c.NArg() == 0 {
+		return Schema{}, errors.New("Schema file required")
+	}
+
+	filename := c.Args().First()
+	format, err := fileFormat(filename, c.String("format"))
+	if err != nil {
+		return Schema{}, err
+	}
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return Schema{}, fmt.Errorf("Cannot open schema file: %s", err)
+	}
+	defer f.Close()
+
+	return readSchema(f, format)
+}
+
+// planSchemaFile returns the changes needed for the shop to match the schema file, the GIDs of the
+// shop's metaobject definitions by type, and the client and access token used to fetch them
+func planSchemaFile(c *cli.Context) ([]change, map[string]string, *gql.Client, string, error) {
+	schema, err := readSchemaFile(c)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	var ownerTypes []string
+	seen := map[string]bool{}
+	for _, doc := range schema.MetafieldDefinitions {
+		if !seen[doc.OwnerType] {
+			seen[doc.OwnerType] = true
+			ownerTypes = append(ownerTypes, doc.OwnerType)
+		}
+	}
+
+	current, err := fetchSchema(client, c.String("shop"), token, ownerTypes, c.Bool("verbose"))
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	changes, err := planSchema(schema, current)
+	if err != nil {
+		return nil, nil, nil, "", err
+	}
+
+	return changes, current.MetaobjectIDs, client, token, nil
+}
+
+func dumpAction(c *cli.Context) error {
+	output := c.String("output")
+	format := formatJSON
+
+	if output != "" || c.String("format") != "" {
+		var err error
+		if format, err = fileFormat(output, c.String("format")); err != nil {
+			return err
+		}
+	}
+
+	ownerTypes := defaultOwnerTypes
+	if c.IsSet("owner-type") {
+		ownerTypes = nil
+		for _, ownerType := range c.StringSlice("owner-type") {
+			ownerTypes = append(ownerTypes, strings.ToUpper(ownerType))
+		}
+	}
+
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	schema, err := fetchSchema(client, c.String("shop"), token, ownerTypes, c.Bool("verbose"))
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		return writeSchema(os.Stdout, schema.Schema, format)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("Cannot create schema file: %s", err)
+	}
+	defer f.Close()
+
+	return writeSchema(f, schema.Schema, format)
+}
+
+func planAction(c *cli.Context) error {
+	changes, _, _, _, err := planSchemaFile(c)
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, changes)
+	return nil
+}
+
+func applyAction(c *cli.Context) error {
+	changes, ids, client, token, err := planSchemaFile(c)
+	if err != nil {
+		return err
+	}
+
+	printPlan(os.Stdout, changes)
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if n := countBreaking(changes); n > 0 {
+		return fmt.Errorf("Cannot apply %d breaking change(s), nothing was changed. Types must be changed by hand", n)
+	}
+
+	if !c.Bool("yes") {
+		ok, err := cmd.Confirm(os.Stdin, os.Stdout, "\nApply these changes?")
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("Nothing was changed")
+		}
+	}
+
+	return applyChanges(client, c.String("shop"), token, c.Bool("verbose"), changes, ids, os.Stdout)
+}
+
+func init() {
+	apiVersionFlag := cmd.APIVersionFlag
+
+	formatFlag := &cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the schema file: json or yaml, defaults to the file's extension",
+	}
+
+	Cmd = cli.Command{
+		Name:  "schema",
+		Usage: "Sync metafield and metaobject definitions between shops",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "dump",
+				Usage: "Write the shop's metaobject and metafield definitions to a schema file",
+				Flags: append(cmd.Flags, apiVersionFlag,
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Format of the schema file: json or yaml, defaults to the output file's extension or json",
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Write the schema to this file instead of stdout",
+					},
+					&cli.StringSliceFlag{
+						Name:  "owner-type",
+						Usage: "Dump the metafield definitions of this owner type, e.g., PRODUCT, can be given multiple times. Defaults to the common owner types",
+					},
+				),
+				Action: dumpAction,
+			},
+			{
+				Name:        "plan",
+				ArgsUsage:   "schema.json|schema.yaml",
+				Usage:       "Show the changes needed for the shop to match the schema file",
+				Description: "Definitions and metaobject fields not in the schema file are not changed",
+				Flags:       append(cmd.Flags, apiVersionFlag, formatFlag),
+				Action:      planAction,
+			},
+			{
+				Name:        "apply",
+				ArgsUsage:   "schema.json|schema.yaml",
+				Usage:       "Create and update definitions so the shop matches the schema file",
+				Description: "Metaobject definitions are created before the definitions that refer to them. Nothing is changed if there are breaking changes",
+				Flags: append(cmd.Flags, apiVersionFlag, formatFlag,
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Do not ask for confirmation",
+					},
+				),
+				Action: applyAction,
+			},
+		},
+	}
+}
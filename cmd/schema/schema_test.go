@@ -0,0 +1,287 @@
+package schema
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/metafields"
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+var seed = mock.Seed{
+	MetaobjectDefinitions: []map[string]interface{}{
+		{
+			"type":           "designer",
+			"name":           "Designer",
+			"displayNameKey": "name",
+			"fieldDefinitions": []interface{}{
+				map[string]interface{}{"key": "name", "name": "Name", "type": "single_line_text_field", "required": true},
+			},
+		},
+	},
+	MetafieldDefinitions: []map[string]interface{}{
+		{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit", "name": "Fit", "type": "single_line_text_field"},
+	},
+}
+
+func mockClient(t *testing.T) *gql.Client {
+	t.Helper()
+
+	server := mock.NewServer()
+	if err := server.Load(seed); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	old := gql.AdminURL
+	gql.AdminURL = httpServer.URL
+	t.Cleanup(func() { gql.AdminURL = old })
+
+	return gql.NewClient("acme", "shpat_test")
+}
+
+func TestReadSchema(t *testing.T) {
+	file := `
+metaobjectDefinitions:
+  - type: brand
+    fields:
+      - key: designer
+        type: metaobject_reference
+        validations:
+          - name: metaobject_definition_id
+            value: designer
+metafieldDefinitions:
+  - ownerType: product
+    namespace: custom
+    key: brand
+    type: metaobject_reference
+`
+
+	schema, err := readSchema(strings.NewReader(file), formatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Schema{
+		MetaobjectDefinitions: []metaobjectDocument{
+			{
+				Type: "brand",
+				Name: "brand",
+				Fields: []metaobjectFieldDocument{
+					{Key: "designer", Name: "designer", Type: "metaobject_reference", Validations: []metafields.MetafieldValidation{{Name: "metaobject_definition_id", Value: "designer"}}},
+				},
+			},
+		},
+		MetafieldDefinitions: []metafields.DefinitionDocument{
+			{OwnerType: "PRODUCT", Namespace: "custom", Key: "brand", Name: "brand", Type: "metaobject_reference"},
+		},
+	}
+
+	if !reflect.DeepEqual(schema, want) {
+		t.Errorf("schema = %+v, want %+v", schema, want)
+	}
+
+	invalid := []string{
+		`{"metaobjectDefinitions": [{"name": "Brand"}]}`,
+		`{"metaobjectDefinitions": [{"type": "brand", "fields": [{"key": "name"}]}]}`,
+		`{"metaobjectDefinitions": [{"type": "brand"}, {"type": "brand"}]}`,
+		`{"metafieldDefinitions": [{"ownerType": "PRODUCT", "namespace": "custom", "key": "fit"}]}`,
+		`{"metaobjectDefinitions": [{"type": "brand", "fields": [{"key": "designers", "type": "list.metaobject_reference", "validations": [{"name": "metaobject_definition_ids", "value": "designer"}]}]}]}`,
+		`{"metafieldDefinitions": [{"ownerType": "PRODUCT", "namespace": "custom", "key": "brands", "type": "list.metaobject_reference", "validations": [{"name": "metaobject_definition_ids", "value": "brand"}]}]}`,
+		`{"definitions": []}`,
+	}
+
+	for _, file := range invalid {
+		if _, err := readSchema(strings.NewReader(file), formatJSON); err == nil {
+			t.Errorf("readSchema(%s) did not fail", file)
+		}
+	}
+}
+
+func TestMapReferences(t *testing.T) {
+	validations := []metafields.MetafieldValidation{
+		{Name: "max", Value: "10"},
+		{Name: referenceValidation, Value: "gid://shopify/MetaobjectDefinition/1"},
+		{Name: referencesValidation, Value: `["gid://shopify/MetaobjectDefinition/1","gid://shopify/MetaobjectDefinition/2"]`},
+	}
+
+	mapping := map[string]string{"gid://shopify/MetaobjectDefinition/1": "designer", "gid://shopify/MetaobjectDefinition/2": "brand"}
+
+	got, err := mapReferences(validations, mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []metafields.MetafieldValidation{
+		{Name: "max", Value: "10"},
+		{Name: referenceValidation, Value: "designer"},
+		{Name: referencesValidation, Value: `["designer","brand"]`},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mapReferences = %+v, want %+v", got, want)
+	}
+
+	if types, err := referencedTypes(got); err != nil || !reflect.DeepEqual(types, []string{"designer", "designer", "brand"}) {
+		t.Errorf("referencedTypes = %v, %v", types, err)
+	}
+
+	if _, err := referencedTypes([]metafields.MetafieldValidation{{Name: referencesValidation, Value: "designer"}}); err == nil {
+		t.Error("referencedTypes with a validation that isn't a JSON list did not fail")
+	}
+
+	if _, err := mapReferences(validations[1:2], map[string]string{}); err == nil {
+		t.Error("mapReferences with an unknown GID did not fail")
+	}
+}
+
+func reference(typ string) []metafields.MetafieldValidation {
+	return []metafields.MetafieldValidation{{Name: referenceValidation, Value: typ}}
+}
+
+func TestSortMetaobjects(t *testing.T) {
+	docs := []metaobjectDocument{
+		{Type: "story", Fields: []metaobjectFieldDocument{{Key: "brand", Validations: reference("brand")}}},
+		{Type: "brand", Fields: []metaobjectFieldDocument{{Key: "designer", Validations: reference("designer")}}},
+		{Type: "designer"},
+	}
+
+	sorted, err := sortMetaobjects(docs, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, doc := range sorted {
+		types = append(types, doc.Type)
+	}
+
+	if want := []string{"designer", "brand", "story"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("types = %v, want %v", types, want)
+	}
+
+	docs[2].Fields = []metaobjectFieldDocument{{Key: "story", Validations: reference("story")}}
+
+	if _, err := sortMetaobjects(docs, map[string]string{}); err == nil {
+		t.Error("sortMetaobjects with a cycle did not fail")
+	}
+
+	// Existing definitions can refer to each other
+	if _, err := sortMetaobjects(docs, map[string]string{"story": "gid://shopify/MetaobjectDefinition/1"}); err != nil {
+		t.Errorf("sortMetaobjects with an existing definition in the cycle failed: %s", err)
+	}
+}
+
+func TestPlanAndApplySchema(t *testing.T) {
+	client := mockClient(t)
+
+	schema := Schema{
+		MetaobjectDefinitions: []metaobjectDocument{
+			{
+				Type: "story",
+				Name: "Story",
+				Fields: []metaobjectFieldDocument{
+					{Key: "brand", Name: "Brand", Type: "metaobject_reference", Validations: reference("brand")},
+				},
+			},
+			{
+				Type: "brand",
+				Name: "Brand",
+				Fields: []metaobjectFieldDocument{
+					{Key: "designer", Name: "Designer", Type: "metaobject_reference", Validations: reference("designer")},
+				},
+			},
+			{
+				Type:           "designer",
+				Name:           "Designer",
+				DisplayNameKey: "name",
+				Fields: []metaobjectFieldDocument{
+					{Key: "name", Name: "Full name", Type: "single_line_text_field", Required: true},
+					{Key: "bio", Name: "Bio", Type: "multi_line_text_field"},
+				},
+			},
+		},
+		MetafieldDefinitions: []metafields.DefinitionDocument{
+			{OwnerType: "PRODUCT", Namespace: "custom", Key: "fit", Name: "Fit", Type: "single_line_text_field"},
+			{OwnerType: "PRODUCT", Namespace: "custom", Key: "brand", Name: "Brand", Type: "metaobject_reference", Validations: reference("brand"), Pinned: true},
+		},
+	}
+
+	current, err := fetchSchema(client, "acme", "shpat_test", []string{"PRODUCT"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := planSchema(schema, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	printPlan(&out, changes)
+
+	wantPlan := `+ metaobject definition brand
+    + field designer (metaobject_reference)
++ metaobject definition story
+    + field brand (metaobject_reference)
+~ metaobject definition designer
+    field name name: "Name" -> "Full name"
+    + field bio (multi_line_text_field)
++ metafield definition PRODUCT custom.brand
+    type: metaobject_reference
+
+Plan: 3 to add, 1 to change, 0 breaking
+`
+
+	if out.String() != wantPlan {
+		t.Errorf("plan = %s, want %s", out.String(), wantPlan)
+	}
+
+	out.Reset()
+	if err := applyChanges(client, "acme", "shpat_test", false, changes, current.MetaobjectIDs, &out); err != nil {
+		t.Fatalf("applyChanges failed: %s\n%s", err, out.String())
+	}
+
+	current, err = fetchSchema(client, "acme", "shpat_test", []string{"PRODUCT"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes, err = planSchema(schema, current); err != nil || len(changes) != 0 {
+		t.Errorf("changes after applying = %+v, %v, want none", changes, err)
+	}
+
+	// Dumped references are types
+	brand := current.MetafieldDefinitions[len(current.MetafieldDefinitions)-1]
+	if brand.Key != "brand" || !reflect.DeepEqual(brand.Validations, reference("brand")) || !brand.Pinned {
+		t.Errorf("brand metafield definition = %+v", brand)
+	}
+
+	schema.MetafieldDefinitions[0].Type = "url"
+	schema.MetaobjectDefinitions[2].Fields[1].Type = "rich_text_field"
+
+	changes, err = planSchema(schema, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 2 || countBreaking(changes) != 2 {
+		t.Errorf("changes = %+v, want 2 breaking", changes)
+	}
+
+	schema.MetafieldDefinitions[1].Validations = reference("nope")
+	if _, err := planSchema(schema, current); err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("planSchema with an unknown reference = %v, want an error", err)
+	}
+
+	schema.MetafieldDefinitions[1].Validations = []metafields.MetafieldValidation{{Name: referencesValidation, Value: "designer"}}
+	if _, err := planSchema(schema, current); err == nil || !strings.Contains(err.Error(), "must be a JSON list") {
+		t.Errorf("planSchema with an invalid references validation = %v, want an error", err)
+	}
+}
@@ -1,7 +1,6 @@
 package scripttags
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -52,22 +51,8 @@ type ScriptTag struct {
 	UpdatedAt        string `json:"updatedAt"`
 }
 
-type scriptTagsResponse struct {
-	Data struct {
-		ScriptTags struct {
-			Nodes    []ScriptTag `json:"nodes"`
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-		} `json:"scriptTags"`
-	} `json:"data"`
-}
-
 func listScriptTags(client *gql.Client, src string) ([]ScriptTag, error) {
-	vars := map[string]interface{}{
-		"first": 250,
-	}
+	vars := map[string]interface{}{}
 
 	if src != "" {
 		vars["src"] = "src:" + src
@@ -75,48 +60,26 @@ func listScriptTags(client *gql.Client, src string) ([]ScriptTag, error) {
 
 	var tags []ScriptTag
 
-	for {
-		data, err := client.Execute(scriptTagsQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list script tags: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list script tags: %s", err)
-		}
-
-		var response scriptTagsResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list script tags: %s", err)
-		}
-
-		tags = append(tags, response.Data.ScriptTags.Nodes...)
-
-		if !response.Data.ScriptTags.PageInfo.HasNextPage {
-			break
-		}
+	err := gql.Paginate(client, scriptTagsQuery, vars, "scriptTags", func(tag ScriptTag) error {
+		tags = append(tags, tag)
+		return nil
+	})
 
-		vars["after"] = response.Data.ScriptTags.PageInfo.EndCursor
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list script tags: %s", err)
 	}
 
 	return tags, nil
 }
 
 func deleteScriptTag(client *gql.Client, id string) error {
-	data, err := client.Execute(scriptTagDeleteMutation, map[string]interface{}{
+	err := client.ExecuteInto(scriptTagDeleteMutation, map[string]interface{}{
 		"id": id,
-	})
+	}, nil)
 	if err != nil {
 		return fmt.Errorf("Cannot delete script tag: %s", err)
 	}
 
-	userErrors, _ := data.ValuesForPath("data.scriptTagDelete.userErrors")
-	if len(userErrors) > 0 {
-		ueMap := userErrors[0].(map[string]interface{})
-		return fmt.Errorf("Cannot delete script tag: %s", ueMap["message"])
-	}
-
 	return nil
 }
 
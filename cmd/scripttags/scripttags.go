@@ -27,7 +27,10 @@ func deleteAction(c *cli.Context) error {
 
 	var ids []string
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	if scriptTagURL.MatchString(c.Args().Get(0)) {
 		src := c.Args().Get(0)
@@ -66,7 +69,12 @@ func deleteAction(c *cli.Context) error {
 }
 
 func listAction(c *cli.Context) error {
-	tags, err := listScriptTags(cmd.NewGraphQLClient(c), "")
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := listScriptTags(client, "")
 	if err != nil {
 		return fmt.Errorf("Cannot list ScriptTags: %s", err)
 	}
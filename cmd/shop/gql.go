@@ -1,7 +1,6 @@
 package shop
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/ScreenStaring/shopify-dev-tools/gql"
@@ -148,40 +147,26 @@ type shopJSON struct {
 }
 
 type shopResponse struct {
-	Data struct {
-		Shop shopJSON `json:"shop"`
-	} `json:"data"`
+	Shop shopJSON `json:"shop"`
 }
 
 type accessScopesResponse struct {
-	Data struct {
-		CurrentAppInstallation struct {
-			AccessScopes []struct {
-				Handle string `json:"handle"`
-			} `json:"accessScopes"`
-		} `json:"currentAppInstallation"`
-	} `json:"data"`
+	CurrentAppInstallation struct {
+		AccessScopes []struct {
+			Handle string `json:"handle"`
+		} `json:"accessScopes"`
+	} `json:"currentAppInstallation"`
 }
 
 func findShop(shop, token string) (*ShopInfo, error) {
 	client := gql.NewClient(shop, token)
 
-	data, err := client.Execute(shopQuery)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get shop info: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode shop response: %s", err)
-	}
-
 	var response shopResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse shop response: %s", err)
+	if err := client.ExecuteInto(shopQuery, nil, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get shop info: %s", err)
 	}
 
-	s := response.Data.Shop
+	s := response.Shop
 	timezone := s.TimezoneAbbreviation
 	if s.TimezoneOffset != "" {
 		timezone = fmt.Sprintf("(GMT%s) %s", s.TimezoneOffset, s.TimezoneAbbreviation)
@@ -227,23 +212,13 @@ func findShop(shop, token string) (*ShopInfo, error) {
 func findAccessScopes(shop, token string) ([]string, error) {
 	client := gql.NewClient(shop, token)
 
-	data, err := client.Execute(accessScopesQuery)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot get access scopes: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode access scopes response: %s", err)
-	}
-
 	var response accessScopesResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse access scopes response: %s", err)
+	if err := client.ExecuteInto(accessScopesQuery, nil, &response); err != nil {
+		return nil, fmt.Errorf("Cannot get access scopes: %s", err)
 	}
 
 	var scopes []string
-	for _, s := range response.Data.CurrentAppInstallation.AccessScopes {
+	for _, s := range response.CurrentAppInstallation.AccessScopes {
 		scopes = append(scopes, s.Handle)
 	}
 
@@ -26,7 +26,10 @@ func formatField(field string) string {
 
 func accessAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	scopes, err := findAccessScopes(shop, token)
 	if err != nil {
@@ -54,7 +57,10 @@ func accessAction(c *cli.Context) error {
 
 func infoAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 
 	info, err := findShop(shop, token)
 	if err != nil {
@@ -1,7 +1,6 @@
 package themes
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -54,65 +53,33 @@ type Theme struct {
 	UpdatedAt    string
 }
 
-type themesResponse struct {
-	Data struct {
-		Themes struct {
-			Nodes []struct {
-				ID           string `json:"id"`
-				Name         string `json:"name"`
-				Role         string `json:"role"`
-				ThemeStoreID string `json:"themeStoreId"`
-				CreatedAt    string `json:"createdAt"`
-				UpdatedAt    string `json:"updatedAt"`
-			} `json:"nodes"`
-			PageInfo struct {
-				HasNextPage bool   `json:"hasNextPage"`
-				EndCursor   string `json:"endCursor"`
-			} `json:"pageInfo"`
-		} `json:"themes"`
-	} `json:"data"`
+type themeJSON struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Role         string `json:"role"`
+	ThemeStoreID string `json:"themeStoreId"`
+	CreatedAt    string `json:"createdAt"`
+	UpdatedAt    string `json:"updatedAt"`
 }
 
 func listThemes(client *gql.Client) ([]Theme, error) {
-	vars := map[string]interface{}{
-		"first": 250,
-	}
-
 	var themes []Theme
 
-	for {
-		data, err := client.Execute(themesQuery, vars)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list themes: %s", err)
-		}
-
-		b, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("Cannot list themes: %s", err)
-		}
-
-		var response themesResponse
-		if err := json.Unmarshal(b, &response); err != nil {
-			return nil, fmt.Errorf("Cannot list themes: %s", err)
-		}
-
-		for _, n := range response.Data.Themes.Nodes {
-			themes = append(themes, Theme{
-				ID:           themeIDFromGID(n.ID),
-				Gid:          n.ID,
-				Name:         n.Name,
-				Role:         n.Role,
-				ThemeStoreID: n.ThemeStoreID,
-				CreatedAt:    n.CreatedAt,
-				UpdatedAt:    n.UpdatedAt,
-			})
-		}
-
-		if !response.Data.Themes.PageInfo.HasNextPage {
-			break
-		}
+	err := gql.Paginate(client, themesQuery, nil, "themes", func(n themeJSON) error {
+		themes = append(themes, Theme{
+			ID:           themeIDFromGID(n.ID),
+			Gid:          n.ID,
+			Name:         n.Name,
+			Role:         n.Role,
+			ThemeStoreID: n.ThemeStoreID,
+			CreatedAt:    n.CreatedAt,
+			UpdatedAt:    n.UpdatedAt,
+		})
+		return nil
+	})
 
-		vars["after"] = response.Data.Themes.PageInfo.EndCursor
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list themes: %s", err)
 	}
 
 	return themes, nil
@@ -136,7 +103,7 @@ func themeIDFromGID(gid string) int64 {
 // upsertThemeFiles uploads a single file to the theme. The body type is
 // either "TEXT" or "BASE64" per OnlineStoreThemeFilesUpsertFileInput.
 func upsertThemeFiles(client *gql.Client, themeID int64, filename, bodyType, value string) error {
-	data, err := client.Execute(themeFilesUpsertMutation, map[string]interface{}{
+	return client.ExecuteInto(themeFilesUpsertMutation, map[string]interface{}{
 		"themeId": fmt.Sprintf("gid://shopify/OnlineStoreTheme/%d", themeID),
 		"files": []map[string]interface{}{
 			{
@@ -144,16 +111,5 @@ func upsertThemeFiles(client *gql.Client, themeID int64, filename, bodyType, val
 				"body":     map[string]interface{}{"type": bodyType, "value": value},
 			},
 		},
-	})
-	if err != nil {
-		return err
-	}
-
-	userErrors, _ := data.ValuesForPath("data.themeFilesUpsert.userErrors")
-	if len(userErrors) > 0 {
-		ueMap := userErrors[0].(map[string]interface{})
-		return fmt.Errorf("%s", ueMap["message"])
-	}
-
-	return nil
+	}, nil)
 }
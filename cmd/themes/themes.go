@@ -89,7 +89,12 @@ func uploadDirectory(client *gql.Client, themeID int64, source, destination stri
 }
 
 func listAction(c *cli.Context) error {
-	themes, err := listThemes(cmd.NewGraphQLClient(c))
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
+
+	themes, err := listThemes(client)
 	if err != nil {
 		return fmt.Errorf("Cannot list themes: %s", err)
 	}
@@ -120,7 +125,10 @@ func copyAction(c *cli.Context) error {
 		return fmt.Errorf("Theme id '%s' invalid: must be an int", c.Args().Get(0))
 	}
 
-	client := cmd.NewGraphQLClient(c)
+	client, err := cmd.NewGraphQLClient(c)
+	if err != nil {
+		return err
+	}
 
 	args := c.Args().Slice()
 	sources := args[1 : len(args)-1]
@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+)
+
+const (
+	tokenCacheSaltSize   = 16
+	tokenCacheIterations = 100000
+)
+
+// tokenCacheTTL is how long the access token command's output is cached, 0
+// disables caching.
+var tokenCacheTTL time.Duration
+
+// tokenCacheLock serializes access to the cache file within the process, e.g.,
+// when fanning out.
+var tokenCacheLock sync.Mutex
+
+// TokenCacheTTLFlag enables the token cache. It's a global option, i.e., it
+// must be given before the command.
+var TokenCacheTTLFlag = &cli.DurationFlag{
+	Name:        "token-cache-ttl",
+	Usage:       "Cache the output of the access token command for this long, e.g., 1h; 0 disables the cache",
+	EnvVars:     []string{"SDT_TOKEN_CACHE_TTL"},
+	Destination: &tokenCacheTTL,
+}
+
+// CachedToken is an access token command's output for a shop.
+type CachedToken struct {
+	Shop        string    `json:"shop"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// TokenCache is the encrypted on-disk cache of access token command output.
+// It's encrypted with AES-GCM using a key derived from $SDT_TOKEN_CACHE_PASSPHRASE
+// or, when that's not set, a random key kept in a file only readable by the user.
+type TokenCache struct {
+	tokens map[string]*CachedToken
+}
+
+// TokenCachePath returns the location of the token cache, token-cache in the
+// same directory as the config file.
+func TokenCachePath() (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "token-cache"), nil
+}
+
+func tokenCacheKeyPath() (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(filepath.Dir(path), "token-cache.key"), nil
+}
+
+// LoadTokenCache reads and decrypts the token cache. A missing file results in
+// an empty cache.
+func LoadTokenCache() (*TokenCache, error) {
+	cache := &TokenCache{tokens: map[string]*CachedToken{}}
+
+	path, err := TokenCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read token cache %s: %s", path, err)
+	}
+
+	if len(data) < tokenCacheSaltSize {
+		return nil, fmt.Errorf("Cannot read token cache %s: file is corrupt", path)
+	}
+
+	aead, err := tokenCacheCipher(data[:tokenCacheSaltSize], false)
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[tokenCacheSaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("Cannot read token cache %s: file is corrupt", path)
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot decrypt token cache %s: wrong passphrase or corrupt file; remove it with 'sdt auth cache clear'", path)
+	}
+
+	var tokens []*CachedToken
+	if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, fmt.Errorf("Cannot read token cache %s: %s", path, err)
+	}
+
+	for _, token := range tokens {
+		cache.tokens[credentialKey(token.Shop)] = token
+	}
+
+	return cache, nil
+}
+
+// Save encrypts and writes the token cache, dropping expired tokens.
+func (c *TokenCache) Save() error {
+	path, err := TokenCachePath()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(c.Tokens())
+	if err != nil {
+		return fmt.Errorf("Cannot marshal token cache: %s", err)
+	}
+
+	salt := make([]byte, tokenCacheSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("Cannot generate token cache salt: %s", err)
+	}
+
+	aead, err := tokenCacheCipher(salt, true)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("Cannot generate token cache nonce: %s", err)
+	}
+
+	data := append(salt, nonce...)
+	data = aead.Seal(data, nonce, plaintext, nil)
+
+	return writeFileAtomic(path, data)
+}
+
+// Get returns the shop's cached access token or an empty string if it's not
+// cached or has expired.
+func (c *TokenCache) Get(shop string) string {
+	token, ok := c.tokens[credentialKey(shop)]
+	if !ok || !now().Before(token.ExpiresAt) {
+		return ""
+	}
+
+	return token.AccessToken
+}
+
+// Set caches the shop's access token for ttl.
+func (c *TokenCache) Set(shop, token string, ttl time.Duration) {
+	c.tokens[credentialKey(shop)] = &CachedToken{Shop: shop, AccessToken: token, ExpiresAt: now().Add(ttl).UTC().Truncate(time.Second)}
+}
+
+// Delete removes the shop's access token. It returns false if it wasn't cached.
+func (c *TokenCache) Delete(shop string) bool {
+	key := credentialKey(shop)
+	if _, ok := c.tokens[key]; !ok {
+		return false
+	}
+
+	delete(c.tokens, key)
+
+	return true
+}
+
+// Tokens returns the unexpired tokens sorted by shop.
+func (c *TokenCache) Tokens() []*CachedToken {
+	var tokens []*CachedToken
+	for _, token := range c.tokens {
+		if now().Before(token.ExpiresAt) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Shop < tokens[j].Shop })
+
+	return tokens
+}
+
+// ClearTokenCache removes the token cache. Unlike TokenCache.Delete this works
+// when the cache cannot be decrypted.
+func ClearTokenCache() error {
+	path, err := TokenCachePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Cannot remove token cache %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// cachedAccessToken returns the output of the access token command for shop,
+// running it only if the cache doesn't have an unexpired token. Problems with
+// the cache are reported but otherwise ignored.
+func cachedAccessToken(command, shop string) (string, error) {
+	tokenCacheLock.Lock()
+	defer tokenCacheLock.Unlock()
+
+	cache, err := LoadTokenCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring token cache: %s\n", err)
+		return runAccessTokenCommand(command, shop)
+	}
+
+	if token := cache.Get(shop); token != "" {
+		return token, nil
+	}
+
+	token, err := runAccessTokenCommand(command, shop)
+	if err != nil {
+		return "", err
+	}
+
+	cache.Set(shop, token, tokenCacheTTL)
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot update token cache: %s\n", err)
+	}
+
+	return token, nil
+}
+
+// invalidateCachedToken removes the shop's token from the cache if it's the
+// one that was rejected.
+func invalidateCachedToken(shop, token string) {
+	tokenCacheLock.Lock()
+	defer tokenCacheLock.Unlock()
+
+	path, err := TokenCachePath()
+	if err != nil {
+		return
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+
+	cache, err := LoadTokenCache()
+	if err != nil || cache.Get(shop) != token {
+		return
+	}
+
+	cache.Delete(shop)
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot update token cache: %s\n", err)
+	}
+}
+
+// tokenCacheCipher returns the cipher for the token cache. The key file is
+// only created when create is true, i.e., when saving.
+func tokenCacheCipher(salt []byte, create bool) (cipher.AEAD, error) {
+	secret, err := tokenCacheSecret(create)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(pbkdf2(secret, salt, tokenCacheIterations, 32))
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create token cache cipher: %s", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func tokenCacheSecret(create bool) ([]byte, error) {
+	if passphrase := os.Getenv("SDT_TOKEN_CACHE_PASSPHRASE"); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+
+	path, err := tokenCacheKeyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ioutil.ReadFile(path)
+	if err == nil {
+		return key, nil
+	}
+
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("Cannot read token cache key %s: %s", path, err)
+	}
+
+	key = make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("Cannot generate token cache key: %s", err)
+	}
+
+	if err := writeFileAtomic(path, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// writeFileAtomic writes data to a temporary file that's renamed to path so
+// that concurrent readers never see a partial file. It's only readable by the
+// user.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("Cannot create directory for %s: %s", path, err)
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("Cannot write %s: %s", path, err)
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("Cannot write %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// pbkdf2 derives a key from password as specified by RFC 8018 using
+// HMAC-SHA256.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	var key []byte
+
+	for block := uint32(1); len(key) < keyLen; block++ {
+		mac := hmac.New(sha256.New, password)
+		mac.Write(salt)
+		binary.Write(mac, binary.BigEndian, block)
+
+		u := mac.Sum(nil)
+		t := append([]byte(nil), u...)
+
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+
+		key = append(key, t...)
+	}
+
+	return key[:keyLen]
+}
+
+// now is a var so tests can control the time.
+var now = time.Now
+
+func init() {
+	gql.OnUnauthorized = invalidateCachedToken
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// RFC 7914 section 11
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64)); got != want {
+		t.Errorf("pbkdf2() = %s, want %s", got, want)
+	}
+}
+
+// tokenCommand writes a script that outputs a token and records each run.
+func tokenCommand(t *testing.T) (string, func() int) {
+	t.Helper()
+
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	command := filepath.Join(dir, "token.sh")
+
+	script := "#!/bin/sh\necho run >> " + runs + "\necho shpat_$1\n"
+	if err := ioutil.WriteFile(command, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return command, func() int {
+		data, _ := ioutil.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+}
+
+func testTokenCache(t *testing.T, ttl time.Duration) {
+	t.Helper()
+
+	t.Setenv("SDT_CONFIG", filepath.Join(t.TempDir(), "sdt", "config.toml"))
+	t.Setenv("SDT_TOKEN_CACHE_PASSPHRASE", "")
+
+	old := tokenCacheTTL
+	tokenCacheTTL = ttl
+	t.Cleanup(func() { tokenCacheTTL = old })
+}
+
+func TestLookupAccessTokenCachesCommandOutput(t *testing.T) {
+	testTokenCache(t, time.Hour)
+	command, runs := tokenCommand(t)
+
+	for i := 0; i < 2; i++ {
+		token, err := LookupAccessToken("acme", "<"+command)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if token != "shpat_acme" {
+			t.Errorf("token = %q, want %q", token, "shpat_acme")
+		}
+	}
+
+	if runs() != 1 {
+		t.Errorf("command ran %d times, want 1", runs())
+	}
+
+	invalidateCachedToken("acme", "shpat_acme")
+
+	if _, err := LookupAccessToken("acme", "<"+command); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if runs() != 2 {
+		t.Errorf("command ran %d times after invalidation, want 2", runs())
+	}
+}
+
+func TestLookupAccessTokenCacheExpires(t *testing.T) {
+	testTokenCache(t, time.Hour)
+	command, runs := tokenCommand(t)
+
+	oldNow := now
+	defer func() { now = oldNow }()
+
+	LookupAccessToken("acme", "<"+command)
+
+	now = func() time.Time { return oldNow().Add(2 * time.Hour) }
+	LookupAccessToken("acme", "<"+command)
+
+	if runs() != 2 {
+		t.Errorf("command ran %d times, want 2", runs())
+	}
+}
+
+func TestTokenCacheIsEncrypted(t *testing.T) {
+	testTokenCache(t, time.Hour)
+	t.Setenv("SDT_TOKEN_CACHE_PASSPHRASE", "sekret")
+
+	cache, _ := LoadTokenCache()
+	cache.Set("acme", "shpat_123", time.Hour)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("unexpected error saving cache: %s", err)
+	}
+
+	path, _ := TokenCachePath()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "shpat_123") {
+		t.Error("token cache contains the plaintext token")
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("token cache mode = %o, want 600", info.Mode().Perm())
+	}
+
+	t.Setenv("SDT_TOKEN_CACHE_PASSPHRASE", "wrong")
+	if _, err := LoadTokenCache(); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("error = %v, want wrong passphrase error", err)
+	}
+}
+
+func TestLookupAccessTokenWithoutCache(t *testing.T) {
+	testTokenCache(t, 0)
+	command, runs := tokenCommand(t)
+
+	LookupAccessToken("acme", "<"+command)
+	LookupAccessToken("acme", "<"+command)
+
+	if runs() != 2 {
+		t.Errorf("command ran %d times, want 2", runs())
+	}
+
+	path, _ := TokenCachePath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("token cache exists with caching disabled")
+	}
+}
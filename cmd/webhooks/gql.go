@@ -1,7 +1,6 @@
 package webhooks
 
 import (
-	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,8 +8,8 @@ import (
 )
 
 const webhookSubscriptionsQuery = `
-query($first: Int!, $topics: [WebhookSubscriptionTopic!], $uri: String) {
-  webhookSubscriptions(first: $first, topics: $topics, uri: $uri) {
+query($first: Int!, $after: String, $topics: [WebhookSubscriptionTopic!], $uri: String) {
+  webhookSubscriptions(first: $first, after: $after, topics: $topics, uri: $uri) {
     edges {
       node {
         id
@@ -37,6 +36,10 @@ query($first: Int!, $topics: [WebhookSubscriptionTopic!], $uri: String) {
         }
       }
     }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
   }
 }
 `
@@ -134,16 +137,6 @@ type webhookJSON struct {
 	Endpoint  endpointJSON `json:"endpoint"`
 }
 
-type webhooksResponse struct {
-	Data struct {
-		WebhookSubscriptions struct {
-			Edges []struct {
-				Node webhookJSON `json:"node"`
-			} `json:"edges"`
-		} `json:"webhookSubscriptions"`
-	} `json:"data"`
-}
-
 func endpointAddress(e endpointJSON) string {
 	switch e.Typename {
 	case "WebhookHttpEndpoint":
@@ -186,24 +179,9 @@ func listWebhooks(shop, token string, topics []string, options map[string]interf
 		variables["uri"] = address
 	}
 
-	data, err := client.Execute(webhookSubscriptionsQuery, variables)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot list webhooks: %s", err)
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return nil, fmt.Errorf("Cannot re-encode webhooks response: %s", err)
-	}
-
-	var response webhooksResponse
-	if err := json.Unmarshal(b, &response); err != nil {
-		return nil, fmt.Errorf("Cannot parse webhooks response: %s", err)
-	}
-
 	var result []Webhook
-	for _, edge := range response.Data.WebhookSubscriptions.Edges {
-		n := edge.Node
+
+	err := gql.Paginate(client, webhookSubscriptionsQuery, variables, "webhookSubscriptions", func(n webhookJSON) error {
 		result = append(result, Webhook{
 			ID:                  n.LegacyResourceId,
 			GID:                 n.ID,
@@ -216,6 +194,11 @@ func listWebhooks(shop, token string, topics []string, options map[string]interf
 			CreatedAt:           n.CreatedAt,
 			UpdatedAt:           n.UpdatedAt,
 		})
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot list webhooks: %s", err)
 	}
 
 	return result, nil
@@ -224,16 +207,14 @@ func listWebhooks(shop, token string, topics []string, options map[string]interf
 func createWebhook(shop, token, topic, address, format string, fields []string, options map[string]interface{}) (string, error) {
 	client := gql.NewClient(shop, token, options)
 
-	var mutation, resultPath string
+	var mutation string
 	var input map[string]interface{}
 
 	if strings.HasPrefix(address, "arn:") {
 		mutation = eventBridgeWebhookSubscriptionCreateMutation
-		resultPath = "data.eventBridgeWebhookSubscriptionCreate"
 		input = map[string]interface{}{"arn": address}
 	} else {
 		mutation = webhookSubscriptionCreateMutation
-		resultPath = "data.webhookSubscriptionCreate"
 		input = map[string]interface{}{
 			"callbackUrl": address,
 			"format":      format,
@@ -250,63 +231,53 @@ func createWebhook(shop, token, topic, address, format string, fields []string,
 		input["metafields"] = v
 	}
 
-	data, err := client.Execute(mutation, map[string]interface{}{
+	// Both create mutations return the same payload under their own name
+	var response map[string]struct {
+		WebhookSubscription *struct {
+			LegacyResourceId string `json:"legacyResourceId"`
+		} `json:"webhookSubscription"`
+	}
+
+	err := client.ExecuteInto(mutation, map[string]interface{}{
 		"topic":               topicToEnum(topic),
 		"webhookSubscription": input,
-	})
+	}, &response)
 	if err != nil {
 		return "", fmt.Errorf("Cannot create webhook: %s", err)
 	}
 
-	userErrors, _ := data.ValuesForPath(resultPath + ".userErrors")
-	if len(userErrors) > 0 {
-		ueMap := userErrors[0].(map[string]interface{})
-		return "", fmt.Errorf("Cannot create webhook: %s", ueMap["message"])
-	}
-
-	id, err := data.ValueForPath(resultPath + ".webhookSubscription.legacyResourceId")
-	if err != nil {
-		return "", fmt.Errorf("Cannot read created webhook ID: %s", err)
+	for _, payload := range response {
+		if payload.WebhookSubscription != nil {
+			return payload.WebhookSubscription.LegacyResourceId, nil
+		}
 	}
 
-	return fmt.Sprint(id), nil
+	return "", fmt.Errorf("Cannot read created webhook ID: no webhook subscription in response")
 }
 
 func updateWebhook(shop, token, gid string, input map[string]interface{}, options map[string]interface{}) error {
 	client := gql.NewClient(shop, token, options)
 
-	data, err := client.Execute(webhookSubscriptionUpdateMutation, map[string]interface{}{
+	err := client.ExecuteInto(webhookSubscriptionUpdateMutation, map[string]interface{}{
 		"id":                  gid,
 		"webhookSubscription": input,
-	})
+	}, nil)
 	if err != nil {
 		return fmt.Errorf("Cannot update webhook: %s", err)
 	}
 
-	userErrors, _ := data.ValuesForPath("data.webhookSubscriptionUpdate.userErrors")
-	if len(userErrors) > 0 {
-		ueMap := userErrors[0].(map[string]interface{})
-		return fmt.Errorf("Cannot update webhook: %s", ueMap["message"])
-	}
-
 	return nil
 }
 
 func deleteWebhook(shop, token, gid string, options map[string]interface{}) error {
 	client := gql.NewClient(shop, token, options)
 
-	data, err := client.Execute(webhookSubscriptionDeleteMutation, map[string]interface{}{
+	err := client.ExecuteInto(webhookSubscriptionDeleteMutation, map[string]interface{}{
 		"id": gid,
-	})
+	}, nil)
 	if err != nil {
 		return fmt.Errorf("Cannot delete webhook: %s", err)
 	}
 
-	userErrors, _ := data.ValuesForPath("data.webhookSubscriptionDelete.userErrors")
-	if len(userErrors) > 0 {
-		ueMap := userErrors[0].(map[string]interface{})
-		return fmt.Errorf("Cannot delete webhook: %s", ueMap["message"])
-	}
-
 	return nil
 }
@@ -78,7 +78,10 @@ func parseMetafields(raw []string) ([]map[string]string, error) {
 
 func createAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{"verbose": c.Bool("verbose")}
 
 	metafields, err := parseMetafields(c.StringSlice("metafields"))
@@ -121,7 +124,10 @@ func createAction(c *cli.Context) error {
 
 func deleteAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{"verbose": c.Bool("verbose")}
 
 	var webhooks []Webhook
@@ -172,7 +178,10 @@ func updateAction(c *cli.Context) error {
 	}
 
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{"verbose": c.Bool("verbose")}
 	gid := webhookGID(c.Args().Get(0))
 
@@ -201,7 +210,7 @@ func updateAction(c *cli.Context) error {
 		return fmt.Errorf("You must supply at least one option to update")
 	}
 
-	err := updateWebhook(shop, token, gid, input, options)
+	err = updateWebhook(shop, token, gid, input, options)
 	if err != nil {
 		return err
 	}
@@ -212,7 +221,10 @@ func updateAction(c *cli.Context) error {
 
 func listAction(c *cli.Context) error {
 	shop := c.String("shop")
-	token := cmd.LookupAccessToken(shop, c.String("access-token"))
+	token, err := cmd.AccessToken(c)
+	if err != nil {
+		return err
+	}
 	options := map[string]interface{}{}
 
 	if c.IsSet("address") {
@@ -0,0 +1,131 @@
+package gql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// OAuthToken is the result of exchanging an authorization code or client
+// credentials for an access token.
+type OAuthToken struct {
+	AccessToken string `json:"access_token"`
+	Scope       string `json:"scope"`
+	// Seconds until the token expires, 0 if it doesn't
+	ExpiresIn int `json:"expires_in"`
+}
+
+// AuthorizeURL returns the URL used to ask the shop's staff to grant the app
+// with the given client ID the scopes. After they do Shopify redirects to
+// redirectURI with an authorization code.
+func AuthorizeURL(shop, clientID string, scopes []string, redirectURI, state string) string {
+	query := url.Values{
+		"client_id":    {clientID},
+		"scope":        {strings.Join(scopes, ",")},
+		"redirect_uri": {redirectURI},
+		"state":        {state},
+	}
+
+	return adminURL(shop) + "/admin/oauth/authorize?" + query.Encode()
+}
+
+// VerifyHMAC reports whether the hmac parameter of a request from Shopify,
+// e.g., the OAuth callback, was signed with the app's client secret.
+func VerifyHMAC(query url.Values, clientSecret string) bool {
+	signature, err := hex.DecodeString(query.Get("hmac"))
+	if err != nil || len(signature) == 0 {
+		return false
+	}
+
+	params := url.Values{}
+	for name, values := range query {
+		if name != "hmac" {
+			params[name] = values
+		}
+	}
+
+	mac := hmac.New(sha256.New, []byte(clientSecret))
+	mac.Write([]byte(params.Encode()))
+
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// ExchangeAuthorizationCode gets an access token for the shop using the code
+// given to the OAuth callback.
+func ExchangeAuthorizationCode(shop, clientID, clientSecret, code string) (*OAuthToken, error) {
+	form := url.Values{
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"code":          {code},
+	}
+
+	return requestAccessToken(shop, form)
+}
+
+// ExchangeClientCredentials gets an access token for the shop using a custom
+// app's client ID (API key) and secret via the OAuth client credentials grant.
+func ExchangeClientCredentials(shop, clientID, clientSecret string) (*OAuthToken, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+	}
+
+	return requestAccessToken(shop, form)
+}
+
+func requestAccessToken(shop string, form url.Values) (*OAuthToken, error) {
+	endpoint := adminURL(shop) + "/admin/oauth/access_token"
+
+	resp, err := HTTPClient.PostForm(endpoint, form)
+	if err != nil {
+		return nil, fmt.Errorf("Access token request to %s failed: %s", endpoint, err)
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read access token response from %s: %s", endpoint, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var oauthError struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+
+		if json.Unmarshal(body, &oauthError) == nil && oauthError.Error != "" {
+			message := oauthError.Error
+			if oauthError.ErrorDescription != "" {
+				message += ": " + oauthError.ErrorDescription
+			}
+
+			return nil, fmt.Errorf("Access token request failed with HTTP response code %d: %s", resp.StatusCode, message)
+		}
+
+		return nil, fmt.Errorf("Access token request failed with HTTP response code %d", resp.StatusCode)
+	}
+
+	var token OAuthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal access token response: %s", err)
+	}
+
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("Access token response has no access token")
+	}
+
+	return &token, nil
+}
+
+// shopName returns the shop's name given NAME.myshopify.com or just NAME.
+func shopName(shop string) string {
+	return strings.SplitN(shop, ".", 2)[0]
+}
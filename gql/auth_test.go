@@ -0,0 +1,122 @@
+package gql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func testOAuthServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	old := AdminURL
+	AdminURL = server.URL + "/%s"
+	t.Cleanup(func() { AdminURL = old })
+}
+
+func TestExchangeClientCredentials(t *testing.T) {
+	testOAuthServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acme/admin/oauth/access_token" {
+			t.Errorf("path = %q, want %q", r.URL.Path, "/acme/admin/oauth/access_token")
+		}
+
+		r.ParseForm()
+		for name, want := range map[string]string{"grant_type": "client_credentials", "client_id": "key", "client_secret": "secret"} {
+			if got := r.PostForm.Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+
+		w.Write([]byte(`{"access_token":"shpat_123","scope":"read_products","expires_in":86399}`))
+	})
+
+	token, err := ExchangeClientCredentials("acme.myshopify.com", "key", "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if token.AccessToken != "shpat_123" || token.ExpiresIn != 86399 {
+		t.Errorf("token = %+v, want access token shpat_123 expiring in 86399", token)
+	}
+}
+
+func TestExchangeClientCredentialsError(t *testing.T) {
+	testOAuthServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_client","error_description":"Client authentication failed"}`))
+	})
+
+	_, err := ExchangeClientCredentials("acme", "key", "bad")
+	if err == nil || !strings.Contains(err.Error(), "invalid_client: Client authentication failed") {
+		t.Errorf("error = %v, want the OAuth error description", err)
+	}
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	testOAuthServer(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		for name, want := range map[string]string{"client_id": "key", "client_secret": "secret", "code": "abc"} {
+			if got := r.PostForm.Get(name); got != want {
+				t.Errorf("%s = %q, want %q", name, got, want)
+			}
+		}
+
+		if got := r.PostForm.Get("grant_type"); got != "" {
+			t.Errorf("grant_type = %q, want none", got)
+		}
+
+		w.Write([]byte(`{"access_token":"shpat_456","scope":"read_products,write_products"}`))
+	})
+
+	token, err := ExchangeAuthorizationCode("acme", "key", "secret", "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if token.AccessToken != "shpat_456" || token.Scope != "read_products,write_products" {
+		t.Errorf("token = %+v, want access token shpat_456 with read_products,write_products", token)
+	}
+}
+
+func TestAuthorizeURL(t *testing.T) {
+	got := AuthorizeURL("acme.myshopify.com", "key", []string{"read_products", "write_products"}, "http://localhost:3456/callback", "xyz")
+	want := "https://acme.myshopify.com/admin/oauth/authorize?client_id=key&redirect_uri=http%3A%2F%2Flocalhost%3A3456%2Fcallback&scope=read_products%2Cwrite_products&state=xyz"
+
+	if got != want {
+		t.Errorf("AuthorizeURL() = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	// Signed the way Shopify does: the sorted query string without hmac
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("code=abc&shop=acme.myshopify.com&state=xyz&timestamp=1700000000"))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		query  string
+		secret string
+		want   bool
+	}{
+		{name: "valid", query: "shop=acme.myshopify.com&code=abc&hmac=" + signature + "&timestamp=1700000000&state=xyz", secret: "secret", want: true},
+		{name: "wrong secret", query: "shop=acme.myshopify.com&code=abc&hmac=" + signature + "&timestamp=1700000000&state=xyz", secret: "other", want: false},
+		{name: "tampered", query: "shop=evil.myshopify.com&code=abc&hmac=" + signature + "&timestamp=1700000000&state=xyz", secret: "secret", want: false},
+		{name: "missing", query: "shop=acme.myshopify.com&code=abc&timestamp=1700000000&state=xyz", secret: "secret", want: false},
+	}
+
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		if got := VerifyHMAC(query, tt.secret); got != tt.want {
+			t.Errorf("%s: VerifyHMAC() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
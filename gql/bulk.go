@@ -0,0 +1,318 @@
+package gql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const bulkOperationRunQueryMutation = `
+mutation bulkOperationRunQuery($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation {
+      id
+      status
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+const bulkOperationStatusQuery = `
+query($id: ID!) {
+  node(id: $id) {
+    ... on BulkOperation {
+      id
+      status
+      errorCode
+      objectCount
+      rootObjectCount
+      url
+      createdAt
+      completedAt
+    }
+  }
+}
+`
+
+const bulkOperationCancelMutation = `
+mutation bulkOperationCancel($id: ID!) {
+  bulkOperationCancel(id: $id) {
+    bulkOperation {
+      id
+      status
+    }
+    userErrors {
+      field
+      message
+    }
+  }
+}
+`
+
+// BulkPollInterval is how often the status of a bulk operation is checked
+var BulkPollInterval = 2 * time.Second
+
+type BulkOperationResult struct {
+	ID              string `json:"id"`
+	Status          string `json:"status"`
+	ErrorCode       string `json:"errorCode"`
+	ObjectCount     int    `json:"objectCount,string"`
+	RootObjectCount int    `json:"rootObjectCount,string"`
+	URL             string `json:"url"`
+	CreatedAt       string `json:"createdAt"`
+	CompletedAt     string `json:"completedAt"`
+}
+
+type bulkOperationStatusResponse struct {
+	Node BulkOperationResult `json:"node"`
+}
+
+type bulkOperationCancelResponse struct {
+	BulkOperationCancel struct {
+		BulkOperation struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"bulkOperation"`
+	} `json:"bulkOperationCancel"`
+}
+
+type bulkOperationRunQueryResponse struct {
+	BulkOperationRunQuery struct {
+		BulkOperation struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"bulkOperation"`
+	} `json:"bulkOperationRunQuery"`
+}
+
+func FetchBulkOperationStatus(client *Client, operationID string) (*BulkOperationResult, error) {
+	var response bulkOperationStatusResponse
+	if err := client.ExecuteInto(bulkOperationStatusQuery, map[string]interface{}{
+		"id": operationID,
+	}, &response); err != nil {
+		return nil, fmt.Errorf("Cannot fetch bulk operation status: %s", err)
+	}
+
+	return &response.Node, nil
+}
+
+// CancelBulkOperation cancels the operation and returns its ID and status
+func CancelBulkOperation(client *Client, operationID string) (string, string, error) {
+	var response bulkOperationCancelResponse
+	if err := client.ExecuteInto(bulkOperationCancelMutation, map[string]interface{}{
+		"id": operationID,
+	}, &response); err != nil {
+		return "", "", fmt.Errorf("Cannot cancel bulk operation: %s", err)
+	}
+
+	op := response.BulkOperationCancel.BulkOperation
+	return op.ID, op.Status, nil
+}
+
+func StartBulkQuery(client *Client, query string) (string, error) {
+	var response bulkOperationRunQueryResponse
+	if err := client.ExecuteInto(bulkOperationRunQueryMutation, map[string]interface{}{
+		"query": query,
+	}, &response); err != nil {
+		return "", fmt.Errorf("Cannot start bulk query: %s", err)
+	}
+
+	return response.BulkOperationRunQuery.BulkOperation.ID, nil
+}
+
+// WaitForBulkOperation polls the operation until it's finished, calling
+// progress, if given, after each poll. It's an error for the operation
+// to finish without completing.
+func WaitForBulkOperation(client *Client, operationID string, progress func(BulkOperationResult)) (*BulkOperationResult, error) {
+	for {
+		result, err := FetchBulkOperationStatus(client, operationID)
+		if err != nil {
+			return nil, err
+		}
+
+		if progress != nil {
+			progress(*result)
+		}
+
+		switch result.Status {
+		case "COMPLETED":
+			return result, nil
+		case "CANCELED", "EXPIRED":
+			return nil, fmt.Errorf("Bulk operation %s was %s", operationID, strings.ToLower(result.Status))
+		case "FAILED":
+			return nil, fmt.Errorf("Bulk operation %s failed: %s", operationID, result.ErrorCode)
+		}
+
+		time.Sleep(BulkPollInterval)
+	}
+}
+
+// PrintBulkProgress is a progress function for WaitForBulkOperation and
+// BulkQuery that reports the operation's status on stderr.
+func PrintBulkProgress(result BulkOperationResult) {
+	fmt.Fprintf(os.Stderr, "\rBulk query %s: %d objects", strings.ToLower(result.Status), result.ObjectCount)
+	if result.Status == "COMPLETED" {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// BulkQuery runs query as a bulk operation and calls fn with each of its
+// top-level objects, decoded into T.
+//
+// The result's nested objects are added back to their parents so that T can
+// be the same type used for a paginated query. paths maps the GID type of a
+// nested object to the path of its connection in its parent, e.g.,
+// "ProductVariant" to "variants" and "InventoryLevel" to
+// "inventoryItem.inventoryLevels". Objects of other types are ignored.
+func BulkQuery[T any](client *Client, query string, paths map[string]string, fn func(T) error, progress func(BulkOperationResult)) error {
+	operationID, err := StartBulkQuery(client, query)
+	if err != nil {
+		return err
+	}
+
+	result, err := WaitForBulkOperation(client, operationID, progress)
+	if err != nil {
+		return err
+	}
+
+	// No URL when there are no results
+	if result.URL == "" {
+		return nil
+	}
+
+	resp, err := HTTPClient.Get(result.URL)
+	if err != nil {
+		return fmt.Errorf("Cannot download bulk query results: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Cannot download bulk query results: %s", resp.Status)
+	}
+
+	return ReadBulkQueryResults(resp.Body, paths, func(object json.RawMessage) error {
+		var value T
+		if err := json.Unmarshal(object, &value); err != nil {
+			return fmt.Errorf("Cannot decode bulk query result: %s", err)
+		}
+
+		return fn(value)
+	})
+}
+
+// ReadBulkQueryResults reads the JSONL results of a bulk query from r and
+// calls fn with each top-level object once its nested objects have been read.
+// See BulkQuery for paths.
+//
+// Nested objects must follow their top-level object, as they do in
+// Shopify's results, so only one top-level object is held in memory.
+func ReadBulkQueryResults(r io.Reader, paths map[string]string, fn func(json.RawMessage) error) error {
+	var root map[string]interface{}
+	// Objects under root by ID. nil for objects of ignored types.
+	objects := map[string]map[string]interface{}{}
+
+	flush := func() error {
+		if root == nil {
+			return nil
+		}
+
+		object, err := json.Marshal(root)
+		if err != nil {
+			return err
+		}
+
+		return fn(object)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+
+		var object map[string]interface{}
+		if err := decoder.Decode(&object); err != nil {
+			return fmt.Errorf("Cannot parse bulk query result line %d: %s", line, err)
+		}
+
+		id, _ := object["id"].(string)
+		parentID, _ := object["__parentId"].(string)
+		delete(object, "__parentId")
+
+		if parentID == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+
+			root = object
+			objects = map[string]map[string]interface{}{id: object}
+			continue
+		}
+
+		parent, ok := objects[parentID]
+		if !ok {
+			return fmt.Errorf("Bulk query result line %d has unknown parent %s", line, parentID)
+		}
+
+		path := paths[gidType(id)]
+		if parent == nil || path == "" {
+			objects[id] = nil
+			continue
+		}
+
+		appendNode(parent, path, object)
+		objects[id] = object
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Cannot read bulk query results: %s", err)
+	}
+
+	return flush()
+}
+
+// appendNode adds node to the connection at path in object, in both its edges
+// and nodes, whichever the caller decodes.
+func appendNode(object map[string]interface{}, path string, node map[string]interface{}) {
+	for _, name := range strings.Split(path, ".") {
+		next, ok := object[name].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			object[name] = next
+		}
+
+		object = next
+	}
+
+	edges, _ := object["edges"].([]interface{})
+	nodes, _ := object["nodes"].([]interface{})
+	object["edges"] = append(edges, map[string]interface{}{"node": node})
+	object["nodes"] = append(nodes, node)
+}
+
+// gidType returns the type of gid, e.g., "Product" for "gid://shopify/Product/1"
+func gidType(gid string) string {
+	parts := strings.SplitN(strings.TrimPrefix(gid, "gid://shopify/"), "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+
+	return parts[0]
+}
@@ -0,0 +1,70 @@
+package gql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBulkQueryResults(t *testing.T) {
+	jsonl := `{"id":"gid://shopify/Metaobject/1","handle":"a"}
+{"id":"gid://shopify/Metafield/10","key":"x","__parentId":"gid://shopify/Metaobject/1"}
+{"id":"gid://shopify/MediaImage/20","__parentId":"gid://shopify/Metaobject/1"}
+
+{"id":"gid://shopify/Metaobject/2","handle":"b"}
+`
+
+	var objects []string
+	err := ReadBulkQueryResults(strings.NewReader(jsonl), map[string]string{"Metafield": "fields.metafields"}, func(object json.RawMessage) error {
+		objects = append(objects, string(object))
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("ReadBulkQueryResults failed: %s", err)
+	}
+
+	want := []string{
+		`{"fields":{"metafields":{"edges":[{"node":{"id":"gid://shopify/Metafield/10","key":"x"}}],"nodes":[{"id":"gid://shopify/Metafield/10","key":"x"}]}},"handle":"a","id":"gid://shopify/Metaobject/1"}`,
+		`{"handle":"b","id":"gid://shopify/Metaobject/2"}`,
+	}
+
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("objects = %v, want %v", objects, want)
+	}
+}
+
+func TestReadBulkQueryResultsUnknownParent(t *testing.T) {
+	jsonl := `{"id":"gid://shopify/Product/1"}
+{"id":"gid://shopify/Product/2"}
+{"id":"gid://shopify/ProductVariant/10","__parentId":"gid://shopify/Product/1"}
+`
+
+	err := ReadBulkQueryResults(strings.NewReader(jsonl), map[string]string{"ProductVariant": "variants"}, func(json.RawMessage) error { return nil })
+	if err == nil {
+		t.Fatal("err = nil, want error for unknown parent")
+	}
+
+	want := "Bulk query result line 3 has unknown parent gid://shopify/Product/1"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestGIDType(t *testing.T) {
+	tests := []struct {
+		gid  string
+		want string
+	}{
+		{"gid://shopify/Product/1", "Product"},
+		{"gid://shopify/InventoryLevel/1?inventory_item_id=2", "InventoryLevel"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := gidType(tt.gid); got != tt.want {
+			t.Errorf("gidType(%q) = %q, want %q", tt.gid, got, tt.want)
+		}
+	}
+}
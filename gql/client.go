@@ -2,11 +2,14 @@ package gql
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/cheynewallace/tabby"
 	"github.com/clbanning/mxj"
@@ -15,19 +18,35 @@ import (
 )
 
 type Client struct {
-	endpoint  string
-	token     string
-	costDebug bool
-	verbose   bool
+	shop       string
+	endpoint   string
+	token      string
+	costDebug  bool
+	verbose    bool
+	maxRetries int
+	bucket     *bucket
 }
 
 // We omit the "/" after API for the case where there's no version.
-const endpoint = "https://%s.myshopify.com/admin/api%s/graphql.json"
+const endpoint = "%s/admin/api%s/graphql.json"
+
+// AdminURL is where Admin API requests are sent. %s is replaced with the
+// shop's name. It can be changed to send requests elsewhere, e.g., to
+// "sdt mock-server". Set once per process.
+var AdminURL = "https://%s.myshopify.com"
 
 // DefaultAPIVersion is used when NewClient is called without a "version"
 // option. Set once per process (the CLI sets it from --api-version).
 var DefaultAPIVersion string
 
+// ReadOnly prevents mutations from being executed, as does setting the
+// SDT_READONLY environment variable. Set once per process.
+var ReadOnly bool
+
+// OnUnauthorized, when set, is called with the shop and access token of a
+// request that failed with HTTP 401, e.g., to discard a cached token.
+var OnUnauthorized func(shop, token string)
+
 func NewClient(shop, token string, options ...map[string]interface{}) *Client {
 	opts := map[string]interface{}{}
 	if len(options) > 0 {
@@ -43,19 +62,36 @@ func NewClient(shop, token string, options ...map[string]interface{}) *Client {
 	}
 
 	// allow for NAME.myshopify.com or just NAME
-	shop = strings.SplitN(shop, ".", 2)[0]
+	shop = shopName(shop)
 
 	extras, _ := opts["extras"].(bool)
 	verbose, _ := opts["verbose"].(bool)
 
+	retries, ok := opts["retries"].(int)
+	if !ok {
+		retries = defaultMaxRetries
+	}
+
 	return &Client{
-		endpoint:  fmt.Sprintf(endpoint, shop, version),
-		token:     token,
-		costDebug: extras,
-		verbose:   verbose,
+		shop:       shop,
+		endpoint:   fmt.Sprintf(endpoint, adminURL(shop), version),
+		token:      token,
+		costDebug:  extras,
+		verbose:    verbose,
+		maxRetries: retries,
+		bucket:     bucketFor(shop),
 	}
 }
 
+// adminURL returns AdminURL for the shop.
+func adminURL(shop string) string {
+	if !strings.Contains(AdminURL, "%s") {
+		return strings.TrimSuffix(AdminURL, "/")
+	}
+
+	return fmt.Sprintf(AdminURL, shopName(shop))
+}
+
 func containsMutation(query string) bool {
 	doc, err := parser.ParseQuery(&ast.Source{Input: query})
 	if err != nil {
@@ -70,11 +106,106 @@ func containsMutation(query string) bool {
 }
 
 func (c *Client) Execute(q string, variables ...map[string]interface{}) (mxj.Map, error) {
+	body, err := c.execute(q, variables...)
+	if body == nil {
+		return nil, err
+	}
+
+	// results in parse error
+	//result, err = mxj.NewMapJsonReader(resp.Body)
+
+	result, jsonErr := mxj.NewMapJson(body)
+	if jsonErr != nil {
+		return result, fmt.Errorf("Failed to unmarshal GraphQL response body: %s", jsonErr)
+	}
+
+	return result, err
+}
+
+// ExecuteInto runs the query and decodes its "data" field into dest. If a
+// mutation's payload has userErrors, dest is still populated and a UserErrors
+// error is returned; use errors.As to inspect them.
+func (c *Client) ExecuteInto(q string, variables map[string]interface{}, dest interface{}) error {
+	body, err := c.execute(q, variables)
+	if err != nil {
+		return err
+	}
+
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("Failed to unmarshal GraphQL response body: %s", err)
+	}
+
+	if dest != nil && len(response.Data) > 0 {
+		if err := json.Unmarshal(response.Data, dest); err != nil {
+			return fmt.Errorf("Failed to unmarshal GraphQL response data: %s", err)
+		}
+	}
+
+	if !containsMutation(q) {
+		return nil
+	}
+
+	return payloadUserErrors(response.Data)
+}
+
+// payloadUserErrors collects the userErrors of each mutation payload in data.
+func payloadUserErrors(data json.RawMessage) error {
+	var payloads map[string]struct {
+		UserErrors UserErrors `json:"userErrors"`
+	}
+
+	// Payloads without userErrors (or that aren't objects) aren't an error
+	if json.Unmarshal(data, &payloads) != nil {
+		return nil
+	}
+
+	var userErrors UserErrors
+	for _, payload := range payloads {
+		userErrors = append(userErrors, payload.UserErrors...)
+	}
+
+	if len(userErrors) > 0 {
+		return userErrors
+	}
+
+	return nil
+}
+
+// UserError is an entry in a mutation payload's userErrors list.
+type UserError struct {
+	Field   []string `json:"field"`
+	Message string   `json:"message"`
+	Code    string   `json:"code"`
+}
+
+// UserErrors is returned by ExecuteInto when a mutation fails validation.
+type UserErrors []UserError
+
+func (e UserErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, ue := range e {
+		messages[i] = ue.Message
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// execute runs the query and returns the raw response body. On GraphQL
+// errors the body is returned along with the error.
+func (c *Client) execute(q string, variables ...map[string]interface{}) ([]byte, error) {
 	readonly := os.Getenv("SDT_READONLY")
 	if (readonly == "1" || readonly == "true") && containsMutation(q) {
 		return nil, fmt.Errorf("Mutation not allowed in read-only mode (SDT_READONLY environment variable is set)")
 	}
 
+	if ReadOnly && containsMutation(q) {
+		return nil, fmt.Errorf("Mutation not allowed in read-only mode (profile is read-only)")
+	}
+
 	merged := map[string]interface{}{}
 	for _, v := range variables {
 		for k, val := range v {
@@ -84,17 +215,50 @@ func (c *Client) Execute(q string, variables ...map[string]interface{}) (mxj.Map
 	return c.request(q, merged)
 }
 
-func (c *Client) request(gql string, variables map[string]interface{}) (mxj.Map, error) {
-	var result mxj.Map
-
+// request sends the query, waiting first if the shop's query cost budget is
+// exhausted. Throttled requests and HTTP 429s are retried, as are 5xx and
+// connection failures when the request isn't a mutation (it may have been
+// processed). Gives up after maxRetries retries.
+func (c *Client) request(gql string, variables map[string]interface{}) ([]byte, error) {
 	body, err := c.createRequestBody(gql, variables)
 	if err != nil {
-		return result, fmt.Errorf("Failed to marshal GraphQL request body: %s", err)
+		return nil, fmt.Errorf("Failed to marshal GraphQL request body: %s", err)
+	}
+
+	mutation := containsMutation(gql)
+
+	for attempt := 0; ; attempt++ {
+		if waited := c.bucket.reserve(gql); waited > 0 && c.verbose {
+			fmt.Fprintf(os.Stderr, "* waited %s for query cost budget\n", waited)
+		}
+
+		result, err := c.send(gql, body)
+		if err == nil {
+			return result, nil
+		}
+
+		retry, ok := err.(*retryableError)
+		if !ok {
+			return result, err
+		}
+
+		if attempt >= c.maxRetries || (mutation && !retry.safe) {
+			return result, retry.err
+		}
+
+		delay := backoff(attempt, retry.after)
+		if c.verbose {
+			fmt.Fprintf(os.Stderr, "* %s; retrying in %s\n", retry.err, delay)
+		}
+
+		sleep(delay)
 	}
+}
 
-	client := http.Client{}
+func (c *Client) send(gql, body string) ([]byte, error) {
+	var result []byte
 
-	req, err := http.NewRequest("POST", c.endpoint, strings.NewReader(string(body)))
+	req, err := http.NewRequest("POST", c.endpoint, strings.NewReader(body))
 	if err != nil {
 		return result, fmt.Errorf("Failed to make GraphQL request to %s: %s", c.endpoint, err)
 	}
@@ -115,19 +279,42 @@ func (c *Client) request(gql string, variables map[string]interface{}) (mxj.Map,
 		fmt.Fprintf(os.Stderr, ">\n%s\n\n", body)
 	}
 
-	resp, err := client.Do(req)
+	resp, err := HTTPClient.Do(req)
+	if errors.Is(err, ErrNoFixture) {
+		return result, err
+	}
+
 	if err != nil {
-		return result, fmt.Errorf("GraphQL request to %s failed: %s", c.endpoint, err)
+		return result, &retryableError{err: fmt.Errorf("GraphQL request to %s failed: %s", c.endpoint, err)}
 	}
 
 	defer resp.Body.Close()
 	bytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return result, &retryableError{err: fmt.Errorf("Failed to read GraphQL response from %s: %s", c.endpoint, err)}
+	}
 
 	if resp.StatusCode != http.StatusOK {
+		var err error
 		if len(bytes) > 0 {
-			return result, fmt.Errorf("query failed with HTTP response code %d: %s", resp.StatusCode, string(bytes))
+			err = fmt.Errorf("query failed with HTTP response code %d: %s", resp.StatusCode, string(bytes))
+		} else {
+			err = fmt.Errorf("query failed with HTTP response code %d", resp.StatusCode)
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && OnUnauthorized != nil && c.token != "" {
+			OnUnauthorized(c.shop, c.token)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return result, &retryableError{err: err, after: retryAfter(resp.Header), safe: true}
+		}
+
+		if resp.StatusCode >= 500 {
+			return result, &retryableError{err: err, after: retryAfter(resp.Header)}
 		}
-		return result, fmt.Errorf("query failed with HTTP response code %d", resp.StatusCode)
+
+		return result, err
 	}
 
 	if c.verbose {
@@ -140,46 +327,98 @@ func (c *Client) request(gql string, variables map[string]interface{}) (mxj.Map,
 		fmt.Fprintf(os.Stderr, "<\n%s\n\n", string(bytes))
 	}
 
-	// results in parse error
-	//result, err = mxj.NewMapJsonReader(resp.Body)
-
-	result, err = mxj.NewMapJson(bytes)
-	if err != nil {
+	var envelope responseEnvelope
+	if err := json.Unmarshal(bytes, &envelope); err != nil {
 		return result, fmt.Errorf("Failed to unmarshal GraphQL response body: %s", err)
 	}
 
-	if err := responseErrors(result); err != nil {
-		return result, err
+	c.bucket.update(gql, envelope.Extensions.Cost.RequestedQueryCost, envelope.Extensions.Cost.ThrottleStatus)
+
+	if err := responseErrors(envelope.Errors); err != nil {
+		if envelope.throttled() {
+			return bytes, &retryableError{err: err, safe: true}
+		}
+
+		return bytes, err
+	}
+
+	return bytes, nil
+}
+
+// retryAfter parses the Retry-After header, which Shopify sends in seconds.
+func retryAfter(header http.Header) time.Duration {
+	seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64)
+	if err != nil || seconds < 0 {
+		return 0
 	}
 
-	return result, nil
+	return time.Duration(seconds * float64(time.Second))
 }
 
-func responseErrors(result mxj.Map) error {
-	errors, _ := result.ValuesForPath("errors")
+// responseEnvelope is the part of a response common to all queries.
+type responseEnvelope struct {
+	Errors     responseErrorList `json:"errors"`
+	Extensions struct {
+		Cost struct {
+			RequestedQueryCost float64        `json:"requestedQueryCost"`
+			ThrottleStatus     throttleStatus `json:"throttleStatus"`
+		} `json:"cost"`
+	} `json:"extensions"`
+}
+
+type responseError struct {
+	Message    string        `json:"message"`
+	Path       []interface{} `json:"path"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+type responseErrorList []responseError
+
+// UnmarshalJSON accepts the error list as well as the plain string Shopify
+// sometimes returns in its place.
+func (l *responseErrorList) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		*l = responseErrorList{{Message: message}}
+		return nil
+	}
+
+	var errors []responseError
+	if err := json.Unmarshal(data, &errors); err != nil {
+		return err
+	}
+
+	*l = errors
+	return nil
+}
+
+func (e responseEnvelope) throttled() bool {
+	for _, err := range e.Errors {
+		if err.Extensions.Code == "THROTTLED" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func responseErrors(errors responseErrorList) error {
 	if len(errors) == 0 {
 		return nil
 	}
 
 	var messages []string
 	for _, e := range errors {
-		eMap, ok := e.(map[string]interface{})
-		if !ok {
-			messages = append(messages, fmt.Sprint(e))
-			continue
-		}
-
-		message := fmt.Sprint(eMap["message"])
+		message := e.Message
 
-		if path, ok := eMap["path"]; ok {
-			items, ok := path.([]interface{})
-			if ok {
-				parts := make([]string, len(items))
-				for i, p := range items {
-					parts[i] = fmt.Sprint(p)
-				}
-				message += fmt.Sprintf(" at %s", strings.Join(parts, "."))
+		if len(e.Path) > 0 {
+			parts := make([]string, len(e.Path))
+			for i, p := range e.Path {
+				parts[i] = fmt.Sprint(p)
 			}
+			message += fmt.Sprintf(" at %s", strings.Join(parts, "."))
 		}
 
 		messages = append(messages, message)
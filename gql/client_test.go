@@ -1,6 +1,9 @@
 package gql
 
 import (
+	"errors"
+	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -58,3 +61,112 @@ func TestNewClientDefaultAPIVersion(t *testing.T) {
 		t.Errorf("expected versionless endpoint: %s", client.endpoint)
 	}
 }
+
+func TestExecuteIntoDecodesData(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"product":{"id":"gid://shopify/Product/1","legacyResourceId":"9007199254740993"}}}`))
+	})
+
+	var response struct {
+		Product struct {
+			ID               string `json:"id"`
+			LegacyResourceID int64  `json:"legacyResourceId,string"`
+		} `json:"product"`
+	}
+
+	if err := client.ExecuteInto("query { product { id } }", nil, &response); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if response.Product.ID != "gid://shopify/Product/1" {
+		t.Errorf("ID = %q, want %q", response.Product.ID, "gid://shopify/Product/1")
+	}
+
+	if response.Product.LegacyResourceID != 9007199254740993 {
+		t.Errorf("LegacyResourceID = %d, want %d", response.Product.LegacyResourceID, int64(9007199254740993))
+	}
+}
+
+func TestExecuteIntoReturnsUserErrors(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"productSet":{"product":null,"userErrors":[{"field":["input","title"],"message":"Title can't be blank","code":"BLANK"}]}}}`))
+	})
+
+	var response struct {
+		ProductSet struct {
+			Product *struct{} `json:"product"`
+		} `json:"productSet"`
+	}
+
+	err := client.ExecuteInto("mutation { productSet { userErrors { message } } }", nil, &response)
+
+	var userErrors UserErrors
+	if !errors.As(err, &userErrors) {
+		t.Fatalf("err = %v, want UserErrors", err)
+	}
+
+	want := UserErrors{{Field: []string{"input", "title"}, Message: "Title can't be blank", Code: "BLANK"}}
+	if !reflect.DeepEqual(userErrors, want) {
+		t.Errorf("userErrors = %+v, want %+v", userErrors, want)
+	}
+
+	if err.Error() != "Title can't be blank" {
+		t.Errorf("err.Error() = %q, want %q", err.Error(), "Title can't be blank")
+	}
+}
+
+func TestOnUnauthorizedCalledForHTTP401(t *testing.T) {
+	var shop, token string
+
+	old := OnUnauthorized
+	OnUnauthorized = func(s, tok string) { shop, token = s, tok }
+	defer func() { OnUnauthorized = old }()
+
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	if _, err := client.Execute("{ shop { name } }"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if shop != t.Name() || token != "token" {
+		t.Errorf("OnUnauthorized(%q, %q), want (%q, %q)", shop, token, t.Name(), "token")
+	}
+}
+
+const productsFixtureQuery = `query($first: Int!, $after: String) {
+  products(first: $first, after: $after) {
+    edges { node { id title } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+// Replays testdata/fixtures/products, recorded with NewRecorder
+func TestPaginateWithReplayedFixtures(t *testing.T) {
+	old := HTTPClient
+	HTTPClient = &http.Client{Transport: NewReplayer("testdata/fixtures/products")}
+	defer func() { HTTPClient = old }()
+
+	type product struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}
+
+	var products []product
+
+	client := NewClient("acme", "shpat_other", map[string]interface{}{"version": "2026-07"})
+	err := Paginate(client, productsFixtureQuery, nil, "products", func(node product) error {
+		products = append(products, node)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []product{{"gid://shopify/Product/9007199254740993", "Hat"}, {"gid://shopify/Product/2", "Scarf"}}
+	if !reflect.DeepEqual(products, want) {
+		t.Errorf("products = %v, want %v", products, want)
+	}
+}
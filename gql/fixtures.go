@@ -0,0 +1,344 @@
+package gql
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// HTTPClient is used for all requests to Shopify, including staged uploads and
+// bulk operation downloads. Its Transport can be replaced, e.g., by
+// NewRecorder or NewReplayer. Set once per process.
+var HTTPClient = &http.Client{}
+
+// ErrNoFixture is returned by a replayer when there is no recorded response
+// for a request.
+var ErrNoFixture = errors.New("no recorded response")
+
+const redacted = "REDACTED"
+
+// Form fields and JSON properties that contain credentials
+var secretFormParams = map[string]bool{
+	"client_secret": true,
+	"code":          true,
+	"hmac":          true,
+}
+
+var secretJSONProperties = map[string]bool{
+	"access_token": true,
+}
+
+// Response headers worth keeping; the rest are volatile, e.g., Date and X-Request-Id
+var fixtureResponseHeaders = []string{"Content-Type", "Retry-After"}
+
+var multipartBoundary = regexp.MustCompile(`boundary=([^;\s]+)`)
+
+type fixtureRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+type fixtureResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// fixture is a request/response pair as written to disk.
+type fixture struct {
+	Request  fixtureRequest  `json:"request"`
+	Response fixtureResponse `json:"response"`
+}
+
+// fixtures keeps track of how many times each request has been seen so that
+// repeated identical requests, e.g., polling a bulk operation, are stored
+// separately and replayed in order.
+type fixtures struct {
+	dir   string
+	mu    sync.Mutex
+	count map[string]int
+}
+
+func (f *fixtures) next(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count[key]++
+
+	return f.path(key, f.count[key])
+}
+
+func (f *fixtures) path(key string, n int) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%s-%d.json", key, n))
+}
+
+type recorder struct {
+	fixtures
+	transport http.RoundTripper
+}
+
+// NewRecorder returns a transport that sends requests using transport and
+// writes each request/response pair to a file in dir. Credentials and other
+// volatile data are removed from what's written.
+func NewRecorder(dir string, transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	return &recorder{fixtures: fixtures{dir: dir, count: map[string]int{}}, transport: transport}
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	request := normalizeRequest(req, body)
+	fx := fixture{
+		Request: request,
+		Response: fixtureResponse{
+			Status:  resp.StatusCode,
+			Headers: map[string]string{},
+			Body:    fixtureBody(redactBody(respBody, resp.Header.Get("Content-Type"))),
+		},
+	}
+
+	for _, name := range fixtureResponseHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			fx.Response.Headers[name] = value
+		}
+	}
+
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Cannot marshal fixture: %s", err)
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, fmt.Errorf("Cannot create fixture directory %s: %s", r.dir, err)
+	}
+
+	path := r.next(fixtureKey(request))
+	if err := ioutil.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("Cannot write fixture %s: %s", path, err)
+	}
+
+	return resp, nil
+}
+
+type replayer struct {
+	fixtures
+}
+
+// NewReplayer returns a transport that responds to requests with the responses
+// written to dir by a recorder. When a request was recorded more than once the
+// responses are returned in the order they were recorded, with the last one
+// repeated thereafter.
+func NewReplayer(dir string) http.RoundTripper {
+	return &replayer{fixtures{dir: dir, count: map[string]int{}}}
+}
+
+func (r *replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fixtureKey(normalizeRequest(req, body))
+
+	path := r.next(key)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Fall back to the last recorded response
+		matches, _ := filepath.Glob(filepath.Join(r.dir, key+"-*.json"))
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%w for %s %s in %s", ErrNoFixture, req.Method, req.URL, r.dir)
+		}
+
+		data, err = ioutil.ReadFile(r.path(key, len(matches)))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read fixture %s: %s", path, err)
+	}
+
+	var fx fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("Cannot unmarshal fixture %s: %s", path, err)
+	}
+
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", fx.Response.Status, http.StatusText(fx.Response.Status)),
+		StatusCode: fx.Response.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(fixtureBodyBytes(fx.Response.Body))),
+		Request:    req,
+	}
+
+	for name, value := range fx.Response.Headers {
+		resp.Header.Set(name, value)
+	}
+
+	return resp, nil
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read request body: %s", err)
+	}
+
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
+
+// normalizeRequest returns the parts of the request used to match it, with
+// credentials and random multipart boundaries removed.
+func normalizeRequest(req *http.Request, body []byte) fixtureRequest {
+	contentType := req.Header.Get("Content-Type")
+
+	if match := multipartBoundary.FindStringSubmatch(contentType); match != nil {
+		boundary := strings.Trim(match[1], `"`)
+		body = bytes.ReplaceAll(body, []byte(boundary), []byte("BOUNDARY"))
+	}
+
+	u := *req.URL
+	u.RawQuery = redactForm(u.RawQuery)
+
+	return fixtureRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Body:   fixtureBody(redactBody(body, contentType)),
+	}
+}
+
+func fixtureKey(req fixtureRequest) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + req.URL + "\n" + string(req.Body)))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func redactBody(body []byte, contentType string) []byte {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		return []byte(redactForm(string(body)))
+	case "application/json":
+		// UseNumber so large IDs aren't mangled
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var v interface{}
+		if decoder.Decode(&v) != nil {
+			return body
+		}
+
+		redactJSON(v)
+
+		redactedBody, err := json.Marshal(v)
+		if err != nil {
+			return body
+		}
+
+		return redactedBody
+	}
+
+	return body
+}
+
+func redactForm(form string) string {
+	values, err := url.ParseQuery(form)
+	if err != nil || len(values) == 0 {
+		return form
+	}
+
+	for name := range values {
+		if secretFormParams[name] {
+			values.Set(name, redacted)
+		}
+	}
+
+	return values.Encode()
+}
+
+func redactJSON(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if _, ok := value.(string); ok && secretJSONProperties[name] {
+				v[name] = redacted
+			} else {
+				redactJSON(value)
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactJSON(value)
+		}
+	}
+}
+
+// fixtureBody stores JSON bodies as JSON so that fixtures are easy to read and
+// edit, and everything else as a JSON string.
+func fixtureBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return json.RawMessage(trimmed)
+	}
+
+	s, _ := json.Marshal(string(body))
+
+	return json.RawMessage(s)
+}
+
+func fixtureBodyBytes(body json.RawMessage) []byte {
+	if len(body) > 0 && body[0] == '"' {
+		var s string
+		if json.Unmarshal(body, &s) == nil {
+			return []byte(s)
+		}
+	}
+
+	return body
+}
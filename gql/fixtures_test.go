@@ -0,0 +1,112 @@
+package gql
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	stubSleep(t)
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "volatile")
+
+		status := "RUNNING"
+		if requests > 1 {
+			status = "COMPLETED"
+		}
+
+		w.Write([]byte(`{"data":{"currentBulkOperation":{"status":"` + status + `"}}}`))
+	}))
+
+	old := HTTPClient
+	defer func() { HTTPClient = old }()
+
+	query := "{ currentBulkOperation { status } }"
+
+	HTTPClient = &http.Client{Transport: NewRecorder(dir, nil)}
+	client := NewClient("acme", "shpat_secret")
+	client.endpoint = server.URL
+
+	for _, want := range []string{"RUNNING", "COMPLETED"} {
+		result, err := client.Execute(query)
+		if err != nil {
+			t.Fatalf("unexpected error recording: %s", err)
+		}
+
+		if got, _ := result.ValueForPath("data.currentBulkOperation.status"); got != want {
+			t.Errorf("recorded status = %v, want %v", got, want)
+		}
+	}
+
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("%d fixtures written, want 2", len(files))
+	}
+
+	for _, file := range files {
+		data, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(data), "shpat_secret") || strings.Contains(string(data), "volatile") {
+			t.Errorf("fixture %s contains volatile data:\n%s", file, data)
+		}
+	}
+
+	HTTPClient = &http.Client{Transport: NewReplayer(dir)}
+	client = NewClient("acme", "shpat_other")
+	client.endpoint = server.URL
+
+	// The last response is repeated once they've all been replayed
+	for _, want := range []string{"RUNNING", "COMPLETED", "COMPLETED"} {
+		result, err := client.Execute(query)
+		if err != nil {
+			t.Fatalf("unexpected error replaying: %s", err)
+		}
+
+		if got, _ := result.ValueForPath("data.currentBulkOperation.status"); got != want {
+			t.Errorf("replayed status = %v, want %v", got, want)
+		}
+	}
+
+	_, err := client.Execute("{ shop { name } }")
+	if !errors.Is(err, ErrNoFixture) {
+		t.Errorf("error = %v, want ErrNoFixture", err)
+	}
+}
+
+func TestRecordRedactsOAuthCredentials(t *testing.T) {
+	dir := t.TempDir()
+
+	testOAuthServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"shpat_123","scope":"read_products"}`))
+	})
+
+	old := HTTPClient
+	HTTPClient = &http.Client{Transport: NewRecorder(dir, nil)}
+	defer func() { HTTPClient = old }()
+
+	if _, err := ExchangeClientCredentials("acme", "key", "secret"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("%d fixtures written, want 1", len(files))
+	}
+
+	data, _ := ioutil.ReadFile(files[0])
+	if strings.Contains(string(data), "shpat_123") || strings.Contains(string(data), "client_secret=secret") {
+		t.Errorf("fixture contains credentials:\n%s", data)
+	}
+}
@@ -0,0 +1,417 @@
+package mock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// object is a GraphQL object. Its values are scalars, objects, lists, or
+// resolvers for fields that take arguments or are expensive to compute.
+type object map[string]interface{}
+
+type resolver func(args map[string]interface{}) (interface{}, error)
+
+// gqlError is an error in the GraphQL response's errors list.
+type gqlError struct {
+	Message string   `json:"message"`
+	Path    []string `json:"path,omitempty"`
+}
+
+// executor resolves a query's selections against an object. There's no
+// schema: fields that the object doesn't have are null.
+type executor struct {
+	doc       *ast.QueryDocument
+	variables map[string]interface{}
+	// bulk is true when running a bulk operation query: connections return
+	// all of their nodes regardless of their arguments.
+	bulk bool
+}
+
+// parseOperation returns the operation to run and the document it's in.
+func parseOperation(query, operationName string) (*ast.QueryDocument, *ast.OperationDefinition, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Parse error: %s", err)
+	}
+
+	if len(doc.Operations) == 0 {
+		return nil, nil, fmt.Errorf("Document has no operations")
+	}
+
+	if operationName == "" {
+		if len(doc.Operations) > 1 {
+			return nil, nil, fmt.Errorf("An operation name is required when the document has more than one operation")
+		}
+
+		return doc, doc.Operations[0], nil
+	}
+
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return nil, nil, fmt.Errorf("No operation named '%s'", operationName)
+	}
+
+	return doc, op, nil
+}
+
+// variablesFor returns variables with the operation's defaults added.
+func variablesFor(op *ast.OperationDefinition, variables map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for name, value := range variables {
+		result[name] = value
+	}
+
+	for _, def := range op.VariableDefinitions {
+		if _, ok := result[def.Variable]; ok || def.DefaultValue == nil {
+			continue
+		}
+
+		value, err := def.DefaultValue.Value(nil)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid default value for $%s: %s", def.Variable, err)
+		}
+
+		result[def.Variable] = value
+	}
+
+	return result, nil
+}
+
+func (e *executor) selectionSet(obj object, set ast.SelectionSet, path []string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	for _, selection := range set {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			key := selection.Alias
+			if key == "" {
+				key = selection.Name
+			}
+
+			value, err := e.field(obj, selection, append(path, key))
+			if err != nil {
+				return nil, err
+			}
+
+			result[key] = value
+		case *ast.InlineFragment:
+			if !obj.is(selection.TypeCondition) {
+				continue
+			}
+
+			if err := e.merge(result, obj, selection.SelectionSet, path); err != nil {
+				return nil, err
+			}
+		case *ast.FragmentSpread:
+			fragment := e.doc.Fragments.ForName(selection.Name)
+			if fragment == nil {
+				return nil, &gqlError{Message: fmt.Sprintf("Fragment %s was not found", selection.Name), Path: path}
+			}
+
+			if !obj.is(fragment.TypeCondition) {
+				continue
+			}
+
+			if err := e.merge(result, obj, fragment.SelectionSet, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (e *executor) merge(result map[string]interface{}, obj object, set ast.SelectionSet, path []string) error {
+	fields, err := e.selectionSet(obj, set, path)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range fields {
+		result[key] = value
+	}
+
+	return nil
+}
+
+func (e *executor) field(obj object, field *ast.Field, path []string) (interface{}, error) {
+	if field.Name == "__typename" {
+		return obj["__typename"], nil
+	}
+
+	value, ok := obj[field.Name]
+	if !ok {
+		return nil, nil
+	}
+
+	if resolve, ok := value.(resolver); ok {
+		args, err := e.arguments(field)
+		if err != nil {
+			return nil, &gqlError{Message: err.Error(), Path: path}
+		}
+
+		value, err = resolve(args)
+		if err != nil {
+			if gerr, ok := err.(*gqlError); ok {
+				return nil, gerr
+			}
+
+			return nil, &gqlError{Message: err.Error(), Path: path}
+		}
+	}
+
+	return e.value(value, field.SelectionSet, path)
+}
+
+func (e *executor) value(value interface{}, set ast.SelectionSet, path []string) (interface{}, error) {
+	switch value := value.(type) {
+	case object:
+		if value == nil {
+			return nil, nil
+		}
+
+		return e.selectionSet(value, set, path)
+	case []object:
+		result := make([]interface{}, 0, len(value))
+		for i, item := range value {
+			v, err := e.value(item, set, append(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, v)
+		}
+
+		return result, nil
+	}
+
+	return value, nil
+}
+
+func (e *executor) arguments(field *ast.Field) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+
+	for _, arg := range field.Arguments {
+		value, err := arg.Value.Value(e.variables)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid value for argument '%s': %s", arg.Name, err)
+		}
+
+		args[arg.Name] = normalize(value)
+	}
+
+	return args, nil
+}
+
+func (e *gqlError) Error() string {
+	return e.Message
+}
+
+// is reports whether the object is of the type, or implements it.
+func (o object) is(typeName string) bool {
+	if typeName == "" || o["__typename"] == typeName {
+		return true
+	}
+
+	for _, name := range interfaces[typeName] {
+		if o["__typename"] == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// interfaces maps the interfaces used in fragments to their implementations
+var interfaces = map[string][]string{
+	"Node":                   {"Product", "ProductVariant", "Metafield", "Order", "Customer", "WebhookSubscription", "BulkOperation", "Location", "InventoryItem", "Collection", "Publication", "MediaImage", "Video", "ExternalVideo", "Model3d"},
+	"HasMetafields":          {"Product", "ProductVariant", "Order", "Customer", "Shop", "Location", "Collection"},
+	"WebhookEndpoint":        {"WebhookHttpEndpoint", "WebhookEventBridgeEndpoint", "WebhookPubSubEndpoint"},
+	"Media":                  {"MediaImage", "Video", "ExternalVideo", "Model3d"},
+	"LegacyInteroperability": {"Product", "ProductVariant", "Order", "Customer", "WebhookSubscription", "Location", "InventoryItem", "Collection"},
+}
+
+// normalize converts JSON numbers to int64 or float64 and nested values
+// likewise so resolvers see consistent types.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+
+		return v
+	case int:
+		return int64(v)
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range v {
+			result[key] = normalize(item)
+		}
+
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+
+		return result
+	}
+
+	return value
+}
+
+// connection returns the nodes as a connection object, paginated using the
+// first, last, after, before, and reverse arguments.
+func (e *executor) connection(nodes []object, args map[string]interface{}) object {
+	if boolArg(args, "reverse") {
+		reversed := make([]object, len(nodes))
+		for i, node := range nodes {
+			reversed[len(nodes)-1-i] = node
+		}
+
+		nodes = reversed
+	}
+
+	start, end := 0, len(nodes)
+
+	if !e.bulk {
+		if after, ok := args["after"].(string); ok {
+			if i, ok := decodeCursor(after); ok {
+				start = i + 1
+			}
+		}
+
+		if before, ok := args["before"].(string); ok {
+			if i, ok := decodeCursor(before); ok && i < end {
+				end = i
+			}
+		}
+
+		if start > end {
+			start = end
+		}
+
+		if first, ok := intArg(args, "first"); ok && start+first < end {
+			end = start + first
+		}
+
+		if last, ok := intArg(args, "last"); ok && end-last > start {
+			start = end - last
+		}
+	}
+
+	edges := make([]object, 0, end-start)
+	for i := start; i < end; i++ {
+		edges = append(edges, object{"cursor": encodeCursor(i), "node": nodes[i]})
+	}
+
+	pageInfo := object{
+		"hasNextPage":     end < len(nodes),
+		"hasPreviousPage": start > 0,
+		"startCursor":     nil,
+		"endCursor":       nil,
+	}
+
+	if len(edges) > 0 {
+		pageInfo["startCursor"] = edges[0]["cursor"]
+		pageInfo["endCursor"] = edges[len(edges)-1]["cursor"]
+	}
+
+	return object{
+		"edges":    edges,
+		"nodes":    nodes[start:end],
+		"pageInfo": pageInfo,
+	}
+}
+
+func encodeCursor(i int) string {
+	return base64.StdEncoding.EncodeToString([]byte("cursor:" + strconv.Itoa(i)))
+}
+
+func decodeCursor(cursor string) (int, bool) {
+	data, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), "cursor:") {
+		return 0, false
+	}
+
+	i, err := strconv.Atoi(strings.TrimPrefix(string(data), "cursor:"))
+	return i, err == nil
+}
+
+func intArg(args map[string]interface{}, name string) (int, bool) {
+	switch v := args[name].(type) {
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	case string:
+		i, err := strconv.Atoi(v)
+		return i, err == nil
+	}
+
+	return 0, false
+}
+
+func boolArg(args map[string]interface{}, name string) bool {
+	b, _ := args[name].(bool)
+	return b
+}
+
+func stringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func stringsArg(args map[string]interface{}, name string) []string {
+	var result []string
+
+	switch v := args[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+	case string:
+		result = append(result, v)
+	}
+
+	return result
+}
+
+func mapArg(args map[string]interface{}, name string) map[string]interface{} {
+	m, _ := args[name].(map[string]interface{})
+	return m
+}
+
+func mapsArg(args map[string]interface{}, name string) []map[string]interface{} {
+	var result []map[string]interface{}
+
+	switch v := args[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				result = append(result, m)
+			}
+		}
+	case map[string]interface{}:
+		result = append(result, v)
+	}
+
+	return result
+}
@@ -0,0 +1,1040 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// userError returns a mutation's userErrors entry.
+func userError(message string, field ...string) object {
+	var f interface{}
+	if len(field) > 0 {
+		f = field
+	}
+
+	return object{"field": f, "message": message, "code": nil}
+}
+
+// payload returns a mutation payload with fields and no userErrors.
+func payload(fields object) object {
+	if fields == nil {
+		fields = object{}
+	}
+
+	fields["userErrors"] = []object{}
+
+	return fields
+}
+
+// failed returns a mutation payload with the userErrors and fields set to null.
+func failed(errors ...object) object {
+	return object{"userErrors": errors}
+}
+
+// mutationRoot returns the Mutation object.
+func (s *Server) mutationRoot() object {
+	return object{
+		"__typename":                           "Mutation",
+		"productSet":                           resolver(s.productSet),
+		"productUpdate":                        resolver(s.productUpdate),
+		"productDelete":                        resolver(s.productDelete),
+		"collectionCreate":                     resolver(s.collectionCreate),
+		"publishablePublish":                   resolver(s.publishablePublish),
+		"metafieldsSet":                        resolver(s.metafieldsSet),
+		"metafieldsDelete":                     resolver(s.metafieldsDelete),
+		"metafieldDefinitionCreate":            resolver(s.metafieldDefinitionCreate),
+		"metafieldDefinitionUpdate":            resolver(s.metafieldDefinitionUpdate),
+		"metafieldDefinitionDelete":            resolver(s.metafieldDefinitionDelete),
+		"metafieldDefinitionPin":               resolver(s.metafieldDefinitionPin),
+		"metafieldDefinitionUnpin":             resolver(s.metafieldDefinitionUnpin),
+		"metaobjectDefinitionCreate":           resolver(s.metaobjectDefinitionCreate),
+		"metaobjectDefinitionUpdate":           resolver(s.metaobjectDefinitionUpdate),
+		"webhookSubscriptionCreate":            resolver(s.webhookSubscriptionCreate),
+		"eventBridgeWebhookSubscriptionCreate": resolver(s.webhookSubscriptionCreate),
+		"webhookSubscriptionUpdate":            resolver(s.webhookSubscriptionUpdate),
+		"webhookSubscriptionDelete":            resolver(s.webhookSubscriptionDelete),
+		"orderUpdate":                          resolver(s.orderUpdate),
+		"stagedUploadsCreate":                  resolver(s.stagedUploadsCreate),
+		"bulkOperationRunQuery":                resolver(s.bulkOperationRunQuery),
+		"bulkOperationRunMutation":             resolver(s.bulkOperationRunMutation),
+		"bulkOperationCancel":                  resolver(s.bulkOperationCancel),
+	}
+}
+
+func (s *Server) productSet(args map[string]interface{}) (interface{}, error) {
+	input := mapArg(args, "input")
+	if input == nil {
+		return nil, fmt.Errorf("Argument 'input' is required")
+	}
+
+	var p *product
+
+	identifier := mapArg(args, "identifier")
+	if identifier == nil && stringArg(input, "id") != "" {
+		identifier = map[string]interface{}{"id": stringArg(input, "id")}
+	}
+
+	if identifier != nil {
+		p = s.store.productByIdentifier(identifier)
+		if p == nil && stringArg(identifier, "id") != "" {
+			return failed(userError("Product does not exist", "input", "id")), nil
+		}
+
+		if p == nil && stringArg(input, "handle") == "" {
+			input["handle"] = stringArg(identifier, "handle")
+		}
+	}
+
+	if errs := s.validateProductInput(input, p); len(errs) > 0 {
+		return failed(errs...), nil
+	}
+
+	creating := p == nil
+	if creating {
+		p = &product{id: s.store.newID(), status: "ACTIVE", createdAt: time.Now()}
+	}
+
+	s.applyProductInput(p, input)
+
+	if _, ok := input["productOptions"]; ok || creating {
+		p.options = productOptions(input)
+	}
+
+	if _, ok := input["variants"]; ok || creating {
+		p.variants = s.productSetVariants(p, mapsArg(input, "variants"))
+	}
+
+	if creating {
+		s.store.products = append(s.store.products, p)
+	}
+
+	for i, v := range mapsArg(input, "variants") {
+		if file := mapArg(v, "file"); file != nil {
+			_, id, _ := parseGID(stringArg(file, "id"))
+			p.variants[i].media = p.findMedia(id, stringArg(file, "originalSource"))
+		}
+	}
+
+	if _, ok := input["collections"]; ok {
+		var collections []*collection
+		for _, id := range stringsArg(input, "collections") {
+			_, n, _ := parseGID(id)
+			collections = append(collections, s.store.collection(n))
+		}
+
+		s.store.setProductCollections(p, collections)
+	}
+
+	var errs []object
+	for _, m := range mapsArg(input, "metafields") {
+		if err := s.setMetafieldInput(gid("Product", p.id), m); err != nil {
+			errs = append(errs, userError(err.Error(), "input", "metafields"))
+		}
+	}
+
+	for i, v := range mapsArg(input, "variants") {
+		for _, m := range mapsArg(v, "metafields") {
+			if err := s.setMetafieldInput(gid("ProductVariant", p.variants[i].id), m); err != nil {
+				errs = append(errs, userError(err.Error(), "input", "variants", fmt.Sprint(i), "metafields"))
+			}
+		}
+	}
+
+	result := payload(object{"product": s.productView(p), "productSetOperation": nil})
+	if len(errs) > 0 {
+		result["userErrors"] = errs
+	}
+
+	return result, nil
+}
+
+// validateProductInput returns the userErrors for a productSet or
+// productUpdate input. p is nil when a product is being created.
+func (s *Server) validateProductInput(input map[string]interface{}, p *product) []object {
+	var errs []object
+
+	_, hasTitle := input["title"]
+	if (p == nil || hasTitle) && strings.TrimSpace(stringArg(input, "title")) == "" {
+		errs = append(errs, userError("Title can't be blank", "input", "title"))
+	}
+
+	if handle := stringArg(input, "handle"); handle != "" {
+		if existing := s.store.productByHandle(handle); existing != nil && existing != p {
+			errs = append(errs, userError(fmt.Sprintf("Handle '%s' already in use. Please provide a new handle.", handle), "input", "handle"))
+		}
+	}
+
+	if status := stringArg(input, "status"); status != "" && !contains([]string{"ACTIVE", "ARCHIVED", "DRAFT"}, status) {
+		errs = append(errs, userError(fmt.Sprintf("Variable $input of type ProductSetInput! was provided invalid value for status (Expected \"%s\" to be one of: ACTIVE, ARCHIVED, DRAFT)", status), "input", "status"))
+	}
+
+	for i, id := range stringsArg(input, "collections") {
+		var c *collection
+		if name, n, ok := parseGID(id); ok && (name == "" || name == "Collection") {
+			c = s.store.collection(n)
+		}
+
+		if c == nil {
+			errs = append(errs, userError("Collection does not exist", "input", "collections", fmt.Sprint(i)))
+		} else if c.smart {
+			errs = append(errs, userError("Can't add products to a smart collection", "input", "collections", fmt.Sprint(i)))
+		}
+	}
+
+	var optionNames []string
+	for _, option := range mapsArg(input, "productOptions") {
+		optionNames = append(optionNames, stringArg(option, "name"))
+	}
+
+	for i, file := range mapsArg(input, "files") {
+		if id := stringArg(file, "id"); id != "" {
+			if _, n, ok := parseGID(id); !ok || s.store.media(n) == nil {
+				errs = append(errs, userError("File does not exist", "input", "files", fmt.Sprint(i), "id"))
+			}
+		} else if stringArg(file, "originalSource") == "" {
+			errs = append(errs, userError("Original source can't be blank", "input", "files", fmt.Sprint(i), "originalSource"))
+		}
+	}
+
+	for i, v := range mapsArg(input, "variants") {
+		for _, value := range mapsArg(v, "optionValues") {
+			name := stringArg(value, "optionName")
+			if len(optionNames) > 0 && !contains(optionNames, name) {
+				errs = append(errs, userError(fmt.Sprintf("Option '%s' does not exist", name), "input", "variants", fmt.Sprint(i), "optionValues"))
+			}
+		}
+
+		if file := mapArg(v, "file"); file != nil && !containsFile(mapsArg(input, "files"), file) {
+			errs = append(errs, userError("File must be one of the product's files", "input", "variants", fmt.Sprint(i), "file"))
+		}
+
+		for j, quantity := range mapsArg(v, "inventoryQuantities") {
+			_, id, ok := parseGID(stringArg(quantity, "locationId"))
+			if !ok || s.store.location(id) == nil {
+				errs = append(errs, userError("Location does not exist", "input", "variants", fmt.Sprint(i), "inventoryQuantities", fmt.Sprint(j), "locationId"))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (s *Server) applyProductInput(p *product, input map[string]interface{}) {
+	fields := map[string]*string{
+		"title":           &p.title,
+		"descriptionHtml": &p.descriptionHTML,
+		"vendor":          &p.vendor,
+		"productType":     &p.productType,
+		"status":          &p.status,
+	}
+
+	for name, field := range fields {
+		if value, ok := input[name].(string); ok {
+			*field = value
+		}
+	}
+
+	if _, ok := input["tags"]; ok {
+		p.tags = stringsArg(input, "tags")
+		if tags, ok := input["tags"].(string); ok {
+			p.tags = nil
+			for _, tag := range strings.Split(tags, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					p.tags = append(p.tags, tag)
+				}
+			}
+		}
+	}
+
+	// Files replace the product's media, as with productSet. Existing files
+	// are given by ID.
+	if _, ok := input["files"]; ok {
+		var result []*media
+		for _, file := range mapsArg(input, "files") {
+			_, id, _ := parseGID(stringArg(file, "id"))
+
+			m := p.findMedia(id, "")
+			if m == nil && id != 0 {
+				// Another product's file
+				if other := s.store.media(id); other != nil {
+					m = &media{id: s.store.newID(), contentType: other.contentType, source: other.source, alt: other.alt}
+				}
+			}
+
+			if m == nil {
+				m = &media{id: s.store.newID(), contentType: firstNonEmpty(stringArg(file, "contentType"), "IMAGE"), source: stringArg(file, "originalSource")}
+			}
+
+			if alt, ok := file["alt"].(string); ok {
+				m.alt = alt
+			}
+
+			result = append(result, m)
+		}
+
+		p.media = result
+
+		for _, v := range p.variants {
+			if v.media != nil && p.findMedia(v.media.id, "") == nil {
+				v.media = nil
+			}
+		}
+	}
+
+	if handle := stringArg(input, "handle"); handle != "" {
+		p.handle = handle
+	} else if p.handle == "" {
+		p.handle = s.store.uniqueHandle(handleize(p.title), p)
+	}
+
+	p.updatedAt = time.Now()
+}
+
+// containsFile reports whether the file, as given to a variant, is in files
+func containsFile(files []map[string]interface{}, file map[string]interface{}) bool {
+	for _, f := range files {
+		if id := stringArg(file, "id"); id != "" && stringArg(f, "id") == id {
+			return true
+		}
+
+		if source := stringArg(file, "originalSource"); source != "" && stringArg(f, "originalSource") == source {
+			return true
+		}
+	}
+
+	return false
+}
+
+func productOptions(input map[string]interface{}) []productOption {
+	var options []productOption
+	for _, option := range mapsArg(input, "productOptions") {
+		o := productOption{name: stringArg(option, "name")}
+		for _, value := range mapsArg(option, "values") {
+			o.values = append(o.values, stringArg(value, "name"))
+		}
+
+		options = append(options, o)
+	}
+
+	if len(options) == 0 {
+		options = []productOption{{name: "Title", values: []string{"Default Title"}}}
+	}
+
+	return options
+}
+
+// productSetVariants returns the product's variants as given by the inputs.
+// Existing variants are matched by ID, SKU, or options and keep their IDs.
+func (s *Server) productSetVariants(p *product, inputs []map[string]interface{}) []*variant {
+	if len(inputs) == 0 {
+		inputs = []map[string]interface{}{{}}
+	}
+
+	var variants []*variant
+	for _, input := range inputs {
+		options := variantOptions(p, input)
+
+		v := matchVariant(p.variants, input, options)
+		if v == nil {
+			v = &variant{
+				id:               s.store.newID(),
+				inventoryItemID:  s.store.newID(),
+				taxable:          true,
+				inventoryPolicy:  "DENY",
+				requiresShipping: true,
+				price:            "0.00",
+				quantities:       map[int64]map[string]int{},
+				createdAt:        time.Now(),
+			}
+		}
+
+		v.product = p
+		v.options = options
+		applyVariantInput(v, input)
+
+		variants = append(variants, v)
+	}
+
+	return variants
+}
+
+func variantOptions(p *product, input map[string]interface{}) []selectedOption {
+	values := map[string]string{}
+	for _, value := range mapsArg(input, "optionValues") {
+		values[stringArg(value, "optionName")] = stringArg(value, "name")
+	}
+
+	var options []selectedOption
+	for _, option := range p.options {
+		value, ok := values[option.name]
+		if !ok && len(option.values) > 0 {
+			value = option.values[0]
+		}
+
+		options = append(options, selectedOption{name: option.name, value: value})
+	}
+
+	return options
+}
+
+func matchVariant(variants []*variant, input map[string]interface{}, options []selectedOption) *variant {
+	if id := stringArg(input, "id"); id != "" {
+		_, n, _ := parseGID(id)
+		for _, v := range variants {
+			if v.id == n {
+				return v
+			}
+		}
+	}
+
+	if sku := stringArg(input, "sku"); sku != "" {
+		for _, v := range variants {
+			if v.sku == sku {
+				return v
+			}
+		}
+	}
+
+	for _, v := range variants {
+		if fmt.Sprint(v.options) == fmt.Sprint(options) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func applyVariantInput(v *variant, input map[string]interface{}) {
+	fields := map[string]*string{
+		"sku":             &v.sku,
+		"barcode":         &v.barcode,
+		"price":           &v.price,
+		"compareAtPrice":  &v.compareAtPrice,
+		"inventoryPolicy": &v.inventoryPolicy,
+	}
+
+	for name, field := range fields {
+		if value, ok := input[name]; ok && value != nil {
+			*field = fmt.Sprint(value)
+		}
+	}
+
+	if taxable, ok := input["taxable"].(bool); ok {
+		v.taxable = taxable
+	}
+
+	if item := mapArg(input, "inventoryItem"); item != nil {
+		if tracked, ok := item["tracked"].(bool); ok {
+			v.tracked = tracked
+		}
+
+		if requiresShipping, ok := item["requiresShipping"].(bool); ok {
+			v.requiresShipping = requiresShipping
+		}
+
+		if cost, ok := item["cost"]; ok && cost != nil {
+			v.cost = fmt.Sprint(cost)
+		}
+
+		if sku := stringArg(item, "sku"); sku != "" {
+			v.sku = sku
+		}
+	}
+
+	for _, quantity := range mapsArg(input, "inventoryQuantities") {
+		_, locationID, _ := parseGID(stringArg(quantity, "locationId"))
+		n, _ := intArg(quantity, "quantity")
+		name := stringArg(quantity, "name")
+
+		if v.quantities[locationID] == nil {
+			v.quantities[locationID] = map[string]int{}
+		}
+
+		// Without committed inventory available and on hand are the same
+		if name == "available" || name == "on_hand" {
+			v.quantities[locationID]["available"] = n
+			v.quantities[locationID]["on_hand"] = n
+		} else {
+			v.quantities[locationID][name] = n
+		}
+
+		v.tracked = true
+	}
+
+	v.updatedAt = time.Now()
+}
+
+func (s *Server) setMetafieldInput(ownerID string, input map[string]interface{}) error {
+	_, err := s.store.setMetafield(ownerID, stringArg(input, "namespace"), stringArg(input, "key"), fmt.Sprint(input["value"]), stringArg(input, "type"))
+	return err
+}
+
+func (s *Server) productUpdate(args map[string]interface{}) (interface{}, error) {
+	input := mapArg(args, "product")
+	if input == nil {
+		input = mapArg(args, "input")
+	}
+
+	if input == nil {
+		return nil, fmt.Errorf("Argument 'product' is required")
+	}
+
+	p := s.store.productByIdentifier(map[string]interface{}{"id": stringArg(input, "id")})
+	if p == nil {
+		return failed(userError("Product does not exist", "id")), nil
+	}
+
+	if errs := s.validateProductInput(input, p); len(errs) > 0 {
+		return failed(errs...), nil
+	}
+
+	s.applyProductInput(p, input)
+
+	for _, m := range mapsArg(input, "metafields") {
+		if err := s.setMetafieldInput(gid("Product", p.id), m); err != nil {
+			return failed(userError(err.Error(), "metafields")), nil
+		}
+	}
+
+	return payload(object{"product": s.productView(p)}), nil
+}
+
+func (s *Server) productDelete(args map[string]interface{}) (interface{}, error) {
+	input := mapArg(args, "input")
+
+	p := s.store.productByIdentifier(map[string]interface{}{"id": stringArg(input, "id")})
+	if p == nil {
+		return failed(userError("Product does not exist", "id")), nil
+	}
+
+	s.store.deleteProduct(p)
+
+	return payload(object{"deletedProductId": gid("Product", p.id)}), nil
+}
+
+func (s *Server) collectionCreate(args map[string]interface{}) (interface{}, error) {
+	c, err := s.store.newCollection(mapArg(args, "input"))
+	if err != nil {
+		return failed(userError(err.Error(), "input", "title")), nil
+	}
+
+	return payload(object{"collection": s.collectionView(c)}), nil
+}
+
+func (s *Server) publishablePublish(args map[string]interface{}) (interface{}, error) {
+	id := stringArg(args, "id")
+
+	name, n, ok := parseGID(id)
+	if !ok || name != "Product" || s.store.product(n) == nil {
+		return failed(userError("Publishable does not exist", "id")), nil
+	}
+
+	var publications []*publication
+	for i, input := range mapsArg(args, "input") {
+		_, pubID, _ := parseGID(stringArg(input, "publicationId"))
+
+		pub := s.store.publication(pubID)
+		if pub == nil {
+			return failed(userError("Publication does not exist", "input", fmt.Sprint(i), "publicationId")), nil
+		}
+
+		publications = append(publications, pub)
+	}
+
+	for _, pub := range publications {
+		if !containsID(pub.products, n) {
+			pub.products = append(pub.products, n)
+		}
+	}
+
+	return payload(object{"publishable": s.productView(s.store.product(n))}), nil
+}
+
+func (s *Server) metafieldsSet(args map[string]interface{}) (interface{}, error) {
+	inputs := mapsArg(args, "metafields")
+	if len(inputs) > 25 {
+		return failed(userError("Exceeded the maximum metafields input limit of 25.", "metafields")), nil
+	}
+
+	var errs []object
+	for i, input := range inputs {
+		if !s.store.ownerExists(stringArg(input, "ownerId")) {
+			errs = append(errs, userError("Owner does not exist.", "metafields", fmt.Sprint(i), "ownerId"))
+		}
+
+		if stringArg(input, "type") == "" {
+			// Only allowed when the metafield exists
+			if s.store.findMetafield(stringArg(input, "ownerId"), firstNonEmpty(stringArg(input, "namespace"), "$app"), stringArg(input, "key")) == nil {
+				errs = append(errs, userError("Type can't be blank", "metafields", fmt.Sprint(i), "type"))
+			}
+		}
+
+		if stringArg(input, "key") == "" {
+			errs = append(errs, userError("Key can't be blank", "metafields", fmt.Sprint(i), "key"))
+		}
+	}
+
+	// metafieldsSet is atomic
+	if len(errs) > 0 {
+		return object{"metafields": []object{}, "userErrors": errs}, nil
+	}
+
+	var metafields []object
+	for _, input := range inputs {
+		m, _ := s.store.setMetafield(stringArg(input, "ownerId"), stringArg(input, "namespace"), stringArg(input, "key"), fmt.Sprint(input["value"]), stringArg(input, "type"))
+		metafields = append(metafields, s.metafieldView(m))
+	}
+
+	return payload(object{"metafields": metafields}), nil
+}
+
+func (s *Server) metafieldsDelete(args map[string]interface{}) (interface{}, error) {
+	var deleted []object
+
+	for _, input := range mapsArg(args, "metafields") {
+		m := s.store.findMetafield(stringArg(input, "ownerId"), stringArg(input, "namespace"), stringArg(input, "key"))
+		if m == nil {
+			deleted = append(deleted, nil)
+			continue
+		}
+
+		s.store.deleteMetafield(m)
+		deleted = append(deleted, object{"ownerId": m.ownerID, "namespace": m.namespace, "key": m.key})
+	}
+
+	return payload(object{"deletedMetafields": deleted}), nil
+}
+
+func (s *Server) metafieldDefinitionCreate(args map[string]interface{}) (interface{}, error) {
+	d, err := s.store.newMetafieldDefinition(mapArg(args, "definition"))
+	if err != nil {
+		return failed(userError(err.Error(), "definition")), nil
+	}
+
+	return payload(object{"createdDefinition": s.metafieldDefinitionView(d)}), nil
+}
+
+func (s *Server) metafieldDefinitionUpdate(args map[string]interface{}) (interface{}, error) {
+	input := mapArg(args, "definition")
+
+	d := s.store.findMetafieldDefinition(stringArg(input, "ownerType"), stringArg(input, "namespace"), stringArg(input, "key"))
+	if d == nil {
+		return failed(userError("Definition not found.", "definition")), nil
+	}
+
+	if err := s.store.checkValidations(validationsArg(input)); err != nil {
+		return failed(userError(err.Error(), "definition", "validations")), nil
+	}
+
+	s.store.applyMetafieldDefinitionInput(d, input)
+
+	return payload(object{"updatedDefinition": s.metafieldDefinitionView(d), "validationJob": nil}), nil
+}
+
+func (s *Server) metafieldDefinitionDelete(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "id"))
+
+	d := s.store.metafieldDefinition(id)
+	if d == nil {
+		return failed(userError("Definition not found.", "id")), nil
+	}
+
+	s.store.deleteMetafieldDefinition(d, boolArg(args, "deleteAllAssociatedMetafields"))
+
+	return payload(object{"deletedDefinitionId": gid("MetafieldDefinition", d.id)}), nil
+}
+
+func (s *Server) metafieldDefinitionPin(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "definitionId"))
+
+	d := s.store.metafieldDefinition(id)
+	if d == nil {
+		return failed(userError("Definition not found.", "definitionId")), nil
+	}
+
+	if d.pinnedPosition > 0 {
+		return failed(userError("Definition is already pinned.", "definitionId")), nil
+	}
+
+	s.store.pinMetafieldDefinition(d)
+
+	return payload(object{"pinnedDefinition": s.metafieldDefinitionView(d)}), nil
+}
+
+func (s *Server) metafieldDefinitionUnpin(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "definitionId"))
+
+	d := s.store.metafieldDefinition(id)
+	if d == nil {
+		return failed(userError("Definition not found.", "definitionId")), nil
+	}
+
+	if d.pinnedPosition == 0 {
+		return failed(userError("Definition is not pinned.", "definitionId")), nil
+	}
+
+	d.pinnedPosition = 0
+
+	return payload(object{"unpinnedDefinition": s.metafieldDefinitionView(d)}), nil
+}
+
+func (s *Server) metaobjectDefinitionCreate(args map[string]interface{}) (interface{}, error) {
+	d, err := s.store.newMetaobjectDefinition(mapArg(args, "definition"))
+	if err != nil {
+		return failed(userError(err.Error(), "definition")), nil
+	}
+
+	return payload(object{"metaobjectDefinition": metaobjectDefinitionView(d)}), nil
+}
+
+// metaobjectDefinitionUpdate applies the MetaobjectDefinitionUpdateInput. Its fieldDefinitions are
+// operations that create, update, or delete a field.
+func (s *Server) metaobjectDefinitionUpdate(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "id"))
+
+	d := s.store.metaobjectDefinition(id)
+	if d == nil {
+		return failed(userError("Record not found", "id")), nil
+	}
+
+	input := mapArg(args, "definition")
+	updated := *d
+	updated.fields = nil
+	for _, f := range d.fields {
+		field := *f
+		updated.fields = append(updated.fields, &field)
+	}
+
+	for name, field := range map[string]*string{"name": &updated.name, "description": &updated.description, "displayNameKey": &updated.displayNameKey} {
+		if value, ok := input[name].(string); ok {
+			*field = value
+		}
+	}
+
+	for i, operation := range mapsArg(input, "fieldDefinitions") {
+		index := fmt.Sprint(i)
+
+		if create := mapArg(operation, "create"); create != nil {
+			if err := s.store.addMetaobjectField(&updated, create); err != nil {
+				return failed(userError(err.Error(), "definition", "fieldDefinitions", index)), nil
+			}
+
+			continue
+		}
+
+		if update := mapArg(operation, "update"); update != nil {
+			f := updated.field(stringArg(update, "key"))
+			if f == nil {
+				return failed(userError("Field definition not found", "definition", "fieldDefinitions", index)), nil
+			}
+
+			if _, ok := update["validations"]; ok {
+				validations := validationsArg(update)
+				if err := s.store.checkValidations(validations); err != nil {
+					return failed(userError(err.Error(), "definition", "fieldDefinitions", index)), nil
+				}
+
+				f.validations = validations
+			}
+
+			for name, field := range map[string]*string{"name": &f.name, "description": &f.description} {
+				if value, ok := update[name].(string); ok {
+					*field = value
+				}
+			}
+
+			if required, ok := update["required"].(bool); ok {
+				f.required = required
+			}
+
+			continue
+		}
+
+		if del := mapArg(operation, "delete"); del != nil {
+			var kept []*metaobjectFieldDefinition
+			for _, f := range updated.fields {
+				if f.key != stringArg(del, "key") {
+					kept = append(kept, f)
+				}
+			}
+
+			updated.fields = kept
+		}
+	}
+
+	*d = updated
+
+	return payload(object{"metaobjectDefinition": metaobjectDefinitionView(d)}), nil
+}
+
+func (s *Server) webhookSubscriptionCreate(args map[string]interface{}) (interface{}, error) {
+	topic := stringArg(args, "topic")
+	input := mapArg(args, "webhookSubscription")
+
+	if topic == "" {
+		return failed(userError("Topic can't be blank", "topic")), nil
+	}
+
+	w := &webhook{id: s.store.newID(), topic: topic, format: "JSON", apiVersion: s.apiVersion, createdAt: time.Now()}
+	if errs := s.applyWebhookInput(w, input); len(errs) > 0 {
+		return failed(errs...), nil
+	}
+
+	s.store.webhooks = append(s.store.webhooks, w)
+
+	return payload(object{"webhookSubscription": s.webhookView(w)}), nil
+}
+
+func (s *Server) webhookSubscriptionUpdate(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "id"))
+
+	w := s.store.webhook(id)
+	if w == nil {
+		return failed(userError("Webhook subscription does not exist", "id")), nil
+	}
+
+	updated := *w
+	if errs := s.applyWebhookInput(&updated, mapArg(args, "webhookSubscription")); len(errs) > 0 {
+		return failed(errs...), nil
+	}
+
+	*w = updated
+
+	return payload(object{"webhookSubscription": s.webhookView(w)}), nil
+}
+
+func (s *Server) applyWebhookInput(w *webhook, input map[string]interface{}) []object {
+	if arn := stringArg(input, "arn"); arn != "" {
+		w.arn, w.callbackURL = arn, ""
+	}
+
+	if callbackURL := stringArg(input, "callbackUrl"); callbackURL != "" {
+		if !strings.HasPrefix(callbackURL, "https://") {
+			return []object{userError("Address protocol is not supported, use https://", "webhookSubscription", "callbackUrl")}
+		}
+
+		w.callbackURL, w.arn = callbackURL, ""
+	}
+
+	if w.callbackURL == "" && w.arn == "" {
+		return []object{userError("Address can't be blank", "webhookSubscription", "callbackUrl")}
+	}
+
+	if format := stringArg(input, "format"); format != "" {
+		w.format = strings.ToUpper(format)
+	}
+
+	if _, ok := input["includeFields"]; ok {
+		w.includeFields = stringsArg(input, "includeFields")
+	}
+
+	if _, ok := input["metafieldNamespaces"]; ok {
+		w.metafieldNamespaces = stringsArg(input, "metafieldNamespaces")
+	}
+
+	for _, existing := range s.store.webhooks {
+		if existing.id != w.id && existing.topic == w.topic && existing.callbackURL == w.callbackURL && existing.arn == w.arn {
+			return []object{userError("Address for this topic has already been taken", "webhookSubscription", "callbackUrl")}
+		}
+	}
+
+	w.updatedAt = time.Now()
+
+	return nil
+}
+
+func (s *Server) webhookSubscriptionDelete(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "id"))
+
+	w := s.store.webhook(id)
+	if w == nil {
+		return failed(userError("Webhook subscription does not exist", "id")), nil
+	}
+
+	var kept []*webhook
+	for _, existing := range s.store.webhooks {
+		if existing != w {
+			kept = append(kept, existing)
+		}
+	}
+
+	s.store.webhooks = kept
+
+	return payload(object{"deletedWebhookSubscriptionId": gid("WebhookSubscription", w.id)}), nil
+}
+
+func (s *Server) orderUpdate(args map[string]interface{}) (interface{}, error) {
+	input := mapArg(args, "input")
+
+	_, id, _ := parseGID(stringArg(input, "id"))
+	o := s.store.order(id)
+	if o == nil {
+		return failed(userError("Order does not exist", "id")), nil
+	}
+
+	if _, ok := input["customAttributes"]; ok {
+		o.customAttributes = nil
+		for _, a := range mapsArg(input, "customAttributes") {
+			o.customAttributes = append(o.customAttributes, attribute{key: stringArg(a, "key"), value: stringArg(a, "value")})
+		}
+	}
+
+	if note, ok := input["note"].(string); ok {
+		o.note = note
+	}
+
+	if email, ok := input["email"].(string); ok {
+		o.email = email
+	}
+
+	for _, m := range mapsArg(input, "metafields") {
+		if err := s.setMetafieldInput(gid("Order", o.id), m); err != nil {
+			return failed(userError(err.Error(), "metafields")), nil
+		}
+	}
+
+	o.updatedAt = time.Now()
+
+	return payload(object{"order": s.orderView(o)}), nil
+}
+
+func (s *Server) stagedUploadsCreate(args map[string]interface{}) (interface{}, error) {
+	var targets []object
+
+	for _, input := range mapsArg(args, "input") {
+		key := fmt.Sprintf("tmp/%d/%s", s.store.newID(), firstNonEmpty(stringArg(input, "filename"), "upload"))
+
+		targets = append(targets, object{
+			"url":         s.baseURL + "/staged-uploads",
+			"resourceUrl": s.baseURL + "/staged-uploads/" + key,
+			"parameters": []object{
+				{"name": "key", "value": key},
+				{"name": "Content-Type", "value": stringArg(input, "mimeType")},
+			},
+		})
+	}
+
+	return payload(object{"stagedTargets": targets}), nil
+}
+
+// runningBulkOperation returns the operation of the type that hasn't finished, if any.
+func (s *Server) runningBulkOperation(operationType string) *bulkOperation {
+	for _, op := range s.store.bulkOperations {
+		if op.operationType == operationType && (op.status == "CREATED" || op.status == "RUNNING") {
+			return op
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) newBulkOperation(operationType, query string) *bulkOperation {
+	op := &bulkOperation{
+		id:            s.store.newID(),
+		operationType: operationType,
+		status:        "CREATED",
+		query:         query,
+		pollsLeft:     s.BulkOperationPolls,
+		createdAt:     time.Now(),
+	}
+
+	s.store.bulkOperations = append(s.store.bulkOperations, op)
+
+	return op
+}
+
+func (s *Server) bulkOperationRunQuery(args map[string]interface{}) (interface{}, error) {
+	query := stringArg(args, "query")
+
+	if s.runningBulkOperation("QUERY") != nil {
+		return failed(userError("A bulk query operation for this app and shop is already in progress.")), nil
+	}
+
+	result, objects, roots, err := s.runBulkQuery(query)
+	if err != nil {
+		return failed(userError(err.Error(), "query")), nil
+	}
+
+	op := s.newBulkOperation("QUERY", query)
+	op.result = result
+	op.objectCount = objects
+	op.rootObjectCount = roots
+
+	return payload(object{"bulkOperation": s.bulkOperationView(op)}), nil
+}
+
+func (s *Server) bulkOperationRunMutation(args map[string]interface{}) (interface{}, error) {
+	mutation := stringArg(args, "mutation")
+
+	if s.runningBulkOperation("MUTATION") != nil {
+		return failed(userError("A bulk mutation operation for this app and shop is already in progress.")), nil
+	}
+
+	doc, op, err := parseOperation(mutation, "")
+	if err != nil || op.Operation != "mutation" || len(op.SelectionSet) != 1 {
+		return failed(userError("Invalid mutation: a single mutation is required", "mutation")), nil
+	}
+
+	data, ok := s.store.uploads[stringArg(args, "stagedUploadPath")]
+	if !ok {
+		return failed(userError("The JSONL file could not be found. Try uploading the file again, and check that you've entered the URL correctly for the stagedUploadPath mutation argument.", "stagedUploadPath")), nil
+	}
+
+	var result []byte
+	count := 0
+
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		vars, err := decodeVariables([]byte(line))
+		if err != nil {
+			return failed(userError(fmt.Sprintf("Invalid JSON on line %d: %s", i+1, err), "stagedUploadPath")), nil
+		}
+
+		lineResult := map[string]interface{}{"__lineNumber": i}
+
+		data, errs := s.executeOperation(doc, op, vars, false)
+		if len(errs) > 0 {
+			lineResult["errors"] = errs
+		} else {
+			lineResult["data"] = data
+		}
+
+		encoded, _ := json.Marshal(lineResult)
+		result = append(append(result, encoded...), '\n')
+		count++
+	}
+
+	bulk := s.newBulkOperation("MUTATION", mutation)
+	bulk.result = result
+	bulk.objectCount = count
+	bulk.rootObjectCount = count
+
+	return payload(object{"bulkOperation": s.bulkOperationView(bulk)}), nil
+}
+
+func (s *Server) bulkOperationCancel(args map[string]interface{}) (interface{}, error) {
+	_, id, _ := parseGID(stringArg(args, "id"))
+
+	op := s.store.bulkOperation(id)
+	if op == nil {
+		return failed(userError("Bulk operation does not exist", "id")), nil
+	}
+
+	if op.status != "CREATED" && op.status != "RUNNING" {
+		return object{
+			"bulkOperation": s.bulkOperationView(op),
+			"userErrors":    []object{userError(fmt.Sprintf("A bulk operation cannot be canceled when it is %s", strings.ToLower(op.status)))},
+		}, nil
+	}
+
+	op.status = "CANCELING"
+	op.result = nil
+	op.pollsLeft = 0
+
+	return payload(object{"bulkOperation": s.bulkOperationView(op)}), nil
+}
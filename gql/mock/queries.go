@@ -0,0 +1,263 @@
+package mock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryRoot returns the QueryRoot object.
+func (s *Server) queryRoot() object {
+	return object{
+		"__typename": "QueryRoot",
+		"shop":       s.shopView(),
+		"products": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			var nodes []object
+			for _, p := range s.store.products {
+				if query.matches(productFields(p)) {
+					nodes = append(nodes, s.productView(p))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"productsCount": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			count := 0
+			for _, p := range s.store.products {
+				if query.matches(productFields(p)) {
+					count++
+				}
+			}
+
+			return object{"count": int64(count), "precision": "EXACT"}, nil
+		}),
+		"product": s.nodeResolver("Product"),
+		"productByHandle": resolver(func(args map[string]interface{}) (interface{}, error) {
+			if p := s.store.productByHandle(stringArg(args, "handle")); p != nil {
+				return s.productView(p), nil
+			}
+
+			return nil, nil
+		}),
+		"productByIdentifier": resolver(func(args map[string]interface{}) (interface{}, error) {
+			identifier := mapArg(args, "identifier")
+			if p := s.store.productByIdentifier(identifier); p != nil {
+				return s.productView(p), nil
+			}
+
+			return nil, nil
+		}),
+		"productVariants": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			var nodes []object
+			for _, v := range s.store.variants() {
+				if query.matches(variantFields(v)) {
+					nodes = append(nodes, s.variantView(v))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"productVariant": s.nodeResolver("ProductVariant"),
+		"inventoryItem":  s.nodeResolver("InventoryItem"),
+		"orders": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			var nodes []object
+			for _, o := range s.store.orders {
+				if query.matches(orderFields(o)) {
+					nodes = append(nodes, s.orderView(o))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"order": s.nodeResolver("Order"),
+		"customers": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			var nodes []object
+			for _, c := range s.store.customers {
+				if query.matches(customerFields(c)) {
+					nodes = append(nodes, s.customerView(c))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"customer": s.nodeResolver("Customer"),
+		"locations": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var nodes []object
+			for _, l := range s.store.locations {
+				if l.active || boolArg(args, "includeInactive") {
+					nodes = append(nodes, s.locationView(l))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"location": s.nodeResolver("Location"),
+		"collections": resolver(func(args map[string]interface{}) (interface{}, error) {
+			query := parseSearchQuery(stringArg(args, "query"))
+
+			var nodes []object
+			for _, c := range s.store.collections {
+				if query.matches(collectionFields(c)) {
+					nodes = append(nodes, s.collectionView(c))
+				}
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"collection": s.nodeResolver("Collection"),
+		"collectionByHandle": resolver(func(args map[string]interface{}) (interface{}, error) {
+			if c := s.store.collectionByHandle(stringArg(args, "handle")); c != nil {
+				return s.collectionView(c), nil
+			}
+
+			return nil, nil
+		}),
+		"collectionByIdentifier": resolver(func(args map[string]interface{}) (interface{}, error) {
+			identifier := mapArg(args, "identifier")
+			if id := stringArg(identifier, "id"); id != "" {
+				return s.node(id), nil
+			}
+
+			if c := s.store.collectionByHandle(stringArg(identifier, "handle")); c != nil {
+				return s.collectionView(c), nil
+			}
+
+			return nil, nil
+		}),
+		"publications": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var nodes []object
+			for _, pub := range s.store.publications {
+				nodes = append(nodes, s.publicationView(pub))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"publication": s.nodeResolver("Publication"),
+		"metafieldDefinitions": resolver(func(args map[string]interface{}) (interface{}, error) {
+			ownerType := stringArg(args, "ownerType")
+			namespace, key := stringArg(args, "namespace"), stringArg(args, "key")
+			pinned := stringArg(args, "pinnedStatus")
+
+			var nodes []object
+			for _, d := range s.store.definitions {
+				if d.ownerType != ownerType || (namespace != "" && d.namespace != namespace) || (key != "" && d.key != key) {
+					continue
+				}
+
+				if (pinned == "PINNED" && d.pinnedPosition == 0) || (pinned == "UNPINNED" && d.pinnedPosition > 0) {
+					continue
+				}
+
+				nodes = append(nodes, s.metafieldDefinitionView(d))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"metafieldDefinition": s.nodeResolver("MetafieldDefinition"),
+		"metaobjectDefinitions": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var nodes []object
+			for _, d := range s.store.metaobjects {
+				nodes = append(nodes, metaobjectDefinitionView(d))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"metaobjectDefinition": s.nodeResolver("MetaobjectDefinition"),
+		"metaobjectDefinitionByType": resolver(func(args map[string]interface{}) (interface{}, error) {
+			if d := s.store.metaobjectDefinitionByType(stringArg(args, "type")); d != nil {
+				return metaobjectDefinitionView(d), nil
+			}
+
+			return nil, nil
+		}),
+		"webhookSubscriptions": resolver(func(args map[string]interface{}) (interface{}, error) {
+			topics := stringsArg(args, "topics")
+			uri := firstNonEmpty(stringArg(args, "uri"), stringArg(args, "callbackUrl"))
+
+			var nodes []object
+			for _, w := range s.store.webhooks {
+				if len(topics) > 0 && !contains(topics, w.topic) {
+					continue
+				}
+
+				if uri != "" && uri != w.callbackURL && uri != w.arn {
+					continue
+				}
+
+				nodes = append(nodes, s.webhookView(w))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"webhookSubscription": s.nodeResolver("WebhookSubscription"),
+		"node": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.node(stringArg(args, "id")), nil
+		}),
+		"nodes": resolver(func(args map[string]interface{}) (interface{}, error) {
+			ids := stringsArg(args, "ids")
+
+			nodes := make([]object, len(ids))
+			for i, id := range ids {
+				nodes[i] = s.node(id)
+			}
+
+			return nodes, nil
+		}),
+		"currentBulkOperation": resolver(func(args map[string]interface{}) (interface{}, error) {
+			operationType := stringArg(args, "type")
+			if operationType == "" {
+				operationType = "QUERY"
+			}
+
+			for i := len(s.store.bulkOperations) - 1; i >= 0; i-- {
+				op := s.store.bulkOperations[i]
+				if op.operationType == operationType {
+					s.poll(op)
+					return s.bulkOperationView(op), nil
+				}
+			}
+
+			return nil, nil
+		}),
+	}
+}
+
+// nodeResolver resolves a field that looks up an object of the given type by its id argument.
+func (s *Server) nodeResolver(typeName string) resolver {
+	return func(args map[string]interface{}) (interface{}, error) {
+		id := stringArg(args, "id")
+
+		name, n, ok := parseGID(id)
+		if !ok || (name != "" && name != typeName) {
+			return nil, fmt.Errorf("Invalid global id '%s'", id)
+		}
+
+		return s.node(gid(typeName, n)), nil
+	}
+}
+
+// productByIdentifier returns the product with the id or handle in identifier.
+func (s *store) productByIdentifier(identifier map[string]interface{}) *product {
+	if id := stringArg(identifier, "id"); id != "" {
+		if name, n, ok := parseGID(id); ok && (name == "" || name == "Product") {
+			return s.product(n)
+		}
+
+		return nil
+	}
+
+	if handle := stringArg(identifier, "handle"); handle != "" {
+		return s.productByHandle(strings.TrimSpace(handle))
+	}
+
+	return nil
+}
@@ -0,0 +1,270 @@
+package mock
+
+import (
+	"strings"
+	"unicode"
+)
+
+// searchQuery is a parsed Shopify search query, e.g., "status:active AND -tag:sale".
+// It's a list of OR'ed groups of AND'ed terms. Only the syntax sdt uses is
+// supported: field:value terms, quoted values, negation, and prefix matching
+// with a trailing *. Terms without a field match the default fields.
+type searchQuery [][]searchTerm
+
+type searchTerm struct {
+	field  string
+	value  string
+	negate bool
+}
+
+// fieldsFunc returns the values of an object's field that can be searched.
+// The empty field is the default, free text search.
+type fieldsFunc func(field string) []string
+
+func parseSearchQuery(query string) searchQuery {
+	var result searchQuery
+	var group []searchTerm
+
+	for _, token := range tokenize(query) {
+		switch token {
+		case "OR":
+			if len(group) > 0 {
+				result = append(result, group)
+			}
+
+			group = nil
+			continue
+		case "AND":
+			continue
+		}
+
+		term := searchTerm{}
+		if strings.HasPrefix(token, "-") || strings.HasPrefix(token, "NOT ") {
+			term.negate = true
+			token = strings.TrimPrefix(strings.TrimPrefix(token, "-"), "NOT ")
+		}
+
+		if i := strings.Index(token, ":"); i > 0 {
+			term.field = strings.ToLower(token[:i])
+			token = token[i+1:]
+		}
+
+		term.value = strings.Trim(token, `"'`)
+		group = append(group, term)
+	}
+
+	if len(group) > 0 {
+		result = append(result, group)
+	}
+
+	return result
+}
+
+// tokenize splits the query on whitespace, keeping quoted values together.
+func tokenize(query string) []string {
+	var tokens []string
+	var b strings.Builder
+	var quote rune
+
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			b.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			b.WriteRune(r)
+			quote = r
+		case unicode.IsSpace(r):
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+
+	return tokens
+}
+
+func (q searchQuery) matches(fields fieldsFunc) bool {
+	if len(q) == 0 {
+		return true
+	}
+
+	for _, group := range q {
+		matched := true
+		for _, term := range group {
+			if term.matches(fields) == term.negate {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t searchTerm) matches(fields fieldsFunc) bool {
+	value := strings.ToLower(t.value)
+
+	// e.g., status:any for orders
+	if value == "any" || value == "*" {
+		return true
+	}
+
+	for _, candidate := range fields(t.field) {
+		candidate = strings.ToLower(candidate)
+
+		if strings.HasSuffix(value, "*") {
+			if strings.HasPrefix(candidate, strings.TrimSuffix(value, "*")) {
+				return true
+			}
+		} else if t.field == "" {
+			if strings.Contains(candidate, value) {
+				return true
+			}
+		} else if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func productFields(p *product) fieldsFunc {
+	return func(field string) []string {
+		switch field {
+		case "":
+			return []string{p.title, p.handle, p.vendor, p.productType}
+		case "id":
+			return []string{legacyID(p.id)}
+		case "title":
+			return []string{p.title}
+		case "handle":
+			return []string{p.handle}
+		case "status":
+			return []string{p.status}
+		case "vendor":
+			return []string{p.vendor}
+		case "product_type":
+			return []string{p.productType}
+		case "tag":
+			return p.tags
+		case "sku", "barcode":
+			var values []string
+			for _, v := range p.variants {
+				values = append(values, variantFields(v)(field)...)
+			}
+
+			return values
+		}
+
+		return nil
+	}
+}
+
+func variantFields(v *variant) fieldsFunc {
+	return func(field string) []string {
+		switch field {
+		case "":
+			return []string{v.sku, v.barcode, v.title()}
+		case "id":
+			return []string{legacyID(v.id)}
+		case "product_id":
+			return []string{legacyID(v.product.id)}
+		case "sku":
+			return []string{v.sku}
+		case "barcode":
+			return []string{v.barcode}
+		case "title":
+			return []string{v.title()}
+		}
+
+		return nil
+	}
+}
+
+func orderFields(o *order) fieldsFunc {
+	return func(field string) []string {
+		switch field {
+		case "":
+			return []string{o.name, o.email}
+		case "id":
+			return []string{legacyID(o.id)}
+		case "name":
+			return []string{o.name, strings.TrimPrefix(o.name, "#")}
+		case "email":
+			return []string{o.email}
+		case "sku":
+			var values []string
+			for _, li := range o.lineItems {
+				values = append(values, li.sku)
+			}
+
+			return values
+		case "status":
+			if o.cancelledAt != nil {
+				return []string{"cancelled"}
+			}
+
+			if o.closedAt != nil {
+				return []string{"closed"}
+			}
+
+			return []string{"open"}
+		}
+
+		return nil
+	}
+}
+
+func customerFields(c *customer) fieldsFunc {
+	return func(field string) []string {
+		switch field {
+		case "":
+			return []string{c.firstName, c.lastName, c.email}
+		case "id":
+			return []string{legacyID(c.id)}
+		case "email":
+			return []string{c.email}
+		case "first_name":
+			return []string{c.firstName}
+		case "last_name":
+			return []string{c.lastName}
+		}
+
+		return nil
+	}
+}
+
+func collectionFields(c *collection) fieldsFunc {
+	return func(field string) []string {
+		switch field {
+		case "", "title":
+			return []string{c.title}
+		case "id":
+			return []string{legacyID(c.id)}
+		case "handle":
+			return []string{c.handle}
+		case "collection_type":
+			if c.smart {
+				return []string{"smart"}
+			}
+
+			return []string{"custom"}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,709 @@
+// Package mock is a fake Shopify Admin GraphQL API for testing sdt and apps
+// without a shop or network access.
+//
+// The shop's products, variants, collections, publications, metafields and
+// their definitions, metaobject definitions, webhooks, orders, and bulk
+// operations are kept in memory. Queries and mutations are resolved without a schema: the fields sdt
+// uses are supported, others are null.
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// Server is an http.Handler that serves the Admin GraphQL API, staged uploads,
+// bulk operation results, and OAuth access token requests.
+type Server struct {
+	// Number of times a bulk operation's status must be read before it
+	// completes. 0 completes on the first read.
+	BulkOperationPolls int
+	// When set requests must use this access token; otherwise any token is accepted
+	AccessToken string
+
+	mu    sync.Mutex
+	store *store
+	// Set per request
+	exec       *executor
+	baseURL    string
+	apiVersion string
+}
+
+// Seed is the initial data for a Server. Products are productSet inputs and
+// are added to collections that are seeded before them and published to the
+// publications named by their "publications"; line items of orders refer to
+// variants by SKU.
+type Seed struct {
+	Shop         string                   `json:"shop"`
+	Locations    []string                 `json:"locations"`
+	Publications []string                 `json:"publications"`
+	Collections  []SeedCollection         `json:"collections"`
+	Products     []map[string]interface{} `json:"products"`
+	Metafields   []map[string]interface{} `json:"metafields"`
+	// MetaobjectDefinitionCreateInputs, seeded before metafield definitions so these can refer to them
+	MetaobjectDefinitions []map[string]interface{} `json:"metaobjectDefinitions"`
+	// MetafieldDefinitionInputs
+	MetafieldDefinitions []map[string]interface{} `json:"metafieldDefinitions"`
+	Webhooks             []SeedWebhook            `json:"webhooks"`
+	Orders               []SeedOrder              `json:"orders"`
+	Customers            []SeedCustomer           `json:"customers"`
+}
+
+type SeedCollection struct {
+	Title  string `json:"title"`
+	Handle string `json:"handle"`
+	Smart  bool   `json:"smart"`
+}
+
+type SeedWebhook struct {
+	Topic       string `json:"topic"`
+	CallbackURL string `json:"callbackUrl"`
+	ARN         string `json:"arn"`
+	Format      string `json:"format"`
+}
+
+type SeedOrder struct {
+	Name             string            `json:"name"`
+	Email            string            `json:"email"`
+	Note             string            `json:"note"`
+	CustomAttributes map[string]string `json:"customAttributes"`
+	LineItems        []struct {
+		SKU      string `json:"sku"`
+		Quantity int    `json:"quantity"`
+	} `json:"lineItems"`
+	Closed    bool `json:"closed"`
+	Cancelled bool `json:"cancelled"`
+}
+
+type SeedCustomer struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+// The API version reported for webhooks when requests don't include one
+const defaultAPIVersion = "2026-07"
+
+var graphqlPath = regexp.MustCompile(`^(?:/[^/]+)?/admin/api(?:/([^/]+))?/graphql\.json$`)
+var bulkResultPath = regexp.MustCompile(`^/bulk-operations/(\d+)\.jsonl$`)
+
+func NewServer() *Server {
+	return &Server{store: newStore("mock"), apiVersion: defaultAPIVersion}
+}
+
+// LoadFile loads the Seed in the JSON file.
+func (s *Server) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Cannot read seed file: %s", err)
+	}
+
+	var seed Seed
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&seed); err != nil {
+		return fmt.Errorf("Cannot unmarshal seed file %s: %s", path, err)
+	}
+
+	return s.Load(seed)
+}
+
+// Load adds the seed's data to the server's.
+func (s *Server) Load(seed Seed) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.exec = &executor{}
+
+	if seed.Shop != "" {
+		s.store.shopName = seed.Shop
+	}
+
+	for _, name := range seed.Locations {
+		if s.store.locationByName(name) == nil {
+			s.store.locations = append(s.store.locations, &location{id: s.store.newID(), name: name, active: true})
+		}
+	}
+
+	for _, name := range seed.Publications {
+		if s.store.publicationByName(name) == nil {
+			s.store.publications = append(s.store.publications, &publication{id: s.store.newID(), name: name})
+		}
+	}
+
+	for _, c := range seed.Collections {
+		input := map[string]interface{}{"title": c.Title, "handle": c.Handle}
+		if c.Smart {
+			input["ruleSet"] = map[string]interface{}{"appliedDisjunctively": false}
+		}
+
+		if _, err := s.store.newCollection(input); err != nil {
+			return fmt.Errorf("Cannot load collection %q: %s", c.Title, err)
+		}
+	}
+
+	for i, product := range seed.Products {
+		// normalize copies the input so it can be changed
+		input := normalize(map[string]interface{}(product)).(map[string]interface{})
+
+		// Collections can be given by handle
+		if handles := stringsArg(input, "collections"); len(handles) > 0 {
+			ids := make([]interface{}, len(handles))
+			for j, handle := range handles {
+				ids[j] = handle
+				if c := s.store.collectionByHandle(handle); c != nil {
+					ids[j] = gid("Collection", c.id)
+				}
+			}
+
+			input["collections"] = ids
+		}
+
+		// Locations can be given by name
+		for _, v := range mapsArg(input, "variants") {
+			for _, quantity := range mapsArg(v, "inventoryQuantities") {
+				if l := s.store.locationByName(stringArg(quantity, "locationId")); l != nil {
+					quantity["locationId"] = gid("Location", l.id)
+				}
+			}
+		}
+
+		// Publications are given by name
+		publications := stringsArg(input, "publications")
+		delete(input, "publications")
+
+		result, _ := s.productSet(map[string]interface{}{"input": input})
+		if errs := result.(object)["userErrors"].([]object); len(errs) > 0 {
+			return fmt.Errorf("Cannot load product %d: %s", i+1, errs[0]["message"])
+		}
+
+		_, id, _ := parseGID(result.(object)["product"].(object)["id"].(string))
+		for _, name := range publications {
+			pub := s.store.publicationByName(name)
+			if pub == nil {
+				return fmt.Errorf("Cannot load product %d: unknown publication %q", i+1, name)
+			}
+
+			pub.products = append(pub.products, id)
+		}
+	}
+
+	for i, input := range seed.Metafields {
+		if err := s.setMetafieldInput(stringArg(input, "ownerId"), input); err != nil {
+			return fmt.Errorf("Cannot load metafield %d: %s", i+1, err)
+		}
+	}
+
+	for i, input := range seed.MetaobjectDefinitions {
+		if _, err := s.store.newMetaobjectDefinition(normalize(map[string]interface{}(input)).(map[string]interface{})); err != nil {
+			return fmt.Errorf("Cannot load metaobject definition %d: %s", i+1, err)
+		}
+	}
+
+	for i, input := range seed.MetafieldDefinitions {
+		if _, err := s.store.newMetafieldDefinition(normalize(map[string]interface{}(input)).(map[string]interface{})); err != nil {
+			return fmt.Errorf("Cannot load metafield definition %d: %s", i+1, err)
+		}
+	}
+
+	for _, w := range seed.Webhooks {
+		input := map[string]interface{}{"callbackUrl": w.CallbackURL, "arn": w.ARN, "format": w.Format}
+
+		result, _ := s.webhookSubscriptionCreate(map[string]interface{}{"topic": w.Topic, "webhookSubscription": input})
+		if errs := result.(object)["userErrors"].([]object); len(errs) > 0 {
+			return fmt.Errorf("Cannot load %s webhook: %s", w.Topic, errs[0]["message"])
+		}
+	}
+
+	for _, input := range seed.Orders {
+		s.store.orders = append(s.store.orders, s.store.newOrder(input))
+	}
+
+	for _, input := range seed.Customers {
+		now := time.Now()
+		c := &customer{id: s.store.newID(), firstName: input.FirstName, lastName: input.LastName, email: input.Email, createdAt: now, updatedAt: now}
+		s.store.customers = append(s.store.customers, c)
+	}
+
+	return nil
+}
+
+func (s *store) newOrder(input SeedOrder) *order {
+	now := time.Now()
+
+	o := &order{
+		id:                       s.newID(),
+		name:                     input.Name,
+		email:                    input.Email,
+		note:                     input.Note,
+		displayFinancialStatus:   "PAID",
+		displayFulfillmentStatus: "UNFULFILLED",
+		createdAt:                now,
+		updatedAt:                now,
+	}
+
+	if o.name == "" {
+		o.name = fmt.Sprintf("#%d", len(s.orders)+1001)
+	}
+
+	if input.Closed {
+		o.closedAt = &now
+	}
+
+	if input.Cancelled {
+		o.cancelledAt = &now
+	}
+
+	for _, key := range sortedKeys(input.CustomAttributes) {
+		o.customAttributes = append(o.customAttributes, attribute{key: key, value: input.CustomAttributes[key]})
+	}
+
+	for _, item := range input.LineItems {
+		li := &lineItem{id: s.newID(), sku: item.SKU, name: item.SKU, quantity: item.Quantity, fulfillmentStatus: "unfulfilled"}
+
+		for _, v := range s.variants() {
+			if item.SKU != "" && v.sku == item.SKU {
+				li.variant = v
+				li.name = v.product.title + " - " + v.title()
+				break
+			}
+		}
+
+		o.lineItems = append(o.lineItems, li)
+	}
+
+	return o
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	s.baseURL = scheme + "://" + r.Host
+
+	if match := graphqlPath.FindStringSubmatch(r.URL.Path); match != nil && r.Method == http.MethodPost {
+		s.apiVersion = match[1]
+		if s.apiVersion == "" {
+			s.apiVersion = defaultAPIVersion
+		}
+
+		s.serveGraphQL(w, r)
+		return
+	}
+
+	if match := bulkResultPath.FindStringSubmatch(r.URL.Path); match != nil && r.Method == http.MethodGet {
+		s.serveBulkResult(w, match[1])
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/staged-uploads" && r.Method == http.MethodPost:
+		s.serveStagedUpload(w, r)
+	case strings.HasSuffix(r.URL.Path, "/admin/oauth/access_token") && r.Method == http.MethodPost:
+		s.serveAccessToken(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if _, _, ok := r.BasicAuth(); ok {
+		return s.AccessToken == ""
+	}
+
+	token := r.Header.Get("X-Shopify-Access-Token")
+	if token == "" {
+		return false
+	}
+
+	return s.AccessToken == "" || token == s.AccessToken
+}
+
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]interface{}{
+			"errors": "[API] Invalid API key or access token (unrecognized login or wrong password)",
+		})
+		return
+	}
+
+	var request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&request); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"errors": "Invalid JSON in request body"})
+		return
+	}
+
+	response := map[string]interface{}{
+		"extensions": map[string]interface{}{
+			"cost": map[string]interface{}{
+				"requestedQueryCost": 1,
+				"actualQueryCost":    1,
+				"throttleStatus": map[string]interface{}{
+					"maximumAvailable":   2000.0,
+					"currentlyAvailable": 1999,
+					"restoreRate":        100.0,
+				},
+			},
+		},
+	}
+
+	doc, op, err := parseOperation(request.Query, request.OperationName)
+	if err != nil {
+		response["errors"] = []*gqlError{{Message: err.Error()}}
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	data, errs := s.executeOperation(doc, op, request.Variables, false)
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+
+	response["data"] = data
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// executeOperation runs the operation, returning its data or errors.
+func (s *Server) executeOperation(doc *ast.QueryDocument, op *ast.OperationDefinition, variables map[string]interface{}, bulk bool) (map[string]interface{}, []*gqlError) {
+	vars, err := variablesFor(op, variables)
+	if err != nil {
+		return nil, []*gqlError{{Message: err.Error()}}
+	}
+
+	// Bulk mutations run operations from within a resolver
+	previous := s.exec
+	defer func() { s.exec = previous }()
+
+	s.exec = &executor{doc: doc, variables: vars, bulk: bulk}
+
+	root := s.queryRoot()
+	if op.Operation == ast.Mutation {
+		root = s.mutationRoot()
+	}
+
+	data, err := s.exec.selectionSet(root, op.SelectionSet, nil)
+	if err != nil {
+		if gerr, ok := err.(*gqlError); ok {
+			return nil, []*gqlError{gerr}
+		}
+
+		return nil, []*gqlError{{Message: err.Error()}}
+	}
+
+	return data, nil
+}
+
+// runBulkQuery runs a bulk operation query and returns its results as JSONL.
+// Nested connections' nodes are on their own lines with the __parentId of the
+// object they belong to.
+func (s *Server) runBulkQuery(query string) ([]byte, int, int, error) {
+	doc, op, err := parseOperation(query, "")
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if op.Operation != ast.Query || len(op.SelectionSet) != 1 {
+		return nil, 0, 0, fmt.Errorf("Bulk queries must contain exactly one top-level field")
+	}
+
+	if n := countConnections(doc, op.SelectionSet); n > maxBulkConnections {
+		return nil, 0, 0, fmt.Errorf("Bulk queries cannot contain more than %d connections, this one contains %d", maxBulkConnections, n)
+	}
+
+	data, errs := s.executeOperation(doc, op, nil, true)
+	if len(errs) > 0 {
+		return nil, 0, 0, errs[0]
+	}
+
+	var lines []map[string]interface{}
+	roots := 0
+
+	for _, value := range data {
+		nodes, ok := connectionNodes(value)
+		if !ok {
+			return nil, 0, 0, fmt.Errorf("Bulk queries must contain at least one connection")
+		}
+
+		roots = len(nodes)
+		for _, node := range nodes {
+			lines = flattenNode(lines, node, "")
+		}
+	}
+
+	var result bytes.Buffer
+	for _, line := range lines {
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+
+		result.Write(encoded)
+		result.WriteByte('\n')
+	}
+
+	return result.Bytes(), len(lines), roots, nil
+}
+
+// Shopify's limit on the number of connections in a bulk query
+const maxBulkConnections = 5
+
+// countConnections returns the number of connections, fields selecting edges or nodes, in selections.
+func countConnections(doc *ast.QueryDocument, selections ast.SelectionSet) int {
+	n := 0
+
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if isConnection(doc, sel.SelectionSet) {
+				n++
+			}
+
+			n += countConnections(doc, sel.SelectionSet)
+		case *ast.InlineFragment:
+			n += countConnections(doc, sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment := doc.Fragments.ForName(sel.Name); fragment != nil {
+				n += countConnections(doc, fragment.SelectionSet)
+			}
+		}
+	}
+
+	return n
+}
+
+func isConnection(doc *ast.QueryDocument, selections ast.SelectionSet) bool {
+	for _, selection := range selections {
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if sel.Name == "edges" || sel.Name == "nodes" {
+				return true
+			}
+		case *ast.InlineFragment:
+			if isConnection(doc, sel.SelectionSet) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if fragment := doc.Fragments.ForName(sel.Name); fragment != nil && isConnection(doc, fragment.SelectionSet) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// connectionNodes returns the nodes of a connection's edges or nodes field.
+func connectionNodes(value interface{}) ([]map[string]interface{}, bool) {
+	conn, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	var nodes []map[string]interface{}
+
+	if edges, ok := conn["edges"].([]interface{}); ok {
+		for _, edge := range edges {
+			if node, ok := edge.(map[string]interface{})["node"].(map[string]interface{}); ok {
+				nodes = append(nodes, node)
+			}
+		}
+
+		return nodes, true
+	}
+
+	if list, ok := conn["nodes"].([]interface{}); ok {
+		for _, node := range list {
+			if node, ok := node.(map[string]interface{}); ok {
+				nodes = append(nodes, node)
+			}
+		}
+
+		return nodes, true
+	}
+
+	return nil, false
+}
+
+func flattenNode(lines []map[string]interface{}, node map[string]interface{}, parentID string) []map[string]interface{} {
+	line, children := extractConnections(node)
+	if parentID != "" {
+		line["__parentId"] = parentID
+	}
+
+	lines = append(lines, line)
+
+	id, _ := node["id"].(string)
+	for _, nodes := range children {
+		for _, child := range nodes {
+			lines = flattenNode(lines, child, id)
+		}
+	}
+
+	return lines
+}
+
+// extractConnections returns a copy of object without its connections, and
+// the nodes of those connections. Connections within nested objects, e.g., a
+// variant's inventoryItem.inventoryLevels, belong to the object too.
+func extractConnections(object map[string]interface{}) (map[string]interface{}, [][]map[string]interface{}) {
+	result := map[string]interface{}{}
+	var children [][]map[string]interface{}
+
+	for _, key := range sortedFields(object) {
+		value := object[key]
+		if nodes, ok := connectionNodes(value); ok {
+			children = append(children, nodes)
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			var nestedChildren [][]map[string]interface{}
+			value, nestedChildren = extractConnections(nested)
+			children = append(children, nestedChildren...)
+		}
+
+		result[key] = value
+	}
+
+	return result, children
+}
+
+func sortedFields(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// poll updates the operation's status as if time has passed.
+func (s *Server) poll(op *bulkOperation) {
+	switch op.status {
+	case "CREATED", "RUNNING":
+		if op.pollsLeft > 0 {
+			op.pollsLeft--
+			op.status = "RUNNING"
+			return
+		}
+
+		now := time.Now()
+		op.status = "COMPLETED"
+		op.completedAt = &now
+	case "CANCELING":
+		now := time.Now()
+		op.status = "CANCELED"
+		op.completedAt = &now
+	}
+}
+
+func (s *Server) serveBulkResult(w http.ResponseWriter, id string) {
+	for _, op := range s.store.bulkOperations {
+		if legacyID(op.id) == id && op.status == "COMPLETED" {
+			w.Header().Set("Content-Type", "application/jsonl")
+			w.Write(op.result)
+			return
+		}
+	}
+
+	http.Error(w, "No such bulk operation result", http.StatusNotFound)
+}
+
+func (s *Server) serveStagedUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	key := r.FormValue("key")
+	if key == "" {
+		http.Error(w, "No key parameter", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file parameter", http.StatusBadRequest)
+		return
+	}
+
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.store.uploads[key] = data
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) serveAccessToken(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("client_id") == "" || r.PostFormValue("client_secret") == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_request",
+			"error_description": "client_id and client_secret are required",
+		})
+		return
+	}
+
+	token := s.AccessToken
+	if token == "" {
+		token = "shpat_mock"
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": token, "scope": r.PostFormValue("scope")})
+}
+
+func decodeVariables(data []byte) (map[string]interface{}, error) {
+	var vars map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&vars); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write response: %s\n", err)
+	}
+}
@@ -0,0 +1,607 @@
+package mock_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ScreenStaring/shopify-dev-tools/gql"
+	"github.com/ScreenStaring/shopify-dev-tools/gql/mock"
+)
+
+var seed = mock.Seed{
+	Locations: []string{"Warehouse"},
+	Products: []map[string]interface{}{
+		{
+			"title":          "Blue Hat",
+			"tags":           []interface{}{"hats"},
+			"productOptions": []interface{}{map[string]interface{}{"name": "Size", "values": []interface{}{map[string]interface{}{"name": "S"}, map[string]interface{}{"name": "M"}}}},
+			"variants": []interface{}{
+				map[string]interface{}{
+					"sku":                 "HAT-S",
+					"optionValues":        []interface{}{map[string]interface{}{"optionName": "Size", "name": "S"}},
+					"inventoryQuantities": []interface{}{map[string]interface{}{"locationId": "Warehouse", "name": "available", "quantity": 5}},
+				},
+				map[string]interface{}{"sku": "HAT-M", "optionValues": []interface{}{map[string]interface{}{"optionName": "Size", "name": "M"}}},
+			},
+		},
+		{"title": "Red Scarf", "status": "DRAFT", "variants": []interface{}{map[string]interface{}{"sku": "SCARF"}}},
+	},
+	Orders: []mock.SeedOrder{{Email: "buyer@example.com"}},
+}
+
+func testServer(t *testing.T) (*mock.Server, *gql.Client) {
+	server := mock.NewServer()
+	if err := server.Load(seed); err != nil {
+		t.Fatalf("Load failed: %s", err)
+	}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	old := gql.AdminURL
+	gql.AdminURL = httpServer.URL
+	t.Cleanup(func() { gql.AdminURL = old })
+
+	return server, gql.NewClient("acme", "shpat_test", map[string]interface{}{"version": "2026-07"})
+}
+
+type productNode struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Handle   string   `json:"handle"`
+	Tags     []string `json:"tags"`
+	Variants struct {
+		Nodes []struct {
+			SKU           string `json:"sku"`
+			InventoryItem struct {
+				InventoryLevels struct {
+					Edges []struct {
+						Node struct {
+							Location struct {
+								Name string `json:"name"`
+							} `json:"location"`
+							Quantities []struct {
+								Name     string `json:"name"`
+								Quantity int    `json:"quantity"`
+							} `json:"quantities"`
+						} `json:"node"`
+					} `json:"edges"`
+				} `json:"inventoryLevels"`
+			} `json:"inventoryItem"`
+		} `json:"nodes"`
+	} `json:"variants"`
+}
+
+const productsQuery = `
+query($first: Int!, $after: String, $query: String) {
+  products(first: $first, after: $after, query: $query) {
+    edges {
+      node {
+        ...ProductFields
+      }
+    }
+    pageInfo {
+      hasNextPage
+      endCursor
+    }
+  }
+}
+
+fragment ProductFields on Product {
+  id
+  title
+  handle
+  tags
+  variants(first: 10) {
+    nodes {
+      sku
+      inventoryItem {
+        inventoryLevels(first: 5) {
+          edges {
+            node {
+              location { name }
+              quantities(names: ["available"]) { name quantity }
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
+
+func TestPaginateProducts(t *testing.T) {
+	_, client := testServer(t)
+
+	var titles []string
+	err := gql.Paginate(client, productsQuery, map[string]interface{}{"first": 1}, "products", func(p productNode) error {
+		titles = append(titles, p.Title)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("Paginate failed: %s", err)
+	}
+
+	if want := []string{"Blue Hat", "Red Scarf"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("titles = %v, want %v", titles, want)
+	}
+}
+
+func TestProductSearch(t *testing.T) {
+	_, client := testServer(t)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"status:draft", []string{"Red Scarf"}},
+		{"sku:HAT-M", []string{"Blue Hat"}},
+		{"sku:HAT-M OR sku:SCARF", []string{"Blue Hat", "Red Scarf"}},
+		{"-tag:hats", []string{"Red Scarf"}},
+		{"title:'Blue Hat'", []string{"Blue Hat"}},
+		{"handle:red*", []string{"Red Scarf"}},
+		{"scarf", []string{"Red Scarf"}},
+	}
+
+	for _, tt := range tests {
+		var titles []string
+		err := gql.Paginate(client, productsQuery, map[string]interface{}{"first": 10, "query": tt.query}, "products", func(p productNode) error {
+			titles = append(titles, p.Title)
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("Paginate failed: %s", err)
+		}
+
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("products(query: %q) = %v, want %v", tt.query, titles, tt.want)
+		}
+	}
+}
+
+func TestProductSetUpdatesVariantsAndInventory(t *testing.T) {
+	_, client := testServer(t)
+
+	var response struct {
+		ProductSet struct {
+			Product productNode `json:"product"`
+		} `json:"productSet"`
+	}
+
+	mutation := `
+mutation($input: ProductSetInput!, $identifier: ProductSetIdentifiers) {
+  productSet(input: $input, identifier: $identifier) {
+    product { ...ProductFields }
+    userErrors { field message }
+  }
+}
+` + productsQuery[strings.Index(productsQuery, "fragment"):]
+
+	err := client.ExecuteInto(mutation, map[string]interface{}{
+		"identifier": map[string]interface{}{"handle": "blue-hat"},
+		"input": map[string]interface{}{
+			"title": "Blue Hat",
+			"tags":  []string{"hats", "sale"},
+			"productOptions": []interface{}{
+				map[string]interface{}{"name": "Size", "values": []interface{}{map[string]interface{}{"name": "S"}}},
+			},
+			"variants": []interface{}{
+				map[string]interface{}{
+					"sku":                 "HAT-S",
+					"optionValues":        []interface{}{map[string]interface{}{"optionName": "Size", "name": "S"}},
+					"inventoryQuantities": []interface{}{map[string]interface{}{"locationId": locationID(t, client, "Warehouse"), "name": "available", "quantity": 9}},
+				},
+			},
+		},
+	}, &response)
+
+	if err != nil {
+		t.Fatalf("productSet failed: %s", err)
+	}
+
+	p := response.ProductSet.Product
+	if p.Handle != "blue-hat" || !reflect.DeepEqual(p.Tags, []string{"hats", "sale"}) {
+		t.Errorf("product = %+v, want blue-hat tagged hats, sale", p)
+	}
+
+	if len(p.Variants.Nodes) != 1 {
+		t.Fatalf("got %d variants, want 1", len(p.Variants.Nodes))
+	}
+
+	levels := p.Variants.Nodes[0].InventoryItem.InventoryLevels.Edges
+	if len(levels) != 1 || levels[0].Node.Location.Name != "Warehouse" || levels[0].Node.Quantities[0].Quantity != 9 {
+		t.Errorf("inventory levels = %+v, want 9 available at Warehouse", levels)
+	}
+}
+
+func TestProductSetUserErrors(t *testing.T) {
+	_, client := testServer(t)
+
+	err := client.ExecuteInto(`
+mutation($input: ProductSetInput!) {
+  productSet(input: $input) {
+    product { id }
+    userErrors { field message }
+  }
+}`, map[string]interface{}{"input": map[string]interface{}{"handle": "blue-hat", "title": "Another"}}, nil)
+
+	var userErrors gql.UserErrors
+	if !errors.As(err, &userErrors) {
+		t.Fatalf("err = %v, want UserErrors", err)
+	}
+
+	if want := "Handle 'blue-hat' already in use. Please provide a new handle."; userErrors[0].Message != want {
+		t.Errorf("message = %q, want %q", userErrors[0].Message, want)
+	}
+}
+
+func locationID(t *testing.T, client *gql.Client, name string) string {
+	var response struct {
+		Locations struct {
+			Nodes []struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"nodes"`
+		} `json:"locations"`
+	}
+
+	if err := client.ExecuteInto(`{ locations(first: 10) { nodes { id name } } }`, nil, &response); err != nil {
+		t.Fatalf("locations query failed: %s", err)
+	}
+
+	for _, l := range response.Locations.Nodes {
+		if l.Name == name {
+			return l.ID
+		}
+	}
+
+	t.Fatalf("No location named %s", name)
+	return ""
+}
+
+func TestMetafields(t *testing.T) {
+	_, client := testServer(t)
+
+	set := `
+mutation($metafields: [MetafieldsSetInput!]!) {
+  metafieldsSet(metafields: $metafields) {
+    metafields { id }
+    userErrors { field message }
+  }
+}`
+
+	var orders struct {
+		Orders struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"orders"`
+	}
+
+	if err := client.ExecuteInto(`{ orders(first: 1, query: "email:buyer@example.com") { nodes { id } } }`, nil, &orders); err != nil {
+		t.Fatalf("orders query failed: %s", err)
+	}
+
+	ownerID := orders.Orders.Nodes[0].ID
+
+	err := client.ExecuteInto(set, map[string]interface{}{
+		"metafields": []interface{}{
+			map[string]interface{}{"ownerId": ownerID, "namespace": "custom", "key": "gift", "value": "true", "type": "boolean"},
+		},
+	}, nil)
+
+	if err != nil {
+		t.Fatalf("metafieldsSet failed: %s", err)
+	}
+
+	// Type is required when creating
+	err = client.ExecuteInto(set, map[string]interface{}{
+		"metafields": []interface{}{
+			map[string]interface{}{"ownerId": ownerID, "namespace": "custom", "key": "other", "value": "x"},
+		},
+	}, nil)
+
+	var userErrors gql.UserErrors
+	if !errors.As(err, &userErrors) || userErrors[0].Message != "Type can't be blank" {
+		t.Errorf("err = %v, want Type can't be blank", err)
+	}
+
+	var response struct {
+		Order struct {
+			Metafield struct {
+				Value string `json:"value"`
+				Type  string `json:"type"`
+			} `json:"metafield"`
+		} `json:"order"`
+	}
+
+	err = client.ExecuteInto(`query($id: ID!) { order(id: $id) { metafield(namespace: "custom", key: "gift") { value type } } }`, map[string]interface{}{"id": ownerID}, &response)
+	if err != nil {
+		t.Fatalf("order query failed: %s", err)
+	}
+
+	if got := response.Order.Metafield; got.Value != "true" || got.Type != "boolean" {
+		t.Errorf("metafield = %+v, want true of type boolean", got)
+	}
+}
+
+func TestWebhookSubscriptions(t *testing.T) {
+	_, client := testServer(t)
+
+	create := `
+mutation($topic: WebhookSubscriptionTopic!, $webhookSubscription: WebhookSubscriptionInput!) {
+  webhookSubscriptionCreate(topic: $topic, webhookSubscription: $webhookSubscription) {
+    webhookSubscription { id }
+    userErrors { field message }
+  }
+}`
+
+	vars := map[string]interface{}{
+		"topic":               "ORDERS_CREATE",
+		"webhookSubscription": map[string]interface{}{"callbackUrl": "https://example.com/orders"},
+	}
+
+	if err := client.ExecuteInto(create, vars, nil); err != nil {
+		t.Fatalf("webhookSubscriptionCreate failed: %s", err)
+	}
+
+	err := client.ExecuteInto(create, vars, nil)
+
+	var userErrors gql.UserErrors
+	if !errors.As(err, &userErrors) || userErrors[0].Message != "Address for this topic has already been taken" {
+		t.Errorf("err = %v, want address taken", err)
+	}
+
+	var response struct {
+		WebhookSubscriptions struct {
+			Nodes []struct {
+				Topic    string `json:"topic"`
+				Endpoint struct {
+					Typename    string `json:"__typename"`
+					CallbackURL string `json:"callbackUrl"`
+				} `json:"endpoint"`
+			} `json:"nodes"`
+		} `json:"webhookSubscriptions"`
+	}
+
+	query := `{
+  webhookSubscriptions(first: 10, topics: [ORDERS_CREATE]) {
+    nodes {
+      topic
+      endpoint {
+        __typename
+        ... on WebhookHttpEndpoint { callbackUrl }
+      }
+    }
+  }
+}`
+
+	if err := client.ExecuteInto(query, nil, &response); err != nil {
+		t.Fatalf("webhookSubscriptions query failed: %s", err)
+	}
+
+	nodes := response.WebhookSubscriptions.Nodes
+	if len(nodes) != 1 || nodes[0].Endpoint.Typename != "WebhookHttpEndpoint" || nodes[0].Endpoint.CallbackURL != "https://example.com/orders" {
+		t.Errorf("webhooks = %+v, want 1 to https://example.com/orders", nodes)
+	}
+}
+
+type bulkOperation struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	URL    string `json:"url"`
+}
+
+func pollBulkOperation(t *testing.T, client *gql.Client, id string) bulkOperation {
+	var response struct {
+		Node bulkOperation `json:"node"`
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := client.ExecuteInto(`query($id: ID!) { node(id: $id) { ... on BulkOperation { id status url } } }`, map[string]interface{}{"id": id}, &response); err != nil {
+			t.Fatalf("Bulk operation query failed: %s", err)
+		}
+
+		if response.Node.Status == "COMPLETED" {
+			return response.Node
+		}
+	}
+
+	t.Fatalf("Bulk operation %s did not complete: %s", id, response.Node.Status)
+	return response.Node
+}
+
+func download(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Download failed: %s", err)
+	}
+
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestBulkOperationRunQuery(t *testing.T) {
+	server, client := testServer(t)
+	server.BulkOperationPolls = 2
+
+	var response struct {
+		BulkOperationRunQuery struct {
+			BulkOperation bulkOperation `json:"bulkOperation"`
+		} `json:"bulkOperationRunQuery"`
+	}
+
+	err := client.ExecuteInto(`
+mutation($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation { id status }
+    userErrors { field message }
+  }
+}`, map[string]interface{}{"query": `{ products(query: "status:active") { edges { node { id title variants { edges { node { sku } } } } } } }`}, &response)
+
+	if err != nil {
+		t.Fatalf("bulkOperationRunQuery failed: %s", err)
+	}
+
+	op := pollBulkOperation(t, client, response.BulkOperationRunQuery.BulkOperation.ID)
+
+	var product struct {
+		ProductByHandle struct {
+			ID string `json:"id"`
+		} `json:"productByHandle"`
+	}
+
+	if err := client.ExecuteInto(`{ productByHandle(handle: "blue-hat") { id } }`, nil, &product); err != nil {
+		t.Fatalf("productByHandle query failed: %s", err)
+	}
+
+	productID := product.ProductByHandle.ID
+
+	got := download(t, op.URL)
+	want := `{"id":"` + productID + `","title":"Blue Hat"}
+{"__parentId":"` + productID + `","sku":"HAT-S"}
+{"__parentId":"` + productID + `","sku":"HAT-M"}
+`
+
+	if got != want {
+		t.Errorf("result = %q, want %q", got, want)
+	}
+}
+
+func TestBulkOperationRunQueryConnectionLimit(t *testing.T) {
+	mutation := `
+mutation($query: String!) {
+  bulkOperationRunQuery(query: $query) {
+    bulkOperation { id status }
+    userErrors { field message }
+  }
+}`
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: `{ products { edges { node { id media { nodes { id } } metafields { nodes { id } } variants { nodes { id ...VariantMetafields } } } } } } fragment VariantMetafields on ProductVariant { metafields { edges { node { id } } } }`},
+		{query: `{ products { edges { node { id media { nodes { id } } metafields { nodes { id } } collections { nodes { id } } variants { nodes { id metafields { nodes { id } } } } } } } }`, err: "more than 5 connections, this one contains 6"},
+	}
+
+	for _, tt := range tests {
+		_, client := testServer(t)
+
+		err := client.ExecuteInto(mutation, map[string]interface{}{"query": tt.query}, nil)
+		if tt.err == "" && err != nil {
+			t.Errorf("bulkOperationRunQuery(%s) failed: %s", tt.query, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("bulkOperationRunQuery(%s) error = %v, want %q", tt.query, err, tt.err)
+		}
+	}
+}
+
+func TestBulkOperationRunMutation(t *testing.T) {
+	_, client := testServer(t)
+
+	var staged struct {
+		StagedUploadsCreate struct {
+			StagedTargets []struct {
+				URL        string `json:"url"`
+				Parameters []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"parameters"`
+			} `json:"stagedTargets"`
+		} `json:"stagedUploadsCreate"`
+	}
+
+	err := client.ExecuteInto(`
+mutation($input: [StagedUploadInput!]!) {
+  stagedUploadsCreate(input: $input) {
+    stagedTargets { url parameters { name value } }
+    userErrors { field message }
+  }
+}`, map[string]interface{}{"input": []interface{}{map[string]interface{}{"resource": "BULK_MUTATION_VARIABLES", "filename": "bulk.jsonl", "mimeType": "text/jsonl", "httpMethod": "POST"}}}, &staged)
+
+	if err != nil {
+		t.Fatalf("stagedUploadsCreate failed: %s", err)
+	}
+
+	target := staged.StagedUploadsCreate.StagedTargets[0]
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	var key string
+	for _, param := range target.Parameters {
+		writer.WriteField(param.Name, param.Value)
+		if param.Name == "key" {
+			key = param.Value
+		}
+	}
+
+	part, _ := writer.CreateFormFile("file", "bulk.jsonl")
+	part.Write([]byte(`{"input":{"title":"Green Cap"}}` + "\n" + `{"input":{"title":""}}` + "\n"))
+	writer.Close()
+
+	resp, err := http.Post(target.URL, writer.FormDataContentType(), &body)
+	if err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Upload failed: %v %v", resp, err)
+	}
+
+	var response struct {
+		BulkOperationRunMutation struct {
+			BulkOperation bulkOperation `json:"bulkOperation"`
+		} `json:"bulkOperationRunMutation"`
+	}
+
+	err = client.ExecuteInto(`
+mutation($mutation: String!, $stagedUploadPath: String!) {
+  bulkOperationRunMutation(mutation: $mutation, stagedUploadPath: $stagedUploadPath) {
+    bulkOperation { id status }
+    userErrors { field message }
+  }
+}`, map[string]interface{}{
+		"mutation":         `mutation($input: ProductSetInput!) { productSet(input: $input) { product { title } userErrors { message } } }`,
+		"stagedUploadPath": key,
+	}, &response)
+
+	if err != nil {
+		t.Fatalf("bulkOperationRunMutation failed: %s", err)
+	}
+
+	op := pollBulkOperation(t, client, response.BulkOperationRunMutation.BulkOperation.ID)
+
+	got := download(t, op.URL)
+	want := `{"__lineNumber":0,"data":{"productSet":{"product":{"title":"Green Cap"},"userErrors":[]}}}
+{"__lineNumber":1,"data":{"productSet":{"product":null,"userErrors":[{"message":"Title can't be blank"}]}}}
+`
+
+	if got != want {
+		t.Errorf("result = %q, want %q", got, want)
+	}
+}
+
+func TestRequiresAccessToken(t *testing.T) {
+	server := httptest.NewServer(mock.NewServer())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/admin/api/2026-07/graphql.json", "application/json", strings.NewReader(`{"query":"{ shop { name } }"}`))
+	if err != nil {
+		t.Fatalf("Request failed: %s", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,987 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type product struct {
+	id              int64
+	title           string
+	handle          string
+	descriptionHTML string
+	vendor          string
+	productType     string
+	status          string
+	tags            []string
+	options         []productOption
+	variants        []*variant
+	media           []*media
+	createdAt       time.Time
+	updatedAt       time.Time
+}
+
+type media struct {
+	id          int64
+	contentType string
+	// The image's URL, or the original source of other media
+	source string
+	alt    string
+}
+
+type productOption struct {
+	name   string
+	values []string
+}
+
+type variant struct {
+	id               int64
+	inventoryItemID  int64
+	product          *product
+	sku              string
+	barcode          string
+	price            string
+	compareAtPrice   string
+	taxable          bool
+	inventoryPolicy  string
+	tracked          bool
+	requiresShipping bool
+	cost             string
+	options          []selectedOption
+	// One of the product's media
+	media *media
+	// Location ID to quantities by name, e.g., available or on_hand
+	quantities map[int64]map[string]int
+	createdAt  time.Time
+	updatedAt  time.Time
+}
+
+type selectedOption struct {
+	name  string
+	value string
+}
+
+type metafield struct {
+	id          int64
+	ownerID     string
+	namespace   string
+	key         string
+	value       string
+	valueType   string
+	description string
+	createdAt   time.Time
+	updatedAt   time.Time
+}
+
+type metafieldDefinition struct {
+	id          int64
+	name        string
+	namespace   string
+	key         string
+	description string
+	valueType   string
+	ownerType   string
+	validations []metafieldValidation
+	// MetafieldAdminAccess and MetafieldStorefrontAccess
+	adminAccess      string
+	storefrontAccess string
+	// 0 when not pinned
+	pinnedPosition int
+	createdAt      time.Time
+}
+
+type metafieldValidation struct {
+	name  string
+	value string
+}
+
+type metaobjectDefinition struct {
+	id             int64
+	typeName       string
+	name           string
+	description    string
+	displayNameKey string
+	fields         []*metaobjectFieldDefinition
+	createdAt      time.Time
+}
+
+type metaobjectFieldDefinition struct {
+	key         string
+	name        string
+	description string
+	valueType   string
+	required    bool
+	validations []metafieldValidation
+}
+
+type collection struct {
+	id     int64
+	title  string
+	handle string
+	// Smart collections' products are given by rules, they can't be added to
+	smart     bool
+	products  []int64
+	updatedAt time.Time
+}
+
+type publication struct {
+	id       int64
+	name     string
+	products []int64
+}
+
+type location struct {
+	id     int64
+	name   string
+	active bool
+}
+
+type webhook struct {
+	id                  int64
+	topic               string
+	format              string
+	callbackURL         string
+	arn                 string
+	includeFields       []string
+	metafieldNamespaces []string
+	apiVersion          string
+	createdAt           time.Time
+	updatedAt           time.Time
+}
+
+type order struct {
+	id                       int64
+	name                     string
+	note                     string
+	email                    string
+	customAttributes         []attribute
+	lineItems                []*lineItem
+	displayFinancialStatus   string
+	displayFulfillmentStatus string
+	cancelledAt              *time.Time
+	closedAt                 *time.Time
+	createdAt                time.Time
+	updatedAt                time.Time
+}
+
+type customer struct {
+	id        int64
+	firstName string
+	lastName  string
+	email     string
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type attribute struct {
+	key   string
+	value string
+}
+
+type lineItem struct {
+	id                int64
+	variant           *variant
+	sku               string
+	name              string
+	quantity          int
+	fulfillmentStatus string
+}
+
+type bulkOperation struct {
+	id              int64
+	operationType   string
+	status          string
+	errorCode       string
+	query           string
+	objectCount     int
+	rootObjectCount int
+	result          []byte
+	// Number of times the status is read before the operation completes
+	pollsLeft   int
+	createdAt   time.Time
+	completedAt *time.Time
+}
+
+// store is the shop's data.
+type store struct {
+	nextID      int64
+	shopName    string
+	products    []*product
+	metafields  []*metafield
+	definitions []*metafieldDefinition
+	// Metaobject definitions
+	metaobjects    []*metaobjectDefinition
+	locations      []*location
+	collections    []*collection
+	publications   []*publication
+	webhooks       []*webhook
+	orders         []*order
+	customers      []*customer
+	bulkOperations []*bulkOperation
+	// Staged upload key to file contents
+	uploads map[string][]byte
+}
+
+func newStore(shopName string) *store {
+	s := &store{nextID: 1000, shopName: shopName, uploads: map[string][]byte{}}
+	s.locations = append(s.locations, &location{id: s.newID(), name: "Main", active: true})
+	s.publications = append(s.publications, &publication{id: s.newID(), name: "Online Store"})
+
+	return s
+}
+
+func (s *store) newID() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+func gid(typeName string, id int64) string {
+	return fmt.Sprintf("gid://shopify/%s/%d", typeName, id)
+}
+
+// parseGID returns the type and ID of a GID. Bare IDs have no type.
+func parseGID(s string) (string, int64, bool) {
+	if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return "", id, true
+	}
+
+	if !strings.HasPrefix(s, "gid://shopify/") {
+		return "", 0, false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(s, "gid://shopify/"), "/")
+	if len(parts) != 2 {
+		return "", 0, false
+	}
+
+	// Strip any query string, e.g., ?shop=1
+	id, err := strconv.ParseInt(strings.SplitN(parts[1], "?", 2)[0], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+
+	return parts[0], id, true
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func optionalTimestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return timestamp(*t)
+}
+
+func (s *store) product(id int64) *product {
+	for _, p := range s.products {
+		if p.id == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (s *store) productByHandle(handle string) *product {
+	for _, p := range s.products {
+		if p.handle == handle {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func (s *store) variant(id int64) *variant {
+	for _, p := range s.products {
+		for _, v := range p.variants {
+			if v.id == id || v.inventoryItemID == id {
+				return v
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *store) variants() []*variant {
+	var result []*variant
+	for _, p := range s.products {
+		result = append(result, p.variants...)
+	}
+
+	return result
+}
+
+// media returns the media with the ID, of any product.
+func (s *store) media(id int64) *media {
+	for _, p := range s.products {
+		if m := p.findMedia(id, ""); m != nil {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// findMedia returns the product's media with the ID, or if 0 the source.
+func (p *product) findMedia(id int64, source string) *media {
+	for _, m := range p.media {
+		if (id != 0 && m.id == id) || (id == 0 && m.source == source) {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func (s *store) location(id int64) *location {
+	for _, l := range s.locations {
+		if l.id == id {
+			return l
+		}
+	}
+
+	return nil
+}
+
+func (s *store) locationByName(name string) *location {
+	for _, l := range s.locations {
+		if l.name == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+func (s *store) collection(id int64) *collection {
+	for _, c := range s.collections {
+		if c.id == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (s *store) collectionByHandle(handle string) *collection {
+	for _, c := range s.collections {
+		if c.handle == handle {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// newCollection adds the collection in the CollectionInput.
+func (s *store) newCollection(input map[string]interface{}) (*collection, error) {
+	c := &collection{
+		id:        s.newID(),
+		title:     strings.TrimSpace(stringArg(input, "title")),
+		handle:    stringArg(input, "handle"),
+		smart:     mapArg(input, "ruleSet") != nil,
+		updatedAt: time.Now(),
+	}
+
+	if c.title == "" {
+		return nil, fmt.Errorf("Title can't be blank")
+	}
+
+	if c.handle == "" {
+		c.handle = handleize(c.title)
+	}
+
+	// Handles are unique, as with products
+	handle := c.handle
+	for i := 1; s.collectionByHandle(c.handle) != nil; i++ {
+		c.handle = fmt.Sprintf("%s-%d", handle, i)
+	}
+
+	s.collections = append(s.collections, c)
+
+	return c, nil
+}
+
+// productCollections returns the collections the product is in.
+func (s *store) productCollections(p *product) []*collection {
+	var result []*collection
+	for _, c := range s.collections {
+		if containsID(c.products, p.id) {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// setProductCollections makes the custom collections the only ones the product is in.
+func (s *store) setProductCollections(p *product, collections []*collection) {
+	for _, c := range s.collections {
+		if c.smart {
+			continue
+		}
+
+		member := false
+		for _, other := range collections {
+			if other == c {
+				member = true
+				break
+			}
+		}
+
+		if member && !containsID(c.products, p.id) {
+			c.products = append(c.products, p.id)
+			c.updatedAt = time.Now()
+		} else if !member && containsID(c.products, p.id) {
+			c.products = removeID(c.products, p.id)
+			c.updatedAt = time.Now()
+		}
+	}
+}
+
+func (s *store) publication(id int64) *publication {
+	for _, pub := range s.publications {
+		if pub.id == id {
+			return pub
+		}
+	}
+
+	return nil
+}
+
+func (s *store) publicationByName(name string) *publication {
+	for _, pub := range s.publications {
+		if pub.name == name {
+			return pub
+		}
+	}
+
+	return nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func removeID(ids []int64, id int64) []int64 {
+	var kept []int64
+	for _, other := range ids {
+		if other != id {
+			kept = append(kept, other)
+		}
+	}
+
+	return kept
+}
+
+func (s *store) webhook(id int64) *webhook {
+	for _, w := range s.webhooks {
+		if w.id == id {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (s *store) order(id int64) *order {
+	for _, o := range s.orders {
+		if o.id == id {
+			return o
+		}
+	}
+
+	return nil
+}
+
+func (s *store) customer(id int64) *customer {
+	for _, c := range s.customers {
+		if c.id == id {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (s *store) bulkOperation(id int64) *bulkOperation {
+	for _, op := range s.bulkOperations {
+		if op.id == id {
+			return op
+		}
+	}
+
+	return nil
+}
+
+// ownerExists reports whether the GID is of something that can have metafields.
+func (s *store) ownerExists(ownerID string) bool {
+	typeName, id, ok := parseGID(ownerID)
+	if !ok {
+		return false
+	}
+
+	switch typeName {
+	case "Shop":
+		return true
+	case "Product":
+		return s.product(id) != nil
+	case "ProductVariant":
+		v := s.variant(id)
+		return v != nil && v.id == id
+	case "Order":
+		return s.order(id) != nil
+	case "Customer":
+		return s.customer(id) != nil
+	case "Location":
+		return s.location(id) != nil
+	case "Collection":
+		return s.collection(id) != nil
+	}
+
+	return false
+}
+
+// newMetafieldDefinition adds the definition in the MetafieldDefinitionInput.
+func (s *store) newMetafieldDefinition(input map[string]interface{}) (*metafieldDefinition, error) {
+	d := &metafieldDefinition{
+		id:               s.newID(),
+		namespace:        stringArg(input, "namespace"),
+		key:              stringArg(input, "key"),
+		valueType:        stringArg(input, "type"),
+		ownerType:        stringArg(input, "ownerType"),
+		adminAccess:      "PUBLIC_READ_WRITE",
+		storefrontAccess: "NONE",
+		createdAt:        time.Now(),
+	}
+
+	if d.namespace == "" || d.key == "" || d.valueType == "" || d.ownerType == "" {
+		return nil, fmt.Errorf("namespace, key, type, and ownerType are required")
+	}
+
+	if s.findMetafieldDefinition(d.ownerType, d.namespace, d.key) != nil {
+		return nil, fmt.Errorf("Key is in use for %s metafields on the '%s' namespace.", strings.ToLower(d.ownerType), d.namespace)
+	}
+
+	if err := s.checkValidations(validationsArg(input)); err != nil {
+		return nil, err
+	}
+
+	d.name = d.key
+	s.applyMetafieldDefinitionInput(d, input)
+	s.definitions = append(s.definitions, d)
+
+	return d, nil
+}
+
+// applyMetafieldDefinitionInput sets the definition's properties given in the input.
+func (s *store) applyMetafieldDefinitionInput(d *metafieldDefinition, input map[string]interface{}) {
+	if name := stringArg(input, "name"); name != "" {
+		d.name = name
+	}
+
+	if description, ok := input["description"].(string); ok {
+		d.description = description
+	}
+
+	if _, ok := input["validations"]; ok {
+		d.validations = validationsArg(input)
+	}
+
+	access := mapArg(input, "access")
+	if admin := stringArg(access, "admin"); admin != "" {
+		d.adminAccess = admin
+	}
+
+	if storefront := stringArg(access, "storefront"); storefront != "" {
+		d.storefrontAccess = storefront
+	}
+
+	if pin, ok := input["pin"].(bool); ok {
+		if !pin {
+			d.pinnedPosition = 0
+		} else if d.pinnedPosition == 0 {
+			s.pinMetafieldDefinition(d)
+		}
+	}
+}
+
+// pinMetafieldDefinition pins the definition after the other pinned definitions of its owner type.
+func (s *store) pinMetafieldDefinition(d *metafieldDefinition) {
+	position := 0
+	for _, other := range s.definitions {
+		if other.ownerType == d.ownerType && other.pinnedPosition > position {
+			position = other.pinnedPosition
+		}
+	}
+
+	d.pinnedPosition = position + 1
+}
+
+func (s *store) metafieldDefinition(id int64) *metafieldDefinition {
+	for _, d := range s.definitions {
+		if d.id == id {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// deleteMetafieldDefinition deletes the definition and, when withValues is true, its metafields.
+func (s *store) deleteMetafieldDefinition(d *metafieldDefinition, withValues bool) {
+	var kept []*metafieldDefinition
+	for _, existing := range s.definitions {
+		if existing != d {
+			kept = append(kept, existing)
+		}
+	}
+
+	s.definitions = kept
+
+	if !withValues {
+		return
+	}
+
+	var metafields []*metafield
+	for _, m := range s.metafields {
+		if m.namespace != d.namespace || m.key != d.key || ownerType(m.ownerID) != d.ownerType {
+			metafields = append(metafields, m)
+		}
+	}
+
+	s.metafields = metafields
+}
+
+func validationsArg(input map[string]interface{}) []metafieldValidation {
+	var validations []metafieldValidation
+	for _, v := range mapsArg(input, "validations") {
+		validations = append(validations, metafieldValidation{name: stringArg(v, "name"), value: stringArg(v, "value")})
+	}
+
+	return validations
+}
+
+// checkValidations checks that the metaobject definitions referred to by the validations exist.
+func (s *store) checkValidations(validations []metafieldValidation) error {
+	for _, v := range validations {
+		var ids []string
+
+		switch v.name {
+		case "metaobject_definition_id":
+			ids = []string{v.value}
+		case "metaobject_definition_ids":
+			if err := json.Unmarshal([]byte(v.value), &ids); err != nil {
+				return fmt.Errorf("Validations value for option %s must be a list of metaobject definition IDs", v.name)
+			}
+		}
+
+		for _, id := range ids {
+			typeName, n, _ := parseGID(id)
+			if typeName != "MetaobjectDefinition" || s.metaobjectDefinition(n) == nil {
+				return fmt.Errorf("Validations value for option %s must be a valid metaobject definition ID", v.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// newMetaobjectDefinition adds the definition in the MetaobjectDefinitionCreateInput.
+func (s *store) newMetaobjectDefinition(input map[string]interface{}) (*metaobjectDefinition, error) {
+	d := &metaobjectDefinition{
+		id:             s.newID(),
+		typeName:       stringArg(input, "type"),
+		name:           stringArg(input, "name"),
+		description:    stringArg(input, "description"),
+		displayNameKey: stringArg(input, "displayNameKey"),
+		createdAt:      time.Now(),
+	}
+
+	if d.typeName == "" {
+		return nil, fmt.Errorf("Type can't be blank")
+	}
+
+	if s.metaobjectDefinitionByType(d.typeName) != nil {
+		return nil, fmt.Errorf("Type has already been taken")
+	}
+
+	if d.name == "" {
+		d.name = d.typeName
+	}
+
+	for _, field := range mapsArg(input, "fieldDefinitions") {
+		if err := s.addMetaobjectField(d, field); err != nil {
+			return nil, err
+		}
+	}
+
+	s.metaobjects = append(s.metaobjects, d)
+
+	return d, nil
+}
+
+// addMetaobjectField adds the field in the MetaobjectFieldDefinitionCreateInput to the definition.
+func (s *store) addMetaobjectField(d *metaobjectDefinition, input map[string]interface{}) error {
+	f := &metaobjectFieldDefinition{
+		key:         stringArg(input, "key"),
+		name:        stringArg(input, "name"),
+		description: stringArg(input, "description"),
+		valueType:   stringArg(input, "type"),
+		required:    boolArg(input, "required"),
+		validations: validationsArg(input),
+	}
+
+	if f.key == "" || f.valueType == "" {
+		return fmt.Errorf("Field definitions require a key and type")
+	}
+
+	if d.field(f.key) != nil {
+		return fmt.Errorf("Field definition key %s is already in use", f.key)
+	}
+
+	if err := s.checkValidations(f.validations); err != nil {
+		return err
+	}
+
+	if f.name == "" {
+		f.name = f.key
+	}
+
+	d.fields = append(d.fields, f)
+
+	return nil
+}
+
+func (d *metaobjectDefinition) field(key string) *metaobjectFieldDefinition {
+	for _, f := range d.fields {
+		if f.key == key {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (s *store) metaobjectDefinition(id int64) *metaobjectDefinition {
+	for _, d := range s.metaobjects {
+		if d.id == id {
+			return d
+		}
+	}
+
+	return nil
+}
+
+func (s *store) metaobjectDefinitionByType(typeName string) *metaobjectDefinition {
+	for _, d := range s.metaobjects {
+		if d.typeName == typeName {
+			return d
+		}
+	}
+
+	return nil
+}
+
+func (s *store) findMetafieldDefinition(ownerType, namespace, key string) *metafieldDefinition {
+	for _, d := range s.definitions {
+		if d.ownerType == ownerType && d.namespace == namespace && d.key == key {
+			return d
+		}
+	}
+
+	return nil
+}
+
+func (s *store) ownerMetafields(ownerID string) []*metafield {
+	var result []*metafield
+	for _, m := range s.metafields {
+		if m.ownerID == ownerID {
+			result = append(result, m)
+		}
+	}
+
+	return result
+}
+
+func (s *store) findMetafield(ownerID, namespace, key string) *metafield {
+	for _, m := range s.metafields {
+		if m.ownerID == ownerID && m.namespace == namespace && m.key == key {
+			return m
+		}
+	}
+
+	return nil
+}
+
+func (s *store) deleteMetafield(m *metafield) {
+	var kept []*metafield
+	for _, existing := range s.metafields {
+		if existing != m {
+			kept = append(kept, existing)
+		}
+	}
+
+	s.metafields = kept
+}
+
+func (s *store) deleteOwnerMetafields(ownerID string) {
+	var kept []*metafield
+	for _, m := range s.metafields {
+		if m.ownerID != ownerID {
+			kept = append(kept, m)
+		}
+	}
+
+	s.metafields = kept
+}
+
+// setMetafield creates or updates the owner's metafield. The type is only
+// required when creating.
+func (s *store) setMetafield(ownerID, namespace, key, value, valueType string) (*metafield, error) {
+	if namespace == "" {
+		namespace = "$app"
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("Key can't be blank")
+	}
+
+	now := time.Now()
+
+	m := s.findMetafield(ownerID, namespace, key)
+	if m == nil {
+		if valueType == "" {
+			return nil, fmt.Errorf("Type can't be blank")
+		}
+
+		m = &metafield{id: s.newID(), ownerID: ownerID, namespace: namespace, key: key, createdAt: now}
+		s.metafields = append(s.metafields, m)
+	}
+
+	if valueType != "" {
+		m.valueType = valueType
+	}
+
+	m.value = value
+	m.updatedAt = now
+
+	return m, nil
+}
+
+func (s *store) deleteProduct(p *product) {
+	var kept []*product
+	for _, existing := range s.products {
+		if existing != p {
+			kept = append(kept, existing)
+		}
+	}
+
+	s.products = kept
+
+	for _, c := range s.collections {
+		c.products = removeID(c.products, p.id)
+	}
+
+	for _, pub := range s.publications {
+		pub.products = removeID(pub.products, p.id)
+	}
+
+	s.deleteOwnerMetafields(gid("Product", p.id))
+	for _, v := range p.variants {
+		s.deleteOwnerMetafields(gid("ProductVariant", v.id))
+	}
+}
+
+func (p *product) hasOnlyDefaultVariant() bool {
+	return len(p.variants) == 1 && len(p.variants[0].options) == 1 && p.variants[0].options[0].value == "Default Title"
+}
+
+func (v *variant) title() string {
+	var values []string
+	for _, o := range v.options {
+		values = append(values, o.value)
+	}
+
+	return strings.Join(values, " / ")
+}
+
+func (v *variant) position() int {
+	for i, other := range v.product.variants {
+		if other == v {
+			return i + 1
+		}
+	}
+
+	return 0
+}
+
+func (v *variant) quantity(locationID int64, name string) int {
+	return v.quantities[locationID][name]
+}
+
+func (v *variant) totalAvailable() int {
+	total := 0
+	for _, q := range v.quantities {
+		total += q["available"]
+	}
+
+	return total
+}
+
+// handleize returns the title as a handle, e.g., "Blue Hat" becomes "blue-hat".
+func handleize(title string) string {
+	var b strings.Builder
+	dash := false
+
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// uniqueHandle returns handle, with a numeric suffix if another product has it.
+func (s *store) uniqueHandle(handle string, p *product) string {
+	candidate := handle
+	for i := 1; ; i++ {
+		existing := s.productByHandle(candidate)
+		if existing == nil || existing == p {
+			return candidate
+		}
+
+		candidate = fmt.Sprintf("%s-%d", handle, i)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
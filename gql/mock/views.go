@@ -0,0 +1,722 @@
+package mock
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The functions in this file return the GraphQL objects for the store's data.
+// Fields with arguments, or that lead to other objects, are resolvers.
+
+func legacyID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func (s *Server) shopView() object {
+	ownerID := gid("Shop", 1)
+
+	return object{
+		"__typename":      "Shop",
+		"id":              ownerID,
+		"name":            s.store.shopName,
+		"myshopifyDomain": s.store.shopName + ".myshopify.com",
+		"email":           "owner@example.com",
+		"currencyCode":    "USD",
+		"plan":            object{"displayName": "Development", "partnerDevelopment": true, "shopifyPlus": false},
+		"metafields":      s.metafieldsResolver(ownerID),
+		"metafield":       s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) productView(p *product) object {
+	ownerID := gid("Product", p.id)
+
+	options := make([]object, len(p.options))
+	for i, option := range p.options {
+		values := make([]object, len(option.values))
+		for j, value := range option.values {
+			values[j] = object{"name": value}
+		}
+
+		options[i] = object{
+			"id":           gid("ProductOption", p.id*10+int64(i)),
+			"name":         option.name,
+			"position":     int64(i + 1),
+			"values":       option.values,
+			"optionValues": values,
+		}
+	}
+
+	tags := p.tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	totalInventory := 0
+	for _, v := range p.variants {
+		totalInventory += v.totalAvailable()
+	}
+
+	return object{
+		"__typename":            "Product",
+		"id":                    ownerID,
+		"legacyResourceId":      legacyID(p.id),
+		"title":                 p.title,
+		"handle":                p.handle,
+		"descriptionHtml":       p.descriptionHTML,
+		"vendor":                p.vendor,
+		"productType":           p.productType,
+		"status":                p.status,
+		"tags":                  tags,
+		"templateSuffix":        nil,
+		"hasOnlyDefaultVariant": p.hasOnlyDefaultVariant(),
+		"totalInventory":        int64(totalInventory),
+		"options":               options,
+		"createdAt":             timestamp(p.createdAt),
+		"updatedAt":             timestamp(p.updatedAt),
+		"publishedAt":           publishedAt(p),
+		"variantsCount":         object{"count": int64(len(p.variants)), "precision": "EXACT"},
+		"variants": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(s.variantViews(p.variants), args), nil
+		}),
+		"media": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(mediaViews(p), args), nil
+		}),
+		"mediaCount": object{"count": int64(len(p.media)), "precision": "EXACT"},
+		"collections": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var nodes []object
+			for _, c := range s.store.productCollections(p) {
+				nodes = append(nodes, s.collectionView(c))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"publishedOnPublication": resolver(func(args map[string]interface{}) (interface{}, error) {
+			_, id, _ := parseGID(stringArg(args, "publicationId"))
+			if pub := s.store.publication(id); pub != nil {
+				return containsID(pub.products, p.id), nil
+			}
+
+			return false, nil
+		}),
+		"metafields": s.metafieldsResolver(ownerID),
+		"metafield":  s.metafieldResolver(ownerID),
+	}
+}
+
+func mediaViews(p *product) []object {
+	result := make([]object, len(p.media))
+	for i, m := range p.media {
+		result[i] = mediaView(m)
+	}
+
+	return result
+}
+
+// Media types by content type
+var mediaTypes = map[string]string{
+	"IMAGE":          "MediaImage",
+	"VIDEO":          "Video",
+	"EXTERNAL_VIDEO": "ExternalVideo",
+	"MODEL_3D":       "Model3d",
+}
+
+func mediaView(m *media) object {
+	typeName := mediaTypes[m.contentType]
+	if typeName == "" {
+		typeName = "MediaImage"
+	}
+
+	var alt interface{}
+	if m.alt != "" {
+		alt = m.alt
+	}
+
+	view := object{
+		"__typename":       typeName,
+		"id":               gid(typeName, m.id),
+		"alt":              m.alt,
+		"mediaContentType": m.contentType,
+		"status":           "READY",
+	}
+
+	switch typeName {
+	case "MediaImage":
+		view["image"] = object{"url": m.source, "altText": alt}
+	case "ExternalVideo":
+		view["originUrl"] = m.source
+		view["embedUrl"] = m.source
+	default:
+		view["originalSource"] = object{"url": m.source}
+		view["sources"] = []object{{"url": m.source}}
+	}
+
+	return view
+}
+
+func publishedAt(p *product) interface{} {
+	if p.status != "ACTIVE" {
+		return nil
+	}
+
+	return timestamp(p.createdAt)
+}
+
+func (s *Server) productViews(products []*product) []object {
+	result := make([]object, len(products))
+	for i, p := range products {
+		result[i] = s.productView(p)
+	}
+
+	return result
+}
+
+func (s *Server) variantView(v *variant) object {
+	ownerID := gid("ProductVariant", v.id)
+
+	selectedOptions := make([]object, len(v.options))
+	for i, option := range v.options {
+		selectedOptions[i] = object{"name": option.name, "value": option.value}
+	}
+
+	var compareAtPrice interface{}
+	if v.compareAtPrice != "" {
+		compareAtPrice = v.compareAtPrice
+	}
+
+	return object{
+		"__typename":        "ProductVariant",
+		"id":                ownerID,
+		"legacyResourceId":  legacyID(v.id),
+		"title":             v.title(),
+		"displayName":       v.product.title + " - " + v.title(),
+		"sku":               v.sku,
+		"barcode":           v.barcode,
+		"price":             v.price,
+		"compareAtPrice":    compareAtPrice,
+		"taxable":           v.taxable,
+		"inventoryPolicy":   v.inventoryPolicy,
+		"inventoryQuantity": int64(v.totalAvailable()),
+		"position":          int64(v.position()),
+		"selectedOptions":   selectedOptions,
+		"createdAt":         timestamp(v.createdAt),
+		"updatedAt":         timestamp(v.updatedAt),
+		"product": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.productView(v.product), nil
+		}),
+		"inventoryItem": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.inventoryItemView(v), nil
+		}),
+		"media": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var nodes []object
+			if v.media != nil {
+				nodes = append(nodes, mediaView(v.media))
+			}
+
+			return s.exec.connection(nodes, args), nil
+		}),
+		"metafields": s.metafieldsResolver(ownerID),
+		"metafield":  s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) variantViews(variants []*variant) []object {
+	result := make([]object, len(variants))
+	for i, v := range variants {
+		result[i] = s.variantView(v)
+	}
+
+	return result
+}
+
+func (s *Server) inventoryItemView(v *variant) object {
+	var cost interface{}
+	if v.cost != "" {
+		cost = object{"amount": v.cost, "currencyCode": "USD"}
+	}
+
+	return object{
+		"__typename":       "InventoryItem",
+		"id":               gid("InventoryItem", v.inventoryItemID),
+		"legacyResourceId": legacyID(v.inventoryItemID),
+		"sku":              v.sku,
+		"tracked":          v.tracked,
+		"requiresShipping": v.requiresShipping,
+		"unitCost":         cost,
+		"variant": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.variantView(v), nil
+		}),
+		"inventoryLevels": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var levels []object
+			for _, l := range s.store.locations {
+				if _, ok := v.quantities[l.id]; ok {
+					levels = append(levels, s.inventoryLevelView(v, l))
+				}
+			}
+
+			return s.exec.connection(levels, args), nil
+		}),
+	}
+}
+
+func (s *Server) inventoryLevelView(v *variant, l *location) object {
+	return object{
+		"__typename": "InventoryLevel",
+		"id":         gid("InventoryLevel", v.inventoryItemID*1000+l.id),
+		"location":   s.locationView(l),
+		"quantities": resolver(func(args map[string]interface{}) (interface{}, error) {
+			var quantities []object
+			for _, name := range stringsArg(args, "names") {
+				quantities = append(quantities, object{"name": name, "quantity": int64(v.quantity(l.id, name))})
+			}
+
+			return quantities, nil
+		}),
+	}
+}
+
+func (s *Server) collectionView(c *collection) object {
+	ownerID := gid("Collection", c.id)
+
+	var ruleSet interface{}
+	if c.smart {
+		ruleSet = object{"appliedDisjunctively": false, "rules": []object{}}
+	}
+
+	var products []*product
+	for _, id := range c.products {
+		if p := s.store.product(id); p != nil {
+			products = append(products, p)
+		}
+	}
+
+	return object{
+		"__typename":       "Collection",
+		"id":               ownerID,
+		"legacyResourceId": legacyID(c.id),
+		"title":            c.title,
+		"handle":           c.handle,
+		"updatedAt":        timestamp(c.updatedAt),
+		"ruleSet":          ruleSet,
+		"productsCount":    object{"count": int64(len(products)), "precision": "EXACT"},
+		"products": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(s.productViews(products), args), nil
+		}),
+		"metafields": s.metafieldsResolver(ownerID),
+		"metafield":  s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) publicationView(pub *publication) object {
+	var products []*product
+	for _, id := range pub.products {
+		if p := s.store.product(id); p != nil {
+			products = append(products, p)
+		}
+	}
+
+	return object{
+		"__typename": "Publication",
+		"id":         gid("Publication", pub.id),
+		"name":       pub.name,
+		"catalog":    object{"__typename": "AppCatalog", "title": pub.name},
+		"products": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(s.productViews(products), args), nil
+		}),
+	}
+}
+
+func (s *Server) locationView(l *location) object {
+	ownerID := gid("Location", l.id)
+
+	return object{
+		"__typename":           "Location",
+		"id":                   ownerID,
+		"legacyResourceId":     legacyID(l.id),
+		"name":                 l.name,
+		"isActive":             l.active,
+		"isFulfillmentService": false,
+		"address":              object{"formatted": []string{}},
+		"metafields":           s.metafieldsResolver(ownerID),
+		"metafield":            s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) metafieldView(m *metafield) object {
+	return object{
+		"__typename":  "Metafield",
+		"id":          gid("Metafield", m.id),
+		"namespace":   m.namespace,
+		"key":         m.key,
+		"value":       m.value,
+		"type":        m.valueType,
+		"description": m.description,
+		"ownerType":   ownerType(m.ownerID),
+		"createdAt":   timestamp(m.createdAt),
+		"updatedAt":   timestamp(m.updatedAt),
+		"owner": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.node(m.ownerID), nil
+		}),
+	}
+}
+
+func (s *Server) metafieldDefinitionView(d *metafieldDefinition) object {
+	var pinnedPosition interface{}
+	if d.pinnedPosition > 0 {
+		pinnedPosition = d.pinnedPosition
+	}
+
+	return object{
+		"__typename":     "MetafieldDefinition",
+		"id":             gid("MetafieldDefinition", d.id),
+		"name":           d.name,
+		"namespace":      d.namespace,
+		"key":            d.key,
+		"description":    d.description,
+		"type":           object{"name": d.valueType},
+		"ownerType":      d.ownerType,
+		"validations":    validationsView(d.validations),
+		"access":         object{"admin": d.adminAccess, "storefront": d.storefrontAccess},
+		"pinnedPosition": pinnedPosition,
+		"metafieldsCount": resolver(func(args map[string]interface{}) (interface{}, error) {
+			count := 0
+			for _, m := range s.store.metafields {
+				if m.namespace == d.namespace && m.key == d.key && ownerType(m.ownerID) == d.ownerType {
+					count++
+				}
+			}
+
+			return count, nil
+		}),
+	}
+}
+
+func validationsView(validations []metafieldValidation) []object {
+	views := make([]object, len(validations))
+	for i, v := range validations {
+		views[i] = object{"name": v.name, "value": v.value}
+	}
+
+	return views
+}
+
+func metaobjectDefinitionView(d *metaobjectDefinition) object {
+	fields := make([]object, len(d.fields))
+	for i, f := range d.fields {
+		fields[i] = object{
+			"key":         f.key,
+			"name":        f.name,
+			"description": f.description,
+			"required":    f.required,
+			"type":        object{"name": f.valueType},
+			"validations": validationsView(f.validations),
+		}
+	}
+
+	return object{
+		"__typename":       "MetaobjectDefinition",
+		"id":               gid("MetaobjectDefinition", d.id),
+		"type":             d.typeName,
+		"name":             d.name,
+		"description":      d.description,
+		"displayNameKey":   d.displayNameKey,
+		"fieldDefinitions": fields,
+		"metaobjectsCount": 0,
+	}
+}
+
+// ownerType returns the MetafieldOwnerType of the owner's GID.
+func ownerType(ownerID string) string {
+	typeName, _, _ := parseGID(ownerID)
+	if typeName == "ProductVariant" {
+		return "PRODUCTVARIANT"
+	}
+
+	return strings.ToUpper(typeName)
+}
+
+// metafieldsResolver resolves an owner's metafields connection, filtered by
+// the namespace and keys arguments.
+func (s *Server) metafieldsResolver(ownerID string) resolver {
+	return func(args map[string]interface{}) (interface{}, error) {
+		namespace := stringArg(args, "namespace")
+		keys := stringsArg(args, "keys")
+
+		var nodes []object
+		for _, m := range s.store.ownerMetafields(ownerID) {
+			if namespace != "" && m.namespace != namespace {
+				continue
+			}
+
+			if len(keys) > 0 && !contains(keys, m.namespace+"."+m.key) {
+				continue
+			}
+
+			nodes = append(nodes, s.metafieldView(m))
+		}
+
+		return s.exec.connection(nodes, args), nil
+	}
+}
+
+func (s *Server) metafieldResolver(ownerID string) resolver {
+	return func(args map[string]interface{}) (interface{}, error) {
+		namespace, key := stringArg(args, "namespace"), stringArg(args, "key")
+
+		// key can also be given as namespace.key
+		if namespace == "" && strings.Contains(key, ".") {
+			parts := strings.SplitN(key, ".", 2)
+			namespace, key = parts[0], parts[1]
+		}
+
+		m := s.store.findMetafield(ownerID, namespace, key)
+		if m == nil {
+			return nil, nil
+		}
+
+		return s.metafieldView(m), nil
+	}
+}
+
+func (s *Server) webhookView(w *webhook) object {
+	var endpoint object
+	if w.arn != "" {
+		endpoint = object{"__typename": "WebhookEventBridgeEndpoint", "arn": w.arn}
+	} else {
+		endpoint = object{"__typename": "WebhookHttpEndpoint", "callbackUrl": w.callbackURL}
+	}
+
+	includeFields, namespaces := w.includeFields, w.metafieldNamespaces
+	if includeFields == nil {
+		includeFields = []string{}
+	}
+
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+
+	return object{
+		"__typename":          "WebhookSubscription",
+		"id":                  gid("WebhookSubscription", w.id),
+		"legacyResourceId":    legacyID(w.id),
+		"topic":               w.topic,
+		"format":              w.format,
+		"includeFields":       includeFields,
+		"metafieldNamespaces": namespaces,
+		"apiVersion":          object{"handle": w.apiVersion},
+		"callbackUrl":         w.callbackURL,
+		"uri":                 firstNonEmpty(w.callbackURL, w.arn),
+		"endpoint":            endpoint,
+		"createdAt":           timestamp(w.createdAt),
+		"updatedAt":           timestamp(w.updatedAt),
+	}
+}
+
+func (s *Server) orderView(o *order) object {
+	ownerID := gid("Order", o.id)
+
+	attributes := make([]object, len(o.customAttributes))
+	for i, a := range o.customAttributes {
+		attributes[i] = object{"key": a.key, "value": a.value}
+	}
+
+	var lineItems []object
+	for _, li := range o.lineItems {
+		lineItems = append(lineItems, s.lineItemView(li))
+	}
+
+	var note interface{}
+	if o.note != "" {
+		note = o.note
+	}
+
+	return object{
+		"__typename":               "Order",
+		"id":                       ownerID,
+		"legacyResourceId":         legacyID(o.id),
+		"name":                     o.name,
+		"email":                    o.email,
+		"note":                     note,
+		"customAttributes":         attributes,
+		"displayFinancialStatus":   o.displayFinancialStatus,
+		"displayFulfillmentStatus": o.displayFulfillmentStatus,
+		"cancelledAt":              optionalTimestamp(o.cancelledAt),
+		"closedAt":                 optionalTimestamp(o.closedAt),
+		"createdAt":                timestamp(o.createdAt),
+		"updatedAt":                timestamp(o.updatedAt),
+		"fulfillments":             []object{},
+		"lineItems": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(lineItems, args), nil
+		}),
+		"fulfillmentOrders": resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.exec.connection(nil, args), nil
+		}),
+		"metafields": s.metafieldsResolver(ownerID),
+		"metafield":  s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) customerView(c *customer) object {
+	ownerID := gid("Customer", c.id)
+
+	return object{
+		"__typename":       "Customer",
+		"id":               ownerID,
+		"legacyResourceId": legacyID(c.id),
+		"firstName":        c.firstName,
+		"lastName":         c.lastName,
+		"displayName":      strings.TrimSpace(c.firstName + " " + c.lastName),
+		"email":            c.email,
+		"createdAt":        timestamp(c.createdAt),
+		"updatedAt":        timestamp(c.updatedAt),
+		"metafields":       s.metafieldsResolver(ownerID),
+		"metafield":        s.metafieldResolver(ownerID),
+	}
+}
+
+func (s *Server) lineItemView(li *lineItem) object {
+	view := object{
+		"__typename":        "LineItem",
+		"id":                gid("LineItem", li.id),
+		"sku":               li.sku,
+		"name":              li.name,
+		"title":             li.name,
+		"quantity":          int64(li.quantity),
+		"fulfillmentStatus": li.fulfillmentStatus,
+		"product":           nil,
+		"variant":           nil,
+	}
+
+	if li.variant != nil {
+		v := li.variant
+		view["product"] = resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.productView(v.product), nil
+		})
+		view["variant"] = resolver(func(args map[string]interface{}) (interface{}, error) {
+			return s.variantView(v), nil
+		})
+	}
+
+	return view
+}
+
+func (s *Server) bulkOperationView(op *bulkOperation) object {
+	var url, errorCode interface{}
+	if op.status == "COMPLETED" && len(op.result) > 0 {
+		url = s.baseURL + "/bulk-operations/" + legacyID(op.id) + ".jsonl"
+	}
+
+	if op.errorCode != "" {
+		errorCode = op.errorCode
+	}
+
+	return object{
+		"__typename":      "BulkOperation",
+		"id":              gid("BulkOperation", op.id),
+		"type":            op.operationType,
+		"status":          op.status,
+		"errorCode":       errorCode,
+		"query":           op.query,
+		"objectCount":     strconv.Itoa(op.objectCount),
+		"rootObjectCount": strconv.Itoa(op.rootObjectCount),
+		"fileSize":        strconv.Itoa(len(op.result)),
+		"url":             url,
+		"partialDataUrl":  nil,
+		"createdAt":       timestamp(op.createdAt),
+		"completedAt":     optionalTimestamp(op.completedAt),
+	}
+}
+
+// node returns the object with the GID or nil if there isn't one.
+func (s *Server) node(id string) object {
+	typeName, n, ok := parseGID(id)
+	if !ok {
+		return nil
+	}
+
+	switch typeName {
+	case "Shop":
+		return s.shopView()
+	case "Product":
+		if p := s.store.product(n); p != nil {
+			return s.productView(p)
+		}
+	case "ProductVariant":
+		if v := s.store.variant(n); v != nil && v.id == n {
+			return s.variantView(v)
+		}
+	case "InventoryItem":
+		if v := s.store.variant(n); v != nil && v.inventoryItemID == n {
+			return s.inventoryItemView(v)
+		}
+	case "Location":
+		if l := s.store.location(n); l != nil {
+			return s.locationView(l)
+		}
+	case "Collection":
+		if c := s.store.collection(n); c != nil {
+			return s.collectionView(c)
+		}
+	case "Publication":
+		if pub := s.store.publication(n); pub != nil {
+			return s.publicationView(pub)
+		}
+	case "MediaImage", "Video", "ExternalVideo", "Model3d":
+		if m := s.store.media(n); m != nil {
+			return mediaView(m)
+		}
+	case "Metafield":
+		for _, m := range s.store.metafields {
+			if m.id == n {
+				return s.metafieldView(m)
+			}
+		}
+	case "MetafieldDefinition":
+		if d := s.store.metafieldDefinition(n); d != nil {
+			return s.metafieldDefinitionView(d)
+		}
+	case "MetaobjectDefinition":
+		if d := s.store.metaobjectDefinition(n); d != nil {
+			return metaobjectDefinitionView(d)
+		}
+	case "WebhookSubscription":
+		if w := s.store.webhook(n); w != nil {
+			return s.webhookView(w)
+		}
+	case "Order":
+		if o := s.store.order(n); o != nil {
+			return s.orderView(o)
+		}
+	case "Customer":
+		if c := s.store.customer(n); c != nil {
+			return s.customerView(c)
+		}
+	case "BulkOperation":
+		if op := s.store.bulkOperation(n); op != nil {
+			s.poll(op)
+			return s.bulkOperationView(op)
+		}
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,113 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultPageSize is the number of nodes requested per page when the
+// variables given to Paginate don't include "first".
+const DefaultPageSize = 250
+
+// ErrNotFound is returned by Paginate when an object on the path to the
+// connection is null, e.g. a product that doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+type connection struct {
+	Nodes []json.RawMessage `json:"nodes"`
+	Edges []struct {
+		Node json.RawMessage `json:"node"`
+	} `json:"edges"`
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+}
+
+// Paginate runs query until all pages of the connection at path have been
+// read, decoding each node into a T and passing it to fn. path is the
+// dot-separated location of the connection under "data", e.g. "products" or
+// "customer.metafields". The query must take an $after: String variable and
+// select pageInfo { hasNextPage endCursor } along with nodes or edges { node }.
+// Iteration stops at the first error returned by fn.
+func Paginate[T any](client *Client, query string, variables map[string]interface{}, path string, fn func(T) error) error {
+	vars := map[string]interface{}{"first": DefaultPageSize}
+	for k, v := range variables {
+		vars[k] = v
+	}
+
+	for {
+		body, err := client.execute(query, vars)
+		if err != nil {
+			return err
+		}
+
+		conn, err := connectionAt(body, path)
+		if err != nil {
+			return err
+		}
+
+		nodes := conn.Nodes
+		for _, edge := range conn.Edges {
+			nodes = append(nodes, edge.Node)
+		}
+
+		for _, raw := range nodes {
+			var node T
+			if err := json.Unmarshal(raw, &node); err != nil {
+				return fmt.Errorf("Failed to unmarshal %s node: %s", path, err)
+			}
+
+			if err := fn(node); err != nil {
+				return err
+			}
+		}
+
+		if !conn.PageInfo.HasNextPage {
+			return nil
+		}
+
+		vars["after"] = conn.PageInfo.EndCursor
+	}
+}
+
+func connectionAt(body []byte, path string) (*connection, error) {
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal GraphQL response body: %s", err)
+	}
+
+	raw := response.Data
+	for _, name := range strings.Split(path, ".") {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			return nil, fmt.Errorf("Failed to unmarshal %s: %s", path, err)
+		}
+
+		if object == nil {
+			return nil, ErrNotFound
+		}
+
+		var ok bool
+		raw, ok = object[name]
+		if !ok {
+			return nil, fmt.Errorf("Response has no %s", path)
+		}
+	}
+
+	var conn *connection
+	if err := json.Unmarshal(raw, &conn); err != nil {
+		return nil, fmt.Errorf("Failed to unmarshal %s: %s", path, err)
+	}
+
+	if conn == nil {
+		return nil, ErrNotFound
+	}
+
+	return conn, nil
+}
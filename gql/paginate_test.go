@@ -0,0 +1,81 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestPaginateFollowsCursor(t *testing.T) {
+	var afters []interface{}
+
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		afters = append(afters, request.Variables["after"])
+
+		if request.Variables["after"] == nil {
+			w.Write([]byte(`{"data":{"shop":{"products":{"nodes":[{"id":"1"},{"id":"2"}],"pageInfo":{"hasNextPage":true,"endCursor":"abc"}}}}}`))
+			return
+		}
+
+		w.Write([]byte(`{"data":{"shop":{"products":{"edges":[{"node":{"id":"3"}}],"pageInfo":{"hasNextPage":false,"endCursor":"def"}}}}}`))
+	})
+
+	var ids []string
+	err := Paginate(client, "query($first: Int!, $after: String) { x }", nil, "shop.products", func(node struct{ ID string }) error {
+		ids = append(ids, node.ID)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := []string{"1", "2", "3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+
+	if want := []interface{}{nil, "abc"}; !reflect.DeepEqual(afters, want) {
+		t.Errorf("after variables = %v, want %v", afters, want)
+	}
+}
+
+func TestPaginateNullOwnerIsNotFound(t *testing.T) {
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"product":null}}`))
+	})
+
+	err := Paginate(client, "query { x }", nil, "product.metafields", func(node struct{}) error {
+		return nil
+	})
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPaginateStopsOnCallbackError(t *testing.T) {
+	calls := 0
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"data":{"themes":{"nodes":[{"id":"1"}],"pageInfo":{"hasNextPage":true,"endCursor":"abc"}}}}`))
+	})
+
+	stop := errors.New("stop")
+	err := Paginate(client, "query { x }", nil, "themes", func(node struct{}) error {
+		return stop
+	})
+
+	if err != stop {
+		t.Errorf("err = %v, want %v", err, stop)
+	}
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
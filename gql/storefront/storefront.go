@@ -1,6 +1,7 @@
 package storefront
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"github.com/ScreenStaring/shopify-dev-tools/gql"
@@ -11,10 +12,11 @@ type Storefront struct {
 }
 
 const listQuery = `
-{
-  metafieldStorefrontVisibilities(first: 250) {
+query($first: Int!, $after: String) {
+  metafieldStorefrontVisibilities(first: $first, after: $after) {
     pageInfo {
       hasNextPage
+      endCursor
     }
     edges {
       cursor
@@ -59,33 +61,16 @@ func New(shop, token string) *Storefront {
 }
 
 
-func stringifyUserErrors(errors []interface{}) string {
-	var userError []string
-
-	for _, error := range errors {
-		error := error.(map[string]interface{})
-		userError = append(userError, fmt.Sprint(error["message"]))
-	}
-
-	return strings.Join(userError, ", ")
-}
-
-// no pagination...
 func (sf *Storefront) List() ([]map[string]interface{}, error)  {
 	var result []map[string]interface{}
 
-	data, err := sf.client.Execute(listQuery)
-	if err != nil {
-		return result, fmt.Errorf("Failed to retrieve storefront metafields: %s", err)
-	}
+	err := gql.Paginate(sf.client, listQuery, nil, "metafieldStorefrontVisibilities", func(node map[string]interface{}) error {
+		result = append(result, node)
+		return nil
+	})
 
-	nodes, err := data.ValuesForPath("data.metafieldStorefrontVisibilities.edges.node")
 	if err != nil {
-		return result, fmt.Errorf("Failed to extract storefront metafields from response: %s", err)
-	}
-
-	for _, node := range nodes {
-		result = append(result, node.(map[string]interface{}))
+		return result, fmt.Errorf("Failed to retrieve storefront metafields: %s", err)
 	}
 
 	return result, nil
@@ -99,25 +84,27 @@ func (sf *Storefront) Enable(name, owner string) (string, error)  {
 		return result, fmt.Errorf("Metafield key %s invalid: must be in namespace.key format", name)
 	}
 
-	data, err := sf.client.Execute(enableMutation, map[string]interface{}{"namespace": key[0], "key": key[1], "owner": strings.ToUpper(owner)})
-	if err != nil {
-		return result, fmt.Errorf("Failed to enable storefront metafield %s: %s", name, err)
+	var response struct {
+		MetafieldStorefrontVisibilityCreate struct {
+			MetafieldStorefrontVisibility struct {
+				ID string `json:"id"`
+			} `json:"metafieldStorefrontVisibility"`
+		} `json:"metafieldStorefrontVisibilityCreate"`
 	}
 
-	// TODO: collect 'em all!
-	message, _ := data.ValueForPathString("errors[0].message")
-	if len(message) > 0 {
-		return result, fmt.Errorf("Request failed: %s", message)
+	err := sf.client.ExecuteInto(enableMutation, map[string]interface{}{"namespace": key[0], "key": key[1], "owner": strings.ToUpper(owner)}, &response)
+	var userErrors gql.UserErrors
+	if errors.As(err, &userErrors) {
+		return result, fmt.Errorf("Request failed: %s", err)
 	}
 
-	messages, _ := data.ValuesForPath("data.metafieldStorefrontVisibilityCreate.userErrors")
-	if len(messages) > 0 {
-		return result, fmt.Errorf("Request failed: %s", stringifyUserErrors(messages))
+	if err != nil {
+		return result, fmt.Errorf("Failed to enable storefront metafield %s: %s", name, err)
 	}
 
-	result, err = data.ValueForPathString("data.metafieldStorefrontVisibilityCreate.metafieldStorefrontVisibility.id")
-	if err != nil {
-		return result, fmt.Errorf("Failed to extract storefront metafield visibility id from response: %s", err)
+	result = response.MetafieldStorefrontVisibilityCreate.MetafieldStorefrontVisibility.ID
+	if len(result) == 0 {
+		return result, fmt.Errorf("Failed to extract storefront metafield visibility id from response")
 	}
 
 	return result, nil
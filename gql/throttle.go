@@ -0,0 +1,147 @@
+package gql
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 5
+	retryBaseDelay    = 500 * time.Millisecond
+	retryMaxDelay     = 30 * time.Second
+)
+
+// sleep and now are swapped out by tests so throttling can be checked
+// without waiting.
+var sleep = time.Sleep
+var now = time.Now
+
+// throttleStatus mirrors extensions.cost.throttleStatus in Admin API responses.
+type throttleStatus struct {
+	MaximumAvailable   float64 `json:"maximumAvailable"`
+	CurrentlyAvailable float64 `json:"currentlyAvailable"`
+	RestoreRate        float64 `json:"restoreRate"`
+}
+
+// bucket is a client-side copy of a shop's leaky bucket. Shopify reports the
+// bucket's state with every response, between responses we estimate it from
+// the restore rate. Each query's last requested cost is remembered so the next
+// call with the same query waits for enough points before it's sent.
+type bucket struct {
+	mu          sync.Mutex
+	maximum     float64
+	available   float64
+	restoreRate float64
+	updated     time.Time
+	costs       map[string]float64
+}
+
+var buckets = struct {
+	sync.Mutex
+	byShop map[string]*bucket
+}{byShop: map[string]*bucket{}}
+
+// bucketFor returns the bucket shared by all clients for the given shop.
+func bucketFor(shop string) *bucket {
+	buckets.Lock()
+	defer buckets.Unlock()
+
+	b, ok := buckets.byShop[shop]
+	if !ok {
+		b = &bucket{costs: map[string]float64{}}
+		buckets.byShop[shop] = b
+	}
+
+	return b
+}
+
+func (b *bucket) refill(t time.Time) {
+	if b.restoreRate == 0 {
+		return
+	}
+
+	b.available += t.Sub(b.updated).Seconds() * b.restoreRate
+	if b.available > b.maximum {
+		b.available = b.maximum
+	}
+
+	b.updated = t
+}
+
+// reserve blocks until the bucket holds enough points for query and deducts
+// them. Until a response has been seen nothing is known about the bucket so
+// there's no wait. Returns the total time spent waiting.
+func (b *bucket) reserve(query string) time.Duration {
+	var waited time.Duration
+
+	for {
+		b.mu.Lock()
+
+		b.refill(now())
+
+		// A query costing more than the bucket holds waits for a full bucket, Shopify
+		// decides whether it's accepted
+		cost := min(b.costs[query], b.maximum)
+		if b.restoreRate == 0 || b.available >= cost {
+			b.available -= cost
+			b.mu.Unlock()
+			return waited
+		}
+
+		wait := time.Duration((cost - b.available) / b.restoreRate * float64(time.Second))
+		b.mu.Unlock()
+
+		sleep(wait)
+		waited += wait
+	}
+}
+
+// update replaces the estimated state with the state reported by Shopify.
+func (b *bucket) update(query string, requestedCost float64, status throttleStatus) {
+	if status.RestoreRate == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.maximum = status.MaximumAvailable
+	b.available = status.CurrentlyAvailable
+	b.restoreRate = status.RestoreRate
+	b.updated = now()
+
+	if requestedCost > 0 {
+		b.costs[query] = requestedCost
+	}
+}
+
+// retryableError is a failure that may succeed if the request is sent again.
+// after is the minimum time to wait before doing so (e.g. from Retry-After).
+type retryableError struct {
+	err   error
+	after time.Duration
+	// safe is true when Shopify did not process the request (throttled or 429)
+	// and it can be resent even if it's a mutation.
+	safe bool
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+// backoff returns an exponential delay with jitter for the given attempt,
+// never less than min.
+func backoff(attempt int, min time.Duration) time.Duration {
+	d := retryBaseDelay << uint(attempt)
+	if d > retryMaxDelay || d <= 0 {
+		d = retryMaxDelay
+	}
+
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	if d < min {
+		d = min
+	}
+
+	return d
+}
@@ -0,0 +1,189 @@
+package gql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stubSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+
+	var slept []time.Duration
+	clock := time.Now()
+
+	oldSleep, oldNow := sleep, now
+	sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		clock = clock.Add(d)
+	}
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { sleep, now = oldSleep, oldNow })
+
+	return &slept
+}
+
+// testClient returns a client for a unique shop (so it gets its own bucket)
+// that sends its requests to handler.
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := NewClient(strings.ReplaceAll(t.Name(), "/", "-"), "token")
+	client.endpoint = server.URL
+
+	return client
+}
+
+const okResponse = `{"data":{"shop":{"name":"Test"}},"extensions":{"cost":{"requestedQueryCost":10,"throttleStatus":{"maximumAvailable":100,"currentlyAvailable":5,"restoreRate":50}}}}`
+
+func TestRequestRetriesThrottled(t *testing.T) {
+	stubSleep(t)
+
+	calls := 0
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte(`{"errors":[{"message":"Throttled","extensions":{"code":"THROTTLED"}}]}`))
+			return
+		}
+		w.Write([]byte(okResponse))
+	})
+
+	result, err := client.Execute("mutation { shop { name } }")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	name, _ := result.ValueForPathString("data.shop.name")
+	if name != "Test" {
+		t.Errorf("name = %q, want Test", name)
+	}
+}
+
+func TestRequestRetries429WithRetryAfter(t *testing.T) {
+	slept := stubSleep(t)
+
+	calls := 0
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "2.0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(okResponse))
+	})
+
+	if _, err := client.Execute("query { shop { name } }"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	if len(*slept) == 0 || (*slept)[0] < 2*time.Second {
+		t.Errorf("slept = %v, want at least 2s before retrying", *slept)
+	}
+}
+
+func TestRequestDoesNotRetryMutationOn5xx(t *testing.T) {
+	stubSleep(t)
+
+	calls := 0
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := client.Execute("mutation { shop { name } }"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRequestGivesUpAfterMaxRetries(t *testing.T) {
+	stubSleep(t)
+
+	calls := 0
+	client := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	_, err := client.Execute("query { shop { name } }")
+	if err == nil || !strings.Contains(err.Error(), "HTTP response code 503") {
+		t.Fatalf("expected 503 error, got %v", err)
+	}
+
+	if calls != defaultMaxRetries+1 {
+		t.Errorf("calls = %d, want %d", calls, defaultMaxRetries+1)
+	}
+}
+
+func TestBucketReserveWaitsForRestore(t *testing.T) {
+	slept := stubSleep(t)
+
+	b := &bucket{costs: map[string]float64{}}
+	if waited := b.reserve("q"); waited != 0 {
+		t.Errorf("waited = %s before any response, want 0", waited)
+	}
+
+	b.update("q", 100, throttleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 50, RestoreRate: 50})
+	b.reserve("q")
+
+	if len(*slept) == 0 {
+		t.Fatal("expected reserve to wait for the bucket to restore")
+	}
+
+	// 50 points short at 50/second
+	if (*slept)[0] < 900*time.Millisecond || (*slept)[0] > time.Second {
+		t.Errorf("first wait = %s, want about 1s", (*slept)[0])
+	}
+}
+
+func TestBucketReserveCostAboveMaximum(t *testing.T) {
+	stubSleep(t)
+
+	b := &bucket{costs: map[string]float64{}}
+	b.update("q", 1500, throttleStatus{MaximumAvailable: 1000, CurrentlyAvailable: 900, RestoreRate: 50})
+
+	done := make(chan time.Duration)
+	go func() { done <- b.reserve("q") }()
+
+	select {
+	case waited := <-done:
+		// 100 points short of a full bucket at 50/second
+		if waited < 1900*time.Millisecond || waited > 2*time.Second {
+			t.Errorf("waited = %s, want about 2s", waited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reserve did not return")
+	}
+}
+
+func TestBucketForIsSharedPerShop(t *testing.T) {
+	a := NewClient("shared-shop", "token")
+	b := NewClient("shared-shop.myshopify.com", "other-token")
+	c := NewClient("other-shop", "token")
+
+	if a.bucket != b.bucket {
+		t.Error("clients for the same shop should share a bucket")
+	}
+
+	if a.bucket == c.bucket {
+		t.Error("clients for different shops should not share a bucket")
+	}
+}
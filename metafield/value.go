@@ -0,0 +1,249 @@
+// Package metafield checks metafield values against their types
+package metafield
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	colorRe   = regexp.MustCompile(`\A#[0-9A-Fa-f]{6}\z`)
+	decimalRe = regexp.MustCompile(`\A-?\d+(\.\d+)?\z`)
+	gidRe     = regexp.MustCompile(`\Agid://shopify/(\w+)/\S+\z`)
+	// ISO 4217
+	currencyRe = regexp.MustCompile(`\A[A-Z]{3}\z`)
+)
+
+// Units accepted by the measurement types
+var measurementUnits = map[string][]string{
+	"dimension": {"in", "ft", "yd", "mm", "cm", "m"},
+	"volume":    {"ml", "cl", "l", "m3", "us_fl_oz", "us_pt", "us_qt", "us_gal", "imp_fl_oz", "imp_pt", "imp_qt", "imp_gal"},
+	"weight":    {"oz", "lb", "g", "kg"},
+}
+
+// GID types of the reference types' values, an empty list accepts any
+var referenceTypes = map[string][]string{
+	"collection_reference":                 {"Collection"},
+	"company_reference":                    {"Company"},
+	"customer_reference":                   {"Customer"},
+	"file_reference":                       {"GenericFile", "MediaImage", "Video"},
+	"metaobject_reference":                 {"Metaobject"},
+	"mixed_reference":                      {"Metaobject"},
+	"order_reference":                      {"Order"},
+	"page_reference":                       {"Page"},
+	"product_reference":                    {"Product"},
+	"product_taxonomy_value_reference":     {"TaxonomyValue"},
+	"variant_reference":                    {"ProductVariant"},
+	"article_reference":                    {"Article"},
+	"blog_reference":                       {"Blog"},
+	"location_reference":                   {"Location"},
+	"market_reference":                     {"Market"},
+	"product_taxonomy_attribute_reference": {},
+}
+
+// ValidateValue checks that value is valid for the metafield type, e.g., that a
+// number_integer is an integer or that a list.product_reference is a JSON list of product GIDs.
+func ValidateValue(metafieldType, value string) error {
+	if itemType, ok := strings.CutPrefix(metafieldType, "list."); ok {
+		var items []json.RawMessage
+		if err := json.Unmarshal([]byte(value), &items); err != nil {
+			return fmt.Errorf("must be a JSON list")
+		}
+
+		for i, item := range items {
+			// Strings are validated by their content, anything else, e.g., numbers and objects, as is
+			itemValue := string(item)
+			if bytes.HasPrefix(item, []byte(`"`)) {
+				if err := json.Unmarshal(item, &itemValue); err != nil {
+					return fmt.Errorf("item %d is invalid: %s", i+1, err)
+				}
+			}
+
+			if err := validateScalarValue(itemType, itemValue); err != nil {
+				return fmt.Errorf("item %d %s", i+1, err)
+			}
+		}
+
+		return nil
+	}
+
+	return validateScalarValue(metafieldType, value)
+}
+
+func validateScalarValue(metafieldType, value string) error {
+	if gidTypes, ok := referenceTypes[metafieldType]; ok {
+		match := gidRe.FindStringSubmatch(value)
+		if match == nil {
+			return fmt.Errorf("must be a GID")
+		}
+
+		if len(gidTypes) > 0 && !contains(gidTypes, match[1]) {
+			return fmt.Errorf("must be the GID of a %s", strings.Join(gidTypes, " or "))
+		}
+
+		return nil
+	}
+
+	if units, ok := measurementUnits[metafieldType]; ok {
+		var measurement struct {
+			Value json.Number `json:"value"`
+			Unit  string      `json:"unit"`
+		}
+
+		if err := decodeObject(value, &measurement); err != nil {
+			return fmt.Errorf(`must be a JSON object with a value and unit, e.g., {"value": 2.5, "unit": "%s"}`, units[0])
+		}
+
+		if _, err := measurement.Value.Float64(); err != nil {
+			return fmt.Errorf("value must be a number")
+		}
+
+		if !contains(units, measurement.Unit) {
+			return fmt.Errorf("unit must be one of: %s", strings.Join(units, ", "))
+		}
+
+		return nil
+	}
+
+	switch metafieldType {
+	case "single_line_text_field":
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("cannot contain line breaks")
+		}
+	case "multi_line_text_field", "id":
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("must be true or false")
+		}
+	case "number_integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+	case "number_decimal":
+		if !decimalRe.MatchString(value) {
+			return fmt.Errorf("must be a decimal number")
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return fmt.Errorf("must be a date in YYYY-MM-DD format")
+		}
+	case "date_time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			if _, err := time.Parse("2006-01-02T15:04:05", value); err != nil {
+				return fmt.Errorf("must be a date and time in ISO 8601 format")
+			}
+		}
+	case "color":
+		if !colorRe.MatchString(value) {
+			return fmt.Errorf("must be a hex color, e.g., #FF0000")
+		}
+	case "url":
+		return validateURL(value)
+	case "link":
+		var link struct {
+			Text string `json:"text"`
+			URL  string `json:"url"`
+		}
+
+		if err := decodeObject(value, &link); err != nil {
+			return fmt.Errorf(`must be a JSON object with a text and url, e.g., {"text": "Shop", "url": "https://example.com"}`)
+		}
+
+		return validateURL(link.URL)
+	case "json":
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("must be valid JSON")
+		}
+	case "rich_text_field":
+		var root struct {
+			Type string `json:"type"`
+		}
+
+		if err := json.Unmarshal([]byte(value), &root); err != nil || root.Type != "root" {
+			return fmt.Errorf(`must be a JSON object with a type of "root"`)
+		}
+	case "money":
+		var money struct {
+			Amount       string `json:"amount"`
+			CurrencyCode string `json:"currency_code"`
+		}
+
+		if err := decodeObject(value, &money); err != nil {
+			return fmt.Errorf(`must be a JSON object with an amount and currency_code, e.g., {"amount": "5.99", "currency_code": "USD"}`)
+		}
+
+		if !decimalRe.MatchString(money.Amount) {
+			return fmt.Errorf("amount must be a decimal number")
+		}
+
+		if !currencyRe.MatchString(money.CurrencyCode) {
+			return fmt.Errorf("currency_code must be an ISO 4217 currency code, e.g., USD")
+		}
+	case "rating":
+		var rating struct {
+			Value    string `json:"value"`
+			ScaleMin string `json:"scale_min"`
+			ScaleMax string `json:"scale_max"`
+		}
+
+		if err := decodeObject(value, &rating); err != nil {
+			return fmt.Errorf(`must be a JSON object with a value, scale_min, and scale_max, e.g., {"value": "3.5", "scale_min": "1.0", "scale_max": "5.0"}`)
+		}
+
+		v, err := strconv.ParseFloat(rating.Value, 64)
+		if err != nil {
+			return fmt.Errorf("value must be a number")
+		}
+
+		min, err := strconv.ParseFloat(rating.ScaleMin, 64)
+		if err != nil {
+			return fmt.Errorf("scale_min must be a number")
+		}
+
+		max, err := strconv.ParseFloat(rating.ScaleMax, 64)
+		if err != nil {
+			return fmt.Errorf("scale_max must be a number")
+		}
+
+		if v < min || v > max {
+			return fmt.Errorf("value must be between scale_min and scale_max")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", metafieldType)
+	}
+
+	return nil
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || !contains([]string{"http", "https", "mailto", "sms", "tel"}, u.Scheme) {
+		return fmt.Errorf("must be an http, https, mailto, sms, or tel URL")
+	}
+
+	return nil
+}
+
+// decodeObject decodes the JSON object in value into v, failing if it has other properties
+func decodeObject(value string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package metafield
+
+import "testing"
+
+func TestValidateValue(t *testing.T) {
+	tests := []struct {
+		metafieldType string
+		value         string
+		valid         bool
+	}{
+		{"single_line_text_field", "Snug", true},
+		{"single_line_text_field", "Snug\nFit", false},
+		{"multi_line_text_field", "Snug\nFit", true},
+		{"boolean", "true", true},
+		{"boolean", "yes", false},
+		{"number_integer", "-12", true},
+		{"number_integer", "1.5", false},
+		{"number_decimal", "1.50", true},
+		{"number_decimal", "1,5", false},
+		{"date", "2026-10-18", true},
+		{"date", "10/18/2026", false},
+		{"date_time", "2026-10-18T10:00:00Z", true},
+		{"date_time", "2026-10-18T10:00:00", true},
+		{"date_time", "2026-10-18", false},
+		{"color", "#FF00aa", true},
+		{"color", "red", false},
+		{"url", "https://example.com", true},
+		{"url", "example.com", false},
+		{"url", "javascript:alert(1)", false},
+		{"link", `{"text": "Shop", "url": "https://example.com"}`, true},
+		{"link", `{"text": "Shop", "url": "ftp://example.com"}`, false},
+		{"json", `{"a": [1, 2]}`, true},
+		{"json", `{"a":`, false},
+		{"rich_text_field", `{"type": "root", "children": []}`, true},
+		{"rich_text_field", `{"type": "paragraph"}`, false},
+		{"money", `{"amount": "5.99", "currency_code": "USD"}`, true},
+		{"money", `{"amount": 5.99, "currency_code": "USD"}`, false},
+		{"money", `{"amount": "5.99", "currency_code": "usd"}`, false},
+		{"dimension", `{"value": 2.5, "unit": "cm"}`, true},
+		{"dimension", `{"value": 2.5, "unit": "kg"}`, false},
+		{"weight", `{"value": 1, "unit": "kg"}`, true},
+		{"volume", `{"value": "a", "unit": "ml"}`, false},
+		{"rating", `{"value": "3.5", "scale_min": "1.0", "scale_max": "5.0"}`, true},
+		{"rating", `{"value": "6", "scale_min": "1.0", "scale_max": "5.0"}`, false},
+		{"product_reference", "gid://shopify/Product/1", true},
+		{"product_reference", "gid://shopify/Collection/1", false},
+		{"product_reference", "1", false},
+		{"file_reference", "gid://shopify/MediaImage/1", true},
+		{"list.single_line_text_field", `["a", "b"]`, true},
+		{"list.single_line_text_field", `"a"`, false},
+		{"list.number_integer", `[1, 2]`, true},
+		{"list.number_integer", `[1, 2.5]`, false},
+		{"list.product_reference", `["gid://shopify/Product/1", "gid://shopify/Product/2"]`, true},
+		{"list.product_reference", `["gid://shopify/Product/1", "gid://shopify/Order/2"]`, false},
+		{"list.product_reference", `"gid://shopify/Product/1"`, false},
+		{"list.dimension", `[{"value": 2.5, "unit": "cm"}]`, true},
+		{"list.color", `["#FF0000", "blue"]`, false},
+		{"unknown", "x", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateValue(tt.metafieldType, tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateValue(%q, %q) failed: %s", tt.metafieldType, tt.value, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("ValidateValue(%q, %q) did not fail", tt.metafieldType, tt.value)
+		}
+	}
+}
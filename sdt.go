@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ScreenStaring/shopify-dev-tools/cmd"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/admin"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/auth"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/charges"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/collections"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/customers"
@@ -14,8 +16,11 @@ import (
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/locations"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/metafields"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/metaobjects"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/mockserver"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/orders"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/products"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/profile"
+	"github.com/ScreenStaring/shopify-dev-tools/cmd/schema"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/scripttags"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/shop"
 	"github.com/ScreenStaring/shopify-dev-tools/cmd/themes"
@@ -31,8 +36,21 @@ func main() {
 		Usage:                  "Shopify Development Tools",
 		Version:                version,
 		UseShortOptionHandling: true,
+		Flags:                  append(append([]cli.Flag{cmd.ProfileFlag, cmd.TokenCacheTTLFlag, cmd.AdminURLFlag}, cmd.FixtureFlags...), cmd.FanOutFlags...),
+		Before: func(c *cli.Context) error {
+			if err := cmd.ApplyProfile(c); err != nil {
+				return err
+			}
+
+			if err := cmd.UseFixtures(c); err != nil {
+				return err
+			}
+
+			return cmd.FanOut(c)
+		},
 		Commands: []*cli.Command{
 			&admin.Cmd,
+			&auth.Cmd,
 			&charges.Cmd,
 			&collections.Cmd,
 			&draftorders.Cmd,
@@ -40,9 +58,12 @@ func main() {
 			&locations.Cmd,
 			&metafields.Cmd,
 			&metaobjects.Cmd,
+			&mockserver.Cmd,
 			&orders.Cmd,
 			&products.Cmd,
+			&profile.Cmd,
 			&gql.Cmd,
+			&schema.Cmd,
 			&shop.Cmd,
 			&customers.Cmd,
 			&scripttags.Cmd,